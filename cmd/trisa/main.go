@@ -13,8 +13,10 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,6 +24,10 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/trisacrypto/trisa/pkg"
 	"github.com/trisacrypto/trisa/pkg/ivms101"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/conformance"
+	"github.com/trisacrypto/trisa/pkg/openvasp/traddr"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
 	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
 	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
 	env "github.com/trisacrypto/trisa/pkg/trisa/envelope"
@@ -30,6 +36,7 @@ import (
 	"github.com/trisacrypto/trisa/pkg/trisa/mtls"
 	"github.com/trisacrypto/trisa/pkg/trust"
 	cli "github.com/urfave/cli/v2"
+	"golang.org/x/term"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
@@ -119,6 +126,11 @@ func main() {
 					Aliases: []string{"t"},
 					Usage:   "path to transaction payload JSON to load",
 				},
+				&cli.StringFlag{
+					Name:    "transaction-type",
+					Aliases: []string{"type"},
+					Usage:   "the registered type of the transaction payload, e.g. trisa.data.generic.v1beta1.UTXOTransaction (optional)",
+				},
 				&cli.StringFlag{
 					Name:        "out",
 					Aliases:     []string{"o"},
@@ -241,7 +253,34 @@ func main() {
 				&cli.StringFlag{
 					Name:    "unsealing-key",
 					Aliases: []string{"key", "k"},
-					Usage:   "path to private key to unseal the secure envelope",
+					Usage:   "path to private key to unseal the secure envelope (prompts for a passphrase if encrypted)",
+				},
+			},
+		},
+		{
+			Name:      "diff",
+			Usage:     "compare two secure envelopes saved to disk, e.g. to resolve a disputed transfer",
+			UsageText: "trisa diff -k private.pem sent.json received.json",
+			ArgsUsage: "left right",
+			Action:    diff,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "unsealing-key",
+					Aliases: []string{"key", "k"},
+					Usage:   "path to private key to unseal both secure envelopes (prompts for a passphrase if encrypted)",
+				},
+				&cli.StringFlag{
+					Name:  "left-key",
+					Usage: "path to private key to unseal the left secure envelope (overrides -unsealing-key)",
+				},
+				&cli.StringFlag{
+					Name:  "right-key",
+					Usage: "path to private key to unseal the right secure envelope (overrides -unsealing-key)",
+				},
+				&cli.BoolFlag{
+					Name:    "json",
+					Aliases: []string{"j"},
+					Usage:   "print the comparison as json",
 				},
 			},
 		},
@@ -266,7 +305,7 @@ func main() {
 				&cli.StringFlag{
 					Name:    "unsealing-key",
 					Aliases: []string{"key", "k"},
-					Usage:   "path to private key to unseal the incoming secure envelope",
+					Usage:   "path to private key to unseal the incoming secure envelope (prompts for a passphrase if encrypted)",
 				},
 				&cli.BoolFlag{
 					Name:    "payload",
@@ -403,6 +442,117 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:  "certs",
+			Usage: "manage TRISA certificates and trust chains",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "pool",
+					Usage: "manage provider pools of public trust chains",
+					Subcommands: []*cli.Command{
+						{
+							Name:      "diff",
+							Usage:     "print the certificates added, removed, or changed between two pools",
+							ArgsUsage: "original updated",
+							Action:    poolDiff,
+							Flags: []cli.Flag{
+								&cli.StringFlag{
+									Name:    "password",
+									Aliases: []string{"p"},
+									Usage:   "the password of the pools if they are pkcs12 truststores",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "traddr",
+			Usage: "resolve, encode, and decode TRP travel addresses",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "resolve",
+					Usage:     "resolve a travel address, LNURL, URL, or user@vasp identifier to a TRP inquiry endpoint",
+					ArgsUsage: "address [address ...]",
+					Action:    traddrResolve,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "well-known-path",
+							Usage: "the path used to lookup the travel address of user@vasp identifiers",
+							Value: openvasp.WellKnownPath,
+						},
+						&cli.DurationFlag{
+							Name:    "timeout",
+							Aliases: []string{"t"},
+							Usage:   "the timeout of well-known travel address lookups",
+							Value:   30 * time.Second,
+						},
+					},
+				},
+				{
+					Name:      "encode",
+					Usage:     "encode a TRP inquiry URL as a travel address",
+					ArgsUsage: "url [url ...]",
+					Action:    traddrEncode,
+				},
+				{
+					Name:      "decode",
+					Usage:     "decode a travel address into a TRP inquiry URL",
+					ArgsUsage: "traddr [traddr ...]",
+					Action:    traddrDecode,
+				},
+			},
+		},
+		{
+			Name:  "trp",
+			Usage: "test TRP endpoints of counterparties and local servers",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "conformance",
+					Usage:     "run the TRP conformance scenarios against an endpoint and report the results",
+					ArgsUsage: "address",
+					Action:    trpConformance,
+					Flags: []cli.Flag{
+						&cli.StringSliceFlag{
+							Name:    "scenario",
+							Aliases: []string{"s"},
+							Usage:   "only run the specified scenarios (can be specified multiple times)",
+						},
+						&cli.StringFlag{
+							Name:    "api-version",
+							Aliases: []string{"v"},
+							Usage:   "the api version to send in valid requests",
+							Value:   openvasp.APIVersion,
+						},
+						&cli.DurationFlag{
+							Name:    "callback-timeout",
+							Aliases: []string{"t"},
+							Usage:   "how long to wait for callbacks from the endpoint, 0 to skip callback scenarios",
+							Value:   conformance.DefaultCallbackTimeout,
+						},
+						&cli.StringFlag{
+							Name:  "callback-addr",
+							Usage: "the address to listen for callbacks on (by default a local server is used)",
+						},
+						&cli.StringFlag{
+							Name:  "callback-url",
+							Usage: "the public url of the callback server that is sent to the endpoint",
+						},
+						&cli.BoolFlag{
+							Name:    "insecure",
+							Aliases: []string{"S"},
+							Usage:   "do not verify the tls certificate of the endpoint",
+						},
+						&cli.BoolFlag{
+							Name:    "json",
+							Aliases: []string{"j"},
+							Usage:   "print the report as json",
+						},
+					},
+				},
+			},
+		},
 	}
 	app.Run(os.Args)
 }
@@ -468,7 +618,7 @@ func envelope(c *cli.Context) (err error) {
 		return cli.Exit(err, 1)
 	}
 
-	if payload.Transaction, err = loadTransaction(c.String("transaction")); err != nil {
+	if payload.Transaction, err = loadTransaction(c.String("transaction"), c.String("transaction-type")); err != nil {
 		return cli.Exit(err, 1)
 	}
 
@@ -647,6 +797,95 @@ func open(c *cli.Context) (err error) {
 	return printJSON(unsealedEnvelope)
 }
 
+func diff(c *cli.Context) (err error) {
+	if c.NArg() != 2 {
+		return cli.Exit("specify the paths to the left and right secure envelopes", 1)
+	}
+
+	envelopes := make([]*env.Envelope, 2)
+	for i, side := range []string{"left", "right"} {
+		path := c.Args().Get(i)
+
+		var msg *api.SecureEnvelope
+		if msg, err = loadEnvelope(path); err != nil {
+			return cli.Exit(fmt.Errorf("could not load %s envelope: %s", side, err), 1)
+		}
+
+		opts := make([]env.Option, 0, 1)
+		keyPath := c.String(side + "-key")
+		if keyPath == "" {
+			keyPath = c.String("unsealing-key")
+		}
+
+		if keyPath != "" {
+			var unsealingKey interface{}
+			if unsealingKey, err = loadPrivateKey(keyPath); err != nil {
+				return cli.Exit(err, 1)
+			}
+			opts = append(opts, env.WithUnsealingKey(unsealingKey))
+		}
+
+		if envelopes[i], err = env.Wrap(msg, opts...); err != nil {
+			return cli.Exit(fmt.Errorf("could not wrap %s envelope: %s", side, err), 1)
+		}
+	}
+
+	var cmp *env.Comparison
+	if cmp, err = env.Compare(envelopes[0], envelopes[1]); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if c.Bool("json") {
+		return printJSON(cmp)
+	}
+
+	fmt.Printf("state:       %s / %s\n", cmp.Left, cmp.Right)
+	fmt.Printf("envelope id: %s\n", cmp.EnvelopeID)
+	fmt.Printf("ciphertext:  %s\n", cmp.Ciphertext)
+	fmt.Printf("hmac:        %s\n", cmp.HMAC)
+	fmt.Printf("error:       %s\n", cmp.Error)
+	fmt.Printf("payload:     %s\n", cmp.Payload)
+
+	if cmp.Payload == env.Incomparable && (cmp.Left == env.Sealed || cmp.Right == env.Sealed) {
+		fmt.Println("\nspecify unsealing keys to compare the payloads of sealed envelopes")
+	}
+
+	printDifferences("identity", cmp.Identity)
+	printDifferences("transaction", cmp.Transaction)
+	printTimestampDelta("sent_at", cmp.SentAt)
+	printTimestampDelta("received_at", cmp.ReceivedAt)
+	return nil
+}
+
+func printDifferences(name string, diffs []*env.Difference) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s:\n", name)
+	for _, d := range diffs {
+		fmt.Printf("~ %s\n", d.Path)
+		if d.Left != "" {
+			fmt.Printf("  - %s\n", d.Left)
+		}
+		if d.Right != "" {
+			fmt.Printf("  + %s\n", d.Right)
+		}
+	}
+}
+
+func printTimestampDelta(name string, delta *env.TimestampDelta) {
+	if delta == nil {
+		return
+	}
+
+	fmt.Printf("\n! %s differs", name)
+	if delta.Delta != 0 {
+		fmt.Printf(" by %s", delta.Delta)
+	}
+	fmt.Printf("\n  - %s\n  + %s\n", delta.Left, delta.Right)
+}
+
 //====================================================================================
 // TRISA RPC Commands
 //====================================================================================
@@ -876,6 +1115,199 @@ func search(c *cli.Context) (err error) {
 	return printJSON(rep)
 }
 
+//====================================================================================
+// Certificate Commands
+//====================================================================================
+
+func poolDiff(c *cli.Context) (err error) {
+	if c.NArg() != 2 {
+		return cli.Exit("specify the paths to the original and updated pools", 1)
+	}
+
+	pools := make([]trust.ProviderPool, 2)
+	for i, path := range c.Args().Slice() {
+		var sz *trust.Serializer
+		if sz, err = trust.NewSerializer(false, c.String("password")); err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		if pools[i], err = sz.ReadPoolFile(path); err != nil {
+			return cli.Exit(fmt.Errorf("could not read pool %s: %s", path, err), 1)
+		}
+	}
+
+	var diff *trust.PoolDiff
+	if diff, err = pools[0].Diff(pools[1]); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if diff.IsEmpty() {
+		fmt.Println("pools are identical")
+		return nil
+	}
+
+	for _, name := range sortedNames(diff.Added) {
+		fmt.Printf("+ %s\n", name)
+		certs, _ := diff.Added[name].GetCertificates()
+		printCerts("+", certs)
+	}
+
+	for _, name := range sortedNames(diff.Removed) {
+		fmt.Printf("- %s\n", name)
+		certs, _ := diff.Removed[name].GetCertificates()
+		printCerts("-", certs)
+	}
+
+	changed := make([]string, 0, len(diff.Changed))
+	for name := range diff.Changed {
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+
+	for _, name := range changed {
+		fmt.Printf("~ %s\n", name)
+		printCerts("+", diff.Changed[name].Added)
+		printCerts("-", diff.Changed[name].Removed)
+	}
+	return nil
+}
+
+func sortedNames(pool trust.ProviderPool) []string {
+	names := make([]string, 0, len(pool))
+	for name := range pool {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printCerts(prefix string, certs []*x509.Certificate) {
+	for _, cert := range certs {
+		fmt.Printf("    %s %s (expires %s)\n", prefix, cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		fmt.Printf("      sha256 %s\n", trust.Fingerprint(cert))
+	}
+}
+
+//====================================================================================
+// Travel Address Commands
+//====================================================================================
+
+func traddrResolve(c *cli.Context) (err error) {
+	if c.NArg() == 0 {
+		return cli.Exit("specify at least one address to resolve", 1)
+	}
+
+	var resolver *openvasp.Resolver
+	if resolver, err = openvasp.NewResolver(
+		openvasp.WithHTTPClient(&http.Client{Timeout: c.Duration("timeout")}),
+		openvasp.WithWellKnownPath(c.String("well-known-path")),
+	); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	for _, address := range c.Args().Slice() {
+		var info *trp.Info
+		if info, err = resolver.Resolve(c.Context, address); err != nil {
+			return cli.Exit(fmt.Errorf("could not resolve %s: %s", address, err), 1)
+		}
+		fmt.Println(info.Address)
+	}
+	return nil
+}
+
+func traddrEncode(c *cli.Context) (err error) {
+	if c.NArg() == 0 {
+		return cli.Exit("specify at least one url to encode", 1)
+	}
+
+	for _, uri := range c.Args().Slice() {
+		var address string
+		if address, err = traddr.Make(uri); err != nil {
+			return cli.Exit(fmt.Errorf("could not encode %s: %s", uri, err), 1)
+		}
+		fmt.Println(address)
+	}
+	return nil
+}
+
+func traddrDecode(c *cli.Context) (err error) {
+	if c.NArg() == 0 {
+		return cli.Exit("specify at least one travel address to decode", 1)
+	}
+
+	for _, address := range c.Args().Slice() {
+		var uri string
+		if uri, err = traddr.DecodeURL(address); err != nil {
+			return cli.Exit(fmt.Errorf("could not decode %s: %s", address, err), 1)
+		}
+		fmt.Println(uri)
+	}
+	return nil
+}
+
+//====================================================================================
+// TRP Commands
+//====================================================================================
+
+func trpConformance(c *cli.Context) (err error) {
+	if c.NArg() != 1 {
+		return cli.Exit("specify the address of the trp endpoint to test", 1)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if c.Bool("insecure") {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	opts := []conformance.Option{
+		conformance.WithHTTPClient(client),
+		conformance.WithAPIVersion(c.String("api-version")),
+		conformance.WithCallbackTimeout(c.Duration("callback-timeout")),
+	}
+
+	if addr := c.String("callback-addr"); addr != "" || c.String("callback-url") != "" {
+		opts = append(opts, conformance.WithCallbackServer(addr, c.String("callback-url")))
+	}
+
+	if scenarios := c.StringSlice("scenario"); len(scenarios) > 0 {
+		opts = append(opts, conformance.WithScenarios(scenarios...))
+	}
+
+	var suite *conformance.Suite
+	if suite, err = conformance.New(c.Args().First(), opts...); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var report *conformance.Report
+	if report, err = suite.Run(c.Context); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if c.Bool("json") {
+		if err = printJSON(report); err != nil {
+			return cli.Exit(err, 1)
+		}
+	} else {
+		fmt.Printf("conformance of %s\n\n", report.Endpoint)
+		for _, result := range report.Results {
+			fmt.Printf("%-4s  %-28s %s\n", strings.ToUpper(string(result.Status)), result.Scenario, result.Duration.Round(time.Millisecond))
+			if result.Message != "" {
+				fmt.Printf("      %s\n", result.Message)
+			}
+		}
+
+		passed, failed, skipped := report.Counts()
+		fmt.Printf("\n%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+	}
+
+	if !report.Passed() {
+		return cli.Exit("endpoint is not conformant", 1)
+	}
+	return nil
+}
+
 //====================================================================================
 // Helper Commands - Clients
 //====================================================================================
@@ -1089,11 +1521,39 @@ func loadPrivateKey(path string) (key interface{}, err error) {
 		switch block.Type {
 		case trust.BlockPrivateKey, trust.BlockRSAPrivateKey, trust.BlockECPrivateKey:
 			return trust.ParsePrivateKey(block)
+		case trust.BlockEncryptedKey:
+			var passphrase []byte
+			if passphrase, err = readPassphrase(path); err != nil {
+				return nil, err
+			}
+			return trust.DecryptPKCS8PrivateKey(block.Bytes, passphrase)
 		}
 	}
 	return nil, fmt.Errorf("could not find private key in key file %s", path)
 }
 
+// Read the passphrase for an encrypted private key from the $TRISA_KEY_PASSPHRASE
+// environment variable or prompt the user for it if stdin is a terminal.
+func readPassphrase(path string) (_ []byte, err error) {
+	if passphrase := os.Getenv("TRISA_KEY_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("key file %s is encrypted: set $TRISA_KEY_PASSPHRASE to decrypt it", path)
+	}
+
+	fmt.Fprintf(os.Stderr, "enter passphrase for %s: ", path)
+	var passphrase []byte
+	passphrase, err = term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("could not read passphrase: %s", err)
+	}
+	return passphrase, nil
+}
+
 func dumpKeys(key *api.SigningKey, path string) (err error) {
 	var data []byte
 	switch filepath.Ext(path) {
@@ -1158,7 +1618,7 @@ func loadIdentity(path string) (_ *anypb.Any, err error) {
 	return nil, fmt.Errorf("could not unmarshal identity: unknown type or format")
 }
 
-func loadTransaction(path string) (_ *anypb.Any, err error) {
+func loadTransaction(path, typeName string) (_ *anypb.Any, err error) {
 	opts := protojson.UnmarshalOptions{
 		AllowPartial:   true,
 		DiscardUnknown: false,
@@ -1169,6 +1629,19 @@ func loadTransaction(path string) (_ *anypb.Any, err error) {
 		return nil, fmt.Errorf("could not read transaction from %s", err)
 	}
 
+	// If the type is specified, the data must unmarshal as that type
+	if typeName != "" {
+		var transaction generic.Viewer
+		if transaction, err = generic.Resolve(typeName); err != nil {
+			return nil, err
+		}
+
+		if err = opts.Unmarshal(data, transaction); err != nil {
+			return nil, fmt.Errorf("could not unmarshal transaction as %s: %w", typeName, err)
+		}
+		return anypb.New(transaction)
+	}
+
 	// Attempt to unmarshal the data as a generic Transaction
 	transaction := &generic.Transaction{}
 	if err = opts.Unmarshal(data, transaction); err == nil {
@@ -1181,13 +1654,14 @@ func loadTransaction(path string) (_ *anypb.Any, err error) {
 		return anypb.New(pending)
 	}
 
-	// Attempt to unmarshal the data as a serialized any
+	// Attempt to unmarshal the data as a serialized any whose @type field specifies the
+	// type. Other registered types are not guessed since their fields often overlap.
 	msg := &anypb.Any{}
 	if err = opts.Unmarshal(data, msg); err == nil {
 		return msg, nil
 	}
 
-	return nil, fmt.Errorf("could not unmarshal transaction: unknown type or format")
+	return nil, fmt.Errorf("could not unmarshal transaction: specify the type with --transaction-type or an @type field")
 }
 
 func loadEnvelope(path string) (msg *api.SecureEnvelope, err error) {
@@ -11,6 +11,8 @@ require (
 	github.com/stretchr/testify v1.9.0
 	github.com/trisacrypto/lei v1.0.0
 	github.com/urfave/cli/v2 v2.27.2
+	golang.org/x/crypto v0.25.0
+	golang.org/x/term v0.22.0
 	google.golang.org/grpc v1.65.0
 	google.golang.org/protobuf v1.34.2
 	software.sslmate.com/src/go-pkcs12 v0.4.0
@@ -22,7 +24,6 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
-	golang.org/x/crypto v0.25.0 // indirect
 	golang.org/x/net v0.27.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
 	golang.org/x/text v0.16.0 // indirect
@@ -0,0 +1,82 @@
+/*
+Package memstore implements a generic in-memory store of records keyed by an identifier.
+It backs the default stores of packages that persist records between messages, which
+wrap it with their own record types, identifiers, and errors. Records are copied when
+they are stored and retrieved so that callers cannot modify the contents of the store
+without calling Put.
+*/
+package memstore
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config describes how the records of a store are identified, copied, and ordered.
+type Config[T any] struct {
+	Key      func(T) string    // Returns the identifier of the record
+	Clone    func(T) T         // Returns a copy of the record that shares no mutable state
+	Created  func(T) time.Time // Returns the timestamp that List orders records by
+	NotFound error             // Returned by Get and Delete if the record does not exist
+	NoKey    error             // Returned by Put if the record does not have an identifier
+}
+
+// Store is an in-memory map of records that is safe for concurrent use.
+type Store[T any] struct {
+	mu      sync.RWMutex
+	conf    Config[T]
+	records map[string]T
+}
+
+// New returns an empty store configured for the record type.
+func New[T any](conf Config[T]) *Store[T] {
+	return &Store[T]{conf: conf, records: make(map[string]T)}
+}
+
+// Get returns a copy of the record with the specified identifier.
+func (s *Store[T]) Get(key string) (record T, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.records[key]; ok {
+		return s.conf.Clone(r), nil
+	}
+	return record, s.conf.NotFound
+}
+
+// Put stores a copy of the record, replacing any record with the same identifier.
+func (s *Store[T]) Put(record T) error {
+	key := s.conf.Key(record)
+	if key == "" {
+		return s.conf.NoKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = s.conf.Clone(record)
+	return nil
+}
+
+// Delete the record with the specified identifier.
+func (s *Store[T]) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[key]; !ok {
+		return s.conf.NotFound
+	}
+	delete(s.records, key)
+	return nil
+}
+
+// List returns copies of all records ordered by their creation timestamp.
+func (s *Store[T]) List() ([]T, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]T, 0, len(s.records))
+	for _, r := range s.records {
+		out = append(out, s.conf.Clone(r))
+	}
+
+	sort.Slice(out, func(i, j int) bool { return s.conf.Created(out[i]).Before(s.conf.Created(out[j])) })
+	return out, nil
+}
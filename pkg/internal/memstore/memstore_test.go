@@ -0,0 +1,61 @@
+package memstore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/internal/memstore"
+)
+
+var (
+	errNotFound = errors.New("not found")
+	errNoKey    = errors.New("no key")
+)
+
+type record struct {
+	ID      string
+	Tags    []string
+	Created time.Time
+}
+
+func TestStore(t *testing.T) {
+	store := memstore.New(memstore.Config[*record]{
+		Key: func(r *record) string { return r.ID },
+		Clone: func(r *record) *record {
+			out := *r
+			out.Tags = append([]string(nil), r.Tags...)
+			return &out
+		},
+		Created:  func(r *record) time.Time { return r.Created },
+		NotFound: errNotFound,
+		NoKey:    errNoKey,
+	})
+
+	now := time.Now()
+	require.ErrorIs(t, store.Put(&record{}), errNoKey)
+	require.NoError(t, store.Put(&record{ID: "b", Created: now}))
+	require.NoError(t, store.Put(&record{ID: "a", Tags: []string{"foo"}, Created: now.Add(-time.Minute)}))
+
+	// Records are copied so they cannot be modified without calling Put
+	r, err := store.Get("a")
+	require.NoError(t, err)
+	r.Tags[0] = "bar"
+
+	r, err = store.Get("a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, r.Tags)
+
+	_, err = store.Get("c")
+	require.ErrorIs(t, err, errNotFound)
+
+	records, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, "a", records[0].ID)
+	require.Equal(t, "b", records[1].ID)
+
+	require.NoError(t, store.Delete("a"))
+	require.ErrorIs(t, store.Delete("a"), errNotFound)
+}
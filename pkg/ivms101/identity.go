@@ -419,3 +419,18 @@ func (p *PayloadMetadata) UnmarshalJSON(data []byte) (err error) {
 	p.TransliterationMethod = middle.TransliterationMethod
 	return nil
 }
+
+// Append an intermediary VASP to the end of the transfer path, assigning it the next
+// sequence number in the serial chain (sequence numbers start at 1).
+func (p *TransferPath) Append(vasp *Person) *IntermediaryVasp {
+	var sequence uint64
+	for _, intermediary := range p.TransferPath {
+		if intermediary.Sequence > sequence {
+			sequence = intermediary.Sequence
+		}
+	}
+
+	intermediary := &IntermediaryVasp{IntermediaryVasp: vasp, Sequence: sequence + 1}
+	p.TransferPath = append(p.TransferPath, intermediary)
+	return intermediary
+}
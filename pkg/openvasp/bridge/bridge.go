@@ -0,0 +1,376 @@
+/*
+Package bridge implements a gateway between the TRP and TRISA protocols so that a TRISA
+node can exchange travel rule information with counterparties that only implement TRP.
+
+Incoming TRP inquiries are converted into TRISA envelopes (or the TRISA envelope in the
+sealed or unsealed extension of the inquiry is opened) and forwarded to the TRISA node
+with a transfer RPC; the TRISA reply is then converted into a TRP resolution using a
+configurable Mapping. Inquiries that are acknowledged because the TRISA node has not yet
+decided are tracked by envelope ID so that a later TRISA reply for the envelope can be
+posted to the inquiry callback as a resolution with PostResolution. Outgoing TRISA
+envelopes are sent to TRP counterparties as inquiries with the sealed-trisa-envelope
+extension, sealed with the public key of the x509 certificate from the identity
+endpoint of the counterparty.
+*/
+package bridge
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/client"
+	"github.com/trisacrypto/trisa/pkg/openvasp/tracker"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trisa/peers"
+)
+
+// Peer is the TRISA node that handles the compliance of inquiries received by the bridge.
+type Peer interface {
+	Transfer(in *api.SecureEnvelope) (*api.SecureEnvelope, error)
+	ExchangeKeys(force bool) (*rsa.PublicKey, error)
+}
+
+// Ensure that peers in the TRISA network can be used with the bridge.
+var _ Peer = &peers.Peer{}
+
+// Bridge forwards TRP inquiries to a TRISA peer and TRISA envelopes to TRP counterparties.
+type Bridge struct {
+	peer         Peer
+	unsealingKey interface{}
+	mapping      *Mapping
+	client       *client.Client
+	tracker      *tracker.Tracker
+	envelopeOpts []envelope.Option
+}
+
+// Ensure the bridge can handle TRP inquiries.
+var _ openvasp.InquiryHandler = &Bridge{}
+
+// New creates a bridge that forwards inquiries to the TRISA peer. The unsealing key is
+// the private key of the bridge (a keys.PrivateKey or an *rsa.PrivateKey) that is used to
+// open the replies of the peer and the sealed envelope extensions of TRP inquiries.
+func New(peer Peer, unsealingKey interface{}, opts ...Option) (bridge *Bridge, err error) {
+	if peer == nil {
+		return nil, ErrNoPeer
+	}
+
+	bridge = &Bridge{
+		peer:         peer,
+		unsealingKey: unsealingKey,
+		mapping:      DefaultMapping(),
+	}
+
+	for _, opt := range opts {
+		if err = opt(bridge); err != nil {
+			return nil, err
+		}
+	}
+
+	if bridge.client == nil {
+		if bridge.client, err = client.New(); err != nil {
+			return nil, err
+		}
+	}
+
+	if bridge.tracker == nil {
+		if bridge.tracker, err = tracker.New(); err != nil {
+			return nil, err
+		}
+	}
+	return bridge, nil
+}
+
+// Handler returns the http handler that receives TRP inquiries for the bridge.
+func (b *Bridge) Handler() http.Handler {
+	return openvasp.TransferInquiry(b)
+}
+
+//===========================================================================
+// TRP to TRISA
+//===========================================================================
+
+// OnInquiry forwards the inquiry to the TRISA peer and converts its reply into a TRP
+// resolution. If the peer cannot be reached a 502 status error is returned. If the
+// inquiry is acknowledged, it is tracked by the envelope ID so that it can be resolved
+// with PostResolution when the peer replies later.
+func (b *Bridge) OnInquiry(in *trp.Inquiry) (_ *trp.Resolution, err error) {
+	var inquiry *openvasp.Inquiry
+	if inquiry, err = openvasp.UnwrapInquiry(in, b.unsealingKey); err != nil {
+		return nil, &trp.StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	env := inquiry.Envelope
+	if env == nil {
+		if env, err = openvasp.InquiryToEnvelope(in, b.envelopeOpts...); err != nil {
+			return nil, &trp.StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+	}
+
+	var key *rsa.PublicKey
+	if key, err = b.peer.ExchangeKeys(false); err != nil {
+		return nil, &trp.StatusError{Code: http.StatusBadGateway, Message: "could not exchange keys with trisa node"}
+	}
+
+	var msg *api.SecureEnvelope
+	if msg, err = seal(env, key); err != nil {
+		return nil, err
+	}
+
+	var rep *api.SecureEnvelope
+	if rep, err = b.peer.Transfer(msg); err != nil {
+		// Rejections may be returned as gRPC status errors with the TRISA error details
+		if reject, ok := api.Errorp(err); ok {
+			return b.mapping.Rejection(reject)
+		}
+		return nil, &trp.StatusError{Code: http.StatusBadGateway, Message: "could not forward inquiry to trisa node"}
+	}
+
+	var out *trp.Resolution
+	if out, err = b.Resolve(rep); err != nil || out != nil {
+		return out, err
+	}
+
+	// The inquiry may already be tracked if the counterparty retried it
+	if _, err = b.tracker.Track(env.ID(), in); err != nil && !errors.Is(err, tracker.ErrExists) {
+		return nil, err
+	}
+
+	if _, err = b.tracker.Resolve(env.ID(), &trp.Resolution{Version: openvasp.APIVersion}); err != nil && !errors.Is(err, tracker.ErrInvalidTransition) {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// PostResolution converts a later reply of the TRISA peer to an acknowledged inquiry
+// into a TRP resolution and posts it to the callback of the inquiry, which is looked up
+// by the ID of the reply envelope. If the reply is still not mapped to an approval or a
+// rejection, nothing is posted and a nil resolution is returned.
+func (b *Bridge) PostResolution(ctx context.Context, rep *api.SecureEnvelope) (out *trp.Resolution, err error) {
+	var transfer *tracker.Transfer
+	if transfer, err = b.tracker.Get(rep.Id); err != nil {
+		return nil, fmt.Errorf("could not find inquiry for envelope %q: %w", rep.Id, err)
+	}
+
+	if transfer.State != tracker.Pending {
+		return nil, fmt.Errorf("%w: inquiry for envelope %q is %s", tracker.ErrInvalidTransition, rep.Id, transfer.State)
+	}
+
+	if out, err = b.Resolve(rep); err != nil || out == nil {
+		return nil, err
+	}
+
+	out.Info = &trp.Info{Address: transfer.Inquiry.Callback}
+	if transfer.Inquiry.Info != nil {
+		out.Info.RequestIdentifier = transfer.Inquiry.Info.RequestIdentifier
+	}
+
+	if err = b.client.Resolve(ctx, out); err != nil {
+		return nil, err
+	}
+
+	if _, err = b.tracker.Resolve(rep.Id, out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// Resolve converts the reply of the TRISA peer into a TRP resolution using the mapping.
+func (b *Bridge) Resolve(rep *api.SecureEnvelope) (_ *trp.Resolution, err error) {
+	var env *envelope.Envelope
+	if env, err = envelope.Wrap(rep); err != nil {
+		return nil, fmt.Errorf("could not parse trisa reply: %w", err)
+	}
+
+	if env.IsError() {
+		return b.mapping.Rejection(env.Error())
+	}
+
+	if env, _, err = envelope.Open(rep, envelope.WithUnsealingKey(b.unsealingKey)); err != nil {
+		return nil, fmt.Errorf("could not open trisa reply: %w", err)
+	}
+
+	switch b.mapping.Outcome(env.TransferState()) {
+	case Approve:
+		var payload *api.Payload
+		if payload, err = env.Payload(); err != nil {
+			return nil, err
+		}
+
+		approval := &trp.Approval{Callback: b.mapping.ConfirmationCallback}
+		if approval.Address, err = beneficiaryAddress(payload); err != nil {
+			return nil, err
+		}
+		return &trp.Resolution{Approved: approval}, nil
+
+	case Reject:
+		return &trp.Resolution{Rejected: DefaultRejection}, nil
+
+	default:
+		// A nil resolution acknowledges the inquiry with the api version
+		return nil, nil
+	}
+}
+
+// Returns the beneficiary address from a TRP message or any registered transaction type.
+func beneficiaryAddress(payload *api.Payload) (_ string, err error) {
+	var address string
+	switch {
+	case payload.Transaction == nil:
+	case payload.Transaction.MessageIs(&generic.TRP{}):
+		msg := &generic.TRP{}
+		if err = payload.Transaction.UnmarshalTo(msg); err != nil {
+			return "", err
+		}
+
+		if address = msg.GetApproved().GetAddress(); address == "" {
+			address = msg.GetTransaction().GetBeneficiary()
+		}
+
+	default:
+		var view *generic.Transaction
+		if view, err = generic.View(payload.Transaction); err != nil && !errors.Is(err, generic.ErrUnknownTransaction) {
+			return "", err
+		}
+		address = view.GetBeneficiary()
+	}
+
+	if address == "" {
+		return "", ErrNoBeneficiaryAddress
+	}
+	return address, nil
+}
+
+//===========================================================================
+// TRISA to TRP
+//===========================================================================
+
+// Send the TRISA envelope to the TRP counterparty at the address as an inquiry with the
+// sealed-trisa-envelope extension. If the envelope is not sealed, it is sealed with the
+// public key of the certificate from the identity endpoint of the counterparty. The
+// request identifier of the inquiry is the envelope ID.
+func (b *Bridge) Send(ctx context.Context, address string, env *envelope.Envelope) (_ *trp.Resolution, err error) {
+	if env.State() != envelope.Sealed {
+		var key *rsa.PublicKey
+		if key, err = b.SealingKey(ctx, address); err != nil {
+			return nil, err
+		}
+
+		var msg *api.SecureEnvelope
+		if msg, err = seal(env, key); err != nil {
+			return nil, err
+		}
+
+		if env, err = envelope.Wrap(msg); err != nil {
+			return nil, err
+		}
+	}
+
+	var inquiry *trp.Inquiry
+	if inquiry, err = openvasp.EnvelopeToPayload(env); err != nil {
+		return nil, err
+	}
+
+	inquiry.Callback = b.mapping.ResolutionCallback
+	inquiry.Info = &trp.Info{
+		Address:           address,
+		RequestIdentifier: env.ID(),
+	}
+
+	return b.client.Inquiry(ctx, inquiry)
+}
+
+// SealingKey returns the RSA public key of the x509 certificate from the identity
+// endpoint of the TRP counterparty at the address.
+func (b *Bridge) SealingKey(ctx context.Context, address string) (_ *rsa.PublicKey, err error) {
+	var identity *trp.Identity
+	if identity, err = b.client.Identity(ctx, address); err != nil {
+		return nil, fmt.Errorf("could not retrieve counterparty identity: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(identity.X509))
+	if block == nil {
+		return nil, ErrNoIdentityCertificate
+	}
+
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return nil, fmt.Errorf("could not parse counterparty certificate: %w", err)
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	return key, nil
+}
+
+// Encrypts the envelope if necessary and seals it with the public key.
+func seal(env *envelope.Envelope, key *rsa.PublicKey) (_ *api.SecureEnvelope, err error) {
+	var reject *api.Error
+	if env.State() == envelope.Clear {
+		if env, reject, err = env.Encrypt(); err != nil {
+			return nil, fmt.Errorf("could not encrypt trisa envelope: %w", errorOf(reject, err))
+		}
+	}
+
+	if env, reject, err = env.Seal(envelope.WithSealingKey(key)); err != nil {
+		return nil, fmt.Errorf("could not seal trisa envelope: %w", errorOf(reject, err))
+	}
+	return env.Proto(), nil
+}
+
+func errorOf(reject *api.Error, err error) error {
+	if reject != nil {
+		return reject
+	}
+	return err
+}
+
+//===========================================================================
+// Bridge Options
+//===========================================================================
+
+// Option configures the bridge when it is created.
+type Option func(b *Bridge) error
+
+// Specify how TRISA replies are mapped to TRP resolutions and the callbacks of the bridge.
+func WithMapping(mapping *Mapping) Option {
+	return func(b *Bridge) error {
+		b.mapping = mapping
+		return nil
+	}
+}
+
+// Specify the TRP client used to send inquiries to TRP counterparties.
+func WithClient(client *client.Client) Option {
+	return func(b *Bridge) error {
+		b.client = client
+		return nil
+	}
+}
+
+// Specify the tracker used to correlate acknowledged inquiries with later TRISA replies
+// (an in-memory tracker by default).
+func WithTracker(tracker *tracker.Tracker) Option {
+	return func(b *Bridge) error {
+		b.tracker = tracker
+		return nil
+	}
+}
+
+// Specify options applied to the envelopes created from TRP inquiries.
+func WithEnvelopeOptions(opts ...envelope.Option) Option {
+	return func(b *Bridge) error {
+		b.envelopeOpts = append(b.envelopeOpts, opts...)
+		return nil
+	}
+}
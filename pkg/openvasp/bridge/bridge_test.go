@@ -0,0 +1,397 @@
+package bridge_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/bridge"
+	"github.com/trisacrypto/trisa/pkg/openvasp/tracker"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	beneficiaryAddress = "bc1qbridgebeneficiary"
+	confirmationURL    = "https://bridge.example.com/confirm"
+)
+
+func TestOnInquiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate bridge key")
+
+	peer := newMockPeer(t, &key.PublicKey)
+
+	mapping := bridge.DefaultMapping()
+	mapping.ConfirmationCallback = confirmationURL
+
+	b, err := bridge.New(peer, key, bridge.WithMapping(mapping))
+	require.NoError(t, err, "could not create bridge")
+
+	ts := httptest.NewServer(b.Handler())
+	defer ts.Close()
+
+	t.Run("Approved", func(t *testing.T) {
+		peer.Reply(api.TransferAccepted, nil)
+		out, status := postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusOK, status)
+		require.NotNil(t, out.Approved)
+		require.Equal(t, beneficiaryAddress, out.Approved.Address)
+		require.Equal(t, confirmationURL, out.Approved.Callback)
+
+		// The peer should have received a TRP inquiry in a TRISA envelope
+		require.Equal(t, api.TransferStarted, peer.received.TransferState())
+		payload, err := peer.received.Payload()
+		require.NoError(t, err)
+		require.True(t, payload.Transaction.MessageIs(&generic.TRP{}))
+	})
+
+	t.Run("Pending", func(t *testing.T) {
+		peer.Reply(api.TransferPending, nil)
+		out, status := postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusOK, status)
+		require.Nil(t, out.Approved)
+		require.Empty(t, out.Rejected)
+		require.Equal(t, openvasp.APIVersion, out.Version)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		peer.Reply(api.TransferStateUnspecified, &api.Error{Code: api.ComplianceCheckFail, Message: "sanctioned beneficiary"})
+		out, status := postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "sanctioned beneficiary", out.Rejected)
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		peer.Reply(api.TransferStateUnspecified, &api.Error{Code: api.Unavailable, Message: "try again later"})
+		_, status := postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusServiceUnavailable, status)
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		peer.err = errors.New("connection refused")
+		defer func() { peer.err = nil }()
+
+		_, status := postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusBadGateway, status)
+	})
+
+	t.Run("CustomMapping", func(t *testing.T) {
+		mapping.States[api.TransferPending] = bridge.Reject
+		mapping.Errors = nil
+		defer func() {
+			delete(mapping.States, api.TransferPending)
+			mapping.Errors = bridge.DefaultMapping().Errors
+		}()
+
+		peer.Reply(api.TransferPending, nil)
+		out, status := postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, bridge.DefaultRejection, out.Rejected)
+
+		peer.Reply(api.TransferStateUnspecified, &api.Error{Code: api.Unavailable, Message: "try again later"})
+		out, status = postInquiry(t, ts.URL, "")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "try again later", out.Rejected)
+	})
+}
+
+func TestPostResolution(t *testing.T) {
+	// The originator receives the resolution on its inquiry callback
+	originator := &mockCounterparty{}
+	srv, err := openvasp.NewServer(originator)
+	require.NoError(t, err)
+
+	ots := httptest.NewServer(srv)
+	defer ots.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate bridge key")
+	peer := newMockPeer(t, &key.PublicKey)
+
+	mapping := bridge.DefaultMapping()
+	mapping.ConfirmationCallback = confirmationURL
+
+	b, err := bridge.New(peer, key, bridge.WithMapping(mapping))
+	require.NoError(t, err, "could not create bridge")
+
+	ts := httptest.NewServer(b.Handler())
+	defer ts.Close()
+
+	// The inquiry is acknowledged while the transfer is pending
+	peer.Reply(api.TransferPending, nil)
+	out, status := postInquiry(t, ts.URL, ots.URL+openvasp.ResolutionEndpoint)
+	require.Equal(t, http.StatusOK, status)
+	require.Nil(t, out.Approved)
+	require.Equal(t, openvasp.APIVersion, out.Version)
+
+	// A pending reply does not resolve the inquiry
+	ctx := context.Background()
+	pending, err := peer.replyTo(peer.received)
+	require.NoError(t, err)
+
+	out, err = b.PostResolution(ctx, pending)
+	require.NoError(t, err)
+	require.Nil(t, out)
+	require.Nil(t, originator.resolution)
+
+	// Once the transfer is accepted the approval is posted to the inquiry callback
+	peer.Reply(api.TransferAccepted, nil)
+	accepted, err := peer.replyTo(peer.received)
+	require.NoError(t, err)
+
+	out, err = b.PostResolution(ctx, accepted)
+	require.NoError(t, err)
+	require.NotNil(t, out.Approved)
+	require.Equal(t, beneficiaryAddress, out.Approved.Address)
+
+	require.NotNil(t, originator.resolution, "originator did not receive the resolution")
+	require.Equal(t, beneficiaryAddress, originator.resolution.Approved.Address)
+	require.Equal(t, confirmationURL, originator.resolution.Approved.Callback)
+	require.Equal(t, "a6b0d3f3-1f3c-4a8e-9c64-2d2a4b0f3e51", originator.resolution.Info.RequestIdentifier)
+
+	// The inquiry can only be resolved once
+	_, err = b.PostResolution(ctx, accepted)
+	require.ErrorIs(t, err, tracker.ErrInvalidTransition)
+
+	// Replies to envelopes that were not acknowledged by the bridge cannot be resolved
+	env, err := envelope.New(loadPayload(t))
+	require.NoError(t, err)
+	_, err = b.PostResolution(ctx, env.Proto())
+	require.ErrorIs(t, err, tracker.ErrNotFound)
+}
+
+func TestSend(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate counterparty key")
+
+	counterparty := &mockCounterparty{key: key, cert: selfSigned(t, key)}
+	srv, err := openvasp.NewServer(counterparty)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	mapping := bridge.DefaultMapping()
+	mapping.ResolutionCallback = "https://bridge.example.com/resolve"
+
+	peer := newMockPeer(t, &key.PublicKey)
+	b, err := bridge.New(peer, nil, bridge.WithMapping(mapping))
+	require.NoError(t, err)
+
+	env, err := envelope.New(loadPayload(t))
+	require.NoError(t, err)
+
+	out, err := b.Send(context.Background(), ts.URL, env)
+	require.NoError(t, err)
+	require.NotNil(t, out.Approved)
+	require.Equal(t, beneficiaryAddress, out.Approved.Address)
+
+	require.NotNil(t, counterparty.received, "counterparty did not open the sealed envelope")
+	require.Equal(t, env.ID(), counterparty.received.ID())
+	require.Equal(t, env.ID(), counterparty.inquiry.Info.RequestIdentifier)
+	require.Equal(t, mapping.ResolutionCallback, counterparty.inquiry.Callback)
+
+	t.Run("NoCertificate", func(t *testing.T) {
+		counterparty.cert = ""
+		defer func() { counterparty.cert = selfSigned(t, key) }()
+
+		_, err := b.Send(context.Background(), ts.URL, env)
+		require.ErrorIs(t, err, bridge.ErrNoIdentityCertificate)
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := bridge.New(nil, nil)
+	require.ErrorIs(t, err, bridge.ErrNoPeer)
+}
+
+//===========================================================================
+// Mocks and Helpers
+//===========================================================================
+
+// mockPeer opens envelopes with its own key and replies with the configured transfer
+// state or error, sealing replies with the public key of the bridge.
+type mockPeer struct {
+	key      *rsa.PrivateKey
+	bridge   *rsa.PublicKey
+	state    api.TransferState
+	reject   *api.Error
+	err      error
+	received *envelope.Envelope
+}
+
+func newMockPeer(t *testing.T, bridge *rsa.PublicKey) *mockPeer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate peer key")
+	return &mockPeer{key: key, bridge: bridge}
+}
+
+func (m *mockPeer) Reply(state api.TransferState, reject *api.Error) {
+	m.state = state
+	m.reject = reject
+}
+
+func (m *mockPeer) ExchangeKeys(bool) (*rsa.PublicKey, error) {
+	return &m.key.PublicKey, nil
+}
+
+func (m *mockPeer) Transfer(in *api.SecureEnvelope) (_ *api.SecureEnvelope, err error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.received, _, err = envelope.Open(in, envelope.WithUnsealingKey(m.key)); err != nil {
+		return nil, err
+	}
+	return m.replyTo(m.received)
+}
+
+// Creates a reply to the envelope with the configured transfer state or error, e.g. to
+// send a later reply to an envelope that was acknowledged.
+func (m *mockPeer) replyTo(received *envelope.Envelope) (_ *api.SecureEnvelope, err error) {
+	if m.reject != nil {
+		var env *envelope.Envelope
+		if env, err = received.Reject(m.reject); err != nil {
+			return nil, err
+		}
+		return env.Proto(), nil
+	}
+
+	var payload *api.Payload
+	if payload, err = received.Payload(); err != nil {
+		return nil, err
+	}
+
+	payload = proto.Clone(payload).(*api.Payload)
+	payload.ReceivedAt = time.Now().Format(time.RFC3339)
+	if payload.Transaction, err = anypb.New(&generic.Transaction{Beneficiary: beneficiaryAddress}); err != nil {
+		return nil, err
+	}
+
+	var env *envelope.Envelope
+	if env, err = received.Update(payload, envelope.WithTransferState(m.state)); err != nil {
+		return nil, err
+	}
+
+	if env, _, err = env.Encrypt(); err != nil {
+		return nil, err
+	}
+
+	if env, _, err = env.Seal(envelope.WithSealingKey(m.bridge)); err != nil {
+		return nil, err
+	}
+	return env.Proto(), nil
+}
+
+// mockCounterparty is a TRP-only node that opens sealed TRISA envelope extensions.
+type mockCounterparty struct {
+	key        *rsa.PrivateKey
+	cert       string
+	inquiry    *trp.Inquiry
+	received   *envelope.Envelope
+	resolution *trp.Resolution
+}
+
+func (m *mockCounterparty) OnIdentity() (*trp.Identity, error) {
+	return &trp.Identity{Name: "TRP Counterparty", X509: m.cert}, nil
+}
+
+func (m *mockCounterparty) OnInquiry(in *trp.Inquiry) (*trp.Resolution, error) {
+	inquiry, err := openvasp.UnwrapInquiry(in, m.key)
+	if err != nil {
+		return nil, err
+	}
+
+	m.inquiry = in
+	m.received = inquiry.Envelope
+	return &trp.Resolution{Approved: &trp.Approval{Address: beneficiaryAddress, Callback: confirmationURL}}, nil
+}
+
+func (m *mockCounterparty) OnResolution(in *trp.Resolution) error {
+	m.resolution = in
+	return nil
+}
+
+func (m *mockCounterparty) OnConfirmation(*trp.Confirmation) error {
+	return nil
+}
+
+// Posts the inquiry fixture to the url, replacing its callback if one is specified.
+func postInquiry(t *testing.T, url, callback string) (*trp.Resolution, int) {
+	data, err := os.ReadFile("../testdata/inquiry.json")
+	require.NoError(t, err, "could not read inquiry fixture")
+
+	if callback != "" {
+		in := &trp.Inquiry{}
+		require.NoError(t, json.Unmarshal(data, in))
+		in.Callback = callback
+
+		data, err = json.Marshal(in)
+		require.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set(openvasp.APIVersionHeader, openvasp.APIVersion)
+	req.Header.Set(openvasp.RequestIdentifierHeader, "a6b0d3f3-1f3c-4a8e-9c64-2d2a4b0f3e51")
+	req.Header.Set(openvasp.ContentTypeHeader, openvasp.ContentTypeValue)
+
+	rep, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		return nil, rep.StatusCode
+	}
+
+	out := &trp.Resolution{}
+	require.NoError(t, json.NewDecoder(rep.Body).Decode(out))
+	return out, rep.StatusCode
+}
+
+func loadPayload(t *testing.T) *api.Payload {
+	data, err := os.ReadFile("../testdata/inquiry.json")
+	require.NoError(t, err, "could not read inquiry fixture")
+
+	in := &trp.Inquiry{}
+	require.NoError(t, json.Unmarshal(data, in))
+
+	env, err := openvasp.InquiryToEnvelope(in)
+	require.NoError(t, err)
+
+	payload, err := env.Payload()
+	require.NoError(t, err)
+	return payload
+}
+
+func selfSigned(t *testing.T, key *rsa.PrivateKey) string {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "counterparty.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err, "could not create certificate")
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
@@ -0,0 +1,10 @@
+package bridge
+
+import "errors"
+
+var (
+	ErrNoPeer                = errors.New("a trisa peer is required to create a bridge")
+	ErrNoBeneficiaryAddress  = errors.New("trisa reply does not contain a beneficiary address")
+	ErrNoIdentityCertificate = errors.New("counterparty identity does not contain an x509 certificate")
+	ErrUnsupportedKey        = errors.New("counterparty certificate does not contain an rsa public key")
+)
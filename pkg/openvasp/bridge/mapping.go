@@ -0,0 +1,78 @@
+package bridge
+
+import (
+	"net/http"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+)
+
+// Outcome is the TRP resolution of an inquiry that is sent in response to a TRISA reply.
+type Outcome uint8
+
+const (
+	Acknowledge Outcome = iota // respond with the api version and resolve with a callback later
+	Approve                    // approve the inquiry with the beneficiary address of the reply
+	Reject                     // reject the inquiry with a human readable reason
+)
+
+// DefaultRejection is the reason sent in TRP rejections if the TRISA reply does not
+// contain an error message.
+const DefaultRejection = "transfer rejected by beneficiary"
+
+// Mapping describes how TRISA replies are converted into TRP resolutions.
+type Mapping struct {
+	// The TRP outcome for each transfer state of a TRISA reply. States that are not in
+	// the map are acknowledged.
+	States map[api.TransferState]Outcome
+
+	// TRISA error codes that are returned to the TRP counterparty as an HTTP status
+	// error (e.g. 503 if the compliance handler is unavailable) rather than as a
+	// rejection of the inquiry. Errors with codes that are not in the map are rejections.
+	Errors map[api.Error_Code]int
+
+	// The callback URL sent in approvals (for confirmations) and in outgoing inquiries
+	// (for resolutions), e.g. the callback endpoints of an openvasp.Server.
+	ConfirmationCallback string
+	ResolutionCallback   string
+}
+
+// DefaultMapping approves accepted and completed transfers, rejects rejected transfers,
+// acknowledges transfers that are still in progress, and returns errors that indicate
+// that the TRISA node is unavailable as HTTP status errors.
+func DefaultMapping() *Mapping {
+	return &Mapping{
+		States: map[api.TransferState]Outcome{
+			api.TransferAccepted:  Approve,
+			api.TransferCompleted: Approve,
+			api.TransferRejected:  Reject,
+		},
+		Errors: map[api.Error_Code]int{
+			api.Unavailable:   http.StatusServiceUnavailable,
+			api.Maintenance:   http.StatusServiceUnavailable,
+			api.Unimplemented: http.StatusNotImplemented,
+			api.InternalError: http.StatusBadGateway,
+		},
+	}
+}
+
+// Outcome returns the outcome of the transfer state.
+func (m *Mapping) Outcome(state api.TransferState) Outcome {
+	if outcome, ok := m.States[state]; ok {
+		return outcome
+	}
+	return Acknowledge
+}
+
+// Rejection converts a TRISA error into a TRP rejection or a status error.
+func (m *Mapping) Rejection(reject *api.Error) (*trp.Resolution, error) {
+	if code, ok := m.Errors[reject.Code]; ok {
+		return nil, &trp.StatusError{Code: code, Message: reject.Message}
+	}
+
+	out := &trp.Resolution{Rejected: reject.Message}
+	if out.Rejected == "" {
+		out.Rejected = DefaultRejection
+	}
+	return out, nil
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/trisacrypto/trisa/pkg/openvasp"
 	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/discoverability"
+	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/signing"
 	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
 )
 
@@ -46,13 +50,42 @@ func New(opts ...ClientOption) (client *Client, err error) {
 		}
 	}
 
+	// Wrap the transport configured by the options to sign messages
+	if client.signer != nil {
+		hc := *client.client
+		hc.Transport = &signing.Transport{
+			Base:         hc.Transport,
+			Signer:       client.signer,
+			Certificates: signing.NewIdentityCertificates(client),
+			Required:     client.signingRequired,
+		}
+		client.client = &hc
+
+		if !slices.Contains(client.extensions, signing.Extension) {
+			client.extensions = append(client.extensions, signing.Extension)
+		}
+	}
+
+	// Wrap the transport with the middleware so that middleware applies to every
+	// attempt to send a (signed) request.
+	if len(client.middleware) > 0 {
+		hc := *client.client
+		hc.Transport = Chain(hc.Transport, client.middleware...)
+		client.client = &hc
+	}
+
 	return client, nil
 }
 
 type Client struct {
-	client     *http.Client
-	apiVersion string
-	extensions []string
+	client          *http.Client
+	apiVersion      string
+	extensions      []string
+	versions        *openvasp.VersionRange // if not nil, negotiate versions with counterparties
+	negotiated      sync.Map               // negotiated api versions by counterparty host
+	signer          *signing.Signer        // if not nil, sign messages with the signing extension
+	signingRequired bool
+	middleware      []Middleware // wraps the http transport, outermost first
 }
 
 // Ensure the Client implements the TRPv3 and Discoverability Interfaces
@@ -71,6 +104,59 @@ func (c *Client) APIExtensions() []string {
 	return c.extensions
 }
 
+// Negotiate returns the TRP API version to use in requests to the counterparty at the
+// specified address. If version negotiation is not enabled, the default API version of
+// the client is returned. Otherwise the version endpoint of the counterparty is queried
+// and its version is used if it is in the range supported by the client; if the
+// counterparty does not implement the discoverability extension, the default API
+// version is used. Negotiated versions are cached by the host of the counterparty.
+func (c *Client) Negotiate(ctx context.Context, address string) (version string, err error) {
+	if c.versions == nil {
+		return c.apiVersion, nil
+	}
+
+	info := &trp.Info{Address: address}
+
+	var uri *url.URL
+	if uri, err = info.ParseURL(); err != nil {
+		return "", err
+	}
+
+	if cached, ok := c.negotiated.Load(uri.Host); ok {
+		return cached.(string), nil
+	}
+
+	var out *discoverability.Version
+	if out, err = c.Version(ctx, address); err != nil {
+		var serr *trp.StatusError
+		if !errors.As(err, &serr) || (serr.Code != http.StatusNotFound && serr.Code != http.StatusMethodNotAllowed) {
+			return "", fmt.Errorf("could not negotiate api version: %w", err)
+		}
+		out = &discoverability.Version{Version: c.apiVersion}
+	}
+
+	if !c.versions.Supports(out.Version) {
+		return "", fmt.Errorf("%w: counterparty uses version %s, supported versions are %s", trp.ErrUnsupportedVersion, out.Version, c.versions)
+	}
+
+	c.negotiated.Store(uri.Host, out.Version)
+	return out.Version, nil
+}
+
+// Returns a copy of the info with the negotiated API version if a version has not been
+// specified so that the info of the caller is not modified by the negotiation.
+func (c *Client) negotiate(ctx context.Context, info *trp.Info) (_ *trp.Info, err error) {
+	if c.versions == nil || info == nil || info.APIVersion != "" {
+		return info, nil
+	}
+
+	negotiated := *info
+	if negotiated.APIVersion, err = c.Negotiate(ctx, info.Address); err != nil {
+		return nil, err
+	}
+	return &negotiated, nil
+}
+
 //===========================================================================
 // Client Methods - TRPv3
 //===========================================================================
@@ -103,9 +189,14 @@ func (c *Client) Identity(ctx context.Context, address string) (out *trp.Identit
 }
 
 func (c *Client) Inquiry(ctx context.Context, in *trp.Inquiry) (out *trp.Resolution, err error) {
+	var info *trp.Info
+	if info, err = c.negotiate(ctx, in.Info); err != nil {
+		return nil, err
+	}
+
 	// Ensure the the travel rule query parameter is set
 	var uri *url.URL
-	if uri, err = in.Info.ParseURL(); err != nil {
+	if uri, err = info.ParseURL(); err != nil {
 		return nil, err
 	}
 
@@ -113,12 +204,12 @@ func (c *Client) Inquiry(ctx context.Context, in *trp.Inquiry) (out *trp.Resolut
 	query := uri.Query()
 	query.Set("t", "i")
 	uri.RawQuery = query.Encode()
-	in.Info.Address = uri.String()
+	info.Address = uri.String()
 
 	// Send the inquiry request
 	var rep *Response
 	out = &trp.Resolution{}
-	if rep, err = c.Post(ctx, in.Info, in, out); err != nil {
+	if rep, err = c.Post(ctx, info, in, out); err != nil {
 		return nil, err
 	}
 
@@ -127,16 +218,26 @@ func (c *Client) Inquiry(ctx context.Context, in *trp.Inquiry) (out *trp.Resolut
 }
 
 func (c *Client) Resolve(ctx context.Context, in *trp.Resolution) (err error) {
+	var info *trp.Info
+	if info, err = c.negotiate(ctx, in.Info); err != nil {
+		return err
+	}
+
 	// Expects a 204 response
-	if _, err = c.Post(ctx, in.Info, in, nil); err != nil {
+	if _, err = c.Post(ctx, info, in, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (c *Client) Confirm(ctx context.Context, in *trp.Confirmation) (err error) {
+	var info *trp.Info
+	if info, err = c.negotiate(ctx, in.Info); err != nil {
+		return err
+	}
+
 	// Expects a 204 response
-	if _, err = c.Post(ctx, in.Info, in, nil); err != nil {
+	if _, err = c.Post(ctx, info, in, nil); err != nil {
 		return err
 	}
 	return nil
@@ -312,6 +413,23 @@ func (c *Client) do(req *http.Request, out interface{}) (rep *http.Response, err
 	if out != nil && rep.StatusCode != http.StatusNoContent {
 		switch mediaType {
 		case openvasp.MIMEJSON:
+			// Upgrade the response if the request was made with a previous api version
+			if adapter := openvasp.AdapterFor(req.Header.Get(openvasp.APIVersionHeader)); adapter != nil {
+				var data []byte
+				if data, err = io.ReadAll(rep.Body); err != nil {
+					return rep, fmt.Errorf("could not read response body: %w", err)
+				}
+
+				if data, err = adapter.Upgrade(data); err != nil {
+					return rep, fmt.Errorf("could not adapt response to current api version: %w", err)
+				}
+
+				if err = json.Unmarshal(data, out); err != nil {
+					return rep, fmt.Errorf("could not deserialize json response: %w", err)
+				}
+				break
+			}
+
 			if err = json.NewDecoder(rep.Body).Decode(out); err != nil {
 				return rep, fmt.Errorf("could not deserialize json response: %w", err)
 			}
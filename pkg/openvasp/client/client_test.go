@@ -17,6 +17,7 @@ import (
 	"github.com/trisacrypto/trisa/pkg/openvasp/client"
 	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/discoverability"
 	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/slip0044"
 )
 
 func TestClient(t *testing.T) {
@@ -230,6 +231,90 @@ func TestClient(t *testing.T) {
 	})
 }
 
+func TestVersionNegotiation(t *testing.T) {
+	versionCalls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoverability.VersionEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		versionCalls++
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"version": "2.1.0", "vendor": "Legacy"}`))
+	})
+
+	mux.HandleFunc("/", ValidateAPIHeaders(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(openvasp.APIVersionHeader) != "2.1.0" {
+			http.Error(w, "unexpected api version", http.StatusBadRequest)
+			return
+		}
+
+		// The asset must be sent using the TRP v2 numeric coin type
+		payload := make(map[string]interface{})
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if asset, _ := payload["asset"].(map[string]interface{}); asset["slip0044"] != float64(60) {
+			http.Error(w, "asset was not adapted to v2", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"version": "2.1.0"}`))
+	}))
+
+	srv, ta := NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	trpc, err := client.New(client.WithVersionNegotiation("^3.0.0 || ^2.0.0"))
+	require.NoError(t, err, "could not create client")
+
+	for i := 0; i < 2; i++ {
+		inquiry := &trp.Inquiry{}
+		Fixture(t, "testdata/inquiry.json", inquiry)
+		inquiry.Asset = &trp.Asset{SLIP044: slip0044.CoinType_ETH.Enum()}
+		inquiry.Info = &trp.Info{Address: ta.Address}
+
+		out, err := trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err, "could not send inquiry")
+		require.Equal(t, "2.1.0", out.Version)
+		require.Empty(t, inquiry.Info.APIVersion, "the info of the caller should not be modified")
+	}
+
+	// The negotiated version should be cached
+	require.Equal(t, 1, versionCalls)
+
+	version, err := trpc.Negotiate(ctx, ta.Address)
+	require.NoError(t, err)
+	require.Equal(t, "2.1.0", version)
+
+	// Counterparties on unsupported versions cannot be negotiated with
+	trpc, err = client.New(client.WithVersionNegotiation("^3.0.0"))
+	require.NoError(t, err, "could not create client")
+
+	_, err = trpc.Negotiate(ctx, ta.Address)
+	require.ErrorIs(t, err, trp.ErrUnsupportedVersion)
+
+	// Counterparties without the discoverability extension use the default version
+	fallback, fta := NewServer(HandleNoContent(http.MethodPost))
+	defer fallback.Close()
+
+	version, err = trpc.Negotiate(ctx, fta.Address)
+	require.NoError(t, err)
+	require.Equal(t, openvasp.APIVersion, version)
+
+	// Without negotiation the default version is used
+	trpc, err = client.New()
+	require.NoError(t, err, "could not create client")
+
+	version, err = trpc.Negotiate(ctx, ta.Address)
+	require.NoError(t, err)
+	require.Equal(t, openvasp.APIVersion, version)
+	require.Equal(t, 2, versionCalls, "expected only the negotiating clients to query the version")
+}
+
 func NewServer(handler http.Handler) (*httptest.Server, *trp.Info) {
 	srv := httptest.NewServer(handler)
 	ta := &trp.Info{Address: srv.URL}
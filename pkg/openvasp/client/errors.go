@@ -0,0 +1,7 @@
+package client
+
+import "errors"
+
+var (
+	ErrCircuitOpen = errors.New("circuit breaker is open: too many failed requests to host")
+)
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+//===========================================================================
+// Rate Limiting Middleware
+//===========================================================================
+
+// RateLimit limits the number of requests per second sent to each host using a token
+// bucket that allows bursts of up to burst requests. Requests that exceed the limit
+// wait until a token is available or until their context is canceled.
+func RateLimit(rps float64, burst int) Middleware {
+	if burst < 1 {
+		burst = 1
+	}
+
+	limiters := &hostLimiters{
+		rps:     rps,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := limiters.wait(req); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+type hostLimiters struct {
+	sync.Mutex
+	rps     float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Blocks until a token is available for the host of the request.
+func (l *hostLimiters) wait(req *http.Request) error {
+	for {
+		delay := l.reserve(req.URL.Host)
+		if delay <= 0 {
+			return nil
+		}
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Takes a token from the bucket of the host if one is available, otherwise returns the
+// time until the next token will be available.
+func (l *hostLimiters) reserve(host string) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+
+	// Refill the bucket based on the time elapsed since the last request
+	b.tokens += now.Sub(b.last).Seconds() * l.rps
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	if l.rps <= 0 {
+		return time.Second
+	}
+	return time.Duration((1 - b.tokens) / l.rps * float64(time.Second))
+}
+
+//===========================================================================
+// Circuit Breaker Middleware
+//===========================================================================
+
+// CircuitBreaker stops sending requests to a host after threshold consecutive failures
+// (network errors or 5xx responses), immediately returning ErrCircuitOpen instead. Once
+// the cooldown has elapsed a single trial request is allowed through; if it succeeds
+// the circuit is closed, otherwise it is opened for another cooldown period.
+func CircuitBreaker(threshold int, cooldown time.Duration) Middleware {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	breakers := &hostBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (rep *http.Response, err error) {
+			host := req.URL.Host
+			if !breakers.allow(host) {
+				return nil, ErrCircuitOpen
+			}
+
+			rep, err = next.RoundTrip(req)
+			breakers.record(host, err == nil && rep.StatusCode < 500)
+			return rep, err
+		})
+	}
+}
+
+type hostBreakers struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+}
+
+type circuit struct {
+	failures int
+	openedAt time.Time
+	trial    bool // a trial request is in flight after the cooldown
+}
+
+// Returns true if a request may be sent to the host.
+func (b *hostBreakers) allow(host string) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok || c.failures < b.threshold {
+		return true
+	}
+
+	if c.trial || time.Since(c.openedAt) < b.cooldown {
+		return false
+	}
+
+	c.trial = true
+	return true
+}
+
+// Records the outcome of a request to the host, opening or closing the circuit.
+func (b *hostBreakers) record(host string, success bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if success {
+		delete(b.circuits, host)
+		return
+	}
+
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+
+	c.failures++
+	c.trial = false
+	if c.failures >= b.threshold {
+		c.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,320 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+)
+
+// Middleware wraps the round tripper used by the client to send http requests, e.g. to
+// log, retry, or rate limit requests. Middleware is applied to every request made by
+// the client, including requests to the discoverability and identity endpoints.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc allows an ordinary function to be used as an http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps the round tripper with the middleware so that the first middleware is
+// the outermost, e.g. Chain(rt, a, b) sends requests through a, then b, then rt. If the
+// round tripper is nil, the http.DefaultTransport is used.
+func Chain(rt http.RoundTripper, middleware ...Middleware) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		rt = middleware[i](rt)
+	}
+	return rt
+}
+
+//===========================================================================
+// Logging Middleware
+//===========================================================================
+
+// Redacted replaces the values of fields that contain personally identifying info.
+const Redacted = "[REDACTED]"
+
+// RedactedFields are the JSON fields (case-insensitive) whose values are replaced when
+// request and response bodies are logged. The IVMS101 field contains the PII of the
+// originator and beneficiary of a TRP inquiry and the extensions may contain TRISA
+// envelopes whose payloads (and for unsealed envelopes, the keys to decrypt them)
+// contain the same PII.
+var RedactedFields = []string{"IVMS101", "extensions"}
+
+// Logging logs each request and response with the method, url, status code, duration,
+// and the request identifier of the TRP transfer. If debug logging is enabled, the
+// request and response JSON bodies are also logged with the IVMS101 payloads and the
+// extensions redacted.
+// If the logger is nil, the default slog logger is used.
+func Logging(logger *slog.Logger) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (rep *http.Response, err error) {
+			ctx := req.Context()
+			debug := logger.Enabled(ctx, slog.LevelDebug)
+
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.String("request_identifier", req.Header.Get(openvasp.RequestIdentifierHeader)),
+				slog.String("api_version", req.Header.Get(openvasp.APIVersionHeader)),
+			}
+
+			if debug && req.Body != nil {
+				var body []byte
+				if body, req, err = peekRequest(req); err != nil {
+					return nil, err
+				}
+				attrs = append(attrs, slog.String("request", string(Redact(body))))
+			}
+
+			start := time.Now()
+			rep, err = next.RoundTrip(req)
+			attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelWarn, "trp request failed", attrs...)
+				return nil, err
+			}
+
+			attrs = append(attrs, slog.Int("status", rep.StatusCode))
+			if debug && rep.Body != nil {
+				var body []byte
+				if body, err = io.ReadAll(rep.Body); err != nil {
+					rep.Body.Close()
+					return nil, err
+				}
+				rep.Body.Close()
+				rep.Body = io.NopCloser(bytes.NewReader(body))
+				attrs = append(attrs, slog.String("response", string(Redact(body))))
+			}
+
+			level := slog.LevelInfo
+			if rep.StatusCode >= 500 {
+				level = slog.LevelWarn
+			}
+			logger.LogAttrs(ctx, level, "trp request", attrs...)
+			return rep, nil
+		})
+	}
+}
+
+// Redact replaces the values of the RedactedFields anywhere in a JSON document. If the
+// data is not JSON, the length of the data is returned instead of the data so that PII
+// is never logged in unstructured bodies.
+func Redact(data []byte) []byte {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data
+	}
+
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return []byte("[" + strconv.Itoa(len(data)) + " bytes]")
+	}
+
+	out, err := json.Marshal(redact(doc))
+	if err != nil {
+		return []byte(Redacted)
+	}
+	return out
+}
+
+func redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, val := range t {
+			if isRedacted(key) {
+				t[key] = Redacted
+				continue
+			}
+			t[key] = redact(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = redact(val)
+		}
+	}
+	return v
+}
+
+func isRedacted(key string) bool {
+	for _, field := range RedactedFields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+//===========================================================================
+// Retry Middleware
+//===========================================================================
+
+// RetryPolicy configures how requests are retried by the Retry middleware.
+type RetryPolicy struct {
+	MaxRetries int           // the maximum number of retries after the first attempt
+	Backoff    time.Duration // the delay before the first retry, doubled for each retry
+	MaxBackoff time.Duration // the maximum delay between retries, if zero no maximum
+}
+
+// DefaultRetryPolicy retries a request up to 3 times with exponential backoff.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	Backoff:    250 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// Retry resends requests that fail with a network error or a 5xx status code. Every
+// attempt is sent with the same request-identifier header so that the counterparty can
+// detect duplicate requests for the same transfer; if the request does not have an
+// identifier, one is generated before the first attempt. Retries stop if the context
+// of the request is canceled.
+func Retry(policy RetryPolicy) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (rep *http.Response, err error) {
+			ctx := req.Context()
+
+			// Buffer the body so that it can be resent on each attempt
+			if req.Body != nil && req.GetBody == nil {
+				if _, req, err = peekRequest(req); err != nil {
+					return nil, err
+				}
+			}
+
+			// Ensure all attempts share the same request identifier for idempotency
+			if req.Header.Get(openvasp.RequestIdentifierHeader) == "" {
+				req = req.Clone(ctx)
+				req.Header.Set(openvasp.RequestIdentifierHeader, uuid.NewString())
+			}
+
+			backoff := policy.Backoff
+			for attempt := 0; ; attempt++ {
+				var areq *http.Request
+				if areq, err = attemptRequest(req, attempt); err != nil {
+					return nil, err
+				}
+
+				rep, err = next.RoundTrip(areq)
+				if attempt >= policy.MaxRetries || !retryable(ctx, rep, err) {
+					return rep, err
+				}
+
+				// Discard the failed response so the connection can be reused
+				if rep != nil {
+					io.Copy(io.Discard, rep.Body)
+					rep.Body.Close()
+				}
+
+				if err = sleep(ctx, jitter(backoff)); err != nil {
+					return nil, err
+				}
+
+				if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+					backoff = policy.MaxBackoff
+				}
+			}
+		})
+	}
+}
+
+// Returns true if the request should be retried; context errors are never retried.
+func retryable(ctx context.Context, rep *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return rep.StatusCode >= 500
+}
+
+// Returns a copy of the request with a fresh body for every attempt after the first.
+func attemptRequest(req *http.Request, attempt int) (_ *http.Request, err error) {
+	if attempt == 0 || req.GetBody == nil {
+		return req, nil
+	}
+
+	areq := req.Clone(req.Context())
+	if areq.Body, err = req.GetBody(); err != nil {
+		return nil, err
+	}
+	return areq, nil
+}
+
+// Adds up to 10% random jitter to the backoff to prevent synchronized retries.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/10+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Reads the body of the request and returns a copy of the request whose body can be
+// read again and which can be re-created with GetBody.
+func peekRequest(req *http.Request) (body []byte, _ *http.Request, err error) {
+	if req.GetBody != nil {
+		var rc io.ReadCloser
+		if rc, err = req.GetBody(); err != nil {
+			return nil, nil, err
+		}
+		defer rc.Close()
+
+		if body, err = io.ReadAll(rc); err != nil {
+			return nil, nil, err
+		}
+		return body, req, nil
+	}
+
+	if body, err = io.ReadAll(req.Body); err != nil {
+		return nil, nil, err
+	}
+	req.Body.Close()
+
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	out.ContentLength = int64(len(body))
+	return body, out, nil
+}
@@ -0,0 +1,286 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/client"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+func TestLogging(t *testing.T) {
+	srv, ta := NewServer(HandleFixture(http.MethodPost, "testdata/acknowledged.json"))
+	defer srv.Close()
+
+	logs := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	trpc, err := client.New(client.WithMiddleware(client.Logging(logger)))
+	require.NoError(t, err, "could not create client")
+
+	inquiry := &trp.Inquiry{}
+	Fixture(t, "testdata/inquiry.json", inquiry)
+
+	ta.RequestIdentifier = "f3b9e22c-d7d6-4458-bbd9-0320612a0267"
+	inquiry.Info = ta
+
+	out, err := trpc.Inquiry(context.Background(), inquiry)
+	require.NoError(t, err, "middleware should not modify the request or response")
+	require.Equal(t, "3.2.1", out.Version)
+
+	require.Contains(t, logs.String(), `"request_identifier":"f3b9e22c-d7d6-4458-bbd9-0320612a0267"`)
+	require.Contains(t, logs.String(), `"status":200`)
+	require.Contains(t, logs.String(), `\"IVMS101\":\"[REDACTED]\"`)
+	require.NotContains(t, logs.String(), "MachuPichu", "PII was logged")
+
+	// Bodies are not logged unless debug logging is enabled
+	logs.Reset()
+	logger = slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	trpc, err = client.New(client.WithMiddleware(client.Logging(logger)))
+	require.NoError(t, err, "could not create client")
+
+	_, err = trpc.Inquiry(context.Background(), inquiry)
+	require.NoError(t, err)
+	require.Contains(t, logs.String(), `"status":200`)
+	require.NotContains(t, logs.String(), "REDACTED")
+}
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{`{"asset":{"slip0044":"BTC"},"IVMS101":{"originator":{}}}`, `{"IVMS101":"[REDACTED]","asset":{"slip0044":"BTC"}}`},
+		{`{"ivms101":{"originator":{}}}`, `{"ivms101":"[REDACTED]"}`},
+		{`[{"nested":{"IVMS101":{"beneficiary":{}}}},1.5]`, `[{"nested":{"IVMS101":"[REDACTED]"}},1.5]`},
+		{`{"txid":"foo"}`, `{"txid":"foo"}`},
+		{`Alice Smith`, `[11 bytes]`},
+		{``, ``},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, string(client.Redact([]byte(tc.in))))
+	}
+
+	// TRISA envelope extensions contain the PII and the keys to decrypt it
+	inquiry := &trp.Inquiry{
+		Amount: 1.5,
+		Extensions: map[string]interface{}{
+			openvasp.SealedTRISAExtension: &openvasp.SealedTRISAEnvelope{Envelope: `{"payload":"c2VjcmV0IHBheWxvYWQ="}`},
+			openvasp.UnsealedTRISAExtension: &openvasp.UnsealedTRISAEnvelope{
+				Id:            "1234",
+				Payload:       []byte("secret payload"),
+				EncryptionKey: []byte("secret key"),
+				HMACSecret:    []byte("secret hmac"),
+			},
+		},
+	}
+
+	data, err := json.Marshal(inquiry)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"asset":null,"amount":1.5,"callback":"","IVMS101":"[REDACTED]","extensions":"[REDACTED]"}`, string(client.Redact(data)))
+}
+
+func TestRetry(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		calls    int
+		ids      []string
+		bodies   []string
+		failures = []int{http.StatusServiceUnavailable, 0, http.StatusBadGateway}
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		ids = append(ids, r.Header.Get(openvasp.RequestIdentifierHeader))
+		bodies = append(bodies, string(body))
+
+		calls++
+		if calls <= len(failures) {
+			if code := failures[calls-1]; code != 0 {
+				w.WriteHeader(code)
+				return
+			}
+
+			// Simulate a network error by closing the connection
+			panic(http.ErrAbortHandler)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	hc := &http.Client{
+		Transport: client.Chain(nil, client.Retry(client.RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond})),
+	}
+
+	// The request identifier is generated and reused on all attempts
+	rep, err := hc.Post(srv.URL, openvasp.MIMEJSON, strings.NewReader(`{"amount":1}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rep.StatusCode)
+	require.Equal(t, 4, calls)
+	require.NotEmpty(t, ids[0])
+	for i := range ids {
+		require.Equal(t, ids[0], ids[i], "request identifier was not reused")
+		require.Equal(t, `{"amount":1}`, bodies[i], "body was not resent")
+	}
+
+	// Retries stop after the max retries and the last response is returned
+	calls, ids = 0, nil
+	failures = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	hc.Transport = client.Chain(nil, client.Retry(client.RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond}))
+	rep, err = hc.Get(srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, rep.StatusCode)
+	require.Equal(t, 2, calls)
+
+	// Client errors are not retried
+	calls, ids = 0, nil
+	notfound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.NotFound(w, r)
+	}))
+	defer notfound.Close()
+
+	rep, err = hc.Get(notfound.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rep.StatusCode)
+	require.Equal(t, 1, calls)
+
+	// The TRP client uses the request identifier of the transfer on all attempts
+	calls, ids = 0, nil
+	failures = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	trpc, err := client.New(client.WithMiddleware(client.Retry(client.RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond})))
+	require.NoError(t, err)
+
+	confirmation := &trp.Confirmation{Info: &trp.Info{Address: srv.URL, RequestIdentifier: "7a8b0e2f-3e4c-4b8e-9d7e-1c2b3a4d5e6f"}, TXID: "foo"}
+	err = trpc.Confirm(context.Background(), confirmation)
+	require.NoError(t, err)
+	require.Equal(t, 4, calls)
+	require.Equal(t, []string{confirmation.Info.RequestIdentifier, confirmation.Info.RequestIdentifier, confirmation.Info.RequestIdentifier, confirmation.Info.RequestIdentifier}, ids)
+
+	// Retries stop when the context is canceled
+	calls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	hc.Transport = client.Chain(nil, client.Retry(client.RetryPolicy{MaxRetries: 10, Backoff: time.Second}))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	_, err = hc.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 1, calls)
+}
+
+func TestRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer other.Close()
+
+	hc := &http.Client{Transport: client.Chain(nil, client.RateLimit(20, 2))}
+
+	// The burst is sent immediately, subsequent requests wait for a token
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		rep, err := hc.Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, rep.StatusCode)
+	}
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "requests were not rate limited")
+
+	// Each host has its own limit
+	start = time.Now()
+	for i := 0; i < 2; i++ {
+		_, err := hc.Get(other.URL)
+		require.NoError(t, err)
+	}
+	require.Less(t, time.Since(start), 50*time.Millisecond, "hosts should not share a rate limit")
+
+	// Waiting requests are canceled with their context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	hc.Transport = client.Chain(nil, client.RateLimit(0.1, 1))
+	_, err := hc.Get(srv.URL)
+	require.NoError(t, err)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	_, err = hc.Do(req)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		calls  int
+		status = http.StatusInternalServerError
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	hc := &http.Client{Transport: client.Chain(nil, client.CircuitBreaker(2, 50*time.Millisecond))}
+
+	// The circuit opens after the threshold of failures
+	for i := 0; i < 2; i++ {
+		rep, err := hc.Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusInternalServerError, rep.StatusCode)
+	}
+
+	_, err := hc.Get(srv.URL)
+	require.ErrorIs(t, err, client.ErrCircuitOpen)
+	require.Equal(t, 2, calls, "no requests should be sent while the circuit is open")
+
+	// A failed trial request after the cooldown reopens the circuit
+	time.Sleep(60 * time.Millisecond)
+	_, err = hc.Get(srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	_, err = hc.Get(srv.URL)
+	require.ErrorIs(t, err, client.ErrCircuitOpen)
+
+	// A successful trial request closes the circuit
+	status = http.StatusOK
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		rep, err := hc.Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+	}
+	require.Equal(t, 6, calls)
+
+	// Open circuits are not retried
+	status = http.StatusInternalServerError
+	calls = 0
+	hc.Transport = client.Chain(nil,
+		client.Retry(client.RetryPolicy{MaxRetries: 5, Backoff: time.Millisecond}),
+		client.CircuitBreaker(2, time.Minute),
+	)
+
+	_, err = hc.Get(srv.URL)
+	require.True(t, errors.Is(err, client.ErrCircuitOpen))
+	require.Equal(t, 2, calls)
+}
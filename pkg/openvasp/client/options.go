@@ -5,6 +5,8 @@ import (
 	"crypto/x509"
 	"net/http"
 
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/signing"
 	"github.com/trisacrypto/trisa/pkg/trust"
 )
 
@@ -76,6 +78,40 @@ func WithAPIVersion(version string) ClientOption {
 	}
 }
 
+// Negotiate the API version with each counterparty using the discoverability version
+// endpoint before sending transfer requests, accepting any version in the semver range
+// (e.g. "^3.0.0 || ^2.0.0"). Requests to counterparties that use a previous major
+// version of the TRP API are adapted to their version.
+func WithVersionNegotiation(constraint string) ClientOption {
+	return func(c *Client) (err error) {
+		c.versions, err = openvasp.ParseVersionRange(constraint)
+		return err
+	}
+}
+
+// Sign transfer requests using the message signing extension and verify the signatures
+// of responses using the x509 certificate from the identity endpoint of the
+// counterparty. If required is true, successful responses that are not signed are
+// rejected. The extension is added to the default API extensions of the client.
+func WithMessageSigning(signer *signing.Signer, required bool) ClientOption {
+	return func(c *Client) error {
+		c.signer = signer
+		c.signingRequired = required
+		return nil
+	}
+}
+
+// Add middleware to the http transport of the client, e.g. to log, retry, rate limit,
+// or break the circuit of requests. Middleware is applied in the order specified, the
+// first middleware is the outermost, and wraps any transport configured by other
+// options regardless of the order the options are specified in.
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(c *Client) error {
+		c.middleware = append(c.middleware, middleware...)
+		return nil
+	}
+}
+
 // Specify the default API extensions to use in requests (overwrites default extensions).
 func WithAPIExtensions(extensions ...string) ClientOption {
 	return func(c *Client) error {
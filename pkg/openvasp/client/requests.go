@@ -122,10 +122,23 @@ func (c *Client) NewJSONRequest(ctx context.Context, method string, ta *trp.Info
 	case data == nil:
 		body = nil
 	default:
-		body = &bytes.Buffer{}
-		if err = json.NewEncoder(body).Encode(data); err != nil {
+		var payload []byte
+		if payload, err = json.Marshal(data); err != nil {
 			return nil, fmt.Errorf("could not serialize request data as json: %s", err)
 		}
+
+		// Downgrade the payload if the request is made with a previous api version
+		version := ta.APIVersion
+		if version == "" {
+			version = c.apiVersion
+		}
+
+		if adapter := openvasp.AdapterFor(version); adapter != nil {
+			if payload, err = adapter.Downgrade(payload); err != nil {
+				return nil, fmt.Errorf("could not adapt request data to api version %s: %s", version, err)
+			}
+		}
+		body = bytes.NewBuffer(payload)
 	}
 
 	// NOTE: the NewRequest function sets the Accept and Content-Type headers to JSON.
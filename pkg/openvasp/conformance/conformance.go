@@ -0,0 +1,336 @@
+/*
+Package conformance implements a harness that verifies that a TRP endpoint follows the
+Travel Rule Protocol specification. The harness runs a scripted set of scenarios
+against the endpoint, e.g. checking that headers are echoed, that api versions and
+content types are enforced, that inquiries are approved or rejected with well formed
+resolutions, that resolutions and confirmations are sent to and received on callbacks,
+and that the discoverability endpoints are implemented; a pass, fail, or skip result is
+reported for every scenario.
+
+Because a counterparty decides for itself whether to approve or reject an inquiry, the
+harness requests an outcome using the conformance extension in the body of the inquiry.
+Endpoints that do not honor the extension are not considered non-conformant: scenarios
+that depend on an outcome are skipped rather than failed if the outcome is not met.
+The Handler in this package is a reference implementation that honors the extension.
+*/
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+// Extension is the key of the inquiry extension that requests a conformance outcome.
+const Extension = "conformance"
+
+// Outcomes that can be requested from the endpoint using the conformance extension.
+const (
+	ExpectApprove = "approve"
+	ExpectReject  = "reject"
+	ExpectDefer   = "defer" // respond with an acknowledgement and resolve via the callback
+)
+
+// Default timeout to wait for the endpoint to send a resolution to the callback.
+const DefaultCallbackTimeout = 10 * time.Second
+
+// ErrSkipped is returned by a scenario that cannot be run against the endpoint, e.g.
+// because an optional extension is not implemented by the endpoint.
+var ErrSkipped = errors.New("scenario skipped")
+
+// Skip returns an ErrSkipped error with the reason the scenario was skipped.
+func Skip(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrSkipped, fmt.Sprintf(format, a...))
+}
+
+// Suite runs the conformance scenarios against a TRP endpoint.
+type Suite struct {
+	address      string
+	client       *http.Client
+	apiVersion   string
+	timeout      time.Duration
+	callbackAddr string
+	callbackURL  string
+	scenarios    []Scenario
+}
+
+// New creates a conformance suite for the TRP endpoint at the specified address, which
+// may be a travel address, an LNURL, or a URL (see openvasp.Resolve).
+func New(address string, opts ...Option) (suite *Suite, err error) {
+	suite = &Suite{
+		address:    address,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		apiVersion: openvasp.APIVersion,
+		timeout:    DefaultCallbackTimeout,
+		scenarios:  Scenarios(),
+	}
+
+	for _, opt := range opts {
+		if err = opt(suite); err != nil {
+			return nil, err
+		}
+	}
+	return suite, nil
+}
+
+// Run executes all scenarios in order and returns a report of the results. An error is
+// only returned if the endpoint cannot be resolved or the callback server cannot be
+// started; scenario failures are recorded in the report. The endpoint is resolved with
+// the http client of the suite so that well-known lookups use the same transport.
+func (s *Suite) Run(ctx context.Context) (report *Report, err error) {
+	var resolver *openvasp.Resolver
+	if resolver, err = openvasp.NewResolver(openvasp.WithHTTPClient(s.client)); err != nil {
+		return nil, err
+	}
+
+	var info *trp.Info
+	if info, err = resolver.Resolve(ctx, s.address); err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		client:     s.client,
+		apiVersion: s.apiVersion,
+		timeout:    s.timeout,
+		callbacks:  newCallbacks(),
+	}
+
+	if session.endpoint, err = url.Parse(info.Address); err != nil {
+		return nil, err
+	}
+
+	var stop func()
+	if session.callbackURL, stop, err = s.serveCallbacks(session.callbacks); err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	report = &Report{
+		Endpoint: info.Address,
+		Started:  time.Now(),
+		Results:  make([]*Result, 0, len(s.scenarios)),
+	}
+
+	for _, scenario := range s.scenarios {
+		report.Results = append(report.Results, session.run(ctx, scenario))
+	}
+	return report, nil
+}
+
+// Starts the server that receives callbacks from the endpoint and returns the URL that
+// is sent to the endpoint in inquiries. If no listen address is configured, a local
+// test server is used, which is only reachable by endpoints on the same host.
+func (s *Suite) serveCallbacks(handler http.Handler) (uri string, stop func(), err error) {
+	if s.callbackAddr == "" {
+		srv := httptest.NewServer(handler)
+		if uri = s.callbackURL; uri == "" {
+			uri = srv.URL
+		}
+		return uri, srv.Close, nil
+	}
+
+	var sock net.Listener
+	if sock, err = net.Listen("tcp", s.callbackAddr); err != nil {
+		return "", nil, fmt.Errorf("could not listen for callbacks: %w", err)
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(sock)
+
+	if uri = s.callbackURL; uri == "" {
+		uri = "http://" + sock.Addr().String()
+	}
+	return uri, func() { srv.Close() }, nil
+}
+
+//===========================================================================
+// Reports
+//===========================================================================
+
+// Status of a scenario after it has been run.
+type Status string
+
+const (
+	Pass    Status = "pass"
+	Fail    Status = "fail"
+	Skipped Status = "skip"
+)
+
+// Report contains the results of a conformance run.
+type Report struct {
+	Endpoint string    `json:"endpoint"`
+	Started  time.Time `json:"started"`
+	Results  []*Result `json:"results"`
+}
+
+// Result of a single scenario.
+type Result struct {
+	Scenario    string        `json:"scenario"`
+	Description string        `json:"description"`
+	Status      Status        `json:"status"`
+	Message     string        `json:"message,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// Passed returns true if no scenarios failed.
+func (r *Report) Passed() bool {
+	_, failed, _ := r.Counts()
+	return failed == 0
+}
+
+// Counts returns the number of passed, failed, and skipped scenarios.
+func (r *Report) Counts() (passed, failed, skipped int) {
+	for _, result := range r.Results {
+		switch result.Status {
+		case Pass:
+			passed++
+		case Fail:
+			failed++
+		case Skipped:
+			skipped++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// Result returns the result of the named scenario or nil if it was not run.
+func (r *Report) Result(scenario string) *Result {
+	for _, result := range r.Results {
+		if result.Scenario == scenario {
+			return result
+		}
+	}
+	return nil
+}
+
+//===========================================================================
+// Callbacks
+//===========================================================================
+
+// Callback is a request that was posted by the endpoint to the callback server.
+type Callback struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Records the callbacks received by the callback server by request identifier.
+type callbacks struct {
+	sync.Mutex
+	received map[string]chan *Callback
+}
+
+func newCallbacks() *callbacks {
+	return &callbacks{received: make(map[string]chan *Callback)}
+}
+
+// Returns the channel that receives callbacks for the request identifier.
+func (c *callbacks) channel(requestIdentifier string) chan *Callback {
+	c.Lock()
+	defer c.Unlock()
+
+	ch, ok := c.received[requestIdentifier]
+	if !ok {
+		ch = make(chan *Callback, 1)
+		c.received[requestIdentifier] = ch
+	}
+	return ch
+}
+
+func (c *callbacks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := readBody(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Echo the headers back to the endpoint as a conformant TRP server would
+	w.Header().Set(openvasp.APIVersionHeader, r.Header.Get(openvasp.APIVersionHeader))
+	w.Header().Set(openvasp.RequestIdentifierHeader, r.Header.Get(openvasp.RequestIdentifierHeader))
+
+	select {
+	case c.channel(r.Header.Get(openvasp.RequestIdentifierHeader)) <- &Callback{Header: r.Header.Clone(), Body: body}:
+	default:
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//===========================================================================
+// Suite Options
+//===========================================================================
+
+// Option configures the conformance suite when it is created.
+type Option func(s *Suite) error
+
+// Specify the http client used to send requests to the endpoint, e.g. to use mTLS.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Suite) error {
+		s.client = client
+		return nil
+	}
+}
+
+// Specify the api version sent in valid requests to the endpoint.
+func WithAPIVersion(version string) Option {
+	return func(s *Suite) error {
+		if _, err := openvasp.ParseSemVer(version); err != nil {
+			return err
+		}
+		s.apiVersion = version
+		return nil
+	}
+}
+
+// Specify how long to wait for callbacks from the endpoint; if zero the scenarios
+// that require callbacks to the harness are skipped.
+func WithCallbackTimeout(timeout time.Duration) Option {
+	return func(s *Suite) error {
+		s.timeout = timeout
+		return nil
+	}
+}
+
+// Listen for callbacks on the specified address and send the public URL of the callback
+// server to the endpoint. If the URL is empty, the address of the listener is used.
+// This option is required for endpoints that cannot connect to the local host.
+func WithCallbackServer(addr, publicURL string) Option {
+	return func(s *Suite) error {
+		s.callbackAddr = addr
+		s.callbackURL = publicURL
+		return nil
+	}
+}
+
+// Only run the named scenarios (in the order of Scenarios).
+func WithScenarios(names ...string) Option {
+	return func(s *Suite) error {
+		selected := make([]Scenario, 0, len(names))
+		for _, scenario := range Scenarios() {
+			for _, name := range names {
+				if scenario.Name == name {
+					selected = append(selected, scenario)
+					break
+				}
+			}
+		}
+
+		if len(selected) != len(names) {
+			return fmt.Errorf("unknown conformance scenario in %v", names)
+		}
+		s.scenarios = selected
+		return nil
+	}
+}
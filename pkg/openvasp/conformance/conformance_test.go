@@ -0,0 +1,167 @@
+package conformance_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/conformance"
+)
+
+func TestServerConformance(t *testing.T) {
+	handler := &conformance.Handler{Address: "bc1qconformance"}
+	srv, err := openvasp.NewServer(handler)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	handler.Callback = ts.URL + openvasp.ConfirmationEndpoint
+
+	suite, err := conformance.New(ts.URL)
+	require.NoError(t, err)
+
+	report, err := suite.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Results, len(conformance.Scenarios()))
+
+	for _, result := range report.Results {
+		require.Equal(t, conformance.Pass, result.Status, "%s: %s", result.Scenario, result.Message)
+	}
+
+	passed, failed, skipped := report.Counts()
+	require.True(t, report.Passed())
+	require.Equal(t, len(conformance.Scenarios()), passed)
+	require.Zero(t, failed)
+	require.Zero(t, skipped)
+}
+
+func TestTransferInquiryConformance(t *testing.T) {
+	handler := &conformance.Handler{Address: "bc1qconformance"}
+
+	mux := http.NewServeMux()
+	mux.Handle("/trp", openvasp.TransferInquiry(handler))
+	mux.Handle("/confirm", openvasp.TransferConfirmation(handler))
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	handler.Callback = ts.URL + "/confirm"
+
+	suite, err := conformance.New(ts.URL+"/trp", conformance.WithCallbackTimeout(0))
+	require.NoError(t, err)
+
+	report, err := suite.Run(context.Background())
+	require.NoError(t, err)
+	require.True(t, report.Passed(), "transfer inquiry handler should be conformant")
+
+	// Callbacks are disabled and discoverability is not implemented
+	for _, scenario := range []string{
+		conformance.ScenarioResolutionCallback,
+		conformance.ScenarioDiscoverabilityVersion,
+		conformance.ScenarioDiscoverabilityUptime,
+		conformance.ScenarioDiscoverabilityExtension,
+	} {
+		require.Equal(t, conformance.Skipped, report.Result(scenario).Status, scenario)
+	}
+
+	require.Equal(t, conformance.Pass, report.Result(conformance.ScenarioInquiryApproval).Status)
+	require.Equal(t, conformance.Pass, report.Result(conformance.ScenarioTransferConfirmation).Status)
+	require.Equal(t, conformance.Pass, report.Result(conformance.ScenarioInquiryRejection).Status)
+}
+
+func TestNonConformantEndpoint(t *testing.T) {
+	// An endpoint that acknowledges every request without checking headers or echoing
+	// them and never sends a resolution callback.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version":"3.1.0"}`))
+	}))
+	defer ts.Close()
+
+	suite, err := conformance.New(ts.URL, conformance.WithCallbackTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	report, err := suite.Run(context.Background())
+	require.NoError(t, err)
+	require.False(t, report.Passed())
+
+	for _, scenario := range []string{
+		conformance.ScenarioMethodNotAllowed,
+		conformance.ScenarioMissingAPIVersion,
+		conformance.ScenarioUnsupportedAPIVersion,
+		conformance.ScenarioMissingRequestID,
+		conformance.ScenarioUnsupportedContentType,
+		conformance.ScenarioInvalidInquiry,
+		conformance.ScenarioHeaderEcho,
+		conformance.ScenarioInquiryApproval,
+		conformance.ScenarioResolutionCallback,
+	} {
+		result := report.Result(scenario)
+		require.Equal(t, conformance.Fail, result.Status, scenario)
+		require.NotEmpty(t, result.Message, scenario)
+	}
+
+	// Scenarios that depend on an approval are skipped
+	require.Equal(t, conformance.Skipped, report.Result(conformance.ScenarioTransferConfirmation).Status)
+}
+
+func TestResolveWithClient(t *testing.T) {
+	handler := &conformance.Handler{Address: "bc1qconformance"}
+	srv, err := openvasp.NewServer(handler)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	handler.Callback = ts.URL + openvasp.ConfirmationEndpoint
+
+	// The well-known lookup must be made with the http client of the suite
+	lookups := 0
+	client := &http.Client{
+		Transport: roundTripper(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host != "example.com" {
+				return http.DefaultTransport.RoundTrip(req)
+			}
+
+			lookups++
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", openvasp.MIMEJSON)
+			fmt.Fprintf(rec, `{"travel_address": %q}`, ts.URL)
+			return rec.Result(), nil
+		}),
+	}
+
+	suite, err := conformance.New("alice@example.com", conformance.WithHTTPClient(client), conformance.WithScenarios(conformance.ScenarioHeaderEcho))
+	require.NoError(t, err)
+
+	report, err := suite.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, lookups)
+	require.Contains(t, report.Endpoint, ts.URL)
+	require.True(t, report.Passed())
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithScenarios(t *testing.T) {
+	_, err := conformance.New("https://example.com", conformance.WithScenarios("not-a-scenario"))
+	require.Error(t, err)
+
+	suite, err := conformance.New("localhost:1", conformance.WithScenarios(conformance.ScenarioHeaderEcho))
+	require.NoError(t, err)
+
+	report, err := suite.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	require.Equal(t, conformance.Fail, report.Results[0].Status, "cannot connect to the endpoint")
+
+	_, err = conformance.New("https://example.com", conformance.WithAPIVersion("foo"))
+	require.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package conformance
+
+import (
+	"context"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/client"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+// Handler is a reference implementation of openvasp.Handler that honors the outcome
+// requested by the conformance extension of an inquiry: approving the inquiry with the
+// payment address and confirmation callback of the handler, rejecting it, or
+// acknowledging it and posting the approval to the inquiry callback afterwards.
+// Inquiries without the extension are approved.
+type Handler struct {
+	Address  string         // payment address in approvals
+	Callback string         // confirmation callback in approvals
+	Client   *client.Client // used to post deferred resolutions, if nil a default client is used
+}
+
+var _ openvasp.Handler = &Handler{}
+
+// OnIdentity returns a conformance identity without a certificate.
+func (h *Handler) OnIdentity() (*trp.Identity, error) {
+	return &trp.Identity{Name: "TRP Conformance Handler"}, nil
+}
+
+// OnInquiry resolves the inquiry with the requested outcome.
+func (h *Handler) OnInquiry(in *trp.Inquiry) (_ *trp.Resolution, err error) {
+	switch Expectation(in) {
+	case ExpectReject:
+		return &trp.Resolution{Rejected: "rejected by conformance handler"}, nil
+	case ExpectDefer:
+		var trpc *client.Client
+		if trpc, err = h.client(); err != nil {
+			return nil, err
+		}
+
+		resolution := &trp.Resolution{
+			Info: &trp.Info{
+				Address:           in.Callback,
+				APIVersion:        in.Info.APIVersion,
+				RequestIdentifier: in.Info.RequestIdentifier,
+			},
+			Approved: h.approval(),
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultCallbackTimeout)
+			defer cancel()
+			trpc.Resolve(ctx, resolution)
+		}()
+
+		// Acknowledge the inquiry with the api version
+		return nil, nil
+	default:
+		return &trp.Resolution{Approved: h.approval()}, nil
+	}
+}
+
+// OnResolution accepts all valid resolutions.
+func (h *Handler) OnResolution(*trp.Resolution) error {
+	return nil
+}
+
+// OnConfirmation accepts all valid confirmations.
+func (h *Handler) OnConfirmation(*trp.Confirmation) error {
+	return nil
+}
+
+func (h *Handler) approval() *trp.Approval {
+	return &trp.Approval{Address: h.Address, Callback: h.Callback}
+}
+
+func (h *Handler) client() (*client.Client, error) {
+	if h.Client != nil {
+		return h.Client, nil
+	}
+	return client.New()
+}
+
+// Expectation returns the outcome requested by the conformance extension of the
+// inquiry or an empty string if the extension is not present.
+func Expectation(in *trp.Inquiry) string {
+	ext, ok := in.Extensions[Extension].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	expect, _ := ext["expect"].(string)
+	return expect
+}
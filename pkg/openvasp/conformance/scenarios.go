@@ -0,0 +1,499 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/discoverability"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+// Scenario names in the order they are run.
+const (
+	ScenarioMethodNotAllowed         = "method-not-allowed"
+	ScenarioMissingAPIVersion        = "missing-api-version"
+	ScenarioUnsupportedAPIVersion    = "unsupported-api-version"
+	ScenarioMissingRequestID         = "missing-request-identifier"
+	ScenarioUnsupportedContentType   = "unsupported-content-type"
+	ScenarioInvalidInquiry           = "invalid-inquiry"
+	ScenarioHeaderEcho               = "header-echo"
+	ScenarioInquiryApproval          = "inquiry-approval"
+	ScenarioTransferConfirmation     = "transfer-confirmation"
+	ScenarioInquiryRejection         = "inquiry-rejection"
+	ScenarioResolutionCallback       = "resolution-callback"
+	ScenarioDiscoverabilityVersion   = "discoverability-version"
+	ScenarioDiscoverabilityUptime    = "discoverability-uptime"
+	ScenarioDiscoverabilityExtension = "discoverability-extensions"
+)
+
+// Scenario is a scripted check that is run against a TRP endpoint. The scenario passes
+// if Run returns nil, is skipped if Run returns an ErrSkipped error, and otherwise fails.
+type Scenario struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, s *Session) error
+}
+
+// Scenarios returns the conformance scenarios in the order they are run. Scenarios may
+// depend on the outcome of previous scenarios, e.g. a transfer confirmation is only
+// sent if an inquiry was approved.
+func Scenarios() []Scenario {
+	return []Scenario{
+		{ScenarioMethodNotAllowed, "inquiries must be POSTed to the endpoint", methodNotAllowed},
+		{ScenarioMissingAPIVersion, "requests without an api-version header are rejected", missingAPIVersion},
+		{ScenarioUnsupportedAPIVersion, "requests with an unsupported api-version are rejected", unsupportedAPIVersion},
+		{ScenarioMissingRequestID, "requests without a request-identifier header are rejected", missingRequestIdentifier},
+		{ScenarioUnsupportedContentType, "requests that are not application/json are rejected", unsupportedContentType},
+		{ScenarioInvalidInquiry, "inquiries without an IVMS101 payload are rejected", invalidInquiry},
+		{ScenarioHeaderEcho, "the api-version and request-identifier headers are echoed in a json response", headerEcho},
+		{ScenarioInquiryApproval, "an approved inquiry has a payment address and a confirmation callback", inquiryApproval},
+		{ScenarioTransferConfirmation, "a confirmation is accepted on the callback of the approval", transferConfirmation},
+		{ScenarioInquiryRejection, "a rejected inquiry has a human readable reason", inquiryRejection},
+		{ScenarioResolutionCallback, "an acknowledged inquiry is resolved on the inquiry callback", resolutionCallback},
+		{ScenarioDiscoverabilityVersion, "the version endpoint returns the api version as json", discoverabilityVersion},
+		{ScenarioDiscoverabilityUptime, "the uptime endpoint returns the uptime in seconds as plain text", discoverabilityUptime},
+		{ScenarioDiscoverabilityExtension, "the extensions endpoint returns the supported extensions as json", discoverabilityExtensions},
+	}
+}
+
+// Session contains the state shared between the scenarios of a conformance run.
+type Session struct {
+	endpoint    *url.URL
+	client      *http.Client
+	apiVersion  string
+	timeout     time.Duration
+	callbackURL string
+	callbacks   *callbacks
+	approval    *approval // set by the inquiry approval scenario
+}
+
+type approval struct {
+	requestIdentifier string
+	approved          *trp.Approval
+}
+
+func (s *Session) run(ctx context.Context, scenario Scenario) *Result {
+	result := &Result{Scenario: scenario.Name, Description: scenario.Description}
+
+	start := time.Now()
+	err := scenario.Run(ctx, s)
+	result.Duration = time.Since(start)
+
+	switch {
+	case err == nil:
+		result.Status = Pass
+	case errors.Is(err, ErrSkipped):
+		result.Status = Skipped
+		result.Message = strings.TrimPrefix(err.Error(), ErrSkipped.Error()+": ")
+	default:
+		result.Status = Fail
+		result.Message = err.Error()
+	}
+	return result
+}
+
+//===========================================================================
+// Transfer Scenarios
+//===========================================================================
+
+func methodNotAllowed(ctx context.Context, s *Session) (err error) {
+	req := s.request(ctx, http.MethodGet, s.endpoint.String(), nil)
+	return s.expectStatus(req, http.StatusMethodNotAllowed)
+}
+
+func missingAPIVersion(ctx context.Context, s *Session) (err error) {
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), s.inquiry(ExpectApprove))
+	req.Header.Del(openvasp.APIVersionHeader)
+	return s.expectStatus(req, http.StatusBadRequest)
+}
+
+func unsupportedAPIVersion(ctx context.Context, s *Session) (err error) {
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), s.inquiry(ExpectApprove))
+	req.Header.Set(openvasp.APIVersionHeader, "0.0.1")
+	return s.expectStatus(req, http.StatusBadRequest)
+}
+
+func missingRequestIdentifier(ctx context.Context, s *Session) (err error) {
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), s.inquiry(ExpectApprove))
+	req.Header.Del(openvasp.RequestIdentifierHeader)
+	return s.expectStatus(req, http.StatusBadRequest)
+}
+
+func unsupportedContentType(ctx context.Context, s *Session) (err error) {
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), s.inquiry(ExpectApprove))
+	req.Header.Set(openvasp.ContentTypeHeader, openvasp.MIMEPlainText)
+	return s.expectStatus(req, http.StatusUnsupportedMediaType)
+}
+
+func invalidInquiry(ctx context.Context, s *Session) (err error) {
+	inquiry := s.inquiry(ExpectApprove)
+	delete(inquiry, "IVMS101")
+
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), inquiry)
+	return s.expectStatus(req, http.StatusBadRequest)
+}
+
+func headerEcho(ctx context.Context, s *Session) (err error) {
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), s.inquiry(ExpectApprove))
+
+	var rep *http.Response
+	if rep, err = s.do(req, http.StatusOK); err != nil {
+		return err
+	}
+	defer rep.Body.Close()
+
+	if err = checkEcho(req, rep); err != nil {
+		return err
+	}
+
+	if err = checkContentType(rep, openvasp.MIMEJSON); err != nil {
+		return err
+	}
+
+	resolution := &trp.Resolution{}
+	return decodeResolution(rep.Body, resolution)
+}
+
+func inquiryApproval(ctx context.Context, s *Session) (err error) {
+	var (
+		req        *http.Request
+		resolution *trp.Resolution
+	)
+	if req, resolution, err = s.sendInquiry(ctx, ExpectApprove); err != nil {
+		return err
+	}
+
+	if resolution.Approved == nil {
+		return Skip("endpoint did not approve the inquiry (rejected: %q)", resolution.Rejected)
+	}
+
+	s.approval = &approval{
+		requestIdentifier: req.Header.Get(openvasp.RequestIdentifierHeader),
+		approved:          resolution.Approved,
+	}
+	return nil
+}
+
+func transferConfirmation(ctx context.Context, s *Session) (err error) {
+	if s.approval == nil {
+		return Skip("no inquiry was approved")
+	}
+
+	if _, err = url.Parse(s.approval.approved.Callback); err != nil {
+		return fmt.Errorf("invalid approval callback %q: %w", s.approval.approved.Callback, err)
+	}
+
+	confirmation := map[string]interface{}{"txid": "conformance-" + uuid.NewString()}
+	req := s.request(ctx, http.MethodPost, s.approval.approved.Callback, confirmation)
+	req.Header.Set(openvasp.RequestIdentifierHeader, s.approval.requestIdentifier)
+
+	var rep *http.Response
+	if rep, err = s.do(req, http.StatusNoContent, http.StatusOK); err != nil {
+		return err
+	}
+	defer rep.Body.Close()
+	return checkEcho(req, rep)
+}
+
+func inquiryRejection(ctx context.Context, s *Session) (err error) {
+	var resolution *trp.Resolution
+	if _, resolution, err = s.sendInquiry(ctx, ExpectReject); err != nil {
+		return err
+	}
+
+	if resolution.Rejected == "" {
+		return Skip("endpoint did not reject the inquiry")
+	}
+	return nil
+}
+
+func resolutionCallback(ctx context.Context, s *Session) (err error) {
+	if s.timeout == 0 {
+		return Skip("callbacks are disabled")
+	}
+
+	inquiry := s.inquiry(ExpectDefer)
+	req := s.request(ctx, http.MethodPost, s.endpoint.String(), inquiry)
+	requestIdentifier := req.Header.Get(openvasp.RequestIdentifierHeader)
+	callbacks := s.callbacks.channel(requestIdentifier)
+
+	var rep *http.Response
+	if rep, err = s.do(req, http.StatusOK); err != nil {
+		return err
+	}
+	defer rep.Body.Close()
+
+	resolution := &trp.Resolution{}
+	if err = decodeResolution(rep.Body, resolution); err != nil {
+		return err
+	}
+
+	if resolution.Version == "" {
+		return Skip("endpoint resolved the inquiry immediately")
+	}
+
+	var callback *Callback
+	select {
+	case callback = <-callbacks:
+	case <-time.After(s.timeout):
+		return fmt.Errorf("no resolution received on callback after %s", s.timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if rid := callback.Header.Get(openvasp.RequestIdentifierHeader); rid != requestIdentifier {
+		return fmt.Errorf("resolution callback has request-identifier %q, expected %q", rid, requestIdentifier)
+	}
+
+	if version := callback.Header.Get(openvasp.APIVersionHeader); version == "" {
+		return errors.New("resolution callback does not have an api-version header")
+	}
+
+	resolution = &trp.Resolution{}
+	if err = decodeResolution(bytes.NewReader(callback.Body), resolution); err != nil {
+		return fmt.Errorf("invalid resolution callback: %w", err)
+	}
+
+	if resolution.Version != "" {
+		return errors.New("resolution callback must approve or reject the inquiry")
+	}
+	return nil
+}
+
+// Sends a valid inquiry with the expected outcome and returns the resolution, waiting
+// for the resolution callback if the endpoint acknowledges the inquiry.
+func (s *Session) sendInquiry(ctx context.Context, expect string) (req *http.Request, resolution *trp.Resolution, err error) {
+	req = s.request(ctx, http.MethodPost, s.endpoint.String(), s.inquiry(expect))
+	callbacks := s.callbacks.channel(req.Header.Get(openvasp.RequestIdentifierHeader))
+
+	var rep *http.Response
+	if rep, err = s.do(req, http.StatusOK); err != nil {
+		return nil, nil, err
+	}
+	defer rep.Body.Close()
+
+	resolution = &trp.Resolution{}
+	if err = decodeResolution(rep.Body, resolution); err != nil {
+		return nil, nil, err
+	}
+
+	if resolution.Version != "" {
+		if s.timeout == 0 {
+			return nil, nil, Skip("inquiry was acknowledged and callbacks are disabled")
+		}
+
+		select {
+		case callback := <-callbacks:
+			resolution = &trp.Resolution{}
+			if err = decodeResolution(bytes.NewReader(callback.Body), resolution); err != nil {
+				return nil, nil, fmt.Errorf("invalid resolution callback: %w", err)
+			}
+		case <-time.After(s.timeout):
+			return nil, nil, fmt.Errorf("inquiry was acknowledged but no resolution was received after %s", s.timeout)
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	return req, resolution, nil
+}
+
+//===========================================================================
+// Discoverability Scenarios
+//===========================================================================
+
+func discoverabilityVersion(ctx context.Context, s *Session) (err error) {
+	out := &discoverability.Version{}
+	if err = s.discover(ctx, discoverability.VersionEndpoint, openvasp.MIMEJSON, out); err != nil {
+		return err
+	}
+
+	if _, err = openvasp.ParseSemVer(out.Version); err != nil {
+		return fmt.Errorf("version endpoint returned an invalid version: %w", err)
+	}
+	return nil
+}
+
+func discoverabilityUptime(ctx context.Context, s *Session) (err error) {
+	var out discoverability.Uptime
+	return s.discover(ctx, discoverability.UptimeEndpoint, openvasp.MIMEPlainText, &out)
+}
+
+func discoverabilityExtensions(ctx context.Context, s *Session) (err error) {
+	out := &discoverability.Extensions{}
+	return s.discover(ctx, discoverability.ExtensionsEndpoint, openvasp.MIMEJSON, out)
+}
+
+// GETs the discoverability endpoint and decodes the response; if the endpoint does not
+// implement the discoverability extension the scenario is skipped.
+func (s *Session) discover(ctx context.Context, path, contentType string, out interface{}) (err error) {
+	uri := *s.endpoint
+	uri.Path = path
+	uri.RawQuery = ""
+
+	req := s.request(ctx, http.MethodGet, uri.String(), nil)
+	req.Header.Set("Accept", contentType)
+
+	var rep *http.Response
+	if rep, err = s.client.Do(req); err != nil {
+		return fmt.Errorf("could not execute request: %w", err)
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode == http.StatusNotFound {
+		return Skip("endpoint does not implement the discoverability extension")
+	}
+
+	if rep.StatusCode != http.StatusOK {
+		return fmt.Errorf("expected status %d, got %d", http.StatusOK, rep.StatusCode)
+	}
+
+	if err = checkContentType(rep, contentType); err != nil {
+		return err
+	}
+
+	var body []byte
+	if body, err = readBody(rep.Body); err != nil {
+		return err
+	}
+
+	if contentType == openvasp.MIMEPlainText {
+		if err = out.(*discoverability.Uptime).UnmarshalText(bytes.TrimSpace(body)); err != nil {
+			return fmt.Errorf("could not parse plain text response: %w", err)
+		}
+		return nil
+	}
+
+	if err = json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("could not parse json response: %w", err)
+	}
+	return nil
+}
+
+//===========================================================================
+// Helpers
+//===========================================================================
+
+// Returns a valid inquiry that requests the specified outcome as a JSON object so that
+// scenarios can remove or modify fields.
+func (s *Session) inquiry(expect string) map[string]interface{} {
+	person := func(primary, secondary string) map[string]interface{} {
+		return map[string]interface{}{
+			"naturalPerson": map[string]interface{}{
+				"name": map[string]interface{}{
+					"nameIdentifier": []interface{}{
+						map[string]interface{}{
+							"primaryIdentifier":   primary,
+							"secondaryIdentifier": secondary,
+							"nameIdentifierType":  "LEGL",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"asset":    map[string]interface{}{"slip0044": "BTC"},
+		"amount":   0.0001,
+		"callback": s.callbackURL,
+		"IVMS101": map[string]interface{}{
+			"originator": map[string]interface{}{
+				"originatorPersons": []interface{}{person("Conformance", "Originator")},
+			},
+			"beneficiary": map[string]interface{}{
+				"beneficiaryPersons": []interface{}{person("Conformance", "Beneficiary")},
+			},
+		},
+		"extensions": map[string]interface{}{
+			Extension: map[string]interface{}{"expect": expect},
+		},
+	}
+}
+
+// Creates a request with valid TRP headers and a new request identifier.
+func (s *Session) request(ctx context.Context, method, uri string, body interface{}) *http.Request {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, method, uri, reader)
+	req.Header.Set("Accept", openvasp.MIMEJSON)
+	req.Header.Set(openvasp.APIVersionHeader, s.apiVersion)
+	req.Header.Set(openvasp.RequestIdentifierHeader, uuid.NewString())
+	if body != nil {
+		req.Header.Set(openvasp.ContentTypeHeader, openvasp.ContentTypeValue)
+	}
+	return req
+}
+
+// Executes the request and checks that the response has one of the expected statuses.
+func (s *Session) do(req *http.Request, statuses ...int) (rep *http.Response, err error) {
+	if rep, err = s.client.Do(req); err != nil {
+		return nil, fmt.Errorf("could not execute request: %w", err)
+	}
+
+	for _, status := range statuses {
+		if rep.StatusCode == status {
+			return rep, nil
+		}
+	}
+
+	body, _ := readBody(rep.Body)
+	rep.Body.Close()
+	return nil, fmt.Errorf("expected status %d, got %d: %s", statuses[0], rep.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func (s *Session) expectStatus(req *http.Request, status int) (err error) {
+	var rep *http.Response
+	if rep, err = s.do(req, status); err != nil {
+		return err
+	}
+	return rep.Body.Close()
+}
+
+// Checks that the api-version and request-identifier headers are echoed.
+func checkEcho(req *http.Request, rep *http.Response) error {
+	for _, header := range []string{openvasp.APIVersionHeader, openvasp.RequestIdentifierHeader} {
+		if expected, actual := req.Header.Get(header), rep.Header.Get(header); expected != actual {
+			return fmt.Errorf("expected %s header %q to be echoed, got %q", header, expected, actual)
+		}
+	}
+	return nil
+}
+
+func checkContentType(rep *http.Response, expected string) error {
+	mt, _, err := mime.ParseMediaType(rep.Header.Get(openvasp.ContentTypeHeader))
+	if err != nil || mt != expected {
+		return fmt.Errorf("expected content-type %s, got %q", expected, rep.Header.Get(openvasp.ContentTypeHeader))
+	}
+	return nil
+}
+
+func decodeResolution(r io.Reader, resolution *trp.Resolution) (err error) {
+	if err = json.NewDecoder(r).Decode(resolution); err != nil {
+		return fmt.Errorf("could not decode resolution: %w", err)
+	}
+
+	if err = resolution.Validate(); err != nil {
+		return fmt.Errorf("invalid resolution: %w", err)
+	}
+	return nil
+}
+
+func readBody(r io.Reader) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(r, openvasp.MaxPayloadSize))
+}
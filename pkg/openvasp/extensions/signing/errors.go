@@ -0,0 +1,13 @@
+package signing
+
+import "errors"
+
+var (
+	ErrUnsupportedKey      = errors.New("unsupported key type for message signing")
+	ErrNoSigningKey        = errors.New("a key and certificate are required for message signing")
+	ErrMalformedSignature  = errors.New("malformed detached jws signature")
+	ErrInvalidSignature    = errors.New("invalid message signature")
+	ErrMissingSignature    = errors.New("message signature is required")
+	ErrCertificateMismatch = errors.New("message was not signed by the counterparty certificate")
+	ErrNoCertificate       = errors.New("no certificate available to verify the message signature")
+)
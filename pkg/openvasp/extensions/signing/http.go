@@ -0,0 +1,219 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+)
+
+// RequestCertificate returns the certificate of the counterparty that sent a request.
+type RequestCertificate func(r *http.Request) (*x509.Certificate, error)
+
+// CertificateResolver returns the certificate of the counterparty at the address.
+type CertificateResolver interface {
+	Certificate(ctx context.Context, address string) (*x509.Certificate, error)
+}
+
+// PeerCertificate returns the leaf certificate presented by the client of an mTLS
+// connection; it is used to verify requests by default.
+func PeerCertificate(r *http.Request) (*x509.Certificate, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrNoCertificate
+	}
+	return r.TLS.PeerCertificates[0], nil
+}
+
+// Handler is middleware for TRP servers that verifies the signatures of requests and
+// signs the responses. Signed requests are verified using the certificate returned by
+// certs (PeerCertificate if nil). Requests that specify the message-signing extension
+// but are not signed are rejected, as are all unsigned requests if the extension is
+// required. The responses to signed requests are signed by the signer if it is not nil;
+// responses to unsigned requests are not signed. Only POST requests are signed; the
+// identity and discoverability endpoints are not.
+func Handler(signer *Signer, certs RequestCertificate, required bool, next http.Handler) http.Handler {
+	if certs == nil {
+		certs = PeerCertificate
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, openvasp.MaxPayloadSize))
+		if err != nil {
+			http.Error(w, "could not read request body", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		signature := r.Header.Get(SignatureHeader)
+		switch {
+		case signature != "":
+			var cert *x509.Certificate
+			if cert, err = certs(r); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if err = Verify(signature, body, cert); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+		case required || HasExtension(r.Header):
+			http.Error(w, ErrMissingSignature.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if signer == nil || signature == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Buffer the response so that it can be signed before it is written
+		rec := openvasp.NewBufferedWriter(w)
+		next.ServeHTTP(rec, r)
+
+		if data := rec.Body(); len(data) > 0 {
+			var sig string
+			if sig, err = signer.Sign(data); err != nil {
+				http.Error(w, "could not sign response", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(SignatureHeader, sig)
+		}
+
+		w.WriteHeader(rec.Status())
+		w.Write(rec.Body())
+	})
+}
+
+// WithMessageSigning is a TRP server option that wraps the transfer endpoints with
+// Handler so that requests are verified and responses are signed as described above.
+// The message-signing extension is listed as required by the extensions endpoint of the
+// server if required is true and as supported otherwise.
+func WithMessageSigning(signer *Signer, certs RequestCertificate, required bool) openvasp.ServerOption {
+	return openvasp.WithExtension(Extension, required, func(next http.Handler) http.Handler {
+		return Handler(signer, certs, required, next)
+	})
+}
+
+// Transport is an http.RoundTripper for TRP clients that signs the body of POST
+// requests and verifies the signatures of the responses using the certificate of the
+// counterparty returned by the certificate resolver. If required is true, successful
+// responses with a body that are not signed are rejected. The message-signing
+// extension is added to the api-extensions header of signed requests.
+type Transport struct {
+	Base         http.RoundTripper   // The underlying transport (http.DefaultTransport if nil)
+	Signer       *Signer             // Signs the body of outgoing requests
+	Certificates CertificateResolver // Resolves the certificate of the counterparty
+	Required     bool                // Reject unsigned responses
+}
+
+// RoundTrip signs the request and verifies the response.
+func (t *Transport) RoundTrip(req *http.Request) (rep *http.Response, err error) {
+	if req.Method != http.MethodPost {
+		return t.base().RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The request must not be modified, so a signed clone is sent instead
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	if t.Signer != nil {
+		var sig string
+		if sig, err = t.Signer.Sign(body); err != nil {
+			return nil, err
+		}
+		out.Header.Set(SignatureHeader, sig)
+		AddExtension(out.Header)
+	}
+
+	if rep, err = t.base().RoundTrip(out); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	data, err = io.ReadAll(rep.Body)
+	rep.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	rep.Body = io.NopCloser(bytes.NewReader(data))
+
+	signature := rep.Header.Get(SignatureHeader)
+	if signature == "" {
+		if t.Required && len(data) > 0 && rep.StatusCode >= 200 && rep.StatusCode < 300 {
+			return nil, ErrMissingSignature
+		}
+		return rep, nil
+	}
+
+	if t.Certificates == nil {
+		return nil, ErrNoCertificate
+	}
+
+	var cert *x509.Certificate
+	origin := &url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host}
+	if cert, err = t.Certificates.Certificate(req.Context(), origin.String()); err != nil {
+		return nil, err
+	}
+
+	if err = Verify(signature, data, cert); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// HasExtension returns true if the message-signing extension is in the api-extensions
+// header.
+func HasExtension(header http.Header) bool {
+	for _, ext := range strings.Split(header.Get(openvasp.APIExtensionsHeader), ",") {
+		if strings.TrimSpace(ext) == Extension {
+			return true
+		}
+	}
+	return false
+}
+
+// AddExtension adds the message-signing extension to the api-extensions header if it
+// is not already specified.
+func AddExtension(header http.Header) {
+	if HasExtension(header) {
+		return
+	}
+
+	if extensions := header.Get(openvasp.APIExtensionsHeader); extensions != "" {
+		header.Set(openvasp.APIExtensionsHeader, extensions+", "+Extension)
+		return
+	}
+	header.Set(openvasp.APIExtensionsHeader, Extension)
+}
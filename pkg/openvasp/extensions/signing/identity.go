@@ -0,0 +1,73 @@
+package signing
+
+import (
+	"context"
+	"crypto/x509"
+	"net/url"
+	"sync"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/trust"
+)
+
+// IdentityClient fetches the identity of a counterparty from its TRP identity endpoint.
+type IdentityClient interface {
+	Identity(ctx context.Context, address string) (*trp.Identity, error)
+}
+
+// IdentityCertificates resolves the certificates of counterparties from the x509
+// certificate returned by their identity endpoint, caching the certificate by host.
+type IdentityCertificates struct {
+	sync.RWMutex
+	client IdentityClient
+	certs  map[string]*x509.Certificate
+}
+
+var _ CertificateResolver = &IdentityCertificates{}
+
+// NewIdentityCertificates creates a certificate resolver that uses the client to
+// request the identity of counterparties.
+func NewIdentityCertificates(client IdentityClient) *IdentityCertificates {
+	return &IdentityCertificates{
+		client: client,
+		certs:  make(map[string]*x509.Certificate),
+	}
+}
+
+// Certificate returns the leaf certificate of the counterparty at the address.
+func (c *IdentityCertificates) Certificate(ctx context.Context, address string) (cert *x509.Certificate, err error) {
+	var uri *url.URL
+	if uri, err = url.Parse(address); err != nil {
+		return nil, err
+	}
+
+	c.RLock()
+	cert, ok := c.certs[uri.Host]
+	c.RUnlock()
+	if ok {
+		return cert, nil
+	}
+
+	var identity *trp.Identity
+	if identity, err = c.client.Identity(ctx, address); err != nil {
+		return nil, err
+	}
+
+	if identity.X509 == "" {
+		return nil, ErrNoCertificate
+	}
+
+	var provider *trust.Provider
+	if provider, err = trust.New([]byte(identity.X509)); err != nil {
+		return nil, err
+	}
+
+	if cert, err = provider.GetLeafCertificate(); err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	c.certs[uri.Host] = cert
+	c.Unlock()
+	return cert, nil
+}
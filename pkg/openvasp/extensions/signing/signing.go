@@ -0,0 +1,274 @@
+/*
+Package signing implements the TRP message signing extension, which signs the JSON
+body of TRP requests and responses with a detached JSON Web Signature (JWS, see RFC 7515
+Appendix F) using the private key of the VASP's identity certificate. The signature is
+sent in the jws-signature header and is verified using the x509 certificate of the
+counterparty, either from the mTLS connection or from its identity endpoint.
+*/
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/trisacrypto/trisa/pkg/trust"
+)
+
+const (
+	// Extension is the name of the extension specified in the api-extensions header.
+	Extension = "message-signing"
+
+	// SignatureHeader contains the detached JWS of the request or response body.
+	SignatureHeader = "jws-signature"
+)
+
+// JWS algorithms supported for message signing.
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	ES512 = "ES512"
+	EdDSA = "EdDSA"
+)
+
+// Header is the JOSE protected header of a message signature.
+type Header struct {
+	Algorithm  string `json:"alg"`
+	Thumbprint string `json:"x5t#S256,omitempty"` // SHA-256 thumbprint of the signing certificate
+}
+
+// Signer creates detached JWS signatures using the private key of a trust provider.
+type Signer struct {
+	key    crypto.Signer
+	header Header
+}
+
+// NewSigner creates a signer from a private trust provider. The algorithm is chosen
+// based on the type of the private key and the thumbprint of the leaf certificate is
+// included in the signature so that the counterparty can identify the key.
+func NewSigner(provider *trust.Provider) (_ *Signer, err error) {
+	if !provider.IsPrivate() {
+		return nil, trust.ErrKeyRequired
+	}
+
+	var cert *x509.Certificate
+	if cert, err = provider.GetLeafCertificate(); err != nil {
+		return nil, err
+	}
+
+	var key crypto.Signer
+	switch k := provider.GetKey().(type) {
+	case *rsa.PrivateKey:
+		if key, err = provider.GetRSAKeys(); err != nil {
+			return nil, err
+		}
+	case crypto.Signer:
+		key = k
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, k)
+	}
+
+	return NewKeySigner(key, cert)
+}
+
+// NewKeySigner creates a signer from a crypto.Signer whose private key is not held in
+// memory, e.g. a key stored in an HSM or a cloud KMS. The certificate must be the
+// certificate of the public key of the signer.
+func NewKeySigner(key crypto.Signer, cert *x509.Certificate) (_ *Signer, err error) {
+	if key == nil || cert == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	var alg string
+	if alg, err = algorithm(key.Public()); err != nil {
+		return nil, err
+	}
+
+	return &Signer{
+		key:    key,
+		header: Header{Algorithm: alg, Thumbprint: Thumbprint(cert)},
+	}, nil
+}
+
+// Algorithm returns the JWS algorithm used by the signer.
+func (s *Signer) Algorithm() string {
+	return s.header.Algorithm
+}
+
+// Sign the payload, returning the compact serialization of the JWS with a detached
+// payload, e.g. header..signature.
+func (s *Signer) Sign(payload []byte) (_ string, err error) {
+	var header []byte
+	if header, err = json.Marshal(s.header); err != nil {
+		return "", err
+	}
+
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	input := signingInput(protected, payload)
+
+	var sig []byte
+	switch s.header.Algorithm {
+	case EdDSA:
+		if sig, err = s.key.Sign(rand.Reader, input, crypto.Hash(0)); err != nil {
+			return "", err
+		}
+	case RS256:
+		if sig, err = s.key.Sign(rand.Reader, digest(crypto.SHA256, input), crypto.SHA256); err != nil {
+			return "", err
+		}
+	default:
+		hash := ecdsaHash(s.header.Algorithm)
+		if sig, err = s.key.Sign(rand.Reader, digest(hash, input), hash); err != nil {
+			return "", err
+		}
+
+		// Signers return ASN.1 encoded ECDSA signatures but JWS ECDSA signatures are the
+		// fixed size concatenation of r and s.
+		var parsed struct{ R, S *big.Int }
+		if _, err = asn1.Unmarshal(sig, &parsed); err != nil {
+			return "", fmt.Errorf("could not parse ecdsa signature: %w", err)
+		}
+
+		size := (s.key.Public().(*ecdsa.PublicKey).Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		parsed.R.FillBytes(sig[:size])
+		parsed.S.FillBytes(sig[size:])
+	}
+
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Verify the detached JWS signature of the payload using the public key of the
+// certificate. If the signature contains a certificate thumbprint, it must match the
+// thumbprint of the certificate.
+func Verify(signature string, payload []byte, cert *x509.Certificate) (err error) {
+	if cert == nil {
+		return ErrNoCertificate
+	}
+
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return ErrMalformedSignature
+	}
+
+	var data []byte
+	if data, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return ErrMalformedSignature
+	}
+
+	header := Header{}
+	if err = json.Unmarshal(data, &header); err != nil {
+		return ErrMalformedSignature
+	}
+
+	var sig []byte
+	if sig, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return ErrMalformedSignature
+	}
+
+	if header.Thumbprint != "" && header.Thumbprint != Thumbprint(cert) {
+		return ErrCertificateMismatch
+	}
+
+	var alg string
+	if alg, err = algorithm(cert.PublicKey); err != nil {
+		return err
+	}
+
+	if header.Algorithm != alg {
+		return fmt.Errorf("%w: algorithm %q does not match certificate key", ErrInvalidSignature, header.Algorithm)
+	}
+
+	input := signingInput(parts[0], payload)
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest(crypto.SHA256, input), sig); err != nil {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, input, sig) {
+			return ErrInvalidSignature
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return ErrInvalidSignature
+		}
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest(ecdsaHash(alg), input), r, s) {
+			return ErrInvalidSignature
+		}
+	}
+	return nil
+}
+
+// Thumbprint returns the base64 URL encoded SHA-256 thumbprint of the certificate.
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Returns the JWS algorithm for the public key.
+func algorithm(pub crypto.PublicKey) (string, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return RS256, nil
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return ES256, nil
+		case 384:
+			return ES384, nil
+		case 521:
+			return ES512, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
+
+func ecdsaHash(alg string) crypto.Hash {
+	switch alg {
+	case ES384:
+		return crypto.SHA384
+	case ES512:
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	switch hash {
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+}
+
+// The JWS signing input is the protected header and the payload, both base64 URL
+// encoded and joined by a period.
+func signingInput(protected string, payload []byte) []byte {
+	return []byte(protected + "." + base64.RawURLEncoding.EncodeToString(payload))
+}
@@ -0,0 +1,434 @@
+package signing_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/client"
+	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/signing"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/trust"
+)
+
+var payload = []byte(`{"txid": "0x2d3e4b6f9a9a7c1e6b2b0f5d7c8e9f0a1b2c3d4e"}`)
+
+func TestSignVerify(t *testing.T) {
+	ec256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ec521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+	rs, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	testCases := []struct {
+		key crypto.Signer
+		alg string
+	}{
+		{rs, signing.RS256},
+		{ec256, signing.ES256},
+		{ec384, signing.ES384},
+		{ec521, signing.ES512},
+		{ed, signing.EdDSA},
+	}
+
+	other := certificate(t, newProvider(t, ec256))
+	for _, tc := range testCases {
+		provider := newProvider(t, tc.key)
+		cert := certificate(t, provider)
+
+		signer, err := signing.NewSigner(provider)
+		require.NoError(t, err, "could not create %s signer", tc.alg)
+		require.Equal(t, tc.alg, signer.Algorithm())
+
+		sig, err := signer.Sign(payload)
+		require.NoError(t, err, "could not sign with %s", tc.alg)
+		require.Len(t, strings.Split(sig, "."), 3)
+		require.Contains(t, sig, "..", "expected a detached payload")
+
+		require.NoError(t, signing.Verify(sig, payload, cert), "could not verify %s signature", tc.alg)
+		require.ErrorIs(t, signing.Verify(sig, []byte(`{"txid": "foo"}`), cert), signing.ErrInvalidSignature)
+		require.ErrorIs(t, signing.Verify(sig, payload, other), signing.ErrCertificateMismatch)
+		require.ErrorIs(t, signing.Verify(sig, payload, nil), signing.ErrNoCertificate)
+	}
+
+	// Public providers cannot sign messages
+	_, err := signing.NewSigner(newProvider(t, ed).Public())
+	require.ErrorIs(t, err, trust.ErrKeyRequired)
+
+	// Keys that are not held in memory (e.g. in an HSM or KMS) can sign messages
+	for _, key := range []crypto.Signer{ec256, ec384, rs} {
+		cert := certificate(t, newProvider(t, key))
+		signer, err := signing.NewKeySigner(opaqueSigner{key}, cert)
+		require.NoError(t, err)
+
+		sig, err := signer.Sign(payload)
+		require.NoError(t, err, "could not sign with %s opaque signer", signer.Algorithm())
+		require.NoError(t, signing.Verify(sig, payload, cert), "could not verify %s signature", signer.Algorithm())
+	}
+
+	_, err = signing.NewKeySigner(ec256, nil)
+	require.ErrorIs(t, err, signing.ErrNoSigningKey)
+
+	cert := certificate(t, newProvider(t, ed))
+	for _, sig := range []string{"", "foo", "a.b.c", "a..c", "e30..AAAA", "!!!..AAAA"} {
+		require.Error(t, signing.Verify(sig, payload, cert), "expected %q to be invalid", sig)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := newProvider(t, must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)))
+	originator := newProvider(t, must(ecdsa.GenerateKey(elliptic.P384(), rand.Reader)))
+
+	serverSigner, err := signing.NewSigner(server)
+	require.NoError(t, err)
+	originatorSigner, err := signing.NewSigner(originator)
+	require.NoError(t, err)
+
+	certs := func(r *http.Request) (*x509.Certificate, error) {
+		return certificate(t, originator), nil
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	})
+
+	makeRequest := func(handler http.Handler, method, signature, extensions string) *http.Response {
+		r := httptest.NewRequest(method, "/", strings.NewReader(string(payload)))
+		if signature != "" {
+			r.Header.Set(signing.SignatureHeader, signature)
+		}
+		if extensions != "" {
+			r.Header.Set(openvasp.APIExtensionsHeader, extensions)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	signature, err := originatorSigner.Sign(payload)
+	require.NoError(t, err)
+
+	invalid, err := serverSigner.Sign(payload)
+	require.NoError(t, err)
+
+	t.Run("Required", func(t *testing.T) {
+		handler := signing.Handler(serverSigner, certs, true, next)
+
+		rep := makeRequest(handler, http.MethodPost, "", "")
+		require.Equal(t, http.StatusBadRequest, rep.StatusCode)
+
+		rep = makeRequest(handler, http.MethodPost, invalid, signing.Extension)
+		require.Equal(t, http.StatusUnauthorized, rep.StatusCode)
+
+		rep = makeRequest(handler, http.MethodPost, signature, signing.Extension)
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+
+		body, err := io.ReadAll(rep.Body)
+		require.NoError(t, err)
+		require.Equal(t, payload, body)
+		require.NoError(t, signing.Verify(rep.Header.Get(signing.SignatureHeader), body, certificate(t, server)))
+
+		// Discoverability requests are not signed
+		rep = makeRequest(handler, http.MethodGet, "", "")
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+		require.Empty(t, rep.Header.Get(signing.SignatureHeader))
+	})
+
+	t.Run("Optional", func(t *testing.T) {
+		handler := signing.Handler(nil, certs, false, next)
+
+		rep := makeRequest(handler, http.MethodPost, "", "")
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+
+		rep = makeRequest(handler, http.MethodPost, "", "extended-ivms101, message-signing")
+		require.Equal(t, http.StatusBadRequest, rep.StatusCode)
+
+		rep = makeRequest(handler, http.MethodPost, signature, "")
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+		require.Empty(t, rep.Header.Get(signing.SignatureHeader))
+
+		// Only the responses to signed requests are signed
+		handler = signing.Handler(serverSigner, certs, false, next)
+		rep = makeRequest(handler, http.MethodPost, "", "")
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+		require.Empty(t, rep.Header.Get(signing.SignatureHeader))
+
+		rep = makeRequest(handler, http.MethodPost, signature, "")
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+		require.NotEmpty(t, rep.Header.Get(signing.SignatureHeader))
+	})
+
+	t.Run("PeerCertificate", func(t *testing.T) {
+		// Without an mTLS connection there is no certificate to verify the signature
+		handler := signing.Handler(serverSigner, nil, true, next)
+		rep := makeRequest(handler, http.MethodPost, signature, "")
+		require.Equal(t, http.StatusUnauthorized, rep.StatusCode)
+	})
+}
+
+func TestSignedTransfer(t *testing.T) {
+	beneficiary := newProvider(t, must(rsa.GenerateKey(rand.Reader, 2048)))
+	originator := newProvider(t, must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)))
+	impostor := newProvider(t, must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)))
+
+	beneficiarySigner, err := signing.NewSigner(beneficiary)
+	require.NoError(t, err)
+	originatorSigner, err := signing.NewSigner(originator)
+	require.NoError(t, err)
+	impostorSigner, err := signing.NewSigner(impostor)
+	require.NoError(t, err)
+
+	srv, err := openvasp.NewServer(&handler{identity: beneficiary}, openvasp.WithRequiredExtensions(signing.Extension))
+	require.NoError(t, err)
+
+	certs := func(r *http.Request) (*x509.Certificate, error) {
+		return certificate(t, originator), nil
+	}
+
+	ctx := context.Background()
+	inquiry := &trp.Inquiry{Amount: 1.0, Callback: "https://originator.com/callback", IVMS101: &ivms101.IdentityPayload{}}
+
+	t.Run("Signed", func(t *testing.T) {
+		ts := httptest.NewServer(signing.Handler(beneficiarySigner, certs, true, srv))
+		defer ts.Close()
+
+		trpc, err := client.New(client.WithMessageSigning(originatorSigner, true))
+		require.NoError(t, err)
+		require.Equal(t, []string{signing.Extension}, trpc.APIExtensions())
+
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		out, err := trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err, "could not send signed inquiry")
+		require.NotNil(t, out.Approved)
+		require.Equal(t, []string{signing.Extension}, out.Info.APIExtensions)
+
+		err = trpc.Confirm(ctx, &trp.Confirmation{Info: &trp.Info{Address: ts.URL + openvasp.ConfirmationEndpoint}, TXID: "foo"})
+		require.NoError(t, err, "could not send signed confirmation")
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		ts := httptest.NewServer(signing.Handler(beneficiarySigner, certs, true, srv))
+		defer ts.Close()
+
+		trpc, err := client.New(client.WithAPIExtensions(signing.Extension))
+		require.NoError(t, err)
+
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		_, err = trpc.Inquiry(ctx, inquiry)
+		requireStatus(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("Impostor", func(t *testing.T) {
+		// The responses are not signed with the key of the identity certificate
+		ts := httptest.NewServer(signing.Handler(impostorSigner, certs, true, srv))
+		defer ts.Close()
+
+		trpc, err := client.New(client.WithMessageSigning(originatorSigner, true))
+		require.NoError(t, err)
+
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		_, err = trpc.Inquiry(ctx, inquiry)
+		require.ErrorIs(t, err, signing.ErrCertificateMismatch)
+
+		// The requests are not signed with the key of the originator certificate
+		ts = httptest.NewServer(signing.Handler(beneficiarySigner, certs, true, srv))
+		defer ts.Close()
+
+		trpc, err = client.New(client.WithMessageSigning(impostorSigner, true))
+		require.NoError(t, err)
+
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		_, err = trpc.Inquiry(ctx, inquiry)
+		requireStatus(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("UnsignedResponse", func(t *testing.T) {
+		ts := httptest.NewServer(signing.Handler(nil, certs, true, srv))
+		defer ts.Close()
+
+		trpc, err := client.New(client.WithMessageSigning(originatorSigner, true))
+		require.NoError(t, err)
+
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		_, err = trpc.Inquiry(ctx, inquiry)
+		require.ErrorIs(t, err, signing.ErrMissingSignature)
+	})
+}
+
+func TestWithMessageSigning(t *testing.T) {
+	beneficiary := newProvider(t, must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)))
+	originator := newProvider(t, must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader)))
+
+	beneficiarySigner, err := signing.NewSigner(beneficiary)
+	require.NoError(t, err)
+	originatorSigner, err := signing.NewSigner(originator)
+	require.NoError(t, err)
+
+	certs := func(r *http.Request) (*x509.Certificate, error) {
+		return certificate(t, originator), nil
+	}
+
+	ctx := context.Background()
+	inquiry := &trp.Inquiry{Amount: 1.0, Callback: "https://originator.com/callback", IVMS101: &ivms101.IdentityPayload{}}
+
+	t.Run("Required", func(t *testing.T) {
+		srv, err := openvasp.NewServer(&handler{identity: beneficiary}, signing.WithMessageSigning(beneficiarySigner, certs, true))
+		require.NoError(t, err)
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		trpc, err := client.New(client.WithMessageSigning(originatorSigner, true))
+		require.NoError(t, err)
+
+		extensions, err := trpc.Extensions(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Equal(t, []string{signing.Extension}, extensions.Required)
+		require.Empty(t, extensions.Supported)
+
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		out, err := trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err, "could not send signed inquiry")
+		require.NotNil(t, out.Approved)
+
+		err = trpc.Confirm(ctx, &trp.Confirmation{Info: &trp.Info{Address: ts.URL + openvasp.ConfirmationEndpoint}, TXID: "foo"})
+		require.NoError(t, err, "could not send signed confirmation")
+
+		// Unsigned requests are rejected
+		trpc, err = client.New()
+		require.NoError(t, err)
+
+		_, err = trpc.Inquiry(ctx, inquiry)
+		requireStatus(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("Supported", func(t *testing.T) {
+		srv, err := openvasp.NewServer(&handler{identity: beneficiary},
+			openvasp.WithSupportedExtensions("extended-ivms101"),
+			signing.WithMessageSigning(beneficiarySigner, certs, false),
+		)
+		require.NoError(t, err)
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		trpc, err := client.New()
+		require.NoError(t, err)
+
+		extensions, err := trpc.Extensions(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Empty(t, extensions.Required)
+		require.Equal(t, []string{"extended-ivms101", signing.Extension}, extensions.Supported)
+
+		// Unsigned requests are accepted but their responses are not signed
+		inquiry.Info = &trp.Info{Address: ts.URL}
+		_, err = trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err, "could not send unsigned inquiry")
+
+		trpc, err = client.New(client.WithMessageSigning(originatorSigner, true))
+		require.NoError(t, err)
+
+		_, err = trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err, "could not send signed inquiry")
+	})
+}
+
+// handler implements the openvasp.Handler interface for testing.
+type handler struct {
+	identity *trust.Provider
+}
+
+func (h *handler) OnIdentity() (*trp.Identity, error) {
+	return openvasp.NewIdentity("Beneficiary VASP", "", h.identity)
+}
+
+func (h *handler) OnInquiry(*trp.Inquiry) (*trp.Resolution, error) {
+	return &trp.Resolution{Approved: &trp.Approval{Address: "payment address", Callback: "https://beneficiary.com/confirm"}}, nil
+}
+
+func (h *handler) OnResolution(*trp.Resolution) error {
+	return nil
+}
+
+func (h *handler) OnConfirmation(*trp.Confirmation) error {
+	return nil
+}
+
+func requireStatus(t *testing.T, err error, code int) {
+	var status *trp.StatusError
+	require.ErrorAs(t, err, &status)
+	require.Equal(t, code, status.Code)
+}
+
+// Creates a provider with a self-signed certificate for the key.
+func newProvider(t *testing.T, key crypto.Signer) *trust.Provider {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "test.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().AddDate(0, 0, 7),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err, "could not create certificate")
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	chain, err := trust.PEMEncodeCertificate(cert)
+	require.NoError(t, err)
+
+	pk, err := trust.PEMEncodePrivateKey(key)
+	require.NoError(t, err)
+
+	provider, err := trust.New(append(chain, pk...))
+	require.NoError(t, err, "could not create provider")
+	return provider
+}
+
+func certificate(t *testing.T, provider *trust.Provider) *x509.Certificate {
+	cert, err := provider.GetLeafCertificate()
+	require.NoError(t, err)
+	return cert
+}
+
+// opaqueSigner hides the concrete type of the private key like an HSM or KMS signer.
+type opaqueSigner struct {
+	key crypto.Signer
+}
+
+func (s opaqueSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
+func must[T any](key T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
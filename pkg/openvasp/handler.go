@@ -1,6 +1,7 @@
 package openvasp
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/discoverability"
 	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
 )
 
@@ -18,7 +20,24 @@ const (
 )
 
 func TransferInquiry(handler InquiryHandler) http.Handler {
-	return APIChecks(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return APIChecks(transferInquiry(handler))
+}
+
+// Returns the inquiry handler without the API checks so that they can be configured.
+func transferInquiry(handler InquiryHandler) http.Handler {
+	return inquiryHandler(func(inquiry *trp.Inquiry) (*trp.Resolution, error) {
+		// Validate the inquiry received
+		if err := ValidateInquiry(inquiry); err != nil {
+			return nil, &trp.StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+		return handler.OnInquiry(inquiry)
+	})
+}
+
+// Decodes the travel rule inquiry and its TRISA extensions then writes the resolution
+// returned by the inquiry handler function.
+func inquiryHandler(onInquiry func(*trp.Inquiry) (*trp.Resolution, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Decode the travel rule inquiry
 		var inquiry *trp.Inquiry
 		if err := decodeJSON(w, r, &inquiry); err != nil {
@@ -26,12 +45,21 @@ func TransferInquiry(handler InquiryHandler) http.Handler {
 			return
 		}
 
+		if inquiry == nil {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
 		// Add the TRP Info to the inquiry from the headers
 		inquiry.Info = ParseTRPInfo(r)
 
-		// TODO: validate the inquiry received
+		// Decode the TRISA extensions into their typed values
+		if err := DecodeTRISAExtensions(inquiry); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		out, err := handler.OnInquiry(inquiry)
+		out, err := onInquiry(inquiry)
 		if err != nil {
 			httpError(w, err)
 			return
@@ -42,20 +70,61 @@ func TransferInquiry(handler InquiryHandler) http.Handler {
 			out = &trp.Resolution{}
 		}
 
-		// If not automatically approved or rejected, add the API version to the reply.
+		// If not automatically approved or rejected, add the negotiated API version to
+		// the reply.
 		if out.Approved == nil && out.Rejected == "" {
-			out.Version = APIVersion
+			if out.Version = inquiry.Info.APIVersion; out.Version == "" {
+				out.Version = APIVersion
+			}
 		}
 
 		// Default response is 200 with the API Version
 		w.Header().Set(ContentTypeHeader, ContentTypeValue)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(out)
-	}))
+	})
+}
+
+func TransferResolution(handler ResolutionHandler) http.Handler {
+	return APIChecks(transferResolution(handler))
+}
+
+// Returns the resolution handler without the API checks so that they can be configured.
+func transferResolution(handler ResolutionHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Decode the resolution callback message
+		var resolution trp.Resolution
+		if err := decodeJSON(w, r, &resolution); err != nil {
+			httpError(w, err)
+			return
+		}
+
+		// Add the TRP Info to the resolution from the headers
+		resolution.Info = ParseTRPInfo(r)
+
+		// Validate the resolution message
+		if err := resolution.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := handler.OnResolution(&resolution); err != nil {
+			httpError(w, err)
+			return
+		}
+
+		// If the resolution is successfully handled then a 204 no-content is returned.
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 func TransferConfirmation(handler ConfirmationHandler) http.Handler {
-	return APIChecks(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return APIChecks(transferConfirmation(handler))
+}
+
+// Returns the confirmation handler without the API checks so that they can be configured.
+func transferConfirmation(handler ConfirmationHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Decode the confirmation message
 		var confirmation trp.Confirmation
 		if err := decodeJSON(w, r, &confirmation); err != nil {
@@ -79,12 +148,23 @@ func TransferConfirmation(handler ConfirmationHandler) http.Handler {
 
 		// If the confirmation is successful then a 204 no-content is returned.
 		w.WriteHeader(http.StatusNoContent)
-	}))
+	})
 }
 
 // APIChecks is middleware that asserts that the headers in the TRP request are correct
-// and valid, ensuring that the core protocol is implemented correctly.
+// and valid, ensuring that the core protocol is implemented correctly. Only the
+// DefaultVersions of the TRP API are accepted; use APIVersionChecks to negotiate
+// other versions.
 func APIChecks(next http.Handler) http.Handler {
+	return APIVersionChecks(DefaultVersions, next)
+}
+
+// APIVersionChecks performs the same checks as APIChecks but accepts any api-version
+// in the specified range, echoing the version back to the client. If an adapter is
+// registered for the major version of the request, the JSON request body is upgraded
+// to the current version before it is handled and the JSON response is downgraded to
+// the version of the request.
+func APIVersionChecks(versions *VersionRange, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// A POST request is expected both for inquiries and confirmations.
 		if r.Method != http.MethodPost {
@@ -93,15 +173,19 @@ func APIChecks(next http.Handler) http.Handler {
 		}
 
 		// Enforce Application Version
-		// TODO: add supported versions
 		apiVersion := r.Header.Get(APIVersionHeader)
-		if apiVersion != APIVersion {
+		if apiVersion == "" {
 			http.Error(w, "must specify api version header "+APIVersion, http.StatusBadRequest)
 			return
 		}
 
-		// Set the APIVersion header in the outgoing response
-		w.Header().Add(APIVersionHeader, APIVersion)
+		if !versions.Supports(apiVersion) {
+			http.Error(w, fmt.Sprintf("unsupported api version %s: must match %s", apiVersion, versions), http.StatusBadRequest)
+			return
+		}
+
+		// Set the negotiated APIVersion header in the outgoing response
+		w.Header().Add(APIVersionHeader, apiVersion)
 
 		// Must specify a request identifier
 		var requestIdentifier string
@@ -128,6 +212,134 @@ func APIChecks(next http.Handler) http.Handler {
 			}
 		}
 
+		if adapter := AdapterFor(apiVersion); adapter != nil {
+			adaptVersion(adapter, next, w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Upgrades the request body using the adapter before it is handled, then downgrades
+// the JSON response body before it is written back to the client.
+func adaptVersion(adapter Adapter, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPayloadSize))
+		if err != nil {
+			http.Error(w, "could not read request body", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if len(data) > 0 {
+			if data, err = adapter.Upgrade(data); err != nil {
+				http.Error(w, "could not adapt request to current api version: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		r.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	rec := NewBufferedWriter(w)
+	next.ServeHTTP(rec, r)
+
+	body := rec.Body()
+	if mt, _, _ := mime.ParseMediaType(w.Header().Get(ContentTypeHeader)); mt == MIMEJSON && len(body) > 0 {
+		var err error
+		if body, err = adapter.Downgrade(body); err != nil {
+			http.Error(w, "could not adapt response to requested api version", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(rec.Status())
+	w.Write(body)
+}
+
+// BufferedWriter captures the status and body of a response so that it can be
+// modified (e.g. adapted or signed) by middleware before it is written. Headers are
+// written directly to the underlying response writer.
+type BufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// NewBufferedWriter wraps the response writer with a 200 status by default.
+func NewBufferedWriter(w http.ResponseWriter) *BufferedWriter {
+	return &BufferedWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *BufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *BufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// Status returns the status code written by the handler.
+func (w *BufferedWriter) Status() int {
+	return w.status
+}
+
+// Body returns the response body written by the handler.
+func (w *BufferedWriter) Body() []byte {
+	return w.body.Bytes()
+}
+
+// ExtensionChecks is middleware that negotiates the extensions specified in the
+// api-extensions header of the TRP request. The request is rejected if it does not
+// specify all of the required extensions or if it uses an extension that is neither
+// required nor supported. The extensions that will be used to handle the request are
+// echoed back in the api-extensions header of the response.
+func ExtensionChecks(extensions *discoverability.Extensions, next http.Handler) http.Handler {
+	known := make(map[string]struct{})
+	if extensions != nil {
+		for _, ext := range extensions.Required {
+			known[ext] = struct{}{}
+		}
+		for _, ext := range extensions.Supported {
+			known[ext] = struct{}{}
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested := ParseTRPInfo(r).APIExtensions
+		present := make(map[string]struct{}, len(requested))
+
+		unsupported := make([]string, 0)
+		for _, ext := range requested {
+			present[ext] = struct{}{}
+			if _, ok := known[ext]; !ok {
+				unsupported = append(unsupported, ext)
+			}
+		}
+
+		if len(unsupported) > 0 {
+			http.Error(w, "unsupported api extensions: "+strings.Join(unsupported, ", "), http.StatusBadRequest)
+			return
+		}
+
+		if extensions != nil {
+			missing := make([]string, 0)
+			for _, ext := range extensions.Required {
+				if _, ok := present[ext]; !ok {
+					missing = append(missing, ext)
+				}
+			}
+
+			if len(missing) > 0 {
+				http.Error(w, "must specify required api extensions: "+strings.Join(missing, ", "), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Echo back the negotiated extensions in the outgoing response
+		if len(requested) > 0 {
+			w.Header().Set(APIExtensionsHeader, strings.Join(requested, ", "))
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -144,7 +356,13 @@ func ParseTRPInfo(r *http.Request) *trp.Info {
 
 	// TODO: do we need escaping or more extensive parsing?
 	if extensions := r.Header.Get(APIExtensionsHeader); extensions != "" {
-		info.APIExtensions = strings.Split(extensions, ",")
+		parts := strings.Split(extensions, ",")
+		info.APIExtensions = make([]string, 0, len(parts))
+		for _, part := range parts {
+			if part = strings.TrimSpace(part); part != "" {
+				info.APIExtensions = append(info.APIExtensions, part)
+			}
+		}
 	}
 
 	return info
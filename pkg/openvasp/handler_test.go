@@ -57,7 +57,7 @@ func TestTransferInquiry(t *testing.T) {
 				return nil, errors.New("invalid TRP info")
 			}
 
-			if i.Amount == 0 || i.Asset == nil || i.Asset.SLIP044 != slip0044.CoinType_BITCOIN || i.Callback != beneficiaryURL || i.IVMS101 == nil {
+			if i.Amount == 0 || i.Asset == nil || i.Asset.SLIP044 == nil || *i.Asset.SLIP044 != slip0044.CoinType_BITCOIN || i.Callback != beneficiaryURL || i.IVMS101 == nil {
 				return nil, errors.New("invalid payload")
 			}
 
@@ -307,13 +307,22 @@ func TestAPIChecks(t *testing.T) {
 	})
 
 	t.Run("APIVersion", func(t *testing.T) {
-		testCases := []string{"", "9.9.999"}
+		testCases := []struct {
+			version string
+			message string
+		}{
+			{"", "must specify api version header 3.1.0\n"},
+			{"9.9.999", "unsupported api version 9.9.999: must match ~3.1.0\n"},
+			{"3.0.0", "unsupported api version 3.0.0: must match ~3.1.0\n"},
+			{"foo", "unsupported api version foo: must match ~3.1.0\n"},
+		}
+
 		for i, tc := range testCases {
 			r := httptest.NewRequest(http.MethodPost, originatorURL, nil)
 			w := httptest.NewRecorder()
 
-			if tc != "" {
-				r.Header.Set(APIVersionHeader, tc)
+			if tc.version != "" {
+				r.Header.Set(APIVersionHeader, tc.version)
 			}
 
 			handler.ServeHTTP(w, r)
@@ -321,7 +330,7 @@ func TestAPIChecks(t *testing.T) {
 
 			require.Equal(t, http.StatusBadRequest, rep.StatusCode, "test case %d failed", i)
 			data, _ := io.ReadAll(rep.Body)
-			require.Equal(t, "must specify api version header 3.1.0\n", string(data), "test case %d failed", i)
+			require.Equal(t, tc.message, string(data), "test case %d failed", i)
 		}
 	})
 
@@ -519,23 +528,31 @@ func TestDecodeJSON(t *testing.T) {
 }
 
 const (
+	CallIdentity     = "OnIdentity"
 	CallInquiry      = "OnInquiry"
+	CallResolution   = "OnResolution"
 	CallConfirmation = "OnConfirmation"
 )
 
-// MockHandler implements the InquiryHandler and Confirmation Handler interface
+// MockHandler implements the Handler interface for all TRP endpoints
 type MockHandler struct {
 	sync.RWMutex
 	calls map[string]int
 
+	CallIdentity     func() (*trp.Identity, error)
 	CallInquiry      func(*trp.Inquiry) (*trp.Resolution, error)
+	CallResolution   func(*trp.Resolution) error
 	CallConfirmation func(*trp.Confirmation) error
 }
 
 func (m *MockHandler) UseError(call string, err error) {
 	switch call {
+	case CallIdentity:
+		m.CallIdentity = func() (*trp.Identity, error) { return nil, err }
 	case CallInquiry:
 		m.CallInquiry = func(*trp.Inquiry) (*trp.Resolution, error) { return nil, err }
+	case CallResolution:
+		m.CallResolution = func(*trp.Resolution) error { return err }
 	case CallConfirmation:
 		m.CallConfirmation = func(*trp.Confirmation) error { return err }
 	default:
@@ -543,6 +560,14 @@ func (m *MockHandler) UseError(call string, err error) {
 	}
 }
 
+func (m *MockHandler) OnIdentity() (*trp.Identity, error) {
+	m.incr(CallIdentity)
+	if m.CallIdentity != nil {
+		return m.CallIdentity()
+	}
+	return nil, errors.New("no mock on identity handler defined")
+}
+
 func (m *MockHandler) OnInquiry(in *trp.Inquiry) (*trp.Resolution, error) {
 	m.incr(CallInquiry)
 	if m.CallInquiry != nil {
@@ -551,6 +576,14 @@ func (m *MockHandler) OnInquiry(in *trp.Inquiry) (*trp.Resolution, error) {
 	return nil, errors.New("no mock on inquiry handler defined")
 }
 
+func (m *MockHandler) OnResolution(in *trp.Resolution) error {
+	m.incr(CallResolution)
+	if m.CallResolution != nil {
+		return m.CallResolution(in)
+	}
+	return errors.New("no mock on resolution handler defined")
+}
+
 func (m *MockHandler) OnConfirmation(in *trp.Confirmation) error {
 	m.incr(CallConfirmation)
 	if m.CallConfirmation != nil {
@@ -566,7 +599,9 @@ func (m *MockHandler) Reset() {
 		m.calls[call] = 0
 	}
 
+	m.CallIdentity = nil
 	m.CallInquiry = nil
+	m.CallResolution = nil
 	m.CallConfirmation = nil
 }
 
@@ -2,10 +2,27 @@ package openvasp
 
 import "github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
 
+// Handler implements all of the TRP endpoints so that a single type can be used to
+// create a Server that mounts the complete Travel Rule Protocol.
+type Handler interface {
+	IdentityHandler
+	InquiryHandler
+	ResolutionHandler
+	ConfirmationHandler
+}
+
+type IdentityHandler interface {
+	OnIdentity() (*trp.Identity, error)
+}
+
 type InquiryHandler interface {
 	OnInquiry(*trp.Inquiry) (*trp.Resolution, error)
 }
 
+type ResolutionHandler interface {
+	OnResolution(*trp.Resolution) error
+}
+
 type ConfirmationHandler interface {
 	OnConfirmation(*trp.Confirmation) error
 }
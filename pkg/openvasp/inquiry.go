@@ -0,0 +1,179 @@
+package openvasp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+)
+
+// Inquiry unifies a TRP inquiry with the TRISA payload that was sent in a sealed or
+// unsealed TRISA envelope extension. If the inquiry was sent with an envelope, then
+// the asset, amount, and IVMS101 fields of the TRP inquiry are populated from the
+// payload when they were not specified by the counterparty. If the inquiry was sent
+// without a TRISA extension, the envelope and payload are nil.
+type Inquiry struct {
+	*trp.Inquiry
+	Envelope *envelope.Envelope // The opened (decrypted) envelope from the extension
+	Payload  *api.Payload       // The decrypted TRISA payload from the extension
+}
+
+// TRISAInquiryHandler handles TRP inquiries whose TRISA envelope extensions have
+// already been opened by the TRISATransferInquiry handler.
+type TRISAInquiryHandler interface {
+	OnTRISAInquiry(*Inquiry) (*trp.Resolution, error)
+}
+
+// TRISATransferInquiry is a TRP transfer inquiry handler that opens any sealed or
+// unsealed TRISA envelope extensions using the unsealing key (which may be a
+// keys.PrivateKey or an *rsa.PrivateKey) and validates the unified inquiry before
+// passing it to the handler. If the unsealing key is nil, inquiries with sealed
+// TRISA envelopes are rejected.
+func TRISATransferInquiry(handler TRISAInquiryHandler, unsealingKey interface{}) http.Handler {
+	return APIChecks(trisaTransferInquiry(handler, unsealingKey))
+}
+
+// Returns the TRISA inquiry handler without the API checks so they can be configured.
+func trisaTransferInquiry(handler TRISAInquiryHandler, unsealingKey interface{}) http.Handler {
+	return inquiryHandler(func(in *trp.Inquiry) (*trp.Resolution, error) {
+		inquiry, err := UnwrapInquiry(in, unsealingKey)
+		if err != nil {
+			return nil, &trp.StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+
+		if err = inquiry.Validate(); err != nil {
+			return nil, &trp.StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+		}
+
+		return handler.OnTRISAInquiry(inquiry)
+	})
+}
+
+// UnwrapInquiry decodes the TRISA extensions of the inquiry and opens the envelope,
+// returning a unified inquiry with both the TRP fields and the decrypted payload.
+func UnwrapInquiry(in *trp.Inquiry, unsealingKey interface{}) (inquiry *Inquiry, err error) {
+	if err = DecodeTRISAExtensions(in); err != nil {
+		return nil, err
+	}
+
+	inquiry = &Inquiry{Inquiry: in}
+	sealed, unsealed := TRISAExtensions(in)
+
+	var env *envelope.Envelope
+	switch {
+	case sealed != nil && unsealed != nil:
+		return nil, trp.ErrAmbiguousTRISA
+
+	case sealed != nil:
+		if unsealingKey == nil {
+			return nil, trp.ErrUnsealingKeyRequired
+		}
+
+		var msg *api.SecureEnvelope
+		if msg, err = sealed.SecureEnvelope(); err != nil {
+			return nil, fmt.Errorf("could not parse sealed trisa envelope: %w", err)
+		}
+
+		if env, _, err = envelope.Open(msg, envelope.WithUnsealingKey(unsealingKey)); err != nil {
+			return nil, fmt.Errorf("could not open sealed trisa envelope: %w", err)
+		}
+
+	case unsealed != nil:
+		if env, err = envelope.Wrap(unsealed.SecureEnvelope()); err != nil {
+			return nil, fmt.Errorf("could not parse unsealed trisa envelope: %w", err)
+		}
+
+		if env, _, err = env.Decrypt(); err != nil {
+			return nil, fmt.Errorf("could not decrypt unsealed trisa envelope: %w", err)
+		}
+
+	default:
+		return inquiry, nil
+	}
+
+	inquiry.Envelope = env
+	if inquiry.Payload, err = env.Payload(); err != nil {
+		return nil, err
+	}
+
+	if err = populateInquiry(in, inquiry.Payload); err != nil {
+		return nil, fmt.Errorf("could not populate inquiry from trisa payload: %w", err)
+	}
+	return inquiry, nil
+}
+
+// DecodeTRISAExtensions replaces the generic JSON values of the sealed and unsealed
+// TRISA envelope extensions in the inquiry with *SealedTRISAEnvelope and
+// *UnsealedTRISAEnvelope values respectively. Other extensions are not modified.
+func DecodeTRISAExtensions(in *trp.Inquiry) (err error) {
+	if ext, ok := in.Extensions[SealedTRISAExtension]; ok {
+		if _, ok := ext.(*SealedTRISAEnvelope); !ok {
+			sealed := &SealedTRISAEnvelope{}
+			if err = decodeExtension(ext, sealed); err != nil {
+				return fmt.Errorf("could not decode %s extension: %w", SealedTRISAExtension, err)
+			}
+			in.Extensions[SealedTRISAExtension] = sealed
+		}
+	}
+
+	if ext, ok := in.Extensions[UnsealedTRISAExtension]; ok {
+		if _, ok := ext.(*UnsealedTRISAEnvelope); !ok {
+			unsealed := &UnsealedTRISAEnvelope{}
+			if err = decodeExtension(ext, unsealed); err != nil {
+				return fmt.Errorf("could not decode %s extension: %w", UnsealedTRISAExtension, err)
+			}
+			in.Extensions[UnsealedTRISAExtension] = unsealed
+		}
+	}
+	return nil
+}
+
+// TRISAExtensions returns the decoded sealed and unsealed TRISA envelope extensions
+// of the inquiry or nil if the extension is not present or has not been decoded.
+func TRISAExtensions(in *trp.Inquiry) (sealed *SealedTRISAEnvelope, unsealed *UnsealedTRISAEnvelope) {
+	sealed, _ = in.Extensions[SealedTRISAExtension].(*SealedTRISAEnvelope)
+	unsealed, _ = in.Extensions[UnsealedTRISAExtension].(*UnsealedTRISAEnvelope)
+	return sealed, unsealed
+}
+
+// ValidateInquiry validates a TRP inquiry that may contain TRISA envelope extensions.
+// If a TRISA envelope is present, the identity and transaction information is in the
+// envelope, so only the callback and the asset (if specified) are validated.
+func ValidateInquiry(in *trp.Inquiry) error {
+	_, hasSealed := in.Extensions[SealedTRISAExtension]
+	_, hasUnsealed := in.Extensions[UnsealedTRISAExtension]
+
+	if !hasSealed && !hasUnsealed {
+		return in.Validate()
+	}
+
+	if hasSealed && hasUnsealed {
+		return trp.ErrAmbiguousTRISA
+	}
+
+	if in.Asset != nil {
+		if err := in.Asset.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if in.Callback == "" {
+		return trp.ErrEmptyCallback
+	}
+	return nil
+}
+
+func decodeExtension(ext, dst interface{}) (err error) {
+	var data []byte
+	if data, err = json.Marshal(ext); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
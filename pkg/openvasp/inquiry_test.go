@@ -0,0 +1,234 @@
+package openvasp_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	. "github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestUnwrapInquiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate rsa key")
+
+	payload := loadPayload(t)
+
+	t.Run("Sealed", func(t *testing.T) {
+		env, _, err := envelope.Seal(payload, envelope.WithRSAPublicKey(&key.PublicKey))
+		require.NoError(t, err, "could not seal envelope")
+
+		in := roundTripInquiry(t, env)
+		_, isMap := in.Extensions[SealedTRISAExtension].(map[string]interface{})
+		require.True(t, isMap, "expected extension to be generic JSON before decoding")
+
+		inquiry, err := UnwrapInquiry(in, key)
+		require.NoError(t, err, "could not unwrap inquiry")
+		require.True(t, proto.Equal(payload, inquiry.Payload), "payload does not match")
+		require.Equal(t, env.ID(), inquiry.Envelope.ID())
+		require.Equal(t, 0.00206412, inquiry.Amount)
+		require.NotNil(t, inquiry.IVMS101)
+		require.NoError(t, inquiry.Validate())
+
+		sealed, unsealed := TRISAExtensions(in)
+		require.NotNil(t, sealed)
+		require.Nil(t, unsealed)
+
+		// An unsealing key is required to open a sealed envelope
+		_, err = UnwrapInquiry(roundTripInquiry(t, env), nil)
+		require.ErrorIs(t, err, trp.ErrUnsealingKeyRequired)
+
+		// The wrong key cannot open the envelope
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err, "could not generate rsa key")
+		_, err = UnwrapInquiry(roundTripInquiry(t, env), other)
+		require.Error(t, err)
+	})
+
+	t.Run("Unsealed", func(t *testing.T) {
+		env, _, err := envelope.Seal(payload, envelope.WithRSAPublicKey(&key.PublicKey))
+		require.NoError(t, err, "could not seal envelope")
+		env, _, err = env.Unseal(envelope.WithRSAPrivateKey(key))
+		require.NoError(t, err, "could not unseal envelope")
+
+		inquiry, err := UnwrapInquiry(roundTripInquiry(t, env), nil)
+		require.NoError(t, err, "could not unwrap inquiry")
+		require.True(t, proto.Equal(payload, inquiry.Payload), "payload does not match")
+		require.NoError(t, inquiry.Validate())
+	})
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		in := &trp.Inquiry{
+			Callback: beneficiaryURL,
+			Extensions: map[string]interface{}{
+				SealedTRISAExtension:   map[string]interface{}{"envelope": "{}"},
+				UnsealedTRISAExtension: map[string]interface{}{"id": "foo"},
+			},
+		}
+
+		_, err := UnwrapInquiry(in, key)
+		require.ErrorIs(t, err, trp.ErrAmbiguousTRISA)
+		require.ErrorIs(t, ValidateInquiry(in), trp.ErrAmbiguousTRISA)
+	})
+
+	t.Run("NoExtensions", func(t *testing.T) {
+		in, err := loadInquiryPayload("testdata/inquiry.json")
+		require.NoError(t, err, "could not load inquiry fixture")
+
+		inquiry, err := UnwrapInquiry(in, nil)
+		require.NoError(t, err)
+		require.Nil(t, inquiry.Envelope)
+		require.Nil(t, inquiry.Payload)
+		require.Same(t, in, inquiry.Inquiry)
+	})
+
+	t.Run("BadExtension", func(t *testing.T) {
+		in := &trp.Inquiry{
+			Extensions: map[string]interface{}{
+				UnsealedTRISAExtension: map[string]interface{}{"payload": 42},
+			},
+		}
+		require.Error(t, DecodeTRISAExtensions(in))
+	})
+}
+
+func TestTRISATransferInquiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate rsa key")
+
+	payload := loadPayload(t)
+	env, _, err := envelope.Seal(payload, envelope.WithRSAPublicKey(&key.PublicKey))
+	require.NoError(t, err, "could not seal envelope")
+
+	mock := &MockTRISAHandler{}
+	handler := TRISATransferInquiry(mock, key)
+
+	makeRequest := func(t *testing.T, in *trp.Inquiry) *http.Response {
+		var body bytes.Buffer
+		require.NoError(t, json.NewEncoder(&body).Encode(in), "could not encode json payload")
+
+		req := httptest.NewRequest(http.MethodPost, originatorURL, &body)
+		req.Header.Set(APIVersionHeader, APIVersion)
+		req.Header.Set(RequestIdentifierHeader, requestIdentifier)
+		req.Header.Set(ContentTypeHeader, ContentTypeValue)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Result()
+	}
+
+	t.Run("Sealed", func(t *testing.T) {
+		mock.OnTRISAInquiryFunc = func(i *Inquiry) (*trp.Resolution, error) {
+			if i.Payload == nil || i.IVMS101 == nil || i.Info.RequestIdentifier != requestIdentifier {
+				return nil, errors.New("invalid unified inquiry")
+			}
+			return &trp.Resolution{Approved: &trp.Approval{Address: "some payment address", Callback: beneficiaryURL}}, nil
+		}
+
+		in, err := EnvelopeToPayload(env)
+		require.NoError(t, err)
+		in.Callback = beneficiaryURL
+
+		rep := makeRequest(t, in)
+		require.Equal(t, http.StatusOK, rep.StatusCode)
+
+		out := &trp.Resolution{}
+		require.NoError(t, json.NewDecoder(rep.Body).Decode(out))
+		require.NotNil(t, out.Approved)
+	})
+
+	t.Run("MissingCallback", func(t *testing.T) {
+		mock.OnTRISAInquiryFunc = nil
+
+		in, err := EnvelopeToPayload(env)
+		require.NoError(t, err)
+
+		rep := makeRequest(t, in)
+		require.Equal(t, http.StatusBadRequest, rep.StatusCode)
+
+		data, err := io.ReadAll(rep.Body)
+		require.NoError(t, err)
+		require.Equal(t, trp.ErrEmptyCallback.Error()+"\n", string(data))
+	})
+}
+
+func TestTransferInquiryValidation(t *testing.T) {
+	mock := &MockHandler{}
+	handler := TransferInquiry(mock)
+
+	in, err := loadInquiryPayload("testdata/inquiry.json")
+	require.NoError(t, err, "could not load inquiry fixture")
+	in.Amount = 0
+
+	var body bytes.Buffer
+	require.NoError(t, json.NewEncoder(&body).Encode(in), "could not encode json payload")
+
+	req := httptest.NewRequest(http.MethodPost, originatorURL, &body)
+	req.Header.Set(APIVersionHeader, APIVersion)
+	req.Header.Set(RequestIdentifierHeader, requestIdentifier)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, 0, mock.Calls(CallInquiry))
+
+	rep := w.Result()
+	require.Equal(t, http.StatusBadRequest, rep.StatusCode)
+
+	data, err := io.ReadAll(rep.Body)
+	require.NoError(t, err)
+	require.Equal(t, trp.ErrNoAmount.Error()+"\n", string(data))
+}
+
+// MockTRISAHandler implements the TRISAInquiryHandler interface
+type MockTRISAHandler struct {
+	OnTRISAInquiryFunc func(*Inquiry) (*trp.Resolution, error)
+}
+
+func (m *MockTRISAHandler) OnTRISAInquiry(in *Inquiry) (*trp.Resolution, error) {
+	if m.OnTRISAInquiryFunc != nil {
+		return m.OnTRISAInquiryFunc(in)
+	}
+	return nil, errors.New("no mock on trisa inquiry handler defined")
+}
+
+// Convert the envelope into a TRP inquiry and serialize it as JSON and back so that
+// the extensions are generic JSON values as they would be when received.
+func roundTripInquiry(t *testing.T, env *envelope.Envelope) *trp.Inquiry {
+	in, err := EnvelopeToPayload(env)
+	require.NoError(t, err, "could not convert envelope to inquiry")
+	in.Callback = beneficiaryURL
+
+	data, err := json.Marshal(in)
+	require.NoError(t, err, "could not marshal inquiry")
+
+	out := &trp.Inquiry{}
+	require.NoError(t, json.Unmarshal(data, out), "could not unmarshal inquiry")
+	return out
+}
+
+func loadPayload(t *testing.T) *api.Payload {
+	identity, err := loadIdentity("testdata/identity.json")
+	require.NoError(t, err, "could not load identity payload fixture")
+	transaction, err := loadTransaction("testdata/transaction.json")
+	require.NoError(t, err, "could not load transaction payload fixture")
+
+	payload := &api.Payload{SentAt: time.Now().Format(time.RFC3339)}
+	payload.Identity, err = anypb.New(identity)
+	require.NoError(t, err, "could not marshal identity payload")
+	payload.Transaction, err = anypb.New(transaction)
+	require.NoError(t, err, "could not marshal transaction payload")
+	return payload
+}
@@ -0,0 +1,226 @@
+package openvasp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp/lnurl"
+	"github.com/trisacrypto/trisa/pkg/openvasp/traddr"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+// WellKnownPath is the default path that is used to look up the travel address of an
+// email-like identifier such as alice@vasp.com; the local part of the identifier is
+// appended to the path, e.g. https://vasp.com/.well-known/travel-address/alice.
+const WellKnownPath = "/.well-known/travel-address/"
+
+// WellKnownAddress is returned by the well-known lookup endpoint. The travel address
+// may be a travel address, an LNURL, or a URL of the TRP inquiry endpoint.
+type WellKnownAddress struct {
+	TravelAddress string `json:"travel_address"`
+}
+
+// Resolver converts whatever a customer may paste as the address of the beneficiary
+// VASP into the TRP inquiry endpoint of the VASP. Travel addresses (ta...), LNURLs
+// (lnurl1... optionally prefixed with lightning:), bare URLs with or without a scheme,
+// and email-like identifiers (user@vasp.com) that are looked up using the well-known
+// travel address endpoint of the domain are all supported.
+type Resolver struct {
+	client        *http.Client
+	wellKnownPath string
+}
+
+// NewResolver creates a resolver that uses a default http client with a timeout to
+// perform well-known lookups unless otherwise specified by the options.
+func NewResolver(opts ...ResolverOption) (resolver *Resolver, err error) {
+	resolver = &Resolver{
+		client:        &http.Client{Timeout: 30 * time.Second},
+		wellKnownPath: WellKnownPath,
+	}
+
+	for _, opt := range opts {
+		if err = opt(resolver); err != nil {
+			return nil, err
+		}
+	}
+	return resolver, nil
+}
+
+// Resolve an address into TRP info whose address is the https inquiry endpoint of the
+// VASP, including the t=i query parameter. The input is normalized by trimming
+// whitespace and URI prefixes and the TLD of the endpoint is validated.
+func (r *Resolver) Resolve(ctx context.Context, address string) (info *trp.Info, err error) {
+	address = normalizeAddress(address)
+
+	var endpoint string
+	switch {
+	case address == "":
+		return nil, trp.ErrUnknownTravelAddress
+
+	case isEmailAddress(address):
+		if endpoint, err = r.lookup(ctx, address); err != nil {
+			return nil, err
+		}
+
+	default:
+		if endpoint, err = resolveEndpoint(address); err != nil {
+			return nil, err
+		}
+	}
+
+	return &trp.Info{Address: endpoint}, nil
+}
+
+// Looks up the travel address of an email-like identifier using the well-known endpoint
+// of its domain and resolves the travel address that is returned.
+func (r *Resolver) lookup(ctx context.Context, address string) (_ string, err error) {
+	user, domain, _ := strings.Cut(address, "@")
+
+	var u *traddr.URL
+	if u, err = traddr.Parse(domain); err != nil {
+		return "", fmt.Errorf("could not parse domain of %q: %w", address, err)
+	}
+
+	if err = u.ValidTLD(); err != nil {
+		return "", err
+	}
+
+	u.Scheme = "https"
+	u.Path = r.wellKnownPath + url.PathEscape(user)
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil); err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", MIMEJSON)
+
+	var rep *http.Response
+	if rep, err = r.client.Do(req); err != nil {
+		return "", fmt.Errorf("could not lookup travel address of %q: %w", address, err)
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		return "", &trp.StatusError{Code: rep.StatusCode, Message: fmt.Sprintf("could not lookup travel address of %q", address)}
+	}
+
+	out := &WellKnownAddress{}
+	if err = json.NewDecoder(rep.Body).Decode(out); err != nil {
+		return "", fmt.Errorf("could not decode well-known travel address response: %w", err)
+	}
+
+	// The well-known endpoint must not return another email-like identifier to
+	// prevent lookup loops between domains.
+	if address = normalizeAddress(out.TravelAddress); address == "" || isEmailAddress(address) {
+		return "", fmt.Errorf("%w: invalid well-known travel address %q", trp.ErrUnknownTravelAddress, out.TravelAddress)
+	}
+	return resolveEndpoint(address)
+}
+
+// Resolve is a convenience function that resolves an address with the default resolver.
+func Resolve(ctx context.Context, address string) (*trp.Info, error) {
+	resolver, _ := NewResolver()
+	return resolver.Resolve(ctx, address)
+}
+
+// Converts a travel address, LNURL, or URL into the https inquiry endpoint.
+func resolveEndpoint(address string) (_ string, err error) {
+	var rawURL string
+	switch {
+	case isTravelAddress(address):
+		if rawURL, err = traddr.DecodeURL(address); err != nil {
+			return "", err
+		}
+
+	case strings.HasPrefix(strings.ToLower(address), "lnurl1"):
+		if rawURL, err = lnurl.Decode(strings.ToLower(address)); err != nil {
+			return "", err
+		}
+
+	default:
+		rawURL = address
+	}
+
+	var u *traddr.URL
+	if u, err = traddr.Parse(rawURL); err != nil {
+		return "", fmt.Errorf("%w: %s", trp.ErrUnknownTravelAddress, err)
+	}
+
+	switch u.Scheme {
+	case "":
+		u.Scheme = "https"
+	case "https", "http":
+	default:
+		return "", fmt.Errorf("%w: unhandled scheme %q", trp.ErrUnknownTravelAddress, u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return "", trp.ErrUnknownTravelAddress
+	}
+
+	if err = u.ValidTLD(); err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("t", "i")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Trims whitespace and URI prefixes that are commonly used with addresses.
+func normalizeAddress(address string) string {
+	address = strings.TrimSpace(address)
+	for _, prefix := range []string{"lightning:", "travel:"} {
+		if len(address) > len(prefix) && strings.EqualFold(address[:len(prefix)], prefix) {
+			address = strings.TrimSpace(address[len(prefix):])
+		}
+	}
+	return address
+}
+
+// Travel addresses are base58 encoded so they cannot contain periods or slashes.
+func isTravelAddress(address string) bool {
+	return strings.HasPrefix(address, "ta") && !strings.ContainsAny(address, "./:@")
+}
+
+// Email-like identifiers have a single @ and no scheme or path.
+func isEmailAddress(address string) bool {
+	return strings.Count(address, "@") == 1 && !strings.ContainsAny(address, "/:") &&
+		!strings.HasPrefix(address, "@") && !strings.HasSuffix(address, "@")
+}
+
+//===========================================================================
+// Resolver Options
+//===========================================================================
+
+// ResolverOption configures the resolver when it is created.
+type ResolverOption func(r *Resolver) error
+
+// Specify the http client used to perform well-known lookups.
+func WithHTTPClient(client *http.Client) ResolverOption {
+	return func(r *Resolver) error {
+		r.client = client
+		return nil
+	}
+}
+
+// Specify the well-known path used to lookup email-like identifiers.
+func WithWellKnownPath(path string) ResolverOption {
+	return func(r *Resolver) error {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("invalid well-known path %q", path)
+		}
+
+		if !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+		r.wellKnownPath = path
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package openvasp_test
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	. "github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/traddr"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+func TestResolve(t *testing.T) {
+	testCases := []struct {
+		address  string
+		expected string
+	}{
+		{"ta2W2HPKfHxgSgrzY178knqXHg1H3jfeQrwQ9JrKBs9wv", "https://beneficiary.com/x/12345?t=i"},
+		{"  ta4uMjxqDq4t7nefCdrm4ssCTewVbvBLzmUnVcYe4SXqGafPTPewG\n", "https://trisa.beneficiary.com/x/12345?t=i"},
+		{"LNURL1DP68GURN8GHJ7MMSV4H8VCTNWQH8GETNWSKKUET59E5K7TE3XGEN7ARPVU7KJMN3W45HY7GF5KZ53", "https://openvasp.test-net.io/123?t=i&tag=inquiry"},
+		{"lightning:lnurl1dp68gurn8ghj7cn9dejkv6trd9shy7fwvdhk6tm5wfcr7arpvu7hgunpwejkcun4d3jkjmn3w45hy7gmsy37e", "https://beneficiary.com/trp?t=i&tag=travelruleinquiry"},
+		{"beneficiary.com/x/12345", "https://beneficiary.com/x/12345?t=i"},
+		{"https://beneficiary.com:8000/trp?t=i", "https://beneficiary.com:8000/trp?t=i"},
+		{"http://localhost:8000", "http://localhost:8000?t=i"},
+		{"trisa.local/trp", "https://trisa.local/trp?t=i"},
+	}
+
+	for _, tc := range testCases {
+		info, err := Resolve(context.Background(), tc.address)
+		require.NoError(t, err, "could not resolve %q", tc.address)
+		require.Equal(t, tc.expected, info.Address)
+	}
+
+	for _, address := range []string{"", "   ", "ftp://beneficiary.com", "beneficiary.notatld/trp", "taNotATravelAddress", "lnurl1invalid"} {
+		_, err := Resolve(context.Background(), address)
+		require.Error(t, err, "expected %q to not resolve", address)
+	}
+
+	_, err := Resolve(context.Background(), "beneficiary.notatld")
+	require.ErrorIs(t, err, traddr.ErrInvalidTLD)
+
+	_, err = Resolve(context.Background(), "alice@beneficiary.notatld")
+	require.ErrorIs(t, err, traddr.ErrInvalidTLD)
+}
+
+func TestResolveWellKnown(t *testing.T) {
+	addresses := map[string]string{
+		"/.well-known/travel-address/alice": "ta2W2HPKfHxgSgrzY178knqXHg1H3jfeQrwQ9JrKBs9wv",
+		"/.well-known/travel-address/bob":   "https://example.com/trp/bob",
+		"/.well-known/travel-address/eve":   "eve@example.com",
+		"/custom/alice":                     "example.com/trp/alice",
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address, ok := addresses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", MIMEJSON)
+		json.NewEncoder(w).Encode(&WellKnownAddress{TravelAddress: address})
+	}))
+	defer srv.Close()
+
+	// The test server certificate is valid for example.com, so route all connections
+	// to the test server rather than performing a DNS lookup.
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.DialTLSContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		dialer := &tls.Dialer{Config: transport.TLSClientConfig.Clone()}
+		dialer.Config.ServerName = "example.com"
+		return dialer.DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	client := &http.Client{Transport: transport}
+
+	resolver, err := NewResolver(WithHTTPClient(client))
+	require.NoError(t, err)
+
+	info, err := resolver.Resolve(context.Background(), "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "https://beneficiary.com/x/12345?t=i", info.Address)
+
+	info, err = resolver.Resolve(context.Background(), " bob@example.com ")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/trp/bob?t=i", info.Address)
+
+	_, err = resolver.Resolve(context.Background(), "eve@example.com")
+	require.ErrorIs(t, err, trp.ErrUnknownTravelAddress, "email addresses should not resolve to other email addresses")
+
+	_, err = resolver.Resolve(context.Background(), "mallory@example.com")
+	require.Error(t, err)
+	serr, ok := err.(*trp.StatusError)
+	require.True(t, ok, "expected a status error")
+	require.Equal(t, http.StatusNotFound, serr.Code)
+
+	resolver, err = NewResolver(WithHTTPClient(client), WithWellKnownPath("/custom"))
+	require.NoError(t, err)
+
+	info, err = resolver.Resolve(context.Background(), "alice@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/trp/alice?t=i", info.Address)
+
+	_, err = NewResolver(WithWellKnownPath("custom"))
+	require.Error(t, err)
+}
@@ -0,0 +1,348 @@
+package openvasp
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp/extensions/discoverability"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/trust"
+)
+
+// Default paths for the callback endpoints of the TRP server. The callback URLs are
+// implementation defined and are sent to the counterparty in the inquiry callback
+// (for resolutions) and in the approval callback (for confirmations).
+const (
+	ResolutionEndpoint   = "/inquiryResolution"
+	ConfirmationEndpoint = "/transferConfirmation"
+)
+
+// Server mounts all of the TRP endpoints, dispatching requests to a single handler.
+// Transfer inquiries are posted to the travel address of the server and are handled by
+// the root path, resolutions and confirmations are received on their callback paths,
+// and the identity and discoverability endpoints are served on their specified paths.
+// Extension negotiation via the api-extensions header is enforced for all of the
+// transfer endpoints but not for the identity or discoverability endpoints, which
+// counterparties use to determine which extensions are required. Transfer requests
+// must use a TRP API version that is in the range specified by WithAPIVersions.
+// Extensions that are implemented by middleware, such as message signing, are added
+// with WithExtension and wrap all of the transfer endpoints.
+//
+// If the handler also implements TRISAInquiryHandler, TRISA envelope extensions are
+// opened with the unsealing key specified by WithUnsealingKey and inquiries are
+// dispatched to OnTRISAInquiry instead of OnInquiry. The sealed and unsealed TRISA
+// envelope extensions are then supported (and listed by the extensions endpoint)
+// unless they were already specified as required or supported extensions.
+type Server struct {
+	handler          Handler
+	mux              *http.ServeMux
+	started          time.Time
+	unsealingKey     interface{}
+	version          *discoverability.Version
+	versions         *VersionRange
+	extensions       *discoverability.Extensions
+	middleware       []extensionMiddleware
+	resolutionPath   string
+	confirmationPath string
+}
+
+// Ensure the Server implements the http.Handler interface
+var _ http.Handler = &Server{}
+
+// NewServer creates a TRP server that uses the handler to respond to TRP requests.
+func NewServer(handler Handler, opts ...ServerOption) (srv *Server, err error) {
+	if handler == nil {
+		return nil, errors.New("a handler is required to create a trp server")
+	}
+
+	srv = &Server{
+		handler:          handler,
+		started:          time.Now(),
+		version:          &discoverability.Version{Version: APIVersion},
+		versions:         DefaultVersions,
+		extensions:       &discoverability.Extensions{},
+		resolutionPath:   ResolutionEndpoint,
+		confirmationPath: ConfirmationEndpoint,
+	}
+
+	for _, opt := range opts {
+		if err = opt(srv); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ext := range srv.middleware {
+		srv.addExtensions(ext.required, ext.name)
+	}
+
+	if srv.resolutionPath == srv.confirmationPath {
+		return nil, fmt.Errorf("resolution and confirmation callbacks cannot both use path %q", srv.resolutionPath)
+	}
+
+	var inquiries http.Handler
+	if trisa, ok := handler.(TRISAInquiryHandler); ok {
+		inquiries = trisaTransferInquiry(trisa, srv.unsealingKey)
+		srv.addExtensions(false, SealedTRISAExtension, UnsealedTRISAExtension)
+	} else {
+		inquiries = transferInquiry(handler)
+	}
+
+	srv.mux = http.NewServeMux()
+	srv.mux.Handle(trp.IdentityEndpoint, discoveryChecks(http.HandlerFunc(srv.Identity)))
+	srv.mux.Handle(discoverability.VersionEndpoint, discoveryChecks(http.HandlerFunc(srv.Version)))
+	srv.mux.Handle(discoverability.UptimeEndpoint, discoveryChecks(http.HandlerFunc(srv.Uptime)))
+	srv.mux.Handle(discoverability.ExtensionsEndpoint, discoveryChecks(http.HandlerFunc(srv.Extensions)))
+	srv.mux.Handle(srv.resolutionPath, srv.transferChecks(transferResolution(handler)))
+	srv.mux.Handle(srv.confirmationPath, srv.transferChecks(transferConfirmation(handler)))
+	srv.mux.Handle("/", srv.transferChecks(inquiries))
+	return srv, nil
+}
+
+// ServeHTTP routes the request to the TRP endpoint handlers.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Identity returns the name, LEI, and x509 certificate of the VASP from the handler.
+func (s *Server) Identity(w http.ResponseWriter, r *http.Request) {
+	identity, err := s.handler.OnIdentity()
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+
+	if identity == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, identity)
+}
+
+// Version returns the TRP version and vendor of the server.
+func (s *Server) Version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.version)
+}
+
+// Uptime returns the number of seconds the server has been running as plain text.
+func (s *Server) Uptime(w http.ResponseWriter, r *http.Request) {
+	text, _ := discoverability.UptimeSince(s.started).MarshalText()
+	w.Header().Set(ContentTypeHeader, MIMEPlainText)
+	w.WriteHeader(http.StatusOK)
+	w.Write(text)
+}
+
+// Extensions returns the required and supported extensions of the server.
+func (s *Server) Extensions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.extensions)
+}
+
+// addExtensions adds the extensions to the required or supported extensions of the
+// server if they are not already listed; an extension that becomes required is removed
+// from the supported extensions. The slices passed to the options are not modified.
+func (s *Server) addExtensions(required bool, extensions ...string) {
+	listed := make(map[string]struct{}, len(s.extensions.Required)+len(s.extensions.Supported))
+	for _, ext := range s.extensions.Required {
+		listed[ext] = struct{}{}
+	}
+	if !required {
+		for _, ext := range s.extensions.Supported {
+			listed[ext] = struct{}{}
+		}
+	}
+
+	added := make(map[string]struct{}, len(extensions))
+	add := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		if _, ok := listed[ext]; !ok {
+			listed[ext] = struct{}{}
+			added[ext] = struct{}{}
+			add = append(add, ext)
+		}
+	}
+
+	if len(add) == 0 {
+		return
+	}
+
+	if !required {
+		s.extensions.Supported = append(append([]string(nil), s.extensions.Supported...), add...)
+		return
+	}
+
+	s.extensions.Required = append(append([]string(nil), s.extensions.Required...), add...)
+	supported := make([]string, 0, len(s.extensions.Supported))
+	for _, ext := range s.extensions.Supported {
+		if _, ok := added[ext]; !ok {
+			supported = append(supported, ext)
+		}
+	}
+	s.extensions.Supported = supported
+}
+
+// transferChecks negotiates the version and extensions of transfer requests. The
+// extension middleware is applied after the extensions are negotiated but before the
+// request body is adapted to the current version, so that it sees the request as sent.
+func (s *Server) transferChecks(next http.Handler) http.Handler {
+	next = APIVersionChecks(s.versions, next)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		next = s.middleware[i].wrap(next)
+	}
+	return ExtensionChecks(s.extensions, next)
+}
+
+// discoveryChecks is middleware for the identity and discoverability endpoints, which
+// only allow GET requests and do not require the TRP headers to be set.
+func discoveryChecks(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Set the APIVersion header and echo back the request identifier if specified
+		w.Header().Add(APIVersionHeader, APIVersion)
+		if requestIdentifier := r.Header.Get(RequestIdentifierHeader); requestIdentifier != "" {
+			w.Header().Add(RequestIdentifierHeader, requestIdentifier)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set(ContentTypeHeader, ContentTypeValue)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(obj)
+}
+
+// NewIdentity creates a TRP identity for the VASP with the specified name and LEI,
+// PEM encoding the leaf certificate of the provider as the x509 certificate.
+func NewIdentity(name, lei string, provider *trust.Provider) (_ *trp.Identity, err error) {
+	identity := &trp.Identity{Name: name, LEI: lei}
+	if provider != nil {
+		var cert *x509.Certificate
+		if cert, err = provider.GetLeafCertificate(); err != nil {
+			return nil, err
+		}
+
+		var data []byte
+		if data, err = trust.PEMEncodeCertificate(cert); err != nil {
+			return nil, err
+		}
+		identity.X509 = string(data)
+	}
+	return identity, nil
+}
+
+//===========================================================================
+// Server Options
+//===========================================================================
+
+// ServerOption allows the TRP server to be configured when it is created.
+type ServerOption func(s *Server) error
+
+// Specify the vendor that is returned by the version endpoint.
+func WithVendor(vendor string) ServerOption {
+	return func(s *Server) error {
+		s.version.Vendor = vendor
+		return nil
+	}
+}
+
+// Specify the semver range of TRP API versions that are accepted in transfer requests
+// (by default only patch releases of the current version are accepted), e.g.
+// "^3.0.0 || ^2.0.0". Requests from previous major versions are adapted to the current
+// version if an adapter is registered for the major version.
+func WithAPIVersions(constraint string) ServerOption {
+	return func(s *Server) (err error) {
+		s.versions, err = ParseVersionRange(constraint)
+		return err
+	}
+}
+
+// Specify extensions that must be used by counterparties in all transfer requests.
+// Required extensions are returned by the extensions endpoint.
+func WithRequiredExtensions(extensions ...string) ServerOption {
+	return func(s *Server) error {
+		s.extensions.Required = extensions
+		return nil
+	}
+}
+
+// Specify extensions that counterparties may use in transfer requests; any extension
+// that is not required or supported is rejected by the server.
+func WithSupportedExtensions(extensions ...string) ServerOption {
+	return func(s *Server) error {
+		s.extensions.Supported = extensions
+		return nil
+	}
+}
+
+// Middleware wraps the transfer endpoints of the server to implement an extension.
+type Middleware func(next http.Handler) http.Handler
+
+type extensionMiddleware struct {
+	name     string
+	required bool
+	wrap     Middleware
+}
+
+// Specify an extension that is implemented by middleware wrapping the transfer
+// endpoints, e.g. signing.WithMessageSigning. The extension is listed as required or
+// supported by the extensions endpoint regardless of the order of the options.
+// Middleware is applied in the order the options are specified.
+func WithExtension(extension string, required bool, middleware Middleware) ServerOption {
+	return func(s *Server) error {
+		if extension == "" || middleware == nil {
+			return errors.New("an extension name and middleware are required")
+		}
+		s.middleware = append(s.middleware, extensionMiddleware{name: extension, required: required, wrap: middleware})
+		return nil
+	}
+}
+
+// Specify the path that inquiry resolution callbacks are received on.
+func WithResolutionPath(path string) ServerOption {
+	return func(s *Server) error {
+		if path == "" || path[0] != '/' {
+			return fmt.Errorf("invalid resolution path %q", path)
+		}
+		s.resolutionPath = path
+		return nil
+	}
+}
+
+// Specify the path that transfer confirmation callbacks are received on.
+func WithConfirmationPath(path string) ServerOption {
+	return func(s *Server) error {
+		if path == "" || path[0] != '/' {
+			return fmt.Errorf("invalid confirmation path %q", path)
+		}
+		s.confirmationPath = path
+		return nil
+	}
+}
+
+// Specify the start time of the server for the uptime endpoint (defaults to the time
+// the server was created).
+func WithStarted(started time.Time) ServerOption {
+	return func(s *Server) error {
+		s.started = started
+		return nil
+	}
+}
+
+// Specify the private key used to unseal sealed TRISA envelope extensions, either a
+// keys.PrivateKey or an *rsa.PrivateKey. Only used if the handler implements the
+// TRISAInquiryHandler interface.
+func WithUnsealingKey(key interface{}) ServerOption {
+	return func(s *Server) error {
+		s.unsealingKey = key
+		return nil
+	}
+}
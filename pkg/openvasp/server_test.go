@@ -0,0 +1,364 @@
+package openvasp_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	. "github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/client"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/slip0044"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trust"
+	"github.com/trisacrypto/trisa/pkg/trust/mock"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestServer(t *testing.T) {
+	mock := &MockHandler{}
+	srv, err := NewServer(mock,
+		WithVendor("TRISA"),
+		WithRequiredExtensions("message-signing"),
+		WithSupportedExtensions("extended-ivms101"),
+		WithStarted(time.Now().Add(-1*time.Hour)),
+	)
+	require.NoError(t, err, "could not create server")
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	trpc, err := client.New(client.WithAPIExtensions("message-signing"))
+	require.NoError(t, err, "could not create client")
+
+	ctx := context.Background()
+
+	t.Run("Identity", func(t *testing.T) {
+		defer mock.Reset()
+		mock.CallIdentity = func() (*trp.Identity, error) {
+			return NewIdentity("Acme VASP", "TVYD005I7Q5IJK0EMI53", nil)
+		}
+
+		identity, err := trpc.Identity(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Equal(t, 1, mock.Calls(CallIdentity))
+		require.Equal(t, "Acme VASP", identity.Name)
+		require.Equal(t, "TVYD005I7Q5IJK0EMI53", identity.LEI)
+		require.Empty(t, identity.X509)
+		require.NotEmpty(t, identity.Info.RequestIdentifier)
+	})
+
+	t.Run("IdentityError", func(t *testing.T) {
+		defer mock.Reset()
+		mock.UseError(CallIdentity, &trp.StatusError{Code: http.StatusServiceUnavailable})
+
+		_, err := trpc.Identity(ctx, ts.URL)
+		require.Error(t, err)
+		require.Equal(t, 1, mock.Calls(CallIdentity))
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		version, err := trpc.Version(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Equal(t, APIVersion, version.Version)
+		require.Equal(t, "TRISA", version.Vendor)
+	})
+
+	t.Run("Uptime", func(t *testing.T) {
+		uptime, err := trpc.Uptime(ctx, ts.URL)
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Duration(uptime), time.Hour)
+	})
+
+	t.Run("Extensions", func(t *testing.T) {
+		extensions, err := trpc.Extensions(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Equal(t, []string{"message-signing"}, extensions.Required)
+		require.Equal(t, []string{"extended-ivms101"}, extensions.Supported)
+	})
+
+	t.Run("Inquiry", func(t *testing.T) {
+		defer mock.Reset()
+		mock.CallInquiry = func(i *trp.Inquiry) (*trp.Resolution, error) {
+			if len(i.Info.APIExtensions) != 1 || i.Info.APIExtensions[0] != "message-signing" {
+				return nil, errors.New("unexpected api extensions")
+			}
+			return nil, nil
+		}
+
+		inquiry, err := loadInquiryPayload("testdata/inquiry.json")
+		require.NoError(t, err, "could not load inquiry fixture")
+		inquiry.Info = &trp.Info{Address: ts.URL + "/transfers"}
+
+		out, err := trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err)
+		require.Equal(t, 1, mock.Calls(CallInquiry))
+		require.Equal(t, APIVersion, out.Version)
+		require.Equal(t, []string{"message-signing"}, out.Info.APIExtensions)
+	})
+
+	t.Run("Resolution", func(t *testing.T) {
+		defer mock.Reset()
+		mock.CallResolution = func(r *trp.Resolution) error {
+			if r.Rejected != "no thanks" {
+				return errors.New("unexpected resolution")
+			}
+			return nil
+		}
+
+		resolution := &trp.Resolution{
+			Info:     &trp.Info{Address: ts.URL + ResolutionEndpoint},
+			Rejected: "no thanks",
+		}
+
+		err := trpc.Resolve(ctx, resolution)
+		require.NoError(t, err)
+		require.Equal(t, 1, mock.Calls(CallResolution))
+		require.Equal(t, 0, mock.Calls(CallInquiry))
+	})
+
+	t.Run("InvalidResolution", func(t *testing.T) {
+		defer mock.Reset()
+		resolution := &trp.Resolution{
+			Info: &trp.Info{Address: ts.URL + ResolutionEndpoint},
+		}
+
+		err := trpc.Resolve(ctx, resolution)
+		require.Error(t, err)
+		require.Equal(t, 0, mock.Calls(CallResolution))
+	})
+
+	t.Run("Confirmation", func(t *testing.T) {
+		defer mock.Reset()
+		mock.CallConfirmation = func(c *trp.Confirmation) error {
+			if c.TXID != "foo" {
+				return errors.New("unexpected txid")
+			}
+			return nil
+		}
+
+		confirmation := &trp.Confirmation{
+			Info: &trp.Info{Address: ts.URL + ConfirmationEndpoint},
+			TXID: "foo",
+		}
+
+		err := trpc.Confirm(ctx, confirmation)
+		require.NoError(t, err)
+		require.Equal(t, 1, mock.Calls(CallConfirmation))
+		require.Equal(t, 0, mock.Calls(CallInquiry))
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		rep, err := http.Post(ts.URL+"/version", MIMEJSON, nil)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusMethodNotAllowed, rep.StatusCode)
+	})
+}
+
+func TestServerVersions(t *testing.T) {
+	mock := &MockHandler{}
+	srv, err := NewServer(mock, WithAPIVersions("^3.0.0 || ^2.0.0"))
+	require.NoError(t, err, "could not create server")
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx := context.Background()
+	mock.CallInquiry = func(i *trp.Inquiry) (*trp.Resolution, error) {
+		if i.Asset == nil || i.Asset.SLIP044 == nil || *i.Asset.SLIP044 != slip0044.CoinType_BITCOIN {
+			return nil, errors.New("asset was not adapted")
+		}
+		return nil, nil
+	}
+
+	for _, version := range []string{"2.1.0", "3.0.2", APIVersion} {
+		trpc, err := client.New(client.WithAPIVersion(version))
+		require.NoError(t, err, "could not create client")
+
+		inquiry, err := loadInquiryPayload("testdata/inquiry.json")
+		require.NoError(t, err, "could not load inquiry fixture")
+		inquiry.Info = &trp.Info{Address: ts.URL}
+
+		out, err := trpc.Inquiry(ctx, inquiry)
+		require.NoError(t, err, "could not send inquiry with version %s", version)
+		require.Equal(t, version, out.Version)
+		require.Equal(t, version, out.Info.APIVersion)
+	}
+
+	// Versions outside of the range are rejected
+	trpc, err := client.New(client.WithAPIVersion("1.0.0"))
+	require.NoError(t, err, "could not create client")
+
+	err = trpc.Confirm(ctx, &trp.Confirmation{Info: &trp.Info{Address: ts.URL + ConfirmationEndpoint}, TXID: "foo"})
+	var serr *trp.StatusError
+	require.ErrorAs(t, err, &serr)
+	require.Equal(t, http.StatusBadRequest, serr.Code)
+	require.Equal(t, "unsupported api version 1.0.0: must match ^3.0.0 || ^2.0.0", serr.Message)
+
+	// Invalid version ranges cannot be used to create a server
+	_, err = NewServer(mock, WithAPIVersions("^foo"))
+	require.ErrorIs(t, err, trp.ErrInvalidVersion)
+}
+
+func TestServerTRISA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "could not generate rsa key")
+
+	env, _, err := envelope.Seal(loadPayload(t), envelope.WithRSAPublicKey(&key.PublicKey))
+	require.NoError(t, err, "could not seal envelope")
+
+	handler := &struct {
+		*MockHandler
+		*MockTRISAHandler
+	}{&MockHandler{}, &MockTRISAHandler{}}
+
+	handler.OnTRISAInquiryFunc = func(i *Inquiry) (*trp.Resolution, error) {
+		if i.Payload == nil {
+			return nil, errors.New("envelope was not opened")
+		}
+		return &trp.Resolution{Approved: &trp.Approval{Address: "some payment address", Callback: beneficiaryURL}}, nil
+	}
+
+	trpc, err := client.New(client.WithAPIExtensions(SealedTRISAExtension))
+	require.NoError(t, err, "could not create client")
+
+	ctx := context.Background()
+	makeInquiry := func(address string) (*trp.Resolution, error) {
+		in, err := EnvelopeToPayload(env)
+		require.NoError(t, err)
+		in.Callback = beneficiaryURL
+		in.Info = &trp.Info{Address: address}
+		return trpc.Inquiry(ctx, in)
+	}
+
+	t.Run("NoOptions", func(t *testing.T) {
+		// The TRISA extensions are supported without having to be specified as options
+		srv, err := NewServer(handler)
+		require.NoError(t, err, "could not create server")
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		extensions, err := trpc.Extensions(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Empty(t, extensions.Required)
+		require.Equal(t, []string{SealedTRISAExtension, UnsealedTRISAExtension}, extensions.Supported)
+
+		// Sealed envelopes are negotiated but cannot be opened without an unsealing key
+		_, err = makeInquiry(ts.URL)
+		var serr *trp.StatusError
+		require.ErrorAs(t, err, &serr)
+		require.Equal(t, http.StatusBadRequest, serr.Code)
+		require.Equal(t, trp.ErrUnsealingKeyRequired.Error(), serr.Message)
+	})
+
+	t.Run("UnsealingKey", func(t *testing.T) {
+		srv, err := NewServer(handler, WithUnsealingKey(key))
+		require.NoError(t, err, "could not create server")
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		out, err := makeInquiry(ts.URL)
+		require.NoError(t, err)
+		require.NotNil(t, out.Approved)
+		require.Equal(t, []string{SealedTRISAExtension}, out.Info.APIExtensions)
+	})
+
+	t.Run("Required", func(t *testing.T) {
+		// Extensions that are already required are not also listed as supported
+		supported := []string{"extended-ivms101"}
+		srv, err := NewServer(handler, WithRequiredExtensions(SealedTRISAExtension), WithSupportedExtensions(supported...))
+		require.NoError(t, err, "could not create server")
+		require.Equal(t, []string{"extended-ivms101"}, supported, "option argument should not be modified")
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		extensions, err := trpc.Extensions(ctx, ts.URL)
+		require.NoError(t, err)
+		require.Equal(t, []string{SealedTRISAExtension}, extensions.Required)
+		require.Equal(t, []string{"extended-ivms101", UnsealedTRISAExtension}, extensions.Supported)
+	})
+
+	// Handlers that do not handle TRISA inquiries do not support the extensions
+	srv, err := NewServer(&MockHandler{})
+	require.NoError(t, err, "could not create server")
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	extensions, err := trpc.Extensions(ctx, ts.URL)
+	require.NoError(t, err)
+	require.Empty(t, extensions.Supported)
+}
+
+func TestExtensionChecks(t *testing.T) {
+	srv, err := NewServer(&MockHandler{}, WithRequiredExtensions("message-signing"), WithSupportedExtensions("extended-ivms101"))
+	require.NoError(t, err, "could not create server")
+
+	makeRequest := func(extensions string) *http.Response {
+		r := httptest.NewRequest(http.MethodPost, beneficiaryURL, strings.NewReader(`{"txid": "foo"}`))
+		r.Header.Set(APIVersionHeader, APIVersion)
+		r.Header.Set(RequestIdentifierHeader, requestIdentifier)
+		r.Header.Set(ContentTypeHeader, ContentTypeValue)
+		if extensions != "" {
+			r.Header.Set(APIExtensionsHeader, extensions)
+		}
+
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	testCases := []struct {
+		extensions string
+		status     int
+		message    string
+	}{
+		{"", http.StatusBadRequest, "must specify required api extensions: message-signing\n"},
+		{"extended-ivms101", http.StatusBadRequest, "must specify required api extensions: message-signing\n"},
+		{"message-signing, foo, bar", http.StatusBadRequest, "unsupported api extensions: foo, bar\n"},
+		{"message-signing", http.StatusInternalServerError, "no mock on inquiry handler defined\n"},
+		{"message-signing,extended-ivms101", http.StatusInternalServerError, "no mock on inquiry handler defined\n"},
+	}
+
+	for i, tc := range testCases {
+		rep := makeRequest(tc.extensions)
+		require.Equal(t, tc.status, rep.StatusCode, "test case %d failed", i)
+
+		data, err := io.ReadAll(rep.Body)
+		require.NoError(t, err)
+		require.Equal(t, tc.message, string(data), "test case %d failed", i)
+	}
+
+	// Discoverability endpoints do not require extensions to be negotiated
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/extensions", nil))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestNewIdentity(t *testing.T) {
+	data, err := mock.Chain()
+	require.NoError(t, err, "could not create mock chain")
+
+	provider, err := trust.Decrypt(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decrypt mock chain")
+
+	identity, err := NewIdentity("Acme VASP", "TVYD005I7Q5IJK0EMI53", provider)
+	require.NoError(t, err)
+	require.Equal(t, "Acme VASP", identity.Name)
+	require.True(t, strings.HasPrefix(identity.X509, "-----BEGIN CERTIFICATE-----"))
+
+	certs, err := trust.New([]byte(identity.X509))
+	require.NoError(t, err, "could not parse identity certificate")
+	require.Equal(t, provider.String(), certs.String())
+}
@@ -0,0 +1,12 @@
+package tracker
+
+import "errors"
+
+var (
+	ErrNotFound            = errors.New("no transfer found for the request identifier")
+	ErrExists              = errors.New("a transfer with the request identifier already exists")
+	ErrNoRequestIdentifier = errors.New("a request identifier is required to track a transfer")
+	ErrInvalidTransition   = errors.New("invalid transfer state transition")
+	ErrInvalidResolution   = errors.New("could not determine state from resolution")
+	ErrInvalidConfirmation = errors.New("could not determine state from confirmation")
+)
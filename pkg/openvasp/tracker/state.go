@@ -0,0 +1,63 @@
+package tracker
+
+import "fmt"
+
+// State of a TRP transfer from the perspective of either counterparty. A transfer
+// begins when the inquiry is sent or received and proceeds through the resolution
+// (which may be returned synchronously or later via the inquiry callback) and the
+// confirmation (sent to the approval callback) until it reaches a terminal state.
+//
+//	Inquired -> Pending -> Approved -> Confirmed
+//	    |          |          |-----> Canceled
+//	    |          |----> Rejected
+//	    |---------------> Approved, Rejected
+//
+// Any non-terminal state transitions to Expired if its deadline passes.
+type State uint8
+
+const (
+	Unknown   State = iota
+	Inquired        // the inquiry has been sent or received, awaiting a resolution
+	Pending         // the inquiry was acknowledged, awaiting a resolution callback
+	Approved        // the inquiry was approved, awaiting a confirmation
+	Rejected        // the inquiry was rejected (terminal)
+	Confirmed       // the transaction was confirmed with a txid (terminal)
+	Canceled        // the transaction was canceled after approval (terminal)
+	Expired         // the deadline passed before a callback was received (terminal)
+)
+
+var stateNames = [...]string{"unknown", "inquired", "pending", "approved", "rejected", "confirmed", "canceled", "expired"}
+
+// String returns a human readable representation of the state.
+func (s State) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return fmt.Sprintf("State(%d)", s)
+}
+
+// Terminal returns true if no further transitions are allowed from the state.
+func (s State) Terminal() bool {
+	switch s {
+	case Rejected, Confirmed, Canceled, Expired:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanTransition returns true if the transfer may move from this state to the next.
+func (s State) CanTransition(next State) bool {
+	switch s {
+	case Unknown:
+		return next == Inquired
+	case Inquired:
+		return next == Pending || next == Approved || next == Rejected || next == Expired
+	case Pending:
+		return next == Approved || next == Rejected || next == Expired
+	case Approved:
+		return next == Confirmed || next == Canceled || next == Expired
+	default:
+		return false
+	}
+}
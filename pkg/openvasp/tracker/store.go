@@ -0,0 +1,94 @@
+package tracker
+
+import (
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/internal/memstore"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+// Transfer is the tracked state of a single TRP exchange, correlated by the request
+// identifier that is sent in the headers of every TRP request.
+type Transfer struct {
+	RequestIdentifier string
+	State             State
+	Inquiry           *trp.Inquiry
+	Resolution        *trp.Resolution
+	Confirmation      *trp.Confirmation
+	Created           time.Time
+	Modified          time.Time
+	Deadline          time.Time    // Zero if the transfer does not expire
+	History           []Transition // All state transitions of the transfer in order
+}
+
+// Transition records a change in the state of a transfer.
+type Transition struct {
+	From      State
+	To        State
+	Timestamp time.Time
+}
+
+// Expired returns true if the transfer is not in a terminal state and its deadline has
+// passed at the specified time.
+func (t *Transfer) Expired(now time.Time) bool {
+	return !t.State.Terminal() && !t.Deadline.IsZero() && now.After(t.Deadline)
+}
+
+// Returns a copy of the transfer that does not share its history.
+func (t *Transfer) clone() *Transfer {
+	out := *t
+	out.History = append([]Transition(nil), t.History...)
+	return &out
+}
+
+// Store persists tracked transfers so that callbacks can be correlated with inquiries
+// across process restarts or between the nodes of a cluster. Implementations must be
+// safe for concurrent use and should return ErrNotFound if a transfer does not exist.
+type Store interface {
+	Get(requestIdentifier string) (*Transfer, error)
+	Put(*Transfer) error
+	Delete(requestIdentifier string) error
+	List() ([]*Transfer, error)
+}
+
+// MemoryStore keeps tracked TRP transfers in memory and is the store the tracker uses
+// unless WithStore is specified. Transfers are lost when the process exits, so callbacks
+// for inquiries sent before a restart are rejected as not found.
+type MemoryStore struct {
+	transfers *memstore.Store[*Transfer]
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns an empty in-memory store of TRP transfers.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		transfers: memstore.New(memstore.Config[*Transfer]{
+			Key:      func(t *Transfer) string { return t.RequestIdentifier },
+			Clone:    (*Transfer).clone,
+			Created:  func(t *Transfer) time.Time { return t.Created },
+			NotFound: ErrNotFound,
+			NoKey:    ErrNoRequestIdentifier,
+		}),
+	}
+}
+
+// Get returns a copy of the transfer with the specified request identifier.
+func (s *MemoryStore) Get(requestIdentifier string) (*Transfer, error) {
+	return s.transfers.Get(requestIdentifier)
+}
+
+// Put stores a copy of the transfer under its request identifier.
+func (s *MemoryStore) Put(t *Transfer) error {
+	return s.transfers.Put(t)
+}
+
+// Delete the transfer with the specified request identifier.
+func (s *MemoryStore) Delete(requestIdentifier string) error {
+	return s.transfers.Delete(requestIdentifier)
+}
+
+// List returns copies of all tracked transfers ordered by when the inquiry was recorded.
+func (s *MemoryStore) List() ([]*Transfer, error) {
+	return s.transfers.List()
+}
@@ -0,0 +1,422 @@
+/*
+Package tracker implements a state machine for asynchronous TRP transfers. In TRP the
+beneficiary may respond to an inquiry immediately or later by posting a resolution to
+the callback URL of the inquiry; once approved the originator confirms or cancels the
+transfer by posting to the callback URL of the approval. The tracker records every
+message of the exchange, correlating callbacks with inquiries by the request
+identifier header, expires transfers whose callbacks are not received in time, and
+calls application hooks whenever a transfer changes state.
+*/
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+// Default timeouts for receiving callbacks.
+const (
+	DefaultResolutionTimeout   = 24 * time.Hour
+	DefaultConfirmationTimeout = 72 * time.Hour
+)
+
+// Hook is called after a transfer transitions from one state to another and the
+// transfer has been saved to the store. Hooks are called synchronously in the order
+// they were registered and must not modify the transfer.
+type Hook func(transfer *Transfer, from State)
+
+// Tracker records the messages of TRP transfers and manages their state. The tracker
+// implements the openvasp ResolutionHandler and ConfirmationHandler interfaces so that
+// it can be used to receive callbacks, and can also wrap a TRP client to record
+// outgoing messages.
+type Tracker struct {
+	sync.Mutex
+	store               Store
+	resolutionTimeout   time.Duration
+	confirmationTimeout time.Duration
+	hooks               map[State][]Hook
+	transitions         []Hook
+}
+
+// Ensure the Tracker can handle TRP callbacks
+var (
+	_ openvasp.ResolutionHandler   = &Tracker{}
+	_ openvasp.ConfirmationHandler = &Tracker{}
+)
+
+// New creates a tracker with an in-memory store and the default timeouts unless
+// otherwise specified by the options.
+func New(opts ...Option) (tracker *Tracker, err error) {
+	tracker = &Tracker{
+		resolutionTimeout:   DefaultResolutionTimeout,
+		confirmationTimeout: DefaultConfirmationTimeout,
+		hooks:               make(map[State][]Hook),
+	}
+
+	for _, opt := range opts {
+		if err = opt(tracker); err != nil {
+			return nil, err
+		}
+	}
+
+	if tracker.store == nil {
+		tracker.store = NewMemoryStore()
+	}
+	return tracker, nil
+}
+
+//===========================================================================
+// Recording Messages
+//===========================================================================
+
+// Inquiry starts tracking a transfer when an inquiry is sent or received. The inquiry
+// must have a request identifier that has not already been tracked.
+func (t *Tracker) Inquiry(in *trp.Inquiry) (_ *Transfer, err error) {
+	var id string
+	if id, err = requestIdentifier(in.Info); err != nil {
+		return nil, err
+	}
+	return t.Track(id, in)
+}
+
+// Track starts tracking a transfer for the inquiry with the specified identifier rather
+// than its request identifier, e.g. to correlate the inquiry with the ID of the TRISA
+// envelope it was forwarded in. Resolutions to the transfer must then be recorded with
+// Resolve using the same identifier.
+func (t *Tracker) Track(id string, in *trp.Inquiry) (_ *Transfer, err error) {
+	if id == "" {
+		return nil, ErrNoRequestIdentifier
+	}
+
+	t.Lock()
+	if _, err = t.store.Get(id); err == nil {
+		t.Unlock()
+		return nil, ErrExists
+	} else if !errors.Is(err, ErrNotFound) {
+		t.Unlock()
+		return nil, err
+	}
+
+	now := time.Now()
+	transfer := &Transfer{
+		RequestIdentifier: id,
+		State:             Unknown,
+		Inquiry:           in,
+		Created:           now,
+	}
+
+	return t.transition(transfer, Inquired, now)
+}
+
+// Resolution records a resolution to a tracked inquiry, whether it was returned in
+// response to the inquiry, received on the inquiry callback, or sent to the originator.
+func (t *Tracker) Resolution(in *trp.Resolution) (_ *Transfer, err error) {
+	var id string
+	if id, err = requestIdentifier(in.Info); err != nil {
+		return nil, err
+	}
+	return t.Resolve(id, in)
+}
+
+// Resolve records a resolution to the transfer with the specified identifier, which
+// may differ from the request identifier of the resolution if the transfer was tracked
+// with Track.
+func (t *Tracker) Resolve(id string, in *trp.Resolution) (_ *Transfer, err error) {
+	var next State
+	switch {
+	case in.Approved != nil:
+		next = Approved
+	case in.Rejected != "":
+		next = Rejected
+	case in.Version != "":
+		next = Pending
+	default:
+		return nil, ErrInvalidResolution
+	}
+
+	t.Lock()
+	var transfer *Transfer
+	if transfer, err = t.store.Get(id); err != nil {
+		t.Unlock()
+		return nil, err
+	}
+
+	transfer.Resolution = in
+	return t.transition(transfer, next, time.Now())
+}
+
+// Confirmation records a confirmation or cancellation of an approved transfer.
+func (t *Tracker) Confirmation(in *trp.Confirmation) (_ *Transfer, err error) {
+	var id string
+	if id, err = requestIdentifier(in.Info); err != nil {
+		return nil, err
+	}
+	return t.confirm(id, in)
+}
+
+// Get the tracked transfer with the specified request identifier.
+func (t *Tracker) Get(requestIdentifier string) (*Transfer, error) {
+	return t.store.Get(requestIdentifier)
+}
+
+// Expire transitions all transfers whose deadline has passed at the specified time to
+// the Expired state, returning the expired transfers.
+func (t *Tracker) Expire(now time.Time) (expired []*Transfer, err error) {
+	var transfers []*Transfer
+	if transfers, err = t.store.List(); err != nil {
+		return nil, err
+	}
+
+	for _, transfer := range transfers {
+		if !transfer.Expired(now) {
+			continue
+		}
+
+		t.Lock()
+		// Refresh the transfer in case it was updated since it was listed
+		if transfer, err = t.store.Get(transfer.RequestIdentifier); err != nil || !transfer.Expired(now) {
+			t.Unlock()
+			continue
+		}
+
+		if transfer, err = t.transition(transfer, Expired, now); err != nil {
+			return expired, err
+		}
+		expired = append(expired, transfer)
+	}
+	return expired, nil
+}
+
+// Run expires transfers at the specified interval until the context is canceled.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if _, err := t.Expire(now); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (t *Tracker) confirm(id string, in *trp.Confirmation) (_ *Transfer, err error) {
+	var next State
+	switch {
+	case in.TXID != "" && in.Canceled == "":
+		next = Confirmed
+	case in.Canceled != "" && in.TXID == "":
+		next = Canceled
+	default:
+		return nil, ErrInvalidConfirmation
+	}
+
+	t.Lock()
+	var transfer *Transfer
+	if transfer, err = t.store.Get(id); err != nil {
+		t.Unlock()
+		return nil, err
+	}
+
+	transfer.Confirmation = in
+	return t.transition(transfer, next, time.Now())
+}
+
+// Transition the transfer to the next state, save it, and call the hooks. Must be
+// called with the tracker locked; the lock is released before the hooks are called.
+func (t *Tracker) transition(transfer *Transfer, next State, now time.Time) (_ *Transfer, err error) {
+	from := transfer.State
+	if !from.CanTransition(next) {
+		t.Unlock()
+		return nil, fmt.Errorf("%w: cannot transition from %s to %s", ErrInvalidTransition, from, next)
+	}
+
+	transfer.State = next
+	transfer.Modified = now
+	transfer.History = append(transfer.History, Transition{From: from, To: next, Timestamp: now})
+
+	switch next {
+	case Inquired, Pending:
+		transfer.Deadline = deadline(now, t.resolutionTimeout)
+	case Approved:
+		transfer.Deadline = deadline(now, t.confirmationTimeout)
+	default:
+		transfer.Deadline = time.Time{}
+	}
+
+	err = t.store.Put(transfer)
+	t.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hook := range t.transitions {
+		hook(transfer, from)
+	}
+
+	for _, hook := range t.hooks[next] {
+		hook(transfer, from)
+	}
+	return transfer, nil
+}
+
+func deadline(now time.Time, timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return now.Add(timeout)
+}
+
+func requestIdentifier(info *trp.Info) (string, error) {
+	if info == nil || info.RequestIdentifier == "" {
+		return "", ErrNoRequestIdentifier
+	}
+	return info.RequestIdentifier, nil
+}
+
+//===========================================================================
+// Callback Handlers
+//===========================================================================
+
+// OnResolution records a resolution received on the inquiry callback.
+func (t *Tracker) OnResolution(in *trp.Resolution) (err error) {
+	_, err = t.Resolution(in)
+	return statusError(err)
+}
+
+// OnConfirmation records a confirmation received on the approval callback.
+func (t *Tracker) OnConfirmation(in *trp.Confirmation) (err error) {
+	_, err = t.Confirmation(in)
+	return statusError(err)
+}
+
+// Convert tracker errors into TRP status errors for the callback handlers.
+func statusError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrNotFound):
+		return &trp.StatusError{Code: http.StatusNotFound, Message: err.Error()}
+	case errors.Is(err, ErrInvalidTransition):
+		return &trp.StatusError{Code: http.StatusConflict, Message: err.Error()}
+	case errors.Is(err, ErrNoRequestIdentifier), errors.Is(err, ErrInvalidResolution), errors.Is(err, ErrInvalidConfirmation):
+		return &trp.StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+	default:
+		return err
+	}
+}
+
+//===========================================================================
+// Sending Messages
+//===========================================================================
+
+// SendInquiry tracks the inquiry and sends it using the TRP client, recording the
+// resolution that is returned. If the inquiry does not have a request identifier, one
+// is generated so that callbacks can be correlated. If the inquiry cannot be sent the
+// transfer is no longer tracked so that it can be retried.
+func (t *Tracker) SendInquiry(ctx context.Context, client trp.Client, in *trp.Inquiry) (out *trp.Resolution, err error) {
+	if in.Info == nil {
+		return nil, trp.ErrUnknownTravelAddress
+	}
+
+	if in.Info.RequestIdentifier == "" {
+		in.Info.RequestIdentifier = uuid.NewString()
+	}
+
+	if _, err = t.Inquiry(in); err != nil {
+		return nil, err
+	}
+
+	if out, err = client.Inquiry(ctx, in); err != nil {
+		t.store.Delete(in.Info.RequestIdentifier)
+		return nil, err
+	}
+
+	if _, err = t.Resolve(in.Info.RequestIdentifier, out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// SendResolution sends a resolution to the inquiry callback using the TRP client and
+// records it once it has been delivered.
+func (t *Tracker) SendResolution(ctx context.Context, client trp.Client, in *trp.Resolution) (err error) {
+	if err = client.Resolve(ctx, in); err != nil {
+		return err
+	}
+
+	_, err = t.Resolution(in)
+	return err
+}
+
+// SendConfirmation sends a confirmation to the approval callback using the TRP client
+// and records it once it has been delivered.
+func (t *Tracker) SendConfirmation(ctx context.Context, client trp.Client, in *trp.Confirmation) (err error) {
+	if err = client.Confirm(ctx, in); err != nil {
+		return err
+	}
+
+	_, err = t.Confirmation(in)
+	return err
+}
+
+//===========================================================================
+// Tracker Options
+//===========================================================================
+
+// Option configures the tracker when it is created.
+type Option func(t *Tracker) error
+
+// WithStore specifies the store used to persist transfers (in-memory by default).
+func WithStore(store Store) Option {
+	return func(t *Tracker) error {
+		t.store = store
+		return nil
+	}
+}
+
+// WithResolutionTimeout specifies how long to wait for a resolution after an inquiry is
+// sent or acknowledged. A timeout of zero disables expiration.
+func WithResolutionTimeout(timeout time.Duration) Option {
+	return func(t *Tracker) error {
+		t.resolutionTimeout = timeout
+		return nil
+	}
+}
+
+// WithConfirmationTimeout specifies how long to wait for a confirmation after a transfer
+// is approved. A timeout of zero disables expiration.
+func WithConfirmationTimeout(timeout time.Duration) Option {
+	return func(t *Tracker) error {
+		t.confirmationTimeout = timeout
+		return nil
+	}
+}
+
+// OnState registers a hook that is called when a transfer enters the specified state.
+func OnState(state State, hook Hook) Option {
+	return func(t *Tracker) error {
+		t.hooks[state] = append(t.hooks[state], hook)
+		return nil
+	}
+}
+
+// OnTransition registers a hook that is called on every state transition.
+func OnTransition(hook Hook) Option {
+	return func(t *Tracker) error {
+		t.transitions = append(t.transitions, hook)
+		return nil
+	}
+}
@@ -0,0 +1,248 @@
+package tracker_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/client"
+	"github.com/trisacrypto/trisa/pkg/openvasp/tracker"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+)
+
+const beneficiaryURL = "https://beneficiary.com/transferConfirmation?q=3454366424"
+
+func TestStateTransitions(t *testing.T) {
+	testCases := []struct {
+		from, to tracker.State
+		allowed  bool
+	}{
+		{tracker.Unknown, tracker.Inquired, true},
+		{tracker.Unknown, tracker.Approved, false},
+		{tracker.Inquired, tracker.Pending, true},
+		{tracker.Inquired, tracker.Approved, true},
+		{tracker.Inquired, tracker.Rejected, true},
+		{tracker.Inquired, tracker.Confirmed, false},
+		{tracker.Pending, tracker.Approved, true},
+		{tracker.Pending, tracker.Pending, false},
+		{tracker.Pending, tracker.Expired, true},
+		{tracker.Approved, tracker.Confirmed, true},
+		{tracker.Approved, tracker.Canceled, true},
+		{tracker.Approved, tracker.Rejected, false},
+		{tracker.Rejected, tracker.Approved, false},
+		{tracker.Confirmed, tracker.Canceled, false},
+		{tracker.Expired, tracker.Approved, false},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.allowed, tc.from.CanTransition(tc.to), "%s -> %s", tc.from, tc.to)
+	}
+
+	require.Equal(t, "approved", tracker.Approved.String())
+	require.Equal(t, "State(42)", tracker.State(42).String())
+}
+
+func TestSynchronousApproval(t *testing.T) {
+	transitions := make([]string, 0)
+	var confirmed *tracker.Transfer
+
+	trk, err := tracker.New(
+		tracker.OnTransition(func(tr *tracker.Transfer, from tracker.State) {
+			transitions = append(transitions, from.String()+"->"+tr.State.String())
+		}),
+		tracker.OnState(tracker.Confirmed, func(tr *tracker.Transfer, _ tracker.State) {
+			confirmed = tr
+		}),
+	)
+	require.NoError(t, err, "could not create tracker")
+
+	mock := &MockClient{
+		OnInquiry: func(in *trp.Inquiry) (*trp.Resolution, error) {
+			return &trp.Resolution{Approved: &trp.Approval{Address: "payment address", Callback: beneficiaryURL}}, nil
+		},
+	}
+
+	ctx := context.Background()
+	in := &trp.Inquiry{Info: &trp.Info{Address: "https://beneficiary.com/"}, Amount: 1.0}
+	out, err := trk.SendInquiry(ctx, mock, in)
+	require.NoError(t, err, "could not send inquiry")
+	require.NotNil(t, out.Approved)
+	require.NotEmpty(t, in.Info.RequestIdentifier, "expected a request identifier to be generated")
+
+	transfer, err := trk.Get(in.Info.RequestIdentifier)
+	require.NoError(t, err)
+	require.Equal(t, tracker.Approved, transfer.State)
+	require.WithinDuration(t, time.Now().Add(tracker.DefaultConfirmationTimeout), transfer.Deadline, time.Minute)
+
+	confirmation := &trp.Confirmation{Info: &trp.Info{Address: beneficiaryURL, RequestIdentifier: in.Info.RequestIdentifier}, TXID: "foo"}
+	require.NoError(t, trk.SendConfirmation(ctx, mock, confirmation))
+
+	transfer, err = trk.Get(in.Info.RequestIdentifier)
+	require.NoError(t, err)
+	require.Equal(t, tracker.Confirmed, transfer.State)
+	require.True(t, transfer.Deadline.IsZero())
+	require.Equal(t, []string{"unknown->inquired", "inquired->approved", "approved->confirmed"}, history(transfer))
+	require.Equal(t, history(transfer), transitions)
+	require.NotNil(t, confirmed)
+	require.Equal(t, "foo", confirmed.Confirmation.TXID)
+
+	// Cannot cancel a confirmed transfer
+	_, err = trk.Confirmation(&trp.Confirmation{Info: confirmation.Info, Canceled: "nope"})
+	require.ErrorIs(t, err, tracker.ErrInvalidTransition)
+
+	// Cannot track the same inquiry twice
+	_, err = trk.Inquiry(in)
+	require.ErrorIs(t, err, tracker.ErrExists)
+}
+
+func TestAsyncCallback(t *testing.T) {
+	trk, err := tracker.New()
+	require.NoError(t, err, "could not create tracker")
+
+	// Inquiry is acknowledged but not resolved
+	mock := &MockClient{
+		OnInquiry: func(in *trp.Inquiry) (*trp.Resolution, error) {
+			return &trp.Resolution{Version: openvasp.APIVersion}, nil
+		},
+	}
+
+	ctx := context.Background()
+	in := &trp.Inquiry{Info: &trp.Info{Address: "https://beneficiary.com/", RequestIdentifier: "f716c465-1b37-4a7b-aa32-f49346500721"}}
+	_, err = trk.SendInquiry(ctx, mock, in)
+	require.NoError(t, err, "could not send inquiry")
+
+	transfer, err := trk.Get(in.Info.RequestIdentifier)
+	require.NoError(t, err)
+	require.Equal(t, tracker.Pending, transfer.State)
+
+	// Receive the resolution on the inquiry callback
+	srv := httptest.NewServer(openvasp.TransferResolution(trk))
+	defer srv.Close()
+
+	trpc, err := client.New()
+	require.NoError(t, err)
+
+	resolution := &trp.Resolution{
+		Info:     &trp.Info{Address: srv.URL, RequestIdentifier: in.Info.RequestIdentifier},
+		Rejected: "no thanks",
+	}
+	require.NoError(t, trpc.Resolve(ctx, resolution))
+
+	transfer, err = trk.Get(in.Info.RequestIdentifier)
+	require.NoError(t, err)
+	require.Equal(t, tracker.Rejected, transfer.State)
+	require.Equal(t, "no thanks", transfer.Resolution.Rejected)
+
+	// A second resolution conflicts with the terminal state
+	err = trpc.Resolve(ctx, resolution)
+	requireStatus(t, err, 409)
+
+	// An unknown request identifier is not found
+	resolution.Info = &trp.Info{Address: srv.URL, RequestIdentifier: "c8a1e6b6-fa3c-4f89-9e0a-a8a4e3dcd3b0"}
+	err = trpc.Resolve(ctx, resolution)
+	requireStatus(t, err, 404)
+}
+
+func TestSendInquiryError(t *testing.T) {
+	trk, err := tracker.New()
+	require.NoError(t, err, "could not create tracker")
+
+	mock := &MockClient{
+		OnInquiry: func(in *trp.Inquiry) (*trp.Resolution, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	in := &trp.Inquiry{Info: &trp.Info{Address: "https://beneficiary.com/", RequestIdentifier: "foo"}}
+	_, err = trk.SendInquiry(context.Background(), mock, in)
+	require.EqualError(t, err, "connection refused")
+
+	// The inquiry should no longer be tracked so it can be retried
+	_, err = trk.Get("foo")
+	require.ErrorIs(t, err, tracker.ErrNotFound)
+}
+
+func TestExpire(t *testing.T) {
+	expired := make([]string, 0)
+	trk, err := tracker.New(
+		tracker.WithResolutionTimeout(time.Minute),
+		tracker.WithConfirmationTimeout(0),
+		tracker.OnState(tracker.Expired, func(tr *tracker.Transfer, _ tracker.State) {
+			expired = append(expired, tr.RequestIdentifier)
+		}),
+	)
+	require.NoError(t, err, "could not create tracker")
+
+	for _, id := range []string{"a", "b", "c"} {
+		_, err = trk.Inquiry(&trp.Inquiry{Info: &trp.Info{RequestIdentifier: id}})
+		require.NoError(t, err)
+	}
+
+	// Approved transfers do not expire without a confirmation timeout
+	_, err = trk.Resolution(&trp.Resolution{Info: &trp.Info{RequestIdentifier: "b"}, Approved: &trp.Approval{}})
+	require.NoError(t, err)
+
+	// Rejected transfers are terminal and do not expire
+	_, err = trk.Resolution(&trp.Resolution{Info: &trp.Info{RequestIdentifier: "c"}, Rejected: "no"})
+	require.NoError(t, err)
+
+	transfers, err := trk.Expire(time.Now())
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+
+	transfers, err = trk.Expire(time.Now().Add(2 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, "a", transfers[0].RequestIdentifier)
+	require.Equal(t, tracker.Expired, transfers[0].State)
+	require.Equal(t, []string{"a"}, expired)
+
+	// Late callbacks for expired transfers are rejected
+	_, err = trk.Resolution(&trp.Resolution{Info: &trp.Info{RequestIdentifier: "a"}, Approved: &trp.Approval{}})
+	require.ErrorIs(t, err, tracker.ErrInvalidTransition)
+
+	// Requests must have a request identifier
+	_, err = trk.Inquiry(&trp.Inquiry{})
+	require.ErrorIs(t, err, tracker.ErrNoRequestIdentifier)
+}
+
+func history(transfer *tracker.Transfer) []string {
+	out := make([]string, 0, len(transfer.History))
+	for _, tr := range transfer.History {
+		out = append(out, tr.From.String()+"->"+tr.To.String())
+	}
+	return out
+}
+
+func requireStatus(t *testing.T, err error, code int) {
+	var status *trp.StatusError
+	require.ErrorAs(t, err, &status)
+	require.Equal(t, code, status.Code)
+}
+
+// MockClient implements the trp.Client interface for testing.
+type MockClient struct {
+	OnInquiry func(*trp.Inquiry) (*trp.Resolution, error)
+}
+
+var _ trp.Client = &MockClient{}
+
+func (m *MockClient) Identity(context.Context, string) (*trp.Identity, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockClient) Inquiry(_ context.Context, in *trp.Inquiry) (*trp.Resolution, error) {
+	return m.OnInquiry(in)
+}
+
+func (m *MockClient) Resolve(context.Context, *trp.Resolution) error {
+	return nil
+}
+
+func (m *MockClient) Confirm(context.Context, *trp.Confirmation) error {
+	return nil
+}
@@ -1,6 +1,10 @@
 package openvasp
 
 import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/trisacrypto/trisa/pkg/ivms101"
 	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
 	"github.com/trisacrypto/trisa/pkg/slip0044"
@@ -8,6 +12,7 @@ import (
 	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
 	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // OpenVASP Application Headers
@@ -132,23 +137,8 @@ func EnvelopeToPayload(env *envelope.Envelope) (*trp.Inquiry, error) {
 			return nil, err
 		}
 
-		// Parse the amount and asset type from the transaction.
-		transaction := &generic.Transaction{}
-		if err = payload.Transaction.UnmarshalTo(transaction); err != nil {
-			return nil, err
-		}
-
-		inq := &trp.Inquiry{
-			Asset:   &trp.Asset{},
-			Amount:  transaction.Amount,
-			IVMS101: &ivms101.IdentityPayload{},
-		}
-		if inq.Asset.SLIP044, err = slip0044.ParseCoinType(transaction.AssetType); err != nil {
-			return nil, err
-		}
-
-		// Unmarshal the identity into the TRP payload.
-		if err = payload.Identity.UnmarshalTo(inq.IVMS101); err != nil {
+		inq := &trp.Inquiry{}
+		if err = populateInquiry(inq, payload); err != nil {
 			return nil, err
 		}
 		return inq, nil
@@ -160,3 +150,260 @@ func EnvelopeToPayload(env *envelope.Envelope) (*trp.Inquiry, error) {
 		return nil, trp.ErrEnvelopeError
 	}
 }
+
+// Convert a TRP inquiry into a TRISA envelope in the clear whose payload identity is the
+// IVMS101 identity of the inquiry and whose transaction is a generic.TRP message. The
+// TRP headers are mapped into the TRPInfo of the message and the asset and amount into
+// the TRISA reference transaction. If the request identifier is a UUID it is used as
+// the envelope ID so that all of the messages of a TRP exchange share the same ID. The
+// options are applied to the envelope after it is created. Inquiries with TRISA
+// envelope extensions should be unwrapped with UnwrapInquiry before conversion.
+func InquiryToEnvelope(in *trp.Inquiry, opts ...envelope.Option) (_ *envelope.Envelope, err error) {
+	if in.IVMS101 == nil {
+		return nil, trp.ErrMissingIVMS101
+	}
+
+	msg := &generic.TRP{
+		Message: &generic.TRP_Inquiry{
+			Inquiry: &generic.TRPInquiry{
+				Asset:    make(map[string]string),
+				Amount:   in.Amount,
+				Callback: in.Callback,
+			},
+		},
+		Transaction: &generic.Transaction{
+			Amount: in.Amount,
+		},
+	}
+
+	if in.Asset != nil {
+		inquiry := msg.GetInquiry()
+		if in.Asset.DTI != "" {
+			inquiry.Asset["dti"] = in.Asset.DTI
+			msg.Transaction.AssetType = in.Asset.DTI
+		} else if in.Asset.SLIP044 != nil {
+			inquiry.Asset["slip0044"] = in.Asset.SLIP044.Symbol()
+			msg.Transaction.AssetType = in.Asset.SLIP044.Symbol()
+		}
+	}
+
+	if msg.Extensions, err = marshalExtensions(in.Extensions); err != nil {
+		return nil, err
+	}
+
+	return trpEnvelope(in.Info, msg, in.IVMS101, api.TransferStarted, opts...)
+}
+
+// Convert a TRP resolution into a TRISA envelope in the clear whose transaction is a
+// generic.TRP message. Approvals and rejections are mapped to the approved and rejected
+// TRP messages and set the accepted and rejected transfer states respectively; a
+// resolution with only the version set is pending and has an empty TRP message. TRP
+// resolutions do not contain identity information so the payload identity is empty.
+// An error is returned if the resolution is empty or ambiguous.
+func ResolutionToEnvelope(in *trp.Resolution, opts ...envelope.Option) (_ *envelope.Envelope, err error) {
+	if err = in.Validate(); err != nil {
+		return nil, err
+	}
+
+	msg := &generic.TRP{}
+	state := api.TransferPending
+
+	switch {
+	case in.Approved != nil:
+		state = api.TransferAccepted
+		msg.Message = &generic.TRP_Approved{
+			Approved: &generic.TRPApproved{
+				Address:  in.Approved.Address,
+				Callback: in.Approved.Callback,
+			},
+		}
+		msg.Transaction = &generic.Transaction{Beneficiary: in.Approved.Address}
+	case in.Rejected != "":
+		state = api.TransferRejected
+		msg.Message = &generic.TRP_Rejected{
+			Rejected: &generic.TRPRejected{Rejected: in.Rejected},
+		}
+	}
+
+	return trpEnvelope(in.Info, msg, nil, state, opts...)
+}
+
+// Convert a TRP confirmation into a TRISA envelope in the clear whose transaction is a
+// generic.TRP message. Confirmations with a transaction ID set the completed transfer
+// state and cancellations set the rejected transfer state. TRP confirmations do not
+// contain identity information so the payload identity is empty.
+func ConfirmationToEnvelope(in *trp.Confirmation, opts ...envelope.Option) (_ *envelope.Envelope, err error) {
+	msg := &generic.TRP{}
+	var state api.TransferState
+
+	switch {
+	case in.TXID != "":
+		state = api.TransferCompleted
+		msg.Message = &generic.TRP_Confirmed{
+			Confirmed: &generic.TRPConfirmed{Txid: in.TXID},
+		}
+		msg.Transaction = &generic.Transaction{Txid: in.TXID}
+	case in.Canceled != "":
+		state = api.TransferRejected
+		msg.Message = &generic.TRP_Canceled{
+			Canceled: &generic.TRPCanceled{Canceled: in.Canceled},
+		}
+	default:
+		return nil, trp.ErrEmptyConfirmation
+	}
+
+	return trpEnvelope(in.Info, msg, nil, state, opts...)
+}
+
+// Create a TRISA envelope for the generic TRP message, mapping the TRP info headers.
+func trpEnvelope(info *trp.Info, msg *generic.TRP, identity *ivms101.IdentityPayload, state api.TransferState, opts ...envelope.Option) (_ *envelope.Envelope, err error) {
+	envopts := make([]envelope.Option, 0, len(opts)+2)
+	envopts = append(envopts, envelope.WithTransferState(state))
+
+	if info != nil {
+		msg.Headers = &generic.TRPInfo{
+			Version:           info.APIVersion,
+			RequestIdentifier: info.RequestIdentifier,
+			Extensions:        info.APIExtensions,
+		}
+		msg.EnvelopeId = info.RequestIdentifier
+
+		if _, err = uuid.Parse(info.RequestIdentifier); err == nil {
+			envopts = append(envopts, envelope.WithEnvelopeID(info.RequestIdentifier))
+		}
+	}
+
+	if identity == nil {
+		identity = &ivms101.IdentityPayload{}
+	}
+
+	payload := &api.Payload{
+		SentAt: time.Now().Format(time.RFC3339),
+	}
+
+	if payload.Identity, err = anypb.New(identity); err != nil {
+		return nil, err
+	}
+
+	if payload.Transaction, err = anypb.New(msg); err != nil {
+		return nil, err
+	}
+
+	var env *envelope.Envelope
+	if env, err = envelope.New(payload, append(envopts, opts...)...); err != nil {
+		return nil, err
+	}
+
+	// Ensure the envelope ID is set on the TRP message for archival
+	if msg.EnvelopeId == "" {
+		msg.EnvelopeId = env.ID()
+		if payload.Transaction, err = anypb.New(msg); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// Serialize the extensions as a JSON formatted string, excluding the TRISA envelope
+// extensions, which contain the TRISA payload itself.
+func marshalExtensions(extensions map[string]interface{}) (_ string, err error) {
+	filtered := make(map[string]interface{}, len(extensions))
+	for key, val := range extensions {
+		if key == SealedTRISAExtension || key == UnsealedTRISAExtension {
+			continue
+		}
+		filtered[key] = val
+	}
+
+	if len(filtered) == 0 {
+		return "", nil
+	}
+
+	var data []byte
+	if data, err = json.Marshal(filtered); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Populate the asset, amount, and IVMS101 fields of the inquiry from the TRISA payload
+// if they have not already been set on the inquiry. The transaction of the payload must
+// be a registered generic transaction type (see generic.View) or a generic TRP message
+// containing an inquiry.
+func populateInquiry(inq *trp.Inquiry, payload *api.Payload) (err error) {
+	// Parse the amount and asset type from the transaction.
+	var asset *trp.Asset
+	transaction := &generic.Transaction{}
+
+	if payload.Transaction.MessageIs(&generic.TRP{}) {
+		msg := &generic.TRP{}
+		if err = payload.Transaction.UnmarshalTo(msg); err != nil {
+			return err
+		}
+
+		if msg.Transaction != nil {
+			transaction = msg.Transaction
+		}
+
+		if inquiry := msg.GetInquiry(); inquiry != nil {
+			if inq.Callback == "" {
+				inq.Callback = inquiry.Callback
+			}
+
+			if dti, ok := inquiry.Asset["dti"]; ok {
+				asset = &trp.Asset{DTI: dti}
+			}
+		}
+	} else if transaction, err = generic.View(payload.Transaction); err != nil {
+		return err
+	}
+
+	if inq.Asset == nil {
+		if asset == nil {
+			var coin slip0044.CoinType
+			if coin, err = slip0044.ParseCoinType(transaction.AssetType); err != nil {
+				return err
+			}
+			asset = &trp.Asset{SLIP044: coin.Enum()}
+		}
+		inq.Asset = asset
+	}
+
+	if inq.Amount == 0 {
+		inq.Amount = transaction.Amount
+	}
+
+	// Unmarshal the identity into the TRP payload.
+	if inq.IVMS101 == nil {
+		inq.IVMS101 = &ivms101.IdentityPayload{}
+		if err = payload.Identity.UnmarshalTo(inq.IVMS101); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SecureEnvelope parses the JSON serialized secure envelope of the extension.
+func (s *SealedTRISAEnvelope) SecureEnvelope() (msg *api.SecureEnvelope, err error) {
+	msg = &api.SecureEnvelope{}
+	if err = protojson.Unmarshal([]byte(s.Envelope), msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SecureEnvelope returns the unsealed secure envelope described by the extension. The
+// extension does not include a timestamp so the current time is used.
+func (u *UnsealedTRISAEnvelope) SecureEnvelope() *api.SecureEnvelope {
+	return &api.SecureEnvelope{
+		Id:                  u.Id,
+		Payload:             u.Payload,
+		EncryptionKey:       u.EncryptionKey,
+		EncryptionAlgorithm: u.EncryptionAlgorithm,
+		Hmac:                u.HMAC,
+		HmacSecret:          u.HMACSecret,
+		HmacAlgorithm:       u.HMACAlgorithm,
+		Timestamp:           time.Now().UTC().Format(time.RFC3339Nano),
+		Sealed:              false,
+	}
+}
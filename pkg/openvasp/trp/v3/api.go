@@ -104,8 +104,8 @@ type Inquiry struct {
 // Asset is used to describe the virtual or crypto asset being transferred.
 // This is a sub-data structure: it is nested within an Inquiry.
 type Asset struct {
-	DTI     string            `json:"dti,omitempty"`      // digital token identifier as per Digital Token Identifier Foundation
-	SLIP044 slip0044.CoinType `json:"slip0044,omitempty"` // registered coin types defined by BIP-0044
+	DTI     string             `json:"dti,omitempty"`      // digital token identifier as per Digital Token Identifier Foundation
+	SLIP044 *slip0044.CoinType `json:"slip0044,omitempty"` // registered coin types defined by BIP-0044
 }
 
 //===========================================================================
@@ -20,6 +20,10 @@ var (
 	ErrEmptyAsset            = errors.New("invalid: must specify either DTI or SLIP-0044 asset identifier")
 	ErrNoAmount              = errors.New("invalid: must specify a non-zero amount")
 	ErrMissingIVMS101        = errors.New("invalid: must specify IVMS101 identity payload")
+	ErrAmbiguousTRISA        = errors.New("invalid: cannot specify both sealed and unsealed trisa envelope extensions")
+	ErrUnsealingKeyRequired  = errors.New("an unsealing key is required to open a sealed trisa envelope")
+	ErrInvalidVersion        = errors.New("invalid semantic version")
+	ErrUnsupportedVersion    = errors.New("unsupported trp api version")
 )
 
 type StatusError struct {
@@ -30,7 +30,8 @@ func (i Inquiry) Validate() (err error) {
 }
 
 func (a Asset) Validate() error {
-	if a.DTI == "" && a.SLIP044 == 0 {
+	// NOTE: the SLIP-0044 coin type is a pointer since the zero value is Bitcoin.
+	if a.DTI == "" && a.SLIP044 == nil {
 		return ErrEmptyAsset
 	}
 
@@ -5,8 +5,30 @@ import (
 
 	"github.com/stretchr/testify/require"
 	. "github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/slip0044"
 )
 
+func TestAssetValidate(t *testing.T) {
+	testCases := []struct {
+		asset *Asset
+		err   error
+	}{
+		{&Asset{}, ErrEmptyAsset},
+		{&Asset{DTI: "4H95J0R2X"}, nil},
+		{&Asset{SLIP044: slip0044.CoinType_BITCOIN.Enum()}, nil},
+		{&Asset{SLIP044: slip0044.CoinType_ETH.Enum()}, nil},
+	}
+
+	for i, tc := range testCases {
+		err := tc.asset.Validate()
+		if tc.err != nil {
+			require.ErrorIs(t, err, tc.err, "test case %d failed with mismatched error", i)
+		} else {
+			require.NoError(t, err, "test case %d failed: expected valid asset", i)
+		}
+	}
+}
+
 func TestConfirmationValidate(t *testing.T) {
 	testCases := []struct {
 		confirm *Confirmation
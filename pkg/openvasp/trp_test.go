@@ -9,6 +9,7 @@ import (
 	"github.com/trisacrypto/trisa/pkg/ivms101"
 	. "github.com/trisacrypto/trisa/pkg/openvasp"
 	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/slip0044"
 	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
 	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
 	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
@@ -75,10 +76,148 @@ func TestEnvelopePayload(t *testing.T) {
 	// Convert the clear envelope to a TRP payload
 	payload, err = EnvelopeToPayload(env)
 	require.NoError(t, err, "could not convert envelope to TRP payload")
-	require.Equal(t, &trp.Asset{SLIP044: 0}, payload.Asset, "asset type does not match")
+	require.Equal(t, &trp.Asset{SLIP044: slip0044.CoinType_BITCOIN.Enum()}, payload.Asset, "asset type does not match")
 	require.Equal(t, transaction.Amount, payload.Amount, "amount does not match")
 	require.True(t, proto.Equal(payload.IVMS101, identity), "identity does not match")
 	require.Nil(t, payload.Extensions, "payload should not contain any extensions")
+
+	// Chain specific transaction payloads are converted using the generic view
+	fixture.Transaction, err = anypb.New(&generic.LightningPayment{PaymentHash: "9e017f6d", AmountMsat: 250000000, AssetType: "BTC"})
+	require.NoError(t, err, "could not marshal lightning payment")
+	env, err = envelope.New(fixture)
+	require.NoError(t, err, "could not create clear envelope")
+
+	payload, err = EnvelopeToPayload(env)
+	require.NoError(t, err, "could not convert envelope to TRP payload")
+	require.Equal(t, &trp.Asset{SLIP044: slip0044.CoinType_BITCOIN.Enum()}, payload.Asset, "asset type does not match")
+	require.Equal(t, 0.0025, payload.Amount, "amount does not match")
+}
+
+func TestInquiryToEnvelope(t *testing.T) {
+	in, err := loadInquiryPayload("testdata/inquiry.json")
+	require.NoError(t, err, "could not load inquiry fixture")
+	in.Info = &trp.Info{
+		APIVersion:        APIVersion,
+		RequestIdentifier: requestIdentifier,
+		APIExtensions:     []string{"message-signing"},
+	}
+	in.Extensions = map[string]interface{}{"message-signing": "foo"}
+
+	env, err := InquiryToEnvelope(in)
+	require.NoError(t, err, "could not convert inquiry to envelope")
+	require.Equal(t, envelope.Clear, env.State())
+	require.Equal(t, requestIdentifier, env.ID())
+	require.Equal(t, api.TransferStarted, env.TransferState())
+	require.NoError(t, env.ValidatePayload())
+
+	msg := loadTRP(t, env)
+	require.Equal(t, requestIdentifier, msg.EnvelopeId)
+	require.Equal(t, APIVersion, msg.Headers.Version)
+	require.Equal(t, requestIdentifier, msg.Headers.RequestIdentifier)
+	require.Equal(t, []string{"message-signing"}, msg.Headers.Extensions)
+	require.JSONEq(t, `{"message-signing": "foo"}`, msg.Extensions)
+
+	inquiry := msg.GetInquiry()
+	require.NotNil(t, inquiry, "expected an inquiry message")
+	require.Equal(t, map[string]string{"slip0044": "BTC"}, inquiry.Asset)
+	require.Equal(t, in.Amount, inquiry.Amount)
+	require.Equal(t, beneficiaryURL, inquiry.Callback)
+	require.Equal(t, "BTC", msg.Transaction.AssetType)
+	require.Empty(t, msg.Transaction.Network, "network is not specified by the asset")
+	require.Equal(t, in.Amount, msg.Transaction.Amount)
+
+	// Convert the envelope back into a TRP inquiry
+	out, err := EnvelopeToPayload(env)
+	require.NoError(t, err, "could not convert envelope to inquiry")
+	require.Equal(t, in.Asset, out.Asset)
+	require.Equal(t, in.Amount, out.Amount)
+	require.Equal(t, in.Callback, out.Callback)
+	require.True(t, proto.Equal(in.IVMS101, out.IVMS101), "identity does not match")
+
+	// An identity is required
+	in.IVMS101 = nil
+	_, err = InquiryToEnvelope(in)
+	require.ErrorIs(t, err, trp.ErrMissingIVMS101)
+}
+
+func TestResolutionToEnvelope(t *testing.T) {
+	info := &trp.Info{APIVersion: APIVersion, RequestIdentifier: requestIdentifier}
+
+	testCases := []struct {
+		in    *trp.Resolution
+		state api.TransferState
+		check func(*generic.TRP)
+	}{
+		{
+			&trp.Resolution{Info: info, Version: APIVersion},
+			api.TransferPending,
+			func(msg *generic.TRP) { require.Nil(t, msg.Message) },
+		},
+		{
+			&trp.Resolution{Info: info, Approved: &trp.Approval{Address: "some payment address", Callback: beneficiaryURL}},
+			api.TransferAccepted,
+			func(msg *generic.TRP) {
+				require.Equal(t, "some payment address", msg.GetApproved().Address)
+				require.Equal(t, beneficiaryURL, msg.GetApproved().Callback)
+				require.Equal(t, "some payment address", msg.Transaction.Beneficiary)
+			},
+		},
+		{
+			&trp.Resolution{Info: info, Rejected: "human readable comment"},
+			api.TransferRejected,
+			func(msg *generic.TRP) { require.Equal(t, "human readable comment", msg.GetRejected().Rejected) },
+		},
+	}
+
+	for _, tc := range testCases {
+		env, err := ResolutionToEnvelope(tc.in)
+		require.NoError(t, err)
+		require.Equal(t, requestIdentifier, env.ID())
+		require.Equal(t, tc.state, env.TransferState())
+		require.NoError(t, env.ValidatePayload())
+		tc.check(loadTRP(t, env))
+	}
+
+	// Empty and ambiguous resolutions cannot be converted
+	_, err := ResolutionToEnvelope(&trp.Resolution{Info: info})
+	require.ErrorIs(t, err, trp.ErrEmptyResolution)
+
+	_, err = ResolutionToEnvelope(&trp.Resolution{Info: info, Version: APIVersion, Rejected: "foo"})
+	require.ErrorIs(t, err, trp.ErrAmbiguousResolution)
+}
+
+func TestConfirmationToEnvelope(t *testing.T) {
+	env, err := ConfirmationToEnvelope(&trp.Confirmation{TXID: "foo"})
+	require.NoError(t, err)
+	require.Equal(t, api.TransferCompleted, env.TransferState())
+
+	// Without a request identifier the envelope ID is generated
+	msg := loadTRP(t, env)
+	require.Equal(t, env.ID(), msg.EnvelopeId)
+	require.Nil(t, msg.Headers)
+	require.Equal(t, "foo", msg.GetConfirmed().Txid)
+	require.Equal(t, "foo", msg.Transaction.Txid)
+
+	env, err = ConfirmationToEnvelope(&trp.Confirmation{Info: &trp.Info{RequestIdentifier: "not-a-uuid"}, Canceled: "bar"})
+	require.NoError(t, err)
+	require.Equal(t, api.TransferRejected, env.TransferState())
+
+	msg = loadTRP(t, env)
+	require.Equal(t, "not-a-uuid", msg.EnvelopeId)
+	require.NotEqual(t, "not-a-uuid", env.ID())
+	require.Equal(t, "bar", msg.GetCanceled().Canceled)
+
+	_, err = ConfirmationToEnvelope(&trp.Confirmation{})
+	require.ErrorIs(t, err, trp.ErrEmptyConfirmation)
+}
+
+func loadTRP(t *testing.T, env *envelope.Envelope) *generic.TRP {
+	payload, err := env.Payload()
+	require.NoError(t, err, "could not get envelope payload")
+
+	msg := &generic.TRP{}
+	require.NoError(t, payload.Transaction.UnmarshalTo(msg), "transaction is not a generic.TRP message")
+	return msg
 }
 
 func loadEnvelope(path string) (env *envelope.Envelope, err error) {
@@ -0,0 +1,373 @@
+package openvasp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/slip0044"
+)
+
+// DefaultVersions are the TRP API versions accepted by APIChecks and by the TRP server
+// unless otherwise configured: all patch releases of the current API version.
+var DefaultVersions = MustParseVersionRange("~" + APIVersion)
+
+//===========================================================================
+// Semantic Versions
+//===========================================================================
+
+// SemVer is a major.minor.patch semantic version of the TRP API. Pre-release and build
+// metadata are not used by TRP and are not supported.
+type SemVer struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+}
+
+// ParseSemVer parses a version string such as "3.1.0" or "v3.1"; any components that
+// are omitted are zero.
+func ParseSemVer(version string) (v SemVer, err error) {
+	var n int
+	if v, n, err = parsePartial(version); err != nil {
+		return SemVer{}, err
+	}
+
+	if n == 0 {
+		return SemVer{}, fmt.Errorf("%w: %q", trp.ErrInvalidVersion, version)
+	}
+	return v, nil
+}
+
+// String returns the major.minor.patch representation of the version.
+func (v SemVer) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1 if v is less than o, 1 if v is greater than o, and 0 if equal.
+func (v SemVer) Compare(o SemVer) int {
+	for _, c := range [3][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		switch {
+		case c[0] < c[1]:
+			return -1
+		case c[0] > c[1]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Parses a version that may be partial (e.g. "3", "3.1") or contain wildcards (e.g.
+// "3.x", "*"), returning the number of components that were specified.
+func parsePartial(version string) (v SemVer, n int, err error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if version == "" {
+		return SemVer{}, 0, fmt.Errorf("%w: empty version", trp.ErrInvalidVersion)
+	}
+
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return SemVer{}, 0, fmt.Errorf("%w: %q", trp.ErrInvalidVersion, version)
+	}
+
+	components := [3]uint64{}
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			// No components may be specified after a wildcard
+			for _, rest := range parts[i+1:] {
+				if rest != "x" && rest != "X" && rest != "*" {
+					return SemVer{}, 0, fmt.Errorf("%w: %q", trp.ErrInvalidVersion, version)
+				}
+			}
+			break
+		}
+
+		if components[i], err = strconv.ParseUint(part, 10, 64); err != nil {
+			return SemVer{}, 0, fmt.Errorf("%w: %q", trp.ErrInvalidVersion, version)
+		}
+		n++
+	}
+
+	return SemVer{Major: components[0], Minor: components[1], Patch: components[2]}, n, nil
+}
+
+// Returns the smallest version that is greater than every version matching the first
+// n components of v, e.g. the upper bound of 3.1.x is 3.2.0.
+func upperBound(v SemVer, n int) SemVer {
+	switch n {
+	case 1:
+		return SemVer{Major: v.Major + 1}
+	case 2:
+		return SemVer{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return SemVer{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
+
+//===========================================================================
+// Version Ranges
+//===========================================================================
+
+// VersionRange is a set of semantic versions that are supported by a TRP server or
+// client. Ranges are specified using the common semver constraint syntax: comparators
+// (=, >, >=, <, <=) separated by spaces must all match, tilde ranges (~3.1.0) allow
+// patch releases, caret ranges (^3.0.0) allow minor and patch releases, wildcards
+// (3.x, 3.1.*) and partial versions (3.1) match any omitted components, and
+// alternative ranges are separated by ||, e.g. "^3.0.0 || >=2.1 <3".
+type VersionRange struct {
+	constraint string
+	sets       [][]comparator
+}
+
+type comparator struct {
+	op      string
+	version SemVer
+}
+
+// ParseVersionRange parses a semver constraint into a version range.
+func ParseVersionRange(constraint string) (_ *VersionRange, err error) {
+	r := &VersionRange{constraint: strings.TrimSpace(constraint)}
+	for _, alternative := range strings.Split(r.constraint, "||") {
+		set := make([]comparator, 0, 2)
+		for _, term := range strings.Fields(alternative) {
+			var expanded []comparator
+			if expanded, err = parseComparator(term); err != nil {
+				return nil, err
+			}
+			set = append(set, expanded...)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// MustParseVersionRange parses a semver constraint and panics if it is invalid.
+func MustParseVersionRange(constraint string) *VersionRange {
+	r, err := ParseVersionRange(constraint)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Expands a single constraint term into the comparators that must all match.
+func parseComparator(term string) (_ []comparator, err error) {
+	var op string
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(term, prefix) {
+			op, term = prefix, term[len(prefix):]
+			break
+		}
+	}
+
+	var (
+		v SemVer
+		n int
+	)
+	if v, n, err = parsePartial(term); err != nil {
+		return nil, err
+	}
+
+	// A wildcard matches any version with all operators other than < and >.
+	if n == 0 {
+		switch op {
+		case "<", ">":
+			return nil, fmt.Errorf("%w: %q cannot match any version", trp.ErrInvalidVersion, op+term)
+		default:
+			return []comparator{}, nil
+		}
+	}
+
+	switch op {
+	case "", "=":
+		if n == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return []comparator{{">=", v}, {"<", upperBound(v, n)}}, nil
+	case ">=", "<":
+		return []comparator{{op, v}}, nil
+	case ">":
+		if n == 3 {
+			return []comparator{{">", v}}, nil
+		}
+		return []comparator{{">=", upperBound(v, n)}}, nil
+	case "<=":
+		if n == 3 {
+			return []comparator{{"<=", v}}, nil
+		}
+		return []comparator{{"<", upperBound(v, n)}}, nil
+	case "~":
+		if n == 1 {
+			return []comparator{{">=", v}, {"<", upperBound(v, 1)}}, nil
+		}
+		return []comparator{{">=", v}, {"<", upperBound(v, 2)}}, nil
+	case "^":
+		switch {
+		case v.Major > 0 || n == 1:
+			return []comparator{{">=", v}, {"<", upperBound(v, 1)}}, nil
+		case v.Minor > 0 || n == 2:
+			return []comparator{{">=", v}, {"<", upperBound(v, 2)}}, nil
+		default:
+			return []comparator{{">=", v}, {"<", upperBound(v, 3)}}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown operator in %q", trp.ErrInvalidVersion, op+term)
+}
+
+func (c comparator) matches(v SemVer) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// Contains returns true if the version is in the range.
+func (r *VersionRange) Contains(v SemVer) bool {
+	for _, set := range r.sets {
+		matches := true
+		for _, c := range set {
+			if !c.matches(v) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports parses the version string and returns true if it is in the range.
+func (r *VersionRange) Supports(version string) bool {
+	v, err := ParseSemVer(version)
+	if err != nil {
+		return false
+	}
+	return r.Contains(v)
+}
+
+// String returns the constraint the range was parsed from.
+func (r *VersionRange) String() string {
+	return r.constraint
+}
+
+//===========================================================================
+// Version Adapters
+//===========================================================================
+
+// Adapter converts the JSON payloads of TRP requests and responses between a previous
+// major version of the TRP API and the current version implemented by this package.
+// Servers upgrade incoming requests and downgrade their responses; clients downgrade
+// outgoing requests and upgrade the responses they receive. Adapters must return the
+// data unmodified if it does not need to be converted.
+type Adapter interface {
+	Upgrade(data []byte) ([]byte, error)
+	Downgrade(data []byte) ([]byte, error)
+}
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   = map[uint64]Adapter{
+		2: V2Adapter{},
+	}
+)
+
+// RegisterAdapter registers the adapter for the specified major version of the TRP
+// API, replacing any previously registered adapter.
+func RegisterAdapter(major uint64, adapter Adapter) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	adapters[major] = adapter
+}
+
+// AdapterFor returns the adapter for the major version of the specified API version or
+// nil if no adaptation is required (e.g. for the current major version) or possible.
+func AdapterFor(version string) Adapter {
+	v, err := ParseSemVer(version)
+	if err != nil {
+		return nil
+	}
+
+	adaptersMu.RLock()
+	defer adaptersMu.RUnlock()
+	return adapters[v.Major]
+}
+
+// V2Adapter converts payloads between TRP v2 and v3. In TRP v2 the SLIP-0044 asset is
+// identified by its numeric coin type rather than by its symbol.
+type V2Adapter struct{}
+
+// Upgrade replaces a numeric SLIP-0044 coin type in the asset with its symbol.
+func (V2Adapter) Upgrade(data []byte) ([]byte, error) {
+	return adaptAsset(data, func(coin interface{}) (interface{}, error) {
+		if number, ok := coin.(json.Number); ok {
+			ct, err := strconv.ParseInt(number.String(), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid slip0044 coin type %s", number)
+			}
+
+			symbol, ok := slip0044.CoinType_name[int32(ct)]
+			if !ok {
+				return nil, slip0044.ErrUnknownCoin
+			}
+			return symbol, nil
+		}
+		return coin, nil
+	})
+}
+
+// Downgrade replaces the SLIP-0044 symbol in the asset with its numeric coin type.
+func (V2Adapter) Downgrade(data []byte) ([]byte, error) {
+	return adaptAsset(data, func(coin interface{}) (interface{}, error) {
+		if symbol, ok := coin.(string); ok {
+			ct, err := slip0044.ParseCoinType(symbol)
+			if err != nil {
+				return nil, err
+			}
+			return int32(ct), nil
+		}
+		return coin, nil
+	})
+}
+
+// Applies the conversion to the slip0044 field of the asset of a JSON payload, if any.
+func adaptAsset(data []byte, convert func(interface{}) (interface{}, error)) (_ []byte, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var payload map[string]interface{}
+	if err = decoder.Decode(&payload); err != nil {
+		// Payloads that are not JSON objects are not adapted
+		return data, nil
+	}
+
+	asset, ok := payload["asset"].(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+
+	coin, ok := asset["slip0044"]
+	if !ok {
+		return data, nil
+	}
+
+	if asset["slip0044"], err = convert(coin); err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,125 @@
+package openvasp_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	. "github.com/trisacrypto/trisa/pkg/openvasp"
+	"github.com/trisacrypto/trisa/pkg/openvasp/trp/v3"
+	"github.com/trisacrypto/trisa/pkg/slip0044"
+)
+
+func TestParseSemVer(t *testing.T) {
+	testCases := []struct {
+		version  string
+		expected SemVer
+	}{
+		{"3.1.0", SemVer{3, 1, 0}},
+		{"v3.1.2", SemVer{3, 1, 2}},
+		{"2.1", SemVer{2, 1, 0}},
+		{"2", SemVer{2, 0, 0}},
+		{" 10.20.30 ", SemVer{10, 20, 30}},
+	}
+
+	for _, tc := range testCases {
+		v, err := ParseSemVer(tc.version)
+		require.NoError(t, err, "could not parse %q", tc.version)
+		require.Equal(t, tc.expected, v)
+	}
+
+	for _, version := range []string{"", "x", "3.1.0.1", "3.a", "3.x.1", "-1.0.0", "3.1.0-beta"} {
+		_, err := ParseSemVer(version)
+		require.ErrorIs(t, err, trp.ErrInvalidVersion, "expected %q to be invalid", version)
+	}
+
+	require.Equal(t, "3.1.0", SemVer{3, 1, 0}.String())
+	require.Equal(t, -1, SemVer{2, 9, 9}.Compare(SemVer{3, 0, 0}))
+	require.Equal(t, 1, SemVer{3, 1, 1}.Compare(SemVer{3, 1, 0}))
+	require.Equal(t, 0, SemVer{3, 1, 0}.Compare(SemVer{3, 1, 0}))
+}
+
+func TestVersionRange(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		supported  []string
+		rejected   []string
+	}{
+		{"3.1.0", []string{"3.1.0", "3.1"}, []string{"3.1.1", "3.0.0"}},
+		{"=3.1.0", []string{"3.1.0"}, []string{"3.1.1"}},
+		{"3.1", []string{"3.1.0", "3.1.9"}, []string{"3.2.0", "3.0.9"}},
+		{"3.x", []string{"3.0.0", "3.9.9"}, []string{"2.9.9", "4.0.0"}},
+		{"*", []string{"0.0.1", "2.0.0", "99.0.0"}, nil},
+		{"~3.1.0", []string{"3.1.0", "3.1.5"}, []string{"3.2.0", "3.0.9"}},
+		{"~3.1.2", []string{"3.1.2", "3.1.5"}, []string{"3.1.1", "3.2.0"}},
+		{"~3", []string{"3.0.0", "3.5.0"}, []string{"4.0.0"}},
+		{"^3.0.0", []string{"3.0.0", "3.1.0", "3.9.9"}, []string{"2.9.9", "4.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{">=2.1 <3", []string{"2.1.0", "2.9.9"}, []string{"2.0.9", "3.0.0"}},
+		{">3.0.0", []string{"3.0.1"}, []string{"3.0.0"}},
+		{">3.0", []string{"3.1.0"}, []string{"3.0.9"}},
+		{"<=3.0", []string{"3.0.9", "2.0.0"}, []string{"3.1.0"}},
+		{"<=3.0.0", []string{"3.0.0"}, []string{"3.0.1"}},
+		{"^3.0.0 || ^2.0.0", []string{"2.0.0", "2.5.0", "3.1.0"}, []string{"1.9.0", "4.0.0"}},
+		{"3.1.0 || 2.x", []string{"3.1.0", "2.0.1"}, []string{"3.1.1", "3.0.0"}},
+	}
+
+	for _, tc := range testCases {
+		r, err := ParseVersionRange(tc.constraint)
+		require.NoError(t, err, "could not parse %q", tc.constraint)
+		require.Equal(t, tc.constraint, r.String())
+
+		for _, version := range tc.supported {
+			require.True(t, r.Supports(version), "expected %q to support %q", tc.constraint, version)
+		}
+
+		for _, version := range tc.rejected {
+			require.False(t, r.Supports(version), "expected %q to reject %q", tc.constraint, version)
+		}
+	}
+
+	for _, constraint := range []string{"~a.b", ">=3.1.0 <", "!3.0.0", "3.1.0.0", ">*"} {
+		_, err := ParseVersionRange(constraint)
+		require.ErrorIs(t, err, trp.ErrInvalidVersion, "expected %q to be invalid", constraint)
+	}
+
+	require.False(t, DefaultVersions.Supports("not a version"))
+	require.True(t, DefaultVersions.Supports(APIVersion))
+	require.Panics(t, func() { MustParseVersionRange("~a.b") })
+}
+
+func TestV2Adapter(t *testing.T) {
+	require.Nil(t, AdapterFor(APIVersion))
+	require.Nil(t, AdapterFor("foo"))
+	require.IsType(t, V2Adapter{}, AdapterFor("2.1.0"))
+
+	adapter := V2Adapter{}
+	v2 := `{"asset":{"slip0044":60},"amount":1.5,"callback":"https://example.com"}`
+	v3 := `{"asset":{"slip0044":"ETH"},"amount":1.5,"callback":"https://example.com"}`
+
+	upgraded, err := adapter.Upgrade([]byte(v2))
+	require.NoError(t, err)
+	require.JSONEq(t, v3, string(upgraded))
+
+	downgraded, err := adapter.Downgrade([]byte(v3))
+	require.NoError(t, err)
+	require.JSONEq(t, v2, string(downgraded))
+
+	// Payloads without a slip0044 asset are not modified
+	for _, payload := range []string{`{"asset":{"dti":"4H95J0R2X"}}`, `{"txid":"foo"}`, `"not an object"`} {
+		out, err := adapter.Upgrade([]byte(payload))
+		require.NoError(t, err)
+		require.Equal(t, payload, string(out))
+
+		out, err = adapter.Downgrade([]byte(payload))
+		require.NoError(t, err)
+		require.Equal(t, payload, string(out))
+	}
+
+	// Unknown coin types cannot be adapted
+	_, err = adapter.Upgrade([]byte(`{"asset":{"slip0044":2147483647}}`))
+	require.ErrorIs(t, err, slip0044.ErrUnknownCoin)
+
+	_, err = adapter.Downgrade([]byte(`{"asset":{"slip0044":"NOTACOIN"}}`))
+	require.ErrorIs(t, err, slip0044.ErrUnknownCoin)
+}
@@ -0,0 +1,381 @@
+/*
+Package archive implements an append-only compliance archive for the secure envelopes
+sent to and received from TRISA peers. Envelopes are archived sealed, exactly as they
+were exchanged, so that the HMAC signature of the payload can be re-validated once the
+envelope is unsealed to show that the payload has not been modified. The HMAC secret
+is shared by both counterparties, so the signature does not prove which of them created
+the payload; envelopes signed with Envelope.Sign can be checked for authorship with
+Envelope.VerifySignature. Each entry references the digest of the previous entry,
+forming a tamper-evident hash chain that can be verified at any time and exported for
+regulators.
+*/
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Archive appends secure envelopes to a hash chain stored by a backend.
+type Archive struct {
+	sync.Mutex
+	backend Backend
+	clock   clock.Clock
+	length  uint64
+	head    []byte
+}
+
+// Open an archive stored by the backend. The hash chain is not verified when the
+// archive is opened; call Verify to check the integrity of the existing entries.
+func Open(backend Backend, opts ...Option) (archive *Archive, err error) {
+	if backend == nil {
+		return nil, ErrNoBackend
+	}
+
+	archive = &Archive{backend: backend, clock: clock.System{}}
+	for _, opt := range opts {
+		if err = opt(archive); err != nil {
+			return nil, err
+		}
+	}
+
+	if archive.length, err = backend.Len(); err != nil {
+		return nil, err
+	}
+
+	if archive.length > 0 {
+		var last *Entry
+		if last, err = backend.Get(archive.length); err != nil {
+			return nil, err
+		}
+		archive.head = last.Digest
+	}
+	return archive, nil
+}
+
+// Record appends a sealed secure envelope that was sent to or received from the
+// counterparty to the archive. Envelopes that are not sealed may only be archived if
+// they are error envelopes without a payload, e.g. rejections.
+func (a *Archive) Record(direction envelope.Direction, counterparty string, msg *api.SecureEnvelope) (entry *Entry, err error) {
+	switch {
+	case msg == nil:
+		return nil, ErrNoEnvelope
+	case msg.Id == "":
+		return nil, ErrNoEnvelopeID
+	case !msg.Sealed && (msg.Error == nil || len(msg.Payload) > 0):
+		return nil, ErrNotSealed
+	case direction != envelope.Incoming && direction != envelope.Outgoing:
+		return nil, ErrNoDirection
+	}
+
+	entry = &Entry{
+		Direction:    direction,
+		Counterparty: counterparty,
+		EnvelopeID:   msg.Id,
+	}
+
+	if entry.Data, err = proto.Marshal(msg); err != nil {
+		return nil, fmt.Errorf("could not marshal secure envelope: %w", err)
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	entry.Sequence = a.length + 1
+	entry.Timestamp = a.clock.Now().UTC()
+	entry.Previous = a.head
+	entry.Digest = entry.ComputeDigest()
+
+	if err = a.backend.Append(entry); err != nil {
+		return nil, err
+	}
+
+	a.length = entry.Sequence
+	a.head = entry.Digest
+	return entry, nil
+}
+
+// Len returns the number of entries in the archive.
+func (a *Archive) Len() uint64 {
+	a.Lock()
+	defer a.Unlock()
+	return a.length
+}
+
+// Head returns the digest of the last entry in the archive. The hash chain cannot
+// detect the removal of its most recent entries on its own, so the head should be
+// published or stored outside of the backend and later passed to VerifyHead.
+func (a *Archive) Head() []byte {
+	a.Lock()
+	defer a.Unlock()
+	return append([]byte(nil), a.head...)
+}
+
+// Get the entry with the specified sequence number.
+func (a *Archive) Get(sequence uint64) (*Entry, error) {
+	return a.backend.Get(sequence)
+}
+
+// Range calls fn for every entry in the archive in order until fn returns an error.
+func (a *Archive) Range(fn func(*Entry) error) (err error) {
+	length := a.Len()
+	for seq := uint64(1); seq <= length; seq++ {
+		var entry *Entry
+		if entry, err = a.backend.Get(seq); err != nil {
+			return err
+		}
+
+		if err = fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns all entries that match the filter in order.
+func (a *Archive) Find(filter Filter) (entries []*Entry, err error) {
+	err = a.Range(func(entry *Entry) error {
+		if filter == nil || filter(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Envelope returns all entries for the envelope ID in order.
+func (a *Archive) Envelope(envelopeID string) ([]*Entry, error) {
+	return a.Find(ByEnvelopeID(envelopeID))
+}
+
+// Counterparty returns all entries exchanged with the counterparty in order.
+func (a *Archive) Counterparty(counterparty string) ([]*Entry, error) {
+	return a.Find(ByCounterparty(counterparty))
+}
+
+// Verify the hash chain of the archive, returning a *ChainError if any entry has been
+// modified, removed, or reordered since it was recorded. Because the head is read from
+// the backend when the archive is opened, Verify cannot detect entries that were
+// removed from the end of the archive before it was opened; use VerifyHead with a head
+// that was published or stored outside of the backend to detect truncation.
+func (a *Archive) Verify() error {
+	return a.verify(nil)
+}
+
+// VerifyHead verifies the hash chain of the archive and that it contains the entry
+// with the specified digest, e.g. a head that was previously returned by Head and
+// published or stored externally. Entries appended after the head was published are
+// allowed, but a *ChainError is returned if the archive was truncated before the head.
+func (a *Archive) VerifyHead(head []byte) error {
+	if len(head) == 0 {
+		return ErrNoHead
+	}
+	return a.verify(head)
+}
+
+func (a *Archive) verify(pinned []byte) error {
+	var previous []byte
+	var expected uint64 = 1
+	found := pinned == nil
+
+	err := a.Range(func(entry *Entry) error {
+		switch {
+		case entry.Sequence != expected:
+			return &ChainError{Sequence: expected, Reason: fmt.Sprintf("found sequence %d", entry.Sequence)}
+		case !bytes.Equal(entry.Previous, previous):
+			return &ChainError{Sequence: expected, Reason: "previous digest does not match"}
+		case !bytes.Equal(entry.ComputeDigest(), entry.Digest):
+			return &ChainError{Sequence: expected, Reason: "digest does not match contents"}
+		}
+
+		if !found && bytes.Equal(entry.Digest, pinned) {
+			found = true
+		}
+
+		previous = entry.Digest
+		expected++
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(previous, a.Head()) {
+		return &ChainError{Sequence: expected - 1, Reason: "last digest does not match head"}
+	}
+
+	if !found {
+		return &ChainError{Sequence: expected - 1, Reason: "published head not found, the archive may have been truncated"}
+	}
+	return nil
+}
+
+// ValidateHMAC unseals the envelope of the entry and validates its HMAC signature.
+// Error envelopes without a payload have no HMAC and are always valid.
+func ValidateHMAC(entry *Entry, unsealingKey interface{}) (err error) {
+	var msg *api.SecureEnvelope
+	if msg, err = entry.Envelope(); err != nil {
+		return err
+	}
+
+	if len(msg.Payload) == 0 && msg.Error != nil {
+		return nil
+	}
+
+	var env *envelope.Envelope
+	if env, err = envelope.Wrap(msg); err != nil {
+		return err
+	}
+
+	if env, _, err = env.Unseal(envelope.WithUnsealingKey(unsealingKey)); err != nil {
+		return fmt.Errorf("could not unseal entry %d: %w", entry.Sequence, err)
+	}
+
+	// Create the cipher from the unsealed secrets without decrypting the payload
+	unsealed := env.Proto()
+	if unsealed.HmacAlgorithm != "HMAC-SHA256" {
+		return fmt.Errorf("unsupported digital signature algorithm %q on entry %d", unsealed.HmacAlgorithm, entry.Sequence)
+	}
+
+	if env, err = envelope.Wrap(unsealed, envelope.WithAESGCM(unsealed.EncryptionKey, unsealed.HmacSecret)); err != nil {
+		return err
+	}
+
+	var valid bool
+	if valid, err = env.ValidateHMAC(); err != nil {
+		return fmt.Errorf("could not validate hmac of entry %d: %w", entry.Sequence, err)
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: entry %d", ErrInvalidHMAC, entry.Sequence)
+	}
+	return nil
+}
+
+// KeyFunc returns the unsealing key for an entry, e.g. the private key of the local
+// node for incoming envelopes. Return ErrNoUnsealingKey to skip the entry, e.g. for
+// outgoing envelopes that were sealed with the public key of the counterparty.
+type KeyFunc func(*Entry) (interface{}, error)
+
+// ValidateHMACs validates the HMAC signature of every entry in the archive that has an
+// unsealing key, returning the number of entries that were validated.
+func (a *Archive) ValidateHMACs(keys KeyFunc) (validated int, err error) {
+	err = a.Range(func(entry *Entry) (err error) {
+		var key interface{}
+		if key, err = keys(entry); err != nil {
+			if errors.Is(err, ErrNoUnsealingKey) {
+				return nil
+			}
+			return err
+		}
+
+		if err = ValidateHMAC(entry, key); err != nil {
+			return err
+		}
+		validated++
+		return nil
+	})
+	return validated, err
+}
+
+//===========================================================================
+// Export
+//===========================================================================
+
+// Export is the document written for regulators, which contains the archived secure
+// envelopes in their protocol buffer JSON representation along with the digests of the
+// hash chain so that the export can be independently verified.
+type Export struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Head       []byte         `json:"head"`
+	Entries    []*ExportEntry `json:"entries"`
+}
+
+// ExportEntry is an archive entry with the decoded secure envelope.
+type ExportEntry struct {
+	*Entry
+	Envelope json.RawMessage `json:"envelope"`
+}
+
+// Export writes the entries that match the filter as a JSON document. If the filter is
+// nil all entries are exported.
+func (a *Archive) Export(w io.Writer, filter Filter) (err error) {
+	out := &Export{
+		ExportedAt: a.clock.Now().UTC(),
+		Head:       a.Head(),
+		Entries:    make([]*ExportEntry, 0),
+	}
+
+	var entries []*Entry
+	if entries, err = a.Find(filter); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		var msg *api.SecureEnvelope
+		if msg, err = entry.Envelope(); err != nil {
+			return fmt.Errorf("could not decode entry %d: %w", entry.Sequence, err)
+		}
+
+		item := &ExportEntry{Entry: entry}
+		if item.Envelope, err = protojson.Marshal(msg); err != nil {
+			return err
+		}
+		out.Entries = append(out.Entries, item)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// Filter selects archive entries for lookups and exports.
+type Filter func(*Entry) bool
+
+// ByEnvelopeID selects the entries of a single transfer.
+func ByEnvelopeID(envelopeID string) Filter {
+	return func(e *Entry) bool {
+		return e.EnvelopeID == envelopeID
+	}
+}
+
+// ByCounterparty selects entries exchanged with the counterparty (case insensitive).
+func ByCounterparty(counterparty string) Filter {
+	return func(e *Entry) bool {
+		return strings.EqualFold(e.Counterparty, counterparty)
+	}
+}
+
+// Between selects entries recorded in the half-open interval [start, end).
+func Between(start, end time.Time) Filter {
+	return func(e *Entry) bool {
+		return !e.Timestamp.Before(start) && e.Timestamp.Before(end)
+	}
+}
+
+//===========================================================================
+// Archive Options
+//===========================================================================
+
+// Option configures the archive when it is opened.
+type Option func(a *Archive) error
+
+// Specify the clock used to timestamp entries.
+func WithClock(c clock.Clock) Option {
+	return func(a *Archive) error {
+		a.clock = c
+		return nil
+	}
+}
@@ -0,0 +1,289 @@
+package archive_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/archive"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestArchive(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := clock.NewManual(now)
+
+	arc, err := archive.Open(archive.NewMemoryBackend(), archive.WithClock(clock))
+	require.NoError(t, err)
+	require.Zero(t, arc.Len())
+	require.NoError(t, arc.Verify(), "an empty archive should be valid")
+
+	// Record an exchange with two counterparties
+	first := seal(t, &key.PublicKey)
+	second := seal(t, &key.PublicKey)
+
+	records := []struct {
+		direction    envelope.Direction
+		counterparty string
+		msg          *api.SecureEnvelope
+	}{
+		{envelope.Outgoing, "Bob VASP", first},
+		{envelope.Incoming, "Bob VASP", first},
+		{envelope.Outgoing, "Charlie VASP", second},
+		{envelope.Incoming, "Charlie VASP", reject(t, second.Id)},
+	}
+
+	for i, r := range records {
+		clock.Advance(time.Minute)
+		entry, err := arc.Record(r.direction, r.counterparty, r.msg)
+		require.NoError(t, err)
+		require.Equal(t, uint64(i+1), entry.Sequence)
+		require.Equal(t, now.Add(time.Duration(i+1)*time.Minute), entry.Timestamp)
+		require.Equal(t, arc.Head(), entry.Digest)
+	}
+
+	require.Equal(t, uint64(4), arc.Len())
+	require.NoError(t, arc.Verify())
+
+	t.Run("Lookups", func(t *testing.T) {
+		entries, err := arc.Envelope(first.Id)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		entries, err = arc.Counterparty("charlie vasp")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, second.Id, entries[0].EnvelopeID)
+
+		entries, err = arc.Find(archive.Between(now.Add(2*time.Minute), now.Add(4*time.Minute)))
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, uint64(2), entries[0].Sequence)
+
+		msg, err := entries[0].Envelope()
+		require.NoError(t, err)
+		require.Equal(t, first.Id, msg.Id)
+		require.True(t, msg.Sealed)
+	})
+
+	t.Run("ValidateHMAC", func(t *testing.T) {
+		entry, err := arc.Get(1)
+		require.NoError(t, err)
+		require.NoError(t, archive.ValidateHMAC(entry, key))
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		require.Error(t, archive.ValidateHMAC(entry, other))
+
+		// A forged HMAC signature should be detected
+		msg, err := entry.Envelope()
+		require.NoError(t, err)
+		msg.Hmac[0] ^= 0xff
+		entry.Data, err = proto.Marshal(msg)
+		require.NoError(t, err)
+		require.ErrorIs(t, archive.ValidateHMAC(entry, key), archive.ErrInvalidHMAC)
+
+		// Only validate incoming envelopes that were sealed with the local key
+		validated, err := arc.ValidateHMACs(func(e *archive.Entry) (interface{}, error) {
+			if e.Direction == envelope.Incoming {
+				return key, nil
+			}
+			return nil, archive.ErrNoUnsealingKey
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, validated)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, arc.Export(&buf, archive.ByCounterparty("Bob VASP")))
+
+		export := &archive.Export{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), export))
+		require.Equal(t, arc.Head(), export.Head)
+		require.Len(t, export.Entries, 2)
+		require.Equal(t, envelope.Outgoing, export.Entries[0].Direction)
+		require.Contains(t, string(export.Entries[0].Envelope), first.Id)
+
+		// The digests in the export can be independently verified
+		for _, entry := range export.Entries {
+			require.Equal(t, entry.ComputeDigest(), entry.Digest)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := arc.Record(envelope.Outgoing, "Bob VASP", nil)
+		require.ErrorIs(t, err, archive.ErrNoEnvelope)
+
+		_, err = arc.Record(envelope.Outgoing, "Bob VASP", &api.SecureEnvelope{Id: "foo", Payload: []byte("unsealed")})
+		require.ErrorIs(t, err, archive.ErrNotSealed)
+
+		_, err = arc.Record(0, "Bob VASP", first)
+		require.ErrorIs(t, err, archive.ErrNoDirection)
+		require.Equal(t, uint64(4), arc.Len())
+	})
+}
+
+func TestFileBackend(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	backend, err := archive.OpenFile(path)
+	require.NoError(t, err)
+
+	arc, err := archive.Open(backend)
+	require.NoError(t, err)
+
+	msgs := make([]*api.SecureEnvelope, 0, 3)
+	for i := 0; i < 3; i++ {
+		msg := seal(t, &key.PublicKey)
+		msgs = append(msgs, msg)
+		_, err = arc.Record(envelope.Incoming, "Bob VASP", msg)
+		require.NoError(t, err)
+	}
+
+	head := arc.Head()
+	require.NoError(t, backend.Close())
+
+	// Reopen the archive and continue the chain
+	backend, err = archive.OpenFile(path)
+	require.NoError(t, err)
+	arc, err = archive.Open(backend)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), arc.Len())
+	require.Equal(t, head, arc.Head())
+	require.NoError(t, arc.Verify())
+
+	_, err = arc.Record(envelope.Outgoing, "Bob VASP", msgs[0])
+	require.NoError(t, err)
+	require.NoError(t, arc.Verify())
+
+	// A previously published head is still in the chain after appending entries
+	require.NoError(t, arc.VerifyHead(head))
+	require.ErrorIs(t, arc.VerifyHead(nil), archive.ErrNoHead)
+	published := arc.Head()
+	require.NoError(t, backend.Close())
+
+	t.Run("Tampered", func(t *testing.T) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 4)
+
+		testCases := []struct {
+			name   string
+			modify func(lines []string) []string
+			seq    uint64
+		}{
+			{
+				name: "ModifiedCounterparty",
+				modify: func(lines []string) []string {
+					lines[1] = strings.Replace(lines[1], "Bob VASP", "Eve VASP", 1)
+					return lines
+				},
+				seq: 2,
+			},
+			{
+				name: "RemovedEntry",
+				modify: func(lines []string) []string {
+					return append(lines[:1], lines[2:]...)
+				},
+				seq: 2,
+			},
+			{
+				name: "Reordered",
+				modify: func(lines []string) []string {
+					lines[2], lines[3] = lines[3], lines[2]
+					return lines
+				},
+				seq: 3,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tampered := tc.modify(append([]string(nil), lines...))
+				path := filepath.Join(t.TempDir(), "tampered.jsonl")
+				require.NoError(t, os.WriteFile(path, []byte(strings.Join(tampered, "\n")+"\n"), 0600))
+
+				backend, err := archive.OpenFile(path)
+				require.NoError(t, err)
+				defer backend.Close()
+
+				arc, err := archive.Open(backend)
+				require.NoError(t, err)
+
+				err = arc.Verify()
+				require.ErrorIs(t, err, archive.ErrTampered)
+
+				var chainErr *archive.ChainError
+				require.ErrorAs(t, err, &chainErr)
+				require.Equal(t, tc.seq, chainErr.Sequence)
+			})
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+		path := filepath.Join(t.TempDir(), "truncated.jsonl")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines[:3], "\n")+"\n"), 0600))
+
+		backend, err := archive.OpenFile(path)
+		require.NoError(t, err)
+		defer backend.Close()
+
+		arc, err := archive.Open(backend)
+		require.NoError(t, err)
+
+		// The remaining chain is intact so truncation is only detected with the published head
+		require.NoError(t, arc.Verify())
+		require.NoError(t, arc.VerifyHead(head))
+
+		err = arc.VerifyHead(published)
+		require.ErrorIs(t, err, archive.ErrTampered)
+
+		var chainErr *archive.ChainError
+		require.ErrorAs(t, err, &chainErr)
+		require.Equal(t, uint64(3), chainErr.Sequence)
+	})
+}
+
+func seal(t *testing.T, key *rsa.PublicKey) *api.SecureEnvelope {
+	payload := &api.Payload{SentAt: time.Now().Format(time.RFC3339)}
+
+	var err error
+	payload.Identity, err = anypb.New(&ivms101.IdentityPayload{})
+	require.NoError(t, err)
+	payload.Transaction, err = anypb.New(&generic.Transaction{Txid: "abc123", Amount: 1.5})
+	require.NoError(t, err)
+
+	env, _, err := envelope.Seal(payload, envelope.WithRSAPublicKey(key))
+	require.NoError(t, err)
+	return env.Proto()
+}
+
+func reject(t *testing.T, id string) *api.SecureEnvelope {
+	env, err := envelope.WrapError(api.Errorf(api.ComplianceCheckFail, "rejected"), envelope.WithEnvelopeID(id))
+	require.NoError(t, err)
+	return env.Proto()
+}
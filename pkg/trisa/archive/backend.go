@@ -0,0 +1,166 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Backend stores archive entries in the order they were appended. Backends only need
+// to append and read entries; the archive maintains the hash chain and lookups.
+// Sequence numbers start at 1 and must be contiguous. Implementations must be safe for
+// concurrent use and should return ErrNotFound if an entry does not exist.
+type Backend interface {
+	Append(*Entry) error
+	Get(sequence uint64) (*Entry, error)
+	Len() (uint64, error)
+	Close() error
+}
+
+// MemoryBackend stores entries in memory and is primarily used for testing.
+type MemoryBackend struct {
+	sync.RWMutex
+	entries []*Entry
+}
+
+var _ Backend = &MemoryBackend{}
+
+// NewMemoryBackend returns an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{}
+}
+
+// Append a copy of the entry to the backend.
+func (b *MemoryBackend) Append(e *Entry) error {
+	b.Lock()
+	defer b.Unlock()
+	if e.Sequence != uint64(len(b.entries))+1 {
+		return ErrOutOfSequence
+	}
+	b.entries = append(b.entries, e.clone())
+	return nil
+}
+
+// Get a copy of the entry with the specified sequence number.
+func (b *MemoryBackend) Get(sequence uint64) (*Entry, error) {
+	b.RLock()
+	defer b.RUnlock()
+	if sequence == 0 || sequence > uint64(len(b.entries)) {
+		return nil, ErrNotFound
+	}
+	return b.entries[sequence-1].clone(), nil
+}
+
+// Len returns the number of entries in the backend.
+func (b *MemoryBackend) Len() (uint64, error) {
+	b.RLock()
+	defer b.RUnlock()
+	return uint64(len(b.entries)), nil
+}
+
+// Close is a no-op for the memory backend.
+func (b *MemoryBackend) Close() error {
+	return nil
+}
+
+// FileBackend stores entries as JSON lines in an append-only file on disk. Entries are
+// loaded into memory when the file is opened; every append is synced to disk before
+// it is acknowledged.
+type FileBackend struct {
+	sync.RWMutex
+	file    *os.File
+	entries []*Entry
+}
+
+var _ Backend = &FileBackend{}
+
+// OpenFile opens or creates the archive file at the specified path.
+func OpenFile(path string) (b *FileBackend, err error) {
+	b = &FileBackend{}
+	if b.file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600); err != nil {
+		return nil, err
+	}
+
+	if err = b.load(); err != nil {
+		b.file.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *FileBackend) load() (err error) {
+	if _, err = b.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(b.file)
+	for line := 1; ; line++ {
+		var data []byte
+		if data, err = reader.ReadBytes('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				if len(data) != 0 {
+					return fmt.Errorf("archive file is truncated at line %d", line)
+				}
+				return nil
+			}
+			return err
+		}
+
+		entry := &Entry{}
+		if err = json.Unmarshal(data, entry); err != nil {
+			return fmt.Errorf("could not parse archive entry at line %d: %w", line, err)
+		}
+		b.entries = append(b.entries, entry)
+	}
+}
+
+// Append the entry to the file and sync it to disk.
+func (b *FileBackend) Append(e *Entry) (err error) {
+	b.Lock()
+	defer b.Unlock()
+	if e.Sequence != uint64(len(b.entries))+1 {
+		return ErrOutOfSequence
+	}
+
+	var data []byte
+	if data, err = json.Marshal(e); err != nil {
+		return err
+	}
+
+	if _, err = b.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if err = b.file.Sync(); err != nil {
+		return err
+	}
+
+	b.entries = append(b.entries, e.clone())
+	return nil
+}
+
+// Get a copy of the entry with the specified sequence number.
+func (b *FileBackend) Get(sequence uint64) (*Entry, error) {
+	b.RLock()
+	defer b.RUnlock()
+	if sequence == 0 || sequence > uint64(len(b.entries)) {
+		return nil, ErrNotFound
+	}
+	return b.entries[sequence-1].clone(), nil
+}
+
+// Len returns the number of entries in the file.
+func (b *FileBackend) Len() (uint64, error) {
+	b.RLock()
+	defer b.RUnlock()
+	return uint64(len(b.entries)), nil
+}
+
+// Close the archive file.
+func (b *FileBackend) Close() error {
+	return b.file.Close()
+}
@@ -0,0 +1,64 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+)
+
+// Entry is a secure envelope in the archive. The envelope is stored as the protocol
+// buffer bytes that were recorded so that the digest of the entry does not depend on
+// the serialization of a specific protobuf version. Every entry references the digest
+// of the previous entry, forming a hash chain that makes modification, deletion, or
+// reordering of entries evident.
+type Entry struct {
+	Sequence     uint64             `json:"sequence"`
+	Timestamp    time.Time          `json:"timestamp"`
+	Direction    envelope.Direction `json:"direction"`
+	Counterparty string             `json:"counterparty"`
+	EnvelopeID   string             `json:"envelope_id"`
+	Data         []byte             `json:"data"`
+	Previous     []byte             `json:"previous"`
+	Digest       []byte             `json:"digest"`
+}
+
+// Envelope returns the archived secure envelope.
+func (e *Entry) Envelope() (msg *api.SecureEnvelope, err error) {
+	msg = &api.SecureEnvelope{}
+	if err = proto.Unmarshal(e.Data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// ComputeDigest returns the SHA-256 digest of the fields of the entry, including the
+// digest of the previous entry but excluding its own digest. Variable length fields
+// are length prefixed so that fields cannot be shifted between each other.
+func (e *Entry) ComputeDigest() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, e.Sequence)
+	binary.Write(&buf, binary.BigEndian, e.Timestamp.UnixNano())
+	buf.WriteByte(byte(e.Direction))
+
+	for _, field := range [][]byte{[]byte(e.Counterparty), []byte(e.EnvelopeID), e.Data, e.Previous} {
+		binary.Write(&buf, binary.BigEndian, uint64(len(field)))
+		buf.Write(field)
+	}
+
+	digest := sha256.Sum256(buf.Bytes())
+	return digest[:]
+}
+
+// Returns a copy of the entry that does not share its byte slices.
+func (e *Entry) clone() *Entry {
+	out := *e
+	out.Data = append([]byte(nil), e.Data...)
+	out.Previous = append([]byte(nil), e.Previous...)
+	out.Digest = append([]byte(nil), e.Digest...)
+	return &out
+}
@@ -0,0 +1,34 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound       = errors.New("no archive entry found")
+	ErrNoBackend      = errors.New("a backend is required to open an archive")
+	ErrNoEnvelope     = errors.New("a secure envelope is required to archive")
+	ErrNoEnvelopeID   = errors.New("secure envelope must have an id to archive")
+	ErrNotSealed      = errors.New("only sealed envelopes or error envelopes may be archived")
+	ErrNoDirection    = errors.New("the direction of the envelope is required to archive")
+	ErrOutOfSequence  = errors.New("archive entry is out of sequence")
+	ErrTampered       = errors.New("archive hash chain is broken")
+	ErrInvalidHMAC    = errors.New("secure envelope hmac signature is invalid")
+	ErrNoUnsealingKey = errors.New("no unsealing key available for archive entry")
+	ErrNoHead         = errors.New("a published head digest is required to verify the archive")
+)
+
+// ChainError describes where the hash chain of an archive is broken.
+type ChainError struct {
+	Sequence uint64
+	Reason   string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("%s at entry %d: %s", ErrTampered, e.Sequence, e.Reason)
+}
+
+func (e *ChainError) Unwrap() error {
+	return ErrTampered
+}
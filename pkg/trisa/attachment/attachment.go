@@ -0,0 +1,342 @@
+/*
+Package attachment streams large documents such as KYC document scans alongside a TRISA
+compliance exchange, e.g. when repairing a transfer. Documents are too large to be
+included in a payload, so they are split into segments that are encrypted with the
+STREAM construction (see aesgcm.Stream) and sent as a sequence of secure envelopes over
+the TransferStream RPC. Both the sender and the recipient only hold a single segment in
+memory at a time.
+
+The attachment key is derived from the encryption key of the secure envelope that the
+attachment belongs to and a random salt, so only the counterparties of the exchange can
+decrypt the attachment. The first segment of the stream contains the Attachment
+metadata, including the size and SHA-256 hash of the document, which the recipient
+verifies once the final segment has been received.
+*/
+package attachment
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto/aesgcm"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	HashAlgorithm      = "SHA-256"
+	DefaultSegmentSize = 64 * 1024
+	MaxSegmentSize     = 1024 * 1024
+	saltSize           = 32
+	keyContext         = "trisa attachment v1"
+)
+
+// Sender is implemented by both the client and server of the TransferStream RPC.
+type Sender interface {
+	Send(*api.SecureEnvelope) error
+}
+
+// Receiver is implemented by both the client and server of the TransferStream RPC.
+type Receiver interface {
+	Recv() (*api.SecureEnvelope, error)
+}
+
+// Describe reads the document to compute its size and hash, returning the metadata for
+// a new attachment with a random ID. The envelope ID must be set by the caller before
+// the attachment is sent. Because the document is read in full, the caller must rewind
+// or reopen the reader before sending the attachment.
+func Describe(r io.Reader, filename, mediaType string) (_ *generic.Attachment, err error) {
+	digest := sha256.New()
+
+	var size int64
+	if size, err = io.Copy(digest, r); err != nil {
+		return nil, err
+	}
+
+	return &generic.Attachment{
+		Id:            uuid.NewString(),
+		Filename:      filename,
+		MediaType:     mediaType,
+		Size:          uint64(size),
+		HashAlgorithm: HashAlgorithm,
+		Hash:          digest.Sum(nil),
+		SegmentSize:   DefaultSegmentSize,
+		Created:       time.Now().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// Send the document described by the attachment on the stream. The encryption key is
+// the key of the secure envelope the attachment belongs to, e.g. the EncryptionKey of
+// the unsealed envelope. The document is read one segment at a time and each encrypted
+// segment is sent as a secure envelope with the AES256-GCM-STREAM algorithm.
+func Send(stream Sender, encryptionKey []byte, attachment *generic.Attachment, r io.Reader) (err error) {
+	if err = validate(attachment); err != nil {
+		return err
+	}
+
+	if attachment.SegmentSize == 0 {
+		attachment = proto.Clone(attachment).(*generic.Attachment)
+		attachment.SegmentSize = DefaultSegmentSize
+	}
+
+	var salt []byte
+	if salt, err = crypto.Random(saltSize); err != nil {
+		return err
+	}
+
+	var cipher *aesgcm.Stream
+	if cipher, err = newStream(encryptionKey, attachment.Id, salt); err != nil {
+		return err
+	}
+
+	w := &writer{
+		stream:     stream,
+		cipher:     cipher,
+		envelopeID: attachment.EnvelopeId,
+		ad:         additionalData(attachment.EnvelopeId, attachment.Id),
+		chunk:      &generic.AttachmentChunk{AttachmentId: attachment.Id, Salt: salt},
+	}
+
+	// The first segment contains the attachment metadata
+	var metadata []byte
+	if metadata, err = proto.Marshal(attachment); err != nil {
+		return err
+	}
+
+	if err = w.send(metadata, false); err != nil {
+		return err
+	}
+
+	// Read one segment ahead of the segment being sent so that the final segment can
+	// be marked; an empty document is sent as a single empty final segment.
+	reader := bufio.NewReader(r)
+	segment := make([]byte, attachment.SegmentSize)
+	for {
+		var n int
+		if n, err = io.ReadFull(reader, segment); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("could not read attachment: %w", err)
+		}
+
+		final := err != nil
+		if !final {
+			if _, err = reader.Peek(1); err != nil {
+				if !errors.Is(err, io.EOF) {
+					return fmt.Errorf("could not read attachment: %w", err)
+				}
+				final = true
+			}
+		}
+
+		if err = w.send(segment[:n], final); err != nil {
+			return err
+		}
+
+		if final {
+			return nil
+		}
+	}
+}
+
+// Receive an attachment from the stream, writing the decrypted document to w and
+// returning the attachment metadata once the size and hash of the document have been
+// verified. If an error is returned, the data written to w must be discarded. If the
+// counterparty sends an error envelope instead of an attachment chunk, the *api.Error
+// is returned.
+func Receive(stream Receiver, encryptionKey []byte, w io.Writer) (attachment *generic.Attachment, err error) {
+	var (
+		msg    *api.SecureEnvelope
+		chunk  *generic.AttachmentChunk
+		cipher *aesgcm.Stream
+		ad     []byte
+		digest hash.Hash
+		size   uint64
+	)
+
+	for sequence := uint64(0); ; sequence++ {
+		if msg, chunk, err = recv(stream); err != nil {
+			return nil, err
+		}
+
+		if chunk.Sequence != sequence {
+			return nil, fmt.Errorf("%w: expected segment %d got %d", ErrOutOfSequence, sequence, chunk.Sequence)
+		}
+
+		// The first segment contains the attachment metadata
+		if sequence == 0 {
+			if chunk.Final || len(chunk.Salt) != saltSize {
+				return nil, ErrUnexpectedChunk
+			}
+
+			if cipher, err = newStream(encryptionKey, chunk.AttachmentId, chunk.Salt); err != nil {
+				return nil, err
+			}
+
+			ad = additionalData(msg.Id, chunk.AttachmentId)
+			var metadata []byte
+			if metadata, err = cipher.Open(chunk.Data, ad, false); err != nil {
+				return nil, err
+			}
+
+			attachment = &generic.Attachment{}
+			if err = proto.Unmarshal(metadata, attachment); err != nil {
+				return nil, fmt.Errorf("could not parse attachment metadata: %w", err)
+			}
+
+			switch {
+			case attachment.Id != chunk.AttachmentId || attachment.EnvelopeId != msg.Id:
+				return nil, ErrUnexpectedChunk
+			case attachment.SegmentSize == 0 || attachment.SegmentSize > MaxSegmentSize:
+				return nil, ErrSegmentSize
+			case attachment.HashAlgorithm != HashAlgorithm:
+				return nil, fmt.Errorf("%w %q", ErrUnsupportedHash, attachment.HashAlgorithm)
+			}
+
+			digest = sha256.New()
+			continue
+		}
+
+		if chunk.AttachmentId != attachment.Id || msg.Id != attachment.EnvelopeId {
+			return nil, ErrUnexpectedChunk
+		}
+
+		if len(chunk.Data) > int(attachment.SegmentSize)+cipher.Overhead() {
+			return nil, ErrSegmentSize
+		}
+
+		var segment []byte
+		if segment, err = cipher.Open(chunk.Data, ad, chunk.Final); err != nil {
+			return nil, err
+		}
+
+		if size += uint64(len(segment)); size > attachment.Size {
+			return nil, ErrSizeMismatch
+		}
+
+		digest.Write(segment)
+		if _, err = w.Write(segment); err != nil {
+			return nil, err
+		}
+
+		if chunk.Final {
+			break
+		}
+	}
+
+	if size != attachment.Size {
+		return nil, ErrSizeMismatch
+	}
+
+	if !bytes.Equal(digest.Sum(nil), attachment.Hash) {
+		return nil, ErrHashMismatch
+	}
+	return attachment, nil
+}
+
+//===========================================================================
+// Helpers
+//===========================================================================
+
+type writer struct {
+	stream     Sender
+	cipher     *aesgcm.Stream
+	envelopeID string
+	ad         []byte
+	chunk      *generic.AttachmentChunk
+}
+
+// Encrypt the segment and send it as the next chunk on the stream.
+func (w *writer) send(segment []byte, final bool) (err error) {
+	if w.chunk.Data, err = w.cipher.Seal(segment, w.ad, final); err != nil {
+		return err
+	}
+	w.chunk.Final = final
+
+	var payload []byte
+	if payload, err = proto.Marshal(w.chunk); err != nil {
+		return err
+	}
+
+	msg := &api.SecureEnvelope{
+		Id:                  w.envelopeID,
+		Payload:             payload,
+		EncryptionAlgorithm: aesgcm.StreamAlgorithm,
+		Timestamp:           time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	if err = w.stream.Send(msg); err != nil {
+		return err
+	}
+
+	// The salt is only sent with the first segment
+	w.chunk.Sequence++
+	w.chunk.Salt = nil
+	return nil
+}
+
+// Receives the next secure envelope and parses the attachment chunk.
+func recv(stream Receiver) (msg *api.SecureEnvelope, chunk *generic.AttachmentChunk, err error) {
+	if msg, err = stream.Recv(); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, ErrStreamIncomplete
+		}
+		return nil, nil, err
+	}
+
+	if msg.Error != nil && !msg.Error.IsZero() {
+		return nil, nil, msg.Error
+	}
+
+	if msg.EncryptionAlgorithm != aesgcm.StreamAlgorithm {
+		return nil, nil, ErrNotAttachment
+	}
+
+	chunk = &generic.AttachmentChunk{}
+	if err = proto.Unmarshal(msg.Payload, chunk); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotAttachment, err)
+	}
+	return msg, chunk, nil
+}
+
+func validate(attachment *generic.Attachment) error {
+	switch {
+	case attachment.Id == "":
+		return ErrNoAttachmentID
+	case attachment.EnvelopeId == "":
+		return ErrNoEnvelopeID
+	case len(attachment.Hash) == 0:
+		return ErrNoHash
+	case attachment.HashAlgorithm != HashAlgorithm:
+		return fmt.Errorf("%w %q", ErrUnsupportedHash, attachment.HashAlgorithm)
+	case attachment.SegmentSize > MaxSegmentSize:
+		return ErrSegmentSize
+	}
+	return nil
+}
+
+// Derives a key that is unique to the attachment from the envelope encryption key.
+func newStream(encryptionKey []byte, attachmentID string, salt []byte) (*aesgcm.Stream, error) {
+	if len(encryptionKey) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	mac := hmac.New(sha256.New, encryptionKey)
+	mac.Write([]byte(keyContext))
+	mac.Write([]byte(attachmentID))
+	mac.Write(salt)
+	return aesgcm.NewStream(mac.Sum(nil), nil)
+}
+
+// Binds every segment to the envelope and the attachment.
+func additionalData(envelopeID, attachmentID string) []byte {
+	return []byte(envelopeID + "/" + attachmentID)
+}
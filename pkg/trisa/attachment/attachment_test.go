@@ -0,0 +1,202 @@
+package attachment_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1/mock"
+	"github.com/trisacrypto/trisa/pkg/trisa/attachment"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTransferStream(t *testing.T) {
+	key, err := crypto.Random(32)
+	require.NoError(t, err)
+
+	// A document that spans several segments
+	document := make([]byte, 3*attachment.DefaultSegmentSize+1024)
+	_, err = rand.Read(document)
+	require.NoError(t, err)
+
+	meta, err := attachment.Describe(bytes.NewReader(document), "passport.pdf", "application/pdf")
+	require.NoError(t, err)
+	meta.EnvelopeId = uuid.NewString()
+
+	// The remote peer receives the attachment and replies with the metadata
+	var received bytes.Buffer
+	remote := mock.New(nil)
+	defer remote.Shutdown()
+	remote.OnTransferStream = func(stream api.TRISANetwork_TransferStreamServer) error {
+		out, err := attachment.Receive(stream, key, &received)
+		if err != nil {
+			return err
+		}
+
+		reply := &api.SecureEnvelope{Id: out.EnvelopeId}
+		reply.Payload, _ = proto.Marshal(out)
+		return stream.Send(reply)
+	}
+
+	cc, err := remote.Channel().Connect(context.Background(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	stream, err := api.NewTRISANetworkClient(cc).TransferStream(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, attachment.Send(stream, key, meta, bytes.NewReader(document)))
+	require.NoError(t, stream.CloseSend())
+
+	reply, err := stream.Recv()
+	require.NoError(t, err)
+
+	out := &generic.Attachment{}
+	require.NoError(t, proto.Unmarshal(reply.Payload, out))
+	require.True(t, proto.Equal(meta, out))
+	require.Equal(t, document, received.Bytes())
+}
+
+func TestSendReceive(t *testing.T) {
+	key, err := crypto.Random(32)
+	require.NoError(t, err)
+
+	send := func(t *testing.T, document []byte, segmentSize uint32) *pipe {
+		meta, err := attachment.Describe(bytes.NewReader(document), "kyc.txt", "text/plain")
+		require.NoError(t, err)
+		meta.EnvelopeId = uuid.NewString()
+		meta.SegmentSize = segmentSize
+
+		p := &pipe{}
+		require.NoError(t, attachment.Send(p, key, meta, bytes.NewReader(document)))
+		return p
+	}
+
+	t.Run("Sizes", func(t *testing.T) {
+		tests := []struct {
+			size     int
+			segments int
+		}{
+			{0, 1}, {1, 1}, {16, 1}, {17, 2}, {32, 2}, {100, 7},
+		}
+
+		for _, tc := range tests {
+			document := bytes.Repeat([]byte("x"), tc.size)
+			p := send(t, document, 16)
+			require.Len(t, p.msgs, tc.segments+1, "unexpected number of chunks for %d bytes", tc.size)
+
+			var buf bytes.Buffer
+			meta, err := attachment.Receive(p, key, &buf)
+			require.NoError(t, err)
+			require.Equal(t, uint64(tc.size), meta.Size)
+			require.Equal(t, string(document), buf.String())
+		}
+	})
+
+	document := []byte("a document that will be split into several segments")
+
+	t.Run("WrongKey", func(t *testing.T) {
+		other, err := crypto.Random(32)
+		require.NoError(t, err)
+
+		_, err = attachment.Receive(send(t, document, 16), other, io.Discard)
+		require.Error(t, err)
+	})
+
+	t.Run("Reordered", func(t *testing.T) {
+		p := send(t, document, 16)
+		p.msgs[1], p.msgs[2] = p.msgs[2], p.msgs[1]
+		_, err := attachment.Receive(p, key, io.Discard)
+		require.ErrorIs(t, err, attachment.ErrOutOfSequence)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		p := send(t, document, 16)
+		p.msgs = p.msgs[:len(p.msgs)-1]
+		_, err := attachment.Receive(p, key, io.Discard)
+		require.ErrorIs(t, err, attachment.ErrStreamIncomplete)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		p := send(t, document, 16)
+		chunk := &generic.AttachmentChunk{}
+		require.NoError(t, proto.Unmarshal(p.msgs[1].Payload, chunk))
+		chunk.Data[0] ^= 0xff
+		p.msgs[1].Payload, _ = proto.Marshal(chunk)
+
+		_, err := attachment.Receive(p, key, io.Discard)
+		require.Error(t, err)
+	})
+
+	t.Run("HashMismatch", func(t *testing.T) {
+		meta, err := attachment.Describe(bytes.NewReader(document), "kyc.txt", "text/plain")
+		require.NoError(t, err)
+		meta.EnvelopeId = uuid.NewString()
+
+		// The sender streams a different document than the one that was described
+		p := &pipe{}
+		forged := bytes.ToUpper(document)
+		require.NoError(t, attachment.Send(p, key, meta, bytes.NewReader(forged)))
+
+		_, err = attachment.Receive(p, key, io.Discard)
+		require.ErrorIs(t, err, attachment.ErrHashMismatch)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		p := &pipe{msgs: []*api.SecureEnvelope{
+			{Id: uuid.NewString(), Error: &api.Error{Code: api.Unavailable, Message: "attachments are not supported"}},
+		}}
+
+		_, err := attachment.Receive(p, key, io.Discard)
+		var reject *api.Error
+		require.ErrorAs(t, err, &reject)
+		require.Equal(t, api.Unavailable, reject.Code)
+
+		p = &pipe{msgs: []*api.SecureEnvelope{{Id: uuid.NewString(), Payload: []byte("foo"), EncryptionAlgorithm: "AES256-GCM"}}}
+		_, err = attachment.Receive(p, key, io.Discard)
+		require.ErrorIs(t, err, attachment.ErrNotAttachment)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		meta := &generic.Attachment{}
+		require.ErrorIs(t, attachment.Send(&pipe{}, key, meta, nil), attachment.ErrNoAttachmentID)
+
+		meta.Id = uuid.NewString()
+		require.ErrorIs(t, attachment.Send(&pipe{}, key, meta, nil), attachment.ErrNoEnvelopeID)
+
+		meta.EnvelopeId = uuid.NewString()
+		require.ErrorIs(t, attachment.Send(&pipe{}, key, meta, nil), attachment.ErrNoHash)
+
+		meta.Hash = []byte("foo")
+		meta.HashAlgorithm = attachment.HashAlgorithm
+		require.ErrorIs(t, attachment.Send(&pipe{}, key[:16], meta, nil), attachment.ErrInvalidKey)
+	})
+}
+
+// pipe buffers the secure envelopes sent on a stream so they can be modified before
+// they are received.
+type pipe struct {
+	msgs []*api.SecureEnvelope
+}
+
+func (p *pipe) Send(msg *api.SecureEnvelope) error {
+	p.msgs = append(p.msgs, proto.Clone(msg).(*api.SecureEnvelope))
+	return nil
+}
+
+func (p *pipe) Recv() (msg *api.SecureEnvelope, err error) {
+	if len(p.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg, p.msgs = p.msgs[0], p.msgs[1:]
+	return msg, nil
+}
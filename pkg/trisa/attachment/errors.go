@@ -0,0 +1,18 @@
+package attachment
+
+import "errors"
+
+var (
+	ErrNoAttachmentID   = errors.New("attachment must have an id")
+	ErrNoEnvelopeID     = errors.New("attachment must reference the envelope id of the exchange")
+	ErrNoHash           = errors.New("attachment must have a hash of the document, use Describe to compute it")
+	ErrInvalidKey       = errors.New("a 32 byte envelope encryption key is required to stream attachments")
+	ErrSegmentSize      = errors.New("attachment segment size is invalid")
+	ErrNotAttachment    = errors.New("secure envelope does not contain an attachment chunk")
+	ErrOutOfSequence    = errors.New("attachment chunk received out of sequence")
+	ErrUnexpectedChunk  = errors.New("attachment chunk does not belong to the attachment stream")
+	ErrSizeMismatch     = errors.New("attachment size does not match the received document")
+	ErrHashMismatch     = errors.New("attachment hash does not match the received document")
+	ErrUnsupportedHash  = errors.New("unsupported attachment hash algorithm")
+	ErrStreamIncomplete = errors.New("attachment stream closed before the final segment")
+)
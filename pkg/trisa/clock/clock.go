@@ -0,0 +1,124 @@
+/*
+Package clock provides the time source for components that schedule deadlines or
+timestamp messages so that tests can control the passage of time with a Manual clock
+instead of sleeping.
+*/
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and timers so that tests can control the passage of
+// time with a Manual clock.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a scheduled function call that can be stopped before it fires.
+type Timer interface {
+	Stop() bool
+}
+
+// System uses the time package and is used by default.
+type System struct{}
+
+var _ Clock = System{}
+
+// Now returns the current local time.
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc calls f in its own goroutine after the duration elapses.
+func (System) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Manual only advances when Advance or Set is called, firing any timers that are
+// due synchronously in the order of their deadlines. It is safe for concurrent use.
+type Manual struct {
+	sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+var _ Clock = &Manual{}
+
+// NewManual returns a manual clock set to the specified time.
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *Manual) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// AfterFunc calls f when the clock is advanced past the duration.
+func (c *Manual) AfterFunc(d time.Duration, f func()) Timer {
+	c.Lock()
+	defer c.Unlock()
+	timer := &manualTimer{clock: c, deadline: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance the clock by the duration, firing all timers that are due.
+func (c *Manual) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set the clock to the specified time, firing all timers that are due. Timers are
+// called without the clock locked so that they may schedule additional timers.
+func (c *Manual) Set(now time.Time) {
+	c.Lock()
+	c.now = now
+
+	var due []*manualTimer
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if !timer.deadline.After(now) {
+			due = append(due, timer)
+		} else {
+			pending = append(pending, timer)
+		}
+	}
+	c.timers = pending
+	c.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].deadline.Before(due[j].deadline) })
+	for _, timer := range due {
+		timer.f()
+	}
+}
+
+// Timers returns the number of timers that have not fired or been stopped.
+func (c *Manual) Timers() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.timers)
+}
+
+type manualTimer struct {
+	clock    *Manual
+	deadline time.Time
+	f        func()
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
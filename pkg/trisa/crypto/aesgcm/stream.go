@@ -0,0 +1,111 @@
+package aesgcm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto"
+)
+
+const (
+	StreamAlgorithm       = "AES256-GCM-STREAM"
+	StreamNoncePrefixSize = 7
+)
+
+// Stream implements the STREAM online authenticated encryption construction of Hoang,
+// Reyhanitabar, Rogaway, and Vizár using AES-GCM, which allows large messages to be
+// encrypted and decrypted in segments with bounded memory. The nonce of each segment
+// is composed of a 7 byte prefix, a 4 byte big endian segment counter, and a 1 byte
+// flag that is set on the final segment. Because the counter and final flag are
+// implicit, segments that are reordered, dropped, or truncated from the end of the
+// stream will fail to authenticate. A Stream must be used either to seal or to open a
+// single sequence of segments and must not be reused.
+type Stream struct {
+	aead    cipher.AEAD
+	nonce   []byte
+	counter uint32
+	done    bool
+}
+
+// NewStream creates a STREAM cipher with the 32 byte encryption key and the nonce
+// prefix. The prefix may be nil if the key is unique to the stream (e.g. it is derived
+// from a random salt), otherwise a random prefix must be used for every stream.
+func NewStream(key, prefix []byte) (_ *Stream, err error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("stream requires a 32 byte key, not %d bytes", len(key))
+	}
+
+	if len(prefix) != 0 && len(prefix) != StreamNoncePrefixSize {
+		return nil, fmt.Errorf("stream nonce prefix must be %d bytes", StreamNoncePrefixSize)
+	}
+
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+
+	s := &Stream{nonce: make([]byte, 12)}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+
+	copy(s.nonce, prefix)
+	return s, nil
+}
+
+// Seal the next segment of the stream, authenticating the additional data. The final
+// segment must be sealed with final set to true; no segments can be sealed after it.
+func (s *Stream) Seal(plaintext, additionalData []byte, final bool) (ciphertext []byte, err error) {
+	var nonce []byte
+	if nonce, err = s.next(final); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nil, nonce, plaintext, additionalData), nil
+}
+
+// Open the next segment of the stream. The caller must indicate if the segment is
+// expected to be the final segment; if it is not, authentication will fail.
+func (s *Stream) Open(ciphertext, additionalData []byte, final bool) (plaintext []byte, err error) {
+	var nonce []byte
+	if nonce, err = s.next(final); err != nil {
+		return nil, err
+	}
+
+	if plaintext, err = s.aead.Open(nil, nonce, ciphertext, additionalData); err != nil {
+		return nil, fmt.Errorf("could not decrypt stream segment %d: %w", s.counter-1, err)
+	}
+	return plaintext, nil
+}
+
+// Overhead returns the number of bytes added to each sealed segment.
+func (s *Stream) Overhead() int {
+	return s.aead.Overhead()
+}
+
+// Finalized returns true once the final segment has been sealed or opened.
+func (s *Stream) Finalized() bool {
+	return s.done
+}
+
+// Returns the nonce for the next segment and increments the counter.
+func (s *Stream) next(final bool) ([]byte, error) {
+	if s.done {
+		return nil, crypto.ErrStreamFinalized
+	}
+
+	if s.counter == math.MaxUint32 {
+		return nil, crypto.ErrStreamOverflow
+	}
+
+	binary.BigEndian.PutUint32(s.nonce[StreamNoncePrefixSize:], s.counter)
+	if final {
+		s.nonce[11] = 1
+		s.done = true
+	}
+
+	s.counter++
+	return s.nonce, nil
+}
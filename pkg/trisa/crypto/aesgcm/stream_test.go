@@ -0,0 +1,80 @@
+package aesgcm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto/aesgcm"
+)
+
+func TestStream(t *testing.T) {
+	key, err := crypto.Random(32)
+	require.NoError(t, err)
+
+	ad := []byte("attachment")
+	segments := [][]byte{[]byte("the eagle"), []byte("flies at"), []byte("midnight")}
+
+	sealer, err := aesgcm.NewStream(key, nil)
+	require.NoError(t, err)
+
+	ciphertexts := make([][]byte, 0, len(segments))
+	for i, segment := range segments {
+		ct, err := sealer.Seal(segment, ad, i == len(segments)-1)
+		require.NoError(t, err)
+		require.Len(t, ct, len(segment)+sealer.Overhead())
+		ciphertexts = append(ciphertexts, ct)
+	}
+
+	require.True(t, sealer.Finalized())
+	_, err = sealer.Seal([]byte("more"), ad, false)
+	require.ErrorIs(t, err, crypto.ErrStreamFinalized)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		opener, err := aesgcm.NewStream(key, nil)
+		require.NoError(t, err)
+
+		for i, ct := range ciphertexts {
+			pt, err := opener.Open(ct, ad, i == len(ciphertexts)-1)
+			require.NoError(t, err)
+			require.Equal(t, segments[i], pt)
+		}
+		require.True(t, opener.Finalized())
+	})
+
+	t.Run("Reordered", func(t *testing.T) {
+		opener, err := aesgcm.NewStream(key, nil)
+		require.NoError(t, err)
+
+		_, err = opener.Open(ciphertexts[1], ad, false)
+		require.Error(t, err)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		opener, err := aesgcm.NewStream(key, nil)
+		require.NoError(t, err)
+
+		_, err = opener.Open(ciphertexts[0], ad, false)
+		require.NoError(t, err)
+
+		// The second segment cannot be passed off as the final segment
+		_, err = opener.Open(ciphertexts[1], ad, true)
+		require.Error(t, err)
+	})
+
+	t.Run("AdditionalData", func(t *testing.T) {
+		opener, err := aesgcm.NewStream(key, nil)
+		require.NoError(t, err)
+
+		_, err = opener.Open(ciphertexts[0], []byte("other"), false)
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := aesgcm.NewStream(key[:16], nil)
+		require.Error(t, err)
+
+		_, err = aesgcm.NewStream(key, []byte("short"))
+		require.Error(t, err)
+	})
+}
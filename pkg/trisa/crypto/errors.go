@@ -7,4 +7,6 @@ var (
 	ErrMissingCiphertext     = errors.New("empty cipher text")
 	ErrHMACSignatureMismatch = errors.New("hmac signature mismatch")
 	ErrPrivateKeyRequired    = errors.New("private key required for decryption")
+	ErrStreamFinalized       = errors.New("stream has already processed the final segment")
+	ErrStreamOverflow        = errors.New("stream segment counter overflow")
 )
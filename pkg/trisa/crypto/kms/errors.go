@@ -0,0 +1,27 @@
+package kms
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidKeyFile  = errors.New("local kms key file must contain a 32 byte AES-256 key")
+	ErrInvalidWrapped  = errors.New("wrapped key material is malformed or was wrapped by a different key")
+	ErrMissingVault    = errors.New("vault address and transit key name are required")
+	ErrMissingVaultKey = errors.New("vault transit did not return key material")
+)
+
+// VaultError is returned when the Vault server responds with a non-200 status code.
+type VaultError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *VaultError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("vault returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("vault returned status %d: %s", e.StatusCode, strings.Join(e.Errors, ", "))
+}
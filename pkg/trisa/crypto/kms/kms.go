@@ -0,0 +1,97 @@
+/*
+Package kms implements a crypto.Cipher that seals secure envelopes by wrapping the
+envelope's encryption key and hmac secret with a key management service (KMS) rather
+than with a counterparty's RSA public key. This is primarily intended for long-term
+compliance storage of secure envelopes: archived envelopes are re-sealed with a KMS key
+so that they can be opened for as long as the KMS key exists, independent of the
+lifetime of the TRISA identity certificates used to exchange the envelope.
+
+The KMS is abstracted by the KeyWrapper interface so that different backends (e.g. AWS
+KMS, GCP KMS, or HashiCorp Vault) can be used. This package provides a Vault Transit
+client and a local file-based wrapper that is useful for development and testing.
+
+	wrapper, _ := kms.NewLocal("archive.key")
+	cipher := kms.New(wrapper)
+	sealed, _, _ := envelope.Seal(payload, envelope.WithSeal(cipher))
+*/
+package kms
+
+import (
+	"context"
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto"
+)
+
+// DefaultTimeout is the amount of time the cipher waits for the KMS to wrap or unwrap
+// a key before cancelling the request.
+const DefaultTimeout = 30 * time.Second
+
+// KeyWrapper is the interface to a key management service that can encrypt and decrypt
+// small amounts of key material with a key that never leaves the service.
+type KeyWrapper interface {
+	// Wrap encrypts the plaintext key material with the KMS key.
+	Wrap(ctx context.Context, plaintext []byte) (ciphertext []byte, err error)
+
+	// Unwrap decrypts key material that was previously wrapped with the KMS key.
+	Unwrap(ctx context.Context, ciphertext []byte) (plaintext []byte, err error)
+
+	// KeyID returns an identifier of the KMS key used to wrap key material.
+	KeyID() string
+
+	// Algorithm returns the name of the wrapping algorithm used by the KMS.
+	Algorithm() string
+}
+
+// Cipher implements the crypto.Cipher interface by delegating encryption and decryption
+// to a KeyWrapper so that it can be used to seal and unseal envelopes.
+type Cipher struct {
+	wrapper KeyWrapper
+	timeout time.Duration
+}
+
+// Ensure the Cipher implements the crypto interfaces required to seal envelopes.
+var (
+	_ crypto.Cipher        = &Cipher{}
+	_ crypto.KeyIdentifier = &Cipher{}
+)
+
+// New creates a KMS Cipher that wraps keys with the specified KeyWrapper.
+func New(wrapper KeyWrapper) *Cipher {
+	return &Cipher{wrapper: wrapper, timeout: DefaultTimeout}
+}
+
+// WithTimeout returns the cipher after setting the timeout for KMS requests.
+func (c *Cipher) WithTimeout(timeout time.Duration) *Cipher {
+	c.timeout = timeout
+	return c
+}
+
+// Encrypt the plaintext by wrapping it with the KMS key.
+func (c *Cipher) Encrypt(plaintext []byte) (ciphertext []byte, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.wrapper.Wrap(ctx, plaintext)
+}
+
+// Decrypt the ciphertext by unwrapping it with the KMS key.
+func (c *Cipher) Decrypt(ciphertext []byte) (plaintext []byte, err error) {
+	if len(ciphertext) == 0 {
+		return nil, crypto.ErrMissingCiphertext
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	return c.wrapper.Unwrap(ctx, ciphertext)
+}
+
+// EncryptionAlgorithm returns the wrapping algorithm of the KMS.
+func (c *Cipher) EncryptionAlgorithm() string {
+	return c.wrapper.Algorithm()
+}
+
+// PublicKeySignature implements crypto.KeyIdentifier so that the KMS key ID is stored
+// on sealed envelopes, identifying which KMS key is required to unseal the envelope.
+func (c *Cipher) PublicKeySignature() (string, error) {
+	return c.wrapper.KeyID(), nil
+}
@@ -0,0 +1,190 @@
+package kms_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto/kms"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestLocal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.key")
+
+	// A key should be created if it does not exist
+	wrapper, err := kms.NewLocal(path)
+	require.NoError(t, err, "could not create local kms key")
+	require.FileExists(t, path)
+	require.True(t, strings.HasPrefix(wrapper.KeyID(), "local:"))
+
+	// Loading the key again should result in the same key
+	other, err := kms.NewLocal(path)
+	require.NoError(t, err, "could not load local kms key")
+	require.Equal(t, wrapper.KeyID(), other.KeyID())
+
+	cipher := kms.New(wrapper)
+	require.Equal(t, kms.LocalAlgorithm, cipher.EncryptionAlgorithm())
+
+	ciphertext, err := cipher.Encrypt([]byte("super secret key"))
+	require.NoError(t, err, "could not wrap key")
+
+	plaintext, err := kms.New(other).Decrypt(ciphertext)
+	require.NoError(t, err, "could not unwrap key")
+	require.Equal(t, []byte("super secret key"), plaintext)
+
+	// A different key should not be able to unwrap the key material
+	diff, err := kms.NewLocal(filepath.Join(t.TempDir(), "other.key"))
+	require.NoError(t, err, "could not create local kms key")
+	_, err = kms.New(diff).Decrypt(ciphertext)
+	require.ErrorIs(t, err, kms.ErrInvalidWrapped)
+
+	// Invalid key files should not be loaded
+	badpath := filepath.Join(t.TempDir(), "bad.key")
+	require.NoError(t, os.WriteFile(badpath, []byte("not a key"), 0600))
+	_, err = kms.NewLocal(badpath)
+	require.ErrorIs(t, err, kms.ErrInvalidKeyFile)
+}
+
+func TestVault(t *testing.T) {
+	srv := httptest.NewServer(&transit{token: "s.testing", keyName: "archive"})
+	defer srv.Close()
+
+	wrapper, err := kms.NewVault(srv.URL, "archive", kms.WithVaultToken("s.testing"))
+	require.NoError(t, err, "could not create vault kms")
+	require.Equal(t, "vault:transit/archive", wrapper.KeyID())
+
+	cipher := kms.New(wrapper)
+	require.Equal(t, kms.VaultAlgorithm, cipher.EncryptionAlgorithm())
+
+	ciphertext, err := cipher.Encrypt([]byte("super secret key"))
+	require.NoError(t, err, "could not wrap key")
+	require.True(t, strings.HasPrefix(string(ciphertext), "vault:v1:"))
+
+	plaintext, err := cipher.Decrypt(ciphertext)
+	require.NoError(t, err, "could not unwrap key")
+	require.Equal(t, []byte("super secret key"), plaintext)
+
+	// Non-vault ciphertext should not be sent to the server
+	_, err = cipher.Decrypt([]byte("foo"))
+	require.ErrorIs(t, err, kms.ErrInvalidWrapped)
+
+	// Vault errors should be returned
+	wrapper, err = kms.NewVault(srv.URL, "archive", kms.WithVaultToken("s.wrong"))
+	require.NoError(t, err, "could not create vault kms")
+
+	_, err = kms.New(wrapper).Encrypt([]byte("super secret key"))
+	var verr *kms.VaultError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, http.StatusForbidden, verr.StatusCode)
+	require.Equal(t, []string{"permission denied"}, verr.Errors)
+
+	// A path prefix in the vault address should be preserved
+	mux := http.NewServeMux()
+	mux.Handle("/vault/", http.StripPrefix("/vault", &transit{token: "s.testing", keyName: "archive"}))
+	proxy := httptest.NewServer(mux)
+	defer proxy.Close()
+
+	wrapper, err = kms.NewVault(proxy.URL+"/vault", "archive", kms.WithVaultToken("s.testing"))
+	require.NoError(t, err, "could not create vault kms")
+
+	ciphertext, err = kms.New(wrapper).Encrypt([]byte("super secret key"))
+	require.NoError(t, err, "could not wrap key behind proxy")
+	require.True(t, strings.HasPrefix(string(ciphertext), "vault:v1:"))
+
+	_, err = kms.NewVault("", "archive")
+	require.ErrorIs(t, err, kms.ErrMissingVault)
+}
+
+func TestSealEnvelope(t *testing.T) {
+	wrapper, err := kms.NewLocal(filepath.Join(t.TempDir(), "archive.key"))
+	require.NoError(t, err, "could not create local kms key")
+
+	payload, err := makePayload()
+	require.NoError(t, err, "could not create payload")
+
+	sealed, reject, err := envelope.Seal(payload, envelope.WithSeal(kms.New(wrapper)))
+	require.NoError(t, err, "could not seal envelope with kms")
+	require.Nil(t, reject)
+	require.Equal(t, wrapper.KeyID(), sealed.Proto().PublicKeySignature)
+
+	// The envelope should be opened with only the kms
+	opened, reject, err := envelope.Open(sealed.Proto(), envelope.WithSeal(kms.New(wrapper)))
+	require.NoError(t, err, "could not open envelope with kms")
+	require.Nil(t, reject)
+
+	actual, err := opened.Payload()
+	require.NoError(t, err, "could not get opened payload")
+	require.True(t, proto.Equal(payload, actual), "opened payload does not match")
+}
+
+func makePayload() (_ *api.Payload, err error) {
+	payload := &api.Payload{SentAt: time.Now().Format(time.RFC3339)}
+	if payload.Identity, err = anypb.New(&ivms101.IdentityPayload{}); err != nil {
+		return nil, err
+	}
+
+	if payload.Transaction, err = anypb.New(&generic.Transaction{Txid: "1234", Amount: 42.1}); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// transit is a stand-in for the Vault Transit secrets engine that "encrypts" data by
+// reversing the base64 encoded plaintext.
+type transit struct {
+	token   string
+	keyName string
+}
+
+func (s *transit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Header.Get("X-Vault-Token") != s.token {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+
+	in := make(map[string]string)
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	out := make(map[string]string)
+	switch r.URL.Path {
+	case "/v1/transit/encrypt/" + s.keyName:
+		if _, err := base64.StdEncoding.DecodeString(in["plaintext"]); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		out["ciphertext"] = "vault:v1:" + reverse(in["plaintext"])
+	case "/v1/transit/decrypt/" + s.keyName:
+		out["plaintext"] = reverse(strings.TrimPrefix(in["ciphertext"], "vault:v1:"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": out})
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
@@ -0,0 +1,119 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/trisacrypto/trisa/pkg/trisa/crypto"
+)
+
+const (
+	LocalAlgorithm = "LOCAL-AES256-GCM"
+	localKeySize   = 32
+	localNonceSize = 12
+)
+
+// Local is a KeyWrapper that stores a 32 byte AES-256 key encoded as hex in a file on
+// disk and wraps key material with AES-GCM. It is intended as a stand-in for a real
+// KMS in development and tests and should not be used to protect production archives.
+type Local struct {
+	key   []byte
+	keyID string
+}
+
+// Ensure Local implements the KeyWrapper interface.
+var _ KeyWrapper = &Local{}
+
+// NewLocal loads the AES-256 key from the file at path. If the file does not exist, a
+// new random key is generated and written to the path with user-only permissions.
+func NewLocal(path string) (_ *Local, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		var key []byte
+		if key, err = crypto.Random(localKeySize); err != nil {
+			return nil, err
+		}
+
+		if err = os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600); err != nil {
+			return nil, err
+		}
+		return NewLocalKey(key)
+	}
+
+	var key []byte
+	if key, err = hex.DecodeString(string(bytes.TrimSpace(data))); err != nil {
+		return nil, ErrInvalidKeyFile
+	}
+	return NewLocalKey(key)
+}
+
+// NewLocalKey creates a Local KeyWrapper directly from a 32 byte AES-256 key.
+func NewLocalKey(key []byte) (_ *Local, err error) {
+	if len(key) != localKeySize {
+		return nil, ErrInvalidKeyFile
+	}
+
+	// The key ID is derived from the key so that it does not depend on the file path
+	sum := sha256.Sum256(key)
+	return &Local{key: key, keyID: "local:" + hex.EncodeToString(sum[:8])}, nil
+}
+
+// Wrap encrypts the plaintext with AES-GCM, prepending the random nonce.
+func (l *Local) Wrap(_ context.Context, plaintext []byte) (_ []byte, err error) {
+	var aead cipher.AEAD
+	if aead, err = l.aead(); err != nil {
+		return nil, err
+	}
+
+	var nonce []byte
+	if nonce, err = crypto.Random(localNonceSize); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(l.keyID)), nil
+}
+
+// Unwrap decrypts ciphertext that was wrapped by this key.
+func (l *Local) Unwrap(_ context.Context, ciphertext []byte) (plaintext []byte, err error) {
+	if len(ciphertext) <= localNonceSize {
+		return nil, ErrInvalidWrapped
+	}
+
+	var aead cipher.AEAD
+	if aead, err = l.aead(); err != nil {
+		return nil, err
+	}
+
+	if plaintext, err = aead.Open(nil, ciphertext[:localNonceSize], ciphertext[localNonceSize:], []byte(l.keyID)); err != nil {
+		return nil, ErrInvalidWrapped
+	}
+	return plaintext, nil
+}
+
+// KeyID returns a fingerprint of the local key.
+func (l *Local) KeyID() string {
+	return l.keyID
+}
+
+// Algorithm returns the local wrapping algorithm.
+func (l *Local) Algorithm() string {
+	return LocalAlgorithm
+}
+
+func (l *Local) aead() (_ cipher.AEAD, err error) {
+	var block cipher.Block
+	if block, err = aes.NewCipher(l.key); err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,186 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	VaultAlgorithm    = "VAULT-TRANSIT"
+	DefaultVaultMount = "transit"
+)
+
+// Vault is a KeyWrapper that uses the HashiCorp Vault Transit secrets engine to wrap
+// key material. The wrapped key material is the Vault ciphertext string (e.g.
+// "vault:v1:...") which includes the version of the transit key used, so envelopes
+// can still be unsealed after the transit key is rotated.
+type Vault struct {
+	client    *http.Client
+	endpoint  *url.URL
+	token     string
+	namespace string
+	mount     string
+	keyName   string
+}
+
+// Ensure Vault implements the KeyWrapper interface.
+var _ KeyWrapper = &Vault{}
+
+// VaultOption allows the user to configure the Vault client when it is created.
+type VaultOption func(v *Vault) error
+
+// NewVault creates a Vault Transit KeyWrapper that connects to the Vault server at the
+// specified address and wraps key material with the named transit key.
+func NewVault(addr, keyName string, opts ...VaultOption) (v *Vault, err error) {
+	if addr == "" || keyName == "" {
+		return nil, ErrMissingVault
+	}
+
+	v = &Vault{
+		client:  &http.Client{Timeout: DefaultTimeout},
+		mount:   DefaultVaultMount,
+		keyName: keyName,
+	}
+
+	if v.endpoint, err = url.Parse(addr); err != nil {
+		return nil, fmt.Errorf("could not parse vault address: %w", err)
+	}
+
+	for _, opt := range opts {
+		if err = opt(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// WithVaultToken specifies the token used to authenticate with Vault.
+func WithVaultToken(token string) VaultOption {
+	return func(v *Vault) error {
+		v.token = token
+		return nil
+	}
+}
+
+// WithVaultNamespace specifies the Vault Enterprise namespace of the transit engine.
+func WithVaultNamespace(namespace string) VaultOption {
+	return func(v *Vault) error {
+		v.namespace = namespace
+		return nil
+	}
+}
+
+// WithVaultMount specifies the path the transit engine is mounted at if it is not
+// mounted at the default "transit" path.
+func WithVaultMount(mount string) VaultOption {
+	return func(v *Vault) error {
+		v.mount = strings.Trim(mount, "/")
+		return nil
+	}
+}
+
+// WithVaultClient specifies the HTTP client used to make requests to Vault, e.g. to
+// configure TLS for the connection to the Vault server.
+func WithVaultClient(client *http.Client) VaultOption {
+	return func(v *Vault) error {
+		v.client = client
+		return nil
+	}
+}
+
+// Wrap encrypts the plaintext with the transit key, returning the Vault ciphertext.
+func (v *Vault) Wrap(ctx context.Context, plaintext []byte) (_ []byte, err error) {
+	in := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	out := &vaultReply{}
+	if err = v.do(ctx, "encrypt", in, out); err != nil {
+		return nil, err
+	}
+
+	if out.Data.Ciphertext == "" {
+		return nil, ErrMissingVaultKey
+	}
+	return []byte(out.Data.Ciphertext), nil
+}
+
+// Unwrap decrypts the Vault ciphertext with the transit key.
+func (v *Vault) Unwrap(ctx context.Context, ciphertext []byte) (_ []byte, err error) {
+	if !bytes.HasPrefix(ciphertext, []byte("vault:")) {
+		return nil, ErrInvalidWrapped
+	}
+
+	in := map[string]string{"ciphertext": string(ciphertext)}
+	out := &vaultReply{}
+	if err = v.do(ctx, "decrypt", in, out); err != nil {
+		return nil, err
+	}
+
+	if out.Data.Plaintext == "" {
+		return nil, ErrMissingVaultKey
+	}
+	return base64.StdEncoding.DecodeString(out.Data.Plaintext)
+}
+
+// KeyID returns the mount and name of the transit key.
+func (v *Vault) KeyID() string {
+	return fmt.Sprintf("vault:%s/%s", v.mount, v.keyName)
+}
+
+// Algorithm returns the Vault wrapping algorithm.
+func (v *Vault) Algorithm() string {
+	return VaultAlgorithm
+}
+
+type vaultReply struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext,omitempty"`
+		Plaintext  string `json:"plaintext,omitempty"`
+	} `json:"data"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func (v *Vault) do(ctx context.Context, action string, in interface{}, out *vaultReply) (err error) {
+	// Join rather than resolve the path so that a path prefix in the vault address
+	// (e.g. when vault is behind a proxy) is preserved.
+	endpoint := v.endpoint.JoinPath("v1", v.mount, action, v.keyName)
+
+	var body bytes.Buffer
+	if err = json.NewEncoder(&body).Encode(in); err != nil {
+		return fmt.Errorf("could not encode vault request: %w", err)
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), &body); err != nil {
+		return fmt.Errorf("could not create vault request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	var rep *http.Response
+	if rep, err = v.client.Do(req); err != nil {
+		return err
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode != http.StatusOK {
+		// Attempt to read the vault errors from the body but ignore decoding errors
+		json.NewDecoder(rep.Body).Decode(out)
+		return &VaultError{StatusCode: rep.StatusCode, Errors: out.Errors}
+	}
+
+	if err = json.NewDecoder(rep.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode vault response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,854 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.28.2
+// source: trisa/data/generic/v1beta1/chains.proto
+
+package generic
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TokenStandard int32
+
+const (
+	TokenStandard_UNKNOWN_STANDARD TokenStandard = 0 // the token standard is not specified
+	TokenStandard_ERC20            TokenStandard = 1 // a fungible token
+	TokenStandard_ERC721           TokenStandard = 2 // a non-fungible token
+	TokenStandard_ERC1155          TokenStandard = 3 // a multi-token contract
+)
+
+// Enum value maps for TokenStandard.
+var (
+	TokenStandard_name = map[int32]string{
+		0: "UNKNOWN_STANDARD",
+		1: "ERC20",
+		2: "ERC721",
+		3: "ERC1155",
+	}
+	TokenStandard_value = map[string]int32{
+		"UNKNOWN_STANDARD": 0,
+		"ERC20":            1,
+		"ERC721":           2,
+		"ERC1155":          3,
+	}
+)
+
+func (x TokenStandard) Enum() *TokenStandard {
+	p := new(TokenStandard)
+	*p = x
+	return p
+}
+
+func (x TokenStandard) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TokenStandard) Descriptor() protoreflect.EnumDescriptor {
+	return file_trisa_data_generic_v1beta1_chains_proto_enumTypes[0].Descriptor()
+}
+
+func (TokenStandard) Type() protoreflect.EnumType {
+	return &file_trisa_data_generic_v1beta1_chains_proto_enumTypes[0]
+}
+
+func (x TokenStandard) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TokenStandard.Descriptor instead.
+func (TokenStandard) EnumDescriptor() ([]byte, []int) {
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP(), []int{0}
+}
+
+// UTXOTransaction describes a transaction on an unspent transaction output based chain
+// such as Bitcoin, Litecoin, or Bitcoin Cash. The beneficiary of the transaction is the
+// address of the first output that is not change and the amount is the total of the
+// non-change outputs to that address.
+type UTXOTransaction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Txid        string        `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`                                   // the transaction ID (hash) on the chain
+	Network     string        `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`                             // the chain/network of the transaction
+	AssetType   string        `protobuf:"bytes,3,opt,name=asset_type,json=assetType,proto3" json:"asset_type,omitempty"`        // the symbol of the virtual asset, e.g. BTC
+	Inputs      []*UTXOInput  `protobuf:"bytes,4,rep,name=inputs,proto3" json:"inputs,omitempty"`                               // the previous outputs spent by the transaction
+	Outputs     []*UTXOOutput `protobuf:"bytes,5,rep,name=outputs,proto3" json:"outputs,omitempty"`                             // the outputs created by the transaction
+	Fee         float64       `protobuf:"fixed64,6,opt,name=fee,proto3" json:"fee,omitempty"`                                   // the transaction fee paid to the miner
+	BlockHeight uint64        `protobuf:"varint,7,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"` // the height of the block the transaction was mined in (if confirmed)
+	Timestamp   string        `protobuf:"bytes,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                         // RFC 3339 timestamp of the transaction
+	ExtraJson   string        `protobuf:"bytes,14,opt,name=extra_json,json=extraJson,proto3" json:"extra_json,omitempty"`       // any extra data as a JSON formatted object
+}
+
+func (x *UTXOTransaction) Reset() {
+	*x = UTXOTransaction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UTXOTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UTXOTransaction) ProtoMessage() {}
+
+func (x *UTXOTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UTXOTransaction.ProtoReflect.Descriptor instead.
+func (*UTXOTransaction) Descriptor() ([]byte, []int) {
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UTXOTransaction) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *UTXOTransaction) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *UTXOTransaction) GetAssetType() string {
+	if x != nil {
+		return x.AssetType
+	}
+	return ""
+}
+
+func (x *UTXOTransaction) GetInputs() []*UTXOInput {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+func (x *UTXOTransaction) GetOutputs() []*UTXOOutput {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *UTXOTransaction) GetFee() float64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *UTXOTransaction) GetBlockHeight() uint64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+func (x *UTXOTransaction) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *UTXOTransaction) GetExtraJson() string {
+	if x != nil {
+		return x.ExtraJson
+	}
+	return ""
+}
+
+// UTXOInput is a previous transaction output spent by the originator.
+type UTXOInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Txid    string  `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`       // the transaction ID of the output being spent
+	Vout    uint32  `protobuf:"varint,2,opt,name=vout,proto3" json:"vout,omitempty"`      // the index of the output in the previous transaction
+	Address string  `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"` // the address that controls the output
+	Amount  float64 `protobuf:"fixed64,4,opt,name=amount,proto3" json:"amount,omitempty"` // the value of the output
+}
+
+func (x *UTXOInput) Reset() {
+	*x = UTXOInput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UTXOInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UTXOInput) ProtoMessage() {}
+
+func (x *UTXOInput) ProtoReflect() protoreflect.Message {
+	mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UTXOInput.ProtoReflect.Descriptor instead.
+func (*UTXOInput) Descriptor() ([]byte, []int) {
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UTXOInput) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *UTXOInput) GetVout() uint32 {
+	if x != nil {
+		return x.Vout
+	}
+	return 0
+}
+
+func (x *UTXOInput) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *UTXOInput) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+// UTXOOutput is an output created by the transaction.
+type UTXOOutput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Index   uint32  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`    // the index of the output in the transaction
+	Address string  `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"` // the address the output is paid to
+	Amount  float64 `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"` // the value of the output
+	Change  bool    `protobuf:"varint,4,opt,name=change,proto3" json:"change,omitempty"`  // true if the output returns change to the originator
+}
+
+func (x *UTXOOutput) Reset() {
+	*x = UTXOOutput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UTXOOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UTXOOutput) ProtoMessage() {}
+
+func (x *UTXOOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UTXOOutput.ProtoReflect.Descriptor instead.
+func (*UTXOOutput) Descriptor() ([]byte, []int) {
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UTXOOutput) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *UTXOOutput) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *UTXOOutput) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *UTXOOutput) GetChange() bool {
+	if x != nil {
+		return x.Change
+	}
+	return false
+}
+
+// TokenTransfer describes a transfer of a fungible or non-fungible token by a smart
+// contract on an EVM compatible chain such as Ethereum, Polygon, or Avalanche. The
+// amount is specified in the smallest unit of the token as a decimal string so that
+// 256 bit amounts can be represented without loss of precision.
+type TokenTransfer struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Txid            string        `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`                                                        // the transaction hash on the chain
+	Network         string        `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`                                                  // the chain/network of the transaction
+	ChainId         uint64        `protobuf:"varint,3,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`                                  // the EIP-155 chain ID of the network
+	ContractAddress string        `protobuf:"bytes,4,opt,name=contract_address,json=contractAddress,proto3" json:"contract_address,omitempty"`           // the address of the token contract
+	Standard        TokenStandard `protobuf:"varint,5,opt,name=standard,proto3,enum=trisa.data.generic.v1beta1.TokenStandard" json:"standard,omitempty"` // the token standard implemented by the contract
+	Symbol          string        `protobuf:"bytes,6,opt,name=symbol,proto3" json:"symbol,omitempty"`                                                    // the symbol of the token, e.g. USDC
+	Decimals        uint32        `protobuf:"varint,7,opt,name=decimals,proto3" json:"decimals,omitempty"`                                               // the number of decimals used to display the amount
+	TokenId         string        `protobuf:"bytes,8,opt,name=token_id,json=tokenId,proto3" json:"token_id,omitempty"`                                   // the ID of the token for non-fungible tokens
+	Originator      string        `protobuf:"bytes,9,opt,name=originator,proto3" json:"originator,omitempty"`                                            // the address the tokens are transferred from
+	Beneficiary     string        `protobuf:"bytes,10,opt,name=beneficiary,proto3" json:"beneficiary,omitempty"`                                         // the address the tokens are transferred to
+	Amount          string        `protobuf:"bytes,11,opt,name=amount,proto3" json:"amount,omitempty"`                                                   // the amount in the smallest unit of the token as a decimal string
+	LogIndex        uint32        `protobuf:"varint,12,opt,name=log_index,json=logIndex,proto3" json:"log_index,omitempty"`                              // the index of the transfer event log in the block
+	Memo            string        `protobuf:"bytes,13,opt,name=memo,proto3" json:"memo,omitempty"`                                                       // an optional memo or reference included with the transfer
+	Timestamp       string        `protobuf:"bytes,14,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                             // RFC 3339 timestamp of the transaction
+	ExtraJson       string        `protobuf:"bytes,15,opt,name=extra_json,json=extraJson,proto3" json:"extra_json,omitempty"`                            // any extra data as a JSON formatted object
+}
+
+func (x *TokenTransfer) Reset() {
+	*x = TokenTransfer{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TokenTransfer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenTransfer) ProtoMessage() {}
+
+func (x *TokenTransfer) ProtoReflect() protoreflect.Message {
+	mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenTransfer.ProtoReflect.Descriptor instead.
+func (*TokenTransfer) Descriptor() ([]byte, []int) {
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *TokenTransfer) GetTxid() string {
+	if x != nil {
+		return x.Txid
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *TokenTransfer) GetContractAddress() string {
+	if x != nil {
+		return x.ContractAddress
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetStandard() TokenStandard {
+	if x != nil {
+		return x.Standard
+	}
+	return TokenStandard_UNKNOWN_STANDARD
+}
+
+func (x *TokenTransfer) GetSymbol() string {
+	if x != nil {
+		return x.Symbol
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetDecimals() uint32 {
+	if x != nil {
+		return x.Decimals
+	}
+	return 0
+}
+
+func (x *TokenTransfer) GetTokenId() string {
+	if x != nil {
+		return x.TokenId
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetOriginator() string {
+	if x != nil {
+		return x.Originator
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetBeneficiary() string {
+	if x != nil {
+		return x.Beneficiary
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetLogIndex() uint32 {
+	if x != nil {
+		return x.LogIndex
+	}
+	return 0
+}
+
+func (x *TokenTransfer) GetMemo() string {
+	if x != nil {
+		return x.Memo
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *TokenTransfer) GetExtraJson() string {
+	if x != nil {
+		return x.ExtraJson
+	}
+	return ""
+}
+
+// LightningPayment describes a payment over the Lightning Network. Lightning payments
+// are not recorded on chain, so the payment is identified by its payment hash and the
+// originator and beneficiary are identified by the public keys of their nodes.
+type LightningPayment struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PaymentHash     string `protobuf:"bytes,1,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`             // the hex encoded hash of the payment preimage
+	Invoice         string `protobuf:"bytes,2,opt,name=invoice,proto3" json:"invoice,omitempty"`                                        // the BOLT 11 payment request paid by the originator
+	OriginatorNode  string `protobuf:"bytes,3,opt,name=originator_node,json=originatorNode,proto3" json:"originator_node,omitempty"`    // the public key of the originator's node
+	BeneficiaryNode string `protobuf:"bytes,4,opt,name=beneficiary_node,json=beneficiaryNode,proto3" json:"beneficiary_node,omitempty"` // the public key of the beneficiary's node
+	AmountMsat      uint64 `protobuf:"varint,5,opt,name=amount_msat,json=amountMsat,proto3" json:"amount_msat,omitempty"`               // the amount of the payment in millisatoshis
+	Network         string `protobuf:"bytes,6,opt,name=network,proto3" json:"network,omitempty"`                                        // the chain the channels are anchored to, e.g. bitcoin
+	AssetType       string `protobuf:"bytes,7,opt,name=asset_type,json=assetType,proto3" json:"asset_type,omitempty"`                   // the symbol of the virtual asset, e.g. BTC
+	Description     string `protobuf:"bytes,8,opt,name=description,proto3" json:"description,omitempty"`                                // the description of the invoice
+	Preimage        string `protobuf:"bytes,9,opt,name=preimage,proto3" json:"preimage,omitempty"`                                      // the hex encoded payment preimage as proof of payment (if settled)
+	Timestamp       string `protobuf:"bytes,10,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                   // RFC 3339 timestamp of the payment
+	ExtraJson       string `protobuf:"bytes,14,opt,name=extra_json,json=extraJson,proto3" json:"extra_json,omitempty"`                  // any extra data as a JSON formatted object
+}
+
+func (x *LightningPayment) Reset() {
+	*x = LightningPayment{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LightningPayment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LightningPayment) ProtoMessage() {}
+
+func (x *LightningPayment) ProtoReflect() protoreflect.Message {
+	mi := &file_trisa_data_generic_v1beta1_chains_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LightningPayment.ProtoReflect.Descriptor instead.
+func (*LightningPayment) Descriptor() ([]byte, []int) {
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LightningPayment) GetPaymentHash() string {
+	if x != nil {
+		return x.PaymentHash
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetInvoice() string {
+	if x != nil {
+		return x.Invoice
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetOriginatorNode() string {
+	if x != nil {
+		return x.OriginatorNode
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetBeneficiaryNode() string {
+	if x != nil {
+		return x.BeneficiaryNode
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetAmountMsat() uint64 {
+	if x != nil {
+		return x.AmountMsat
+	}
+	return 0
+}
+
+func (x *LightningPayment) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetAssetType() string {
+	if x != nil {
+		return x.AssetType
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetPreimage() string {
+	if x != nil {
+		return x.Preimage
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *LightningPayment) GetExtraJson() string {
+	if x != nil {
+		return x.ExtraJson
+	}
+	return ""
+}
+
+var File_trisa_data_generic_v1beta1_chains_proto protoreflect.FileDescriptor
+
+var file_trisa_data_generic_v1beta1_chains_proto_rawDesc = []byte{
+	0x0a, 0x27, 0x74, 0x72, 0x69, 0x73, 0x61, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x2f, 0x67, 0x65, 0x6e,
+	0x65, 0x72, 0x69, 0x63, 0x2f, 0x76, 0x31, 0x62, 0x65, 0x74, 0x61, 0x31, 0x2f, 0x63, 0x68, 0x61,
+	0x69, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1a, 0x74, 0x72, 0x69, 0x73, 0x61,
+	0x2e, 0x64, 0x61, 0x74, 0x61, 0x2e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x2e, 0x76, 0x31,
+	0x62, 0x65, 0x74, 0x61, 0x31, 0x22, 0xd1, 0x02, 0x0a, 0x0f, 0x55, 0x54, 0x58, 0x4f, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x78, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x78, 0x69, 0x64, 0x12, 0x18, 0x0a,
+	0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x73, 0x73, 0x65, 0x74,
+	0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x73, 0x73,
+	0x65, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x3d, 0x0a, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x74, 0x72, 0x69, 0x73, 0x61, 0x2e, 0x64,
+	0x61, 0x74, 0x61, 0x2e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x2e, 0x76, 0x31, 0x62, 0x65,
+	0x74, 0x61, 0x31, 0x2e, 0x55, 0x54, 0x58, 0x4f, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x06, 0x69,
+	0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x40, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x74, 0x72, 0x69, 0x73, 0x61, 0x2e, 0x64,
+	0x61, 0x74, 0x61, 0x2e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x2e, 0x76, 0x31, 0x62, 0x65,
+	0x74, 0x61, 0x31, 0x2e, 0x55, 0x54, 0x58, 0x4f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x52, 0x07,
+	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x66, 0x65, 0x65, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x66, 0x65, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1c, 0x0a, 0x09,
+	0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78,
+	0x74, 0x72, 0x61, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x65, 0x78, 0x74, 0x72, 0x61, 0x4a, 0x73, 0x6f, 0x6e, 0x22, 0x65, 0x0a, 0x09, 0x55, 0x54, 0x58,
+	0x4f, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x78, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x78, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x6f,
+	0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x76, 0x6f, 0x75, 0x74, 0x12, 0x18,
+	0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x22, 0x6c, 0x0a, 0x0a, 0x55, 0x54, 0x58, 0x4f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06,
+	0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x22, 0xe1,
+	0x03, 0x0a, 0x0d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72,
+	0x12, 0x12, 0x0a, 0x04, 0x74, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x74, 0x78, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x12, 0x19,
+	0x0a, 0x08, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x07, 0x63, 0x68, 0x61, 0x69, 0x6e, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x10, 0x63, 0x6f, 0x6e,
+	0x74, 0x72, 0x61, 0x63, 0x74, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x74, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x12, 0x45, 0x0a, 0x08, 0x73, 0x74, 0x61, 0x6e, 0x64, 0x61, 0x72, 0x64,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x29, 0x2e, 0x74, 0x72, 0x69, 0x73, 0x61, 0x2e, 0x64,
+	0x61, 0x74, 0x61, 0x2e, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x2e, 0x76, 0x31, 0x62, 0x65,
+	0x74, 0x61, 0x31, 0x2e, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x53, 0x74, 0x61, 0x6e, 0x64, 0x61, 0x72,
+	0x64, 0x52, 0x08, 0x73, 0x74, 0x61, 0x6e, 0x64, 0x61, 0x72, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73,
+	0x79, 0x6d, 0x62, 0x6f, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x79, 0x6d,
+	0x62, 0x6f, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x6c, 0x73, 0x18,
+	0x07, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x64, 0x65, 0x63, 0x69, 0x6d, 0x61, 0x6c, 0x73, 0x12,
+	0x19, 0x0a, 0x08, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x6f, 0x72,
+	0x69, 0x67, 0x69, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x62, 0x65,
+	0x6e, 0x65, 0x66, 0x69, 0x63, 0x69, 0x61, 0x72, 0x79, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x62, 0x65, 0x6e, 0x65, 0x66, 0x69, 0x63, 0x69, 0x61, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06,
+	0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x6d,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x6c, 0x6f, 0x67, 0x5f, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x6c, 0x6f, 0x67, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x65, 0x6d, 0x6f, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6d, 0x65, 0x6d, 0x6f, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78, 0x74, 0x72, 0x61, 0x5f, 0x6a, 0x73, 0x6f,
+	0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x65, 0x78, 0x74, 0x72, 0x61, 0x4a, 0x73,
+	0x6f, 0x6e, 0x22, 0xf8, 0x02, 0x0a, 0x10, 0x4c, 0x69, 0x67, 0x68, 0x74, 0x6e, 0x69, 0x6e, 0x67,
+	0x50, 0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x61, 0x79, 0x6d, 0x65,
+	0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x70,
+	0x61, 0x79, 0x6d, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12, 0x18, 0x0a, 0x07, 0x69, 0x6e,
+	0x76, 0x6f, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x69, 0x6e, 0x76,
+	0x6f, 0x69, 0x63, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x74,
+	0x6f, 0x72, 0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x6f,
+	0x72, 0x69, 0x67, 0x69, 0x6e, 0x61, 0x74, 0x6f, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x29, 0x0a,
+	0x10, 0x62, 0x65, 0x6e, 0x65, 0x66, 0x69, 0x63, 0x69, 0x61, 0x72, 0x79, 0x5f, 0x6e, 0x6f, 0x64,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x62, 0x65, 0x6e, 0x65, 0x66, 0x69, 0x63,
+	0x69, 0x61, 0x72, 0x79, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x61, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x5f, 0x6d, 0x73, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x61,
+	0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x4d, 0x73, 0x61, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x73, 0x73, 0x65, 0x74, 0x5f, 0x74, 0x79, 0x70,
+	0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x61, 0x73, 0x73, 0x65, 0x74, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x65, 0x69, 0x6d, 0x61, 0x67, 0x65,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x65, 0x69, 0x6d, 0x61, 0x67, 0x65,
+	0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x0a, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x1d,
+	0x0a, 0x0a, 0x65, 0x78, 0x74, 0x72, 0x61, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x0e, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x09, 0x65, 0x78, 0x74, 0x72, 0x61, 0x4a, 0x73, 0x6f, 0x6e, 0x2a, 0x49, 0x0a,
+	0x0d, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x53, 0x74, 0x61, 0x6e, 0x64, 0x61, 0x72, 0x64, 0x12, 0x14,
+	0x0a, 0x10, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x5f, 0x53, 0x54, 0x41, 0x4e, 0x44, 0x41,
+	0x52, 0x44, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x52, 0x43, 0x32, 0x30, 0x10, 0x01, 0x12,
+	0x0a, 0x0a, 0x06, 0x45, 0x52, 0x43, 0x37, 0x32, 0x31, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x45,
+	0x52, 0x43, 0x31, 0x31, 0x35, 0x35, 0x10, 0x03, 0x42, 0x45, 0x5a, 0x43, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x72, 0x69, 0x73, 0x61, 0x63, 0x72, 0x79, 0x70,
+	0x74, 0x6f, 0x2f, 0x74, 0x72, 0x69, 0x73, 0x61, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x74, 0x72, 0x69,
+	0x73, 0x61, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x2f, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x2f,
+	0x76, 0x31, 0x62, 0x65, 0x74, 0x61, 0x31, 0x3b, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x69, 0x63, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_trisa_data_generic_v1beta1_chains_proto_rawDescOnce sync.Once
+	file_trisa_data_generic_v1beta1_chains_proto_rawDescData = file_trisa_data_generic_v1beta1_chains_proto_rawDesc
+)
+
+func file_trisa_data_generic_v1beta1_chains_proto_rawDescGZIP() []byte {
+	file_trisa_data_generic_v1beta1_chains_proto_rawDescOnce.Do(func() {
+		file_trisa_data_generic_v1beta1_chains_proto_rawDescData = protoimpl.X.CompressGZIP(file_trisa_data_generic_v1beta1_chains_proto_rawDescData)
+	})
+	return file_trisa_data_generic_v1beta1_chains_proto_rawDescData
+}
+
+var file_trisa_data_generic_v1beta1_chains_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_trisa_data_generic_v1beta1_chains_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_trisa_data_generic_v1beta1_chains_proto_goTypes = []any{
+	(TokenStandard)(0),       // 0: trisa.data.generic.v1beta1.TokenStandard
+	(*UTXOTransaction)(nil),  // 1: trisa.data.generic.v1beta1.UTXOTransaction
+	(*UTXOInput)(nil),        // 2: trisa.data.generic.v1beta1.UTXOInput
+	(*UTXOOutput)(nil),       // 3: trisa.data.generic.v1beta1.UTXOOutput
+	(*TokenTransfer)(nil),    // 4: trisa.data.generic.v1beta1.TokenTransfer
+	(*LightningPayment)(nil), // 5: trisa.data.generic.v1beta1.LightningPayment
+}
+var file_trisa_data_generic_v1beta1_chains_proto_depIdxs = []int32{
+	2, // 0: trisa.data.generic.v1beta1.UTXOTransaction.inputs:type_name -> trisa.data.generic.v1beta1.UTXOInput
+	3, // 1: trisa.data.generic.v1beta1.UTXOTransaction.outputs:type_name -> trisa.data.generic.v1beta1.UTXOOutput
+	0, // 2: trisa.data.generic.v1beta1.TokenTransfer.standard:type_name -> trisa.data.generic.v1beta1.TokenStandard
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_trisa_data_generic_v1beta1_chains_proto_init() }
+func file_trisa_data_generic_v1beta1_chains_proto_init() {
+	if File_trisa_data_generic_v1beta1_chains_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_trisa_data_generic_v1beta1_chains_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*UTXOTransaction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trisa_data_generic_v1beta1_chains_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*UTXOInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trisa_data_generic_v1beta1_chains_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*UTXOOutput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trisa_data_generic_v1beta1_chains_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*TokenTransfer); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_trisa_data_generic_v1beta1_chains_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*LightningPayment); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_trisa_data_generic_v1beta1_chains_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_trisa_data_generic_v1beta1_chains_proto_goTypes,
+		DependencyIndexes: file_trisa_data_generic_v1beta1_chains_proto_depIdxs,
+		EnumInfos:         file_trisa_data_generic_v1beta1_chains_proto_enumTypes,
+		MessageInfos:      file_trisa_data_generic_v1beta1_chains_proto_msgTypes,
+	}.Build()
+	File_trisa_data_generic_v1beta1_chains_proto = out.File
+	file_trisa_data_generic_v1beta1_chains_proto_rawDesc = nil
+	file_trisa_data_generic_v1beta1_chains_proto_goTypes = nil
+	file_trisa_data_generic_v1beta1_chains_proto_depIdxs = nil
+}
@@ -0,0 +1,9 @@
+package generic
+
+import "errors"
+
+var (
+	ErrUnknownTransaction = errors.New("unknown transaction payload type")
+	ErrNoTransaction      = errors.New("no transaction payload")
+	ErrAlreadyRegistered  = errors.New("transaction payload type is already registered")
+)
@@ -0,0 +1,121 @@
+package generic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Viewer is implemented by transaction payloads that can be reduced to a generic
+// Transaction containing the txid, originator, beneficiary, amount, and asset of the
+// transfer. Code that handles the transaction of a TRISA payload should use the view
+// rather than unmarshaling a specific type so that it works with any registered type.
+type Viewer interface {
+	proto.Message
+	View() *Transaction
+}
+
+var registry = struct {
+	sync.RWMutex
+	once  sync.Once
+	types map[protoreflect.FullName]protoreflect.MessageType
+}{
+	types: make(map[protoreflect.FullName]protoreflect.MessageType),
+}
+
+// Registers the generic transaction payloads on first use of the registry rather than
+// in init since the message types are only available once the package is initialized.
+func defaults() {
+	registry.once.Do(func() {
+		for _, v := range []Viewer{
+			&Transaction{},
+			&Pending{},
+			&Sunrise{},
+			&TRP{},
+			&UTXOTransaction{},
+			&TokenTransfer{},
+			&LightningPayment{},
+		} {
+			mt := v.ProtoReflect().Type()
+			registry.types[mt.Descriptor().FullName()] = mt
+		}
+	})
+}
+
+// Register a transaction payload type so that it can be resolved from the type URL of
+// an anypb.Any. The generic transaction payloads are registered by default.
+func Register(v Viewer) error {
+	defaults()
+	mt := v.ProtoReflect().Type()
+	name := mt.Descriptor().FullName()
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.types[name]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyRegistered, name)
+	}
+	registry.types[name] = mt
+	return nil
+}
+
+// Registered returns the full names of all registered transaction payload types in
+// sorted order.
+func Registered() []string {
+	defaults()
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.types))
+	for name := range registry.types {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns a new, empty transaction payload for the type URL (or full name) of
+// a registered transaction payload type.
+func Resolve(typeURL string) (Viewer, error) {
+	name := protoreflect.FullName(typeURL)
+	if i := strings.LastIndexByte(typeURL, '/'); i >= 0 {
+		name = protoreflect.FullName(typeURL[i+1:])
+	}
+
+	defaults()
+	registry.RLock()
+	mt, ok := registry.types[name]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTransaction, typeURL)
+	}
+	return mt.New().Interface().(Viewer), nil
+}
+
+// UnmarshalTransaction resolves the type of the transaction payload and unmarshals it.
+func UnmarshalTransaction(msg *anypb.Any) (v Viewer, err error) {
+	if msg == nil {
+		return nil, ErrNoTransaction
+	}
+
+	if v, err = Resolve(msg.TypeUrl); err != nil {
+		return nil, err
+	}
+
+	if err = msg.UnmarshalTo(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// View unmarshals the transaction payload and returns its generic Transaction view.
+func View(msg *anypb.Any) (_ *Transaction, err error) {
+	var v Viewer
+	if v, err = UnmarshalTransaction(msg); err != nil {
+		return nil, err
+	}
+	return v.View(), nil
+}
@@ -0,0 +1,165 @@
+package generic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestView(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       proto.Message
+		expected *generic.Transaction
+	}{
+		{
+			"Transaction",
+			&generic.Transaction{Txid: "abc", Originator: "alice", Beneficiary: "bob", Amount: 1.5, AssetType: "ETH", Tag: "42"},
+			&generic.Transaction{Txid: "abc", Originator: "alice", Beneficiary: "bob", Amount: 1.5, AssetType: "ETH", Tag: "42"},
+		},
+		{
+			"Pending",
+			&generic.Pending{EnvelopeId: "1234", Transaction: &generic.Transaction{Txid: "abc", Amount: 2}},
+			&generic.Transaction{Txid: "abc", Amount: 2},
+		},
+		{
+			"PendingNoTransaction",
+			&generic.Pending{EnvelopeId: "1234"},
+			&generic.Transaction{},
+		},
+		{
+			"TRP",
+			&generic.TRP{
+				Message:     &generic.TRP_Inquiry{Inquiry: &generic.TRPInquiry{Amount: 0.25}},
+				Transaction: &generic.Transaction{AssetType: "BTC"},
+			},
+			&generic.Transaction{Amount: 0.25, AssetType: "BTC"},
+		},
+		{
+			"UTXOTransaction",
+			&generic.UTXOTransaction{
+				Txid:      "f4184fc5",
+				Network:   "bitcoin",
+				AssetType: "BTC",
+				Inputs: []*generic.UTXOInput{
+					{Txid: "0437cd7f", Vout: 0, Address: "bc1qoriginator", Amount: 0.3},
+					{Txid: "0437cd7f", Vout: 1, Address: "bc1qother", Amount: 0.2},
+				},
+				Outputs: []*generic.UTXOOutput{
+					{Index: 0, Address: "bc1qchange", Amount: 0.1, Change: true},
+					{Index: 1, Address: "bc1qbeneficiary", Amount: 0.25},
+					{Index: 2, Address: "bc1qsomeoneelse", Amount: 0.05},
+					{Index: 3, Address: "bc1qbeneficiary", Amount: 0.125},
+				},
+				Fee: 0.0001,
+			},
+			&generic.Transaction{
+				Txid:        "f4184fc5",
+				Network:     "bitcoin",
+				AssetType:   "BTC",
+				Originator:  "bc1qoriginator",
+				Beneficiary: "bc1qbeneficiary",
+				Amount:      0.375,
+			},
+		},
+		{
+			"TokenTransfer",
+			&generic.TokenTransfer{
+				Txid:            "0x5c504ed4",
+				Network:         "ethereum",
+				ChainId:         1,
+				ContractAddress: "0xa0b86991",
+				Standard:        generic.TokenStandard_ERC20,
+				Symbol:          "USDC",
+				Decimals:        6,
+				Originator:      "0xoriginator",
+				Beneficiary:     "0xbeneficiary",
+				Amount:          "1250500000",
+				Memo:            "invoice 42",
+			},
+			&generic.Transaction{
+				Txid:        "0x5c504ed4",
+				Network:     "ethereum",
+				AssetType:   "USDC",
+				Originator:  "0xoriginator",
+				Beneficiary: "0xbeneficiary",
+				Amount:      1250.5,
+				Tag:         "invoice 42",
+			},
+		},
+		{
+			"TokenTransferNoSymbol",
+			&generic.TokenTransfer{ContractAddress: "0xa0b86991", Amount: "not a number"},
+			&generic.Transaction{AssetType: "0xa0b86991"},
+		},
+		{
+			"LightningPayment",
+			&generic.LightningPayment{
+				PaymentHash:     "9e017f6d",
+				Invoice:         "lnbc2500u1p...",
+				OriginatorNode:  "02originator",
+				BeneficiaryNode: "03beneficiary",
+				AmountMsat:      250000000,
+				Network:         "bitcoin",
+			},
+			&generic.Transaction{
+				Txid:        "9e017f6d",
+				Network:     "bitcoin",
+				AssetType:   "BTC",
+				Originator:  "02originator",
+				Beneficiary: "03beneficiary",
+				Amount:      0.0025,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := anypb.New(tc.in)
+			require.NoError(t, err)
+
+			view, err := generic.View(msg)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(tc.expected, view), "unexpected view %v", view)
+
+			// The view must not modify the original message
+			v, err := generic.UnmarshalTransaction(msg)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(tc.in, v))
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	names := generic.Registered()
+	require.Contains(t, names, "trisa.data.generic.v1beta1.Transaction")
+	require.Contains(t, names, "trisa.data.generic.v1beta1.UTXOTransaction")
+	require.Contains(t, names, "trisa.data.generic.v1beta1.TokenTransfer")
+	require.Contains(t, names, "trisa.data.generic.v1beta1.LightningPayment")
+
+	v, err := generic.Resolve("type.googleapis.com/trisa.data.generic.v1beta1.TokenTransfer")
+	require.NoError(t, err)
+	require.IsType(t, &generic.TokenTransfer{}, v)
+
+	v, err = generic.Resolve("trisa.data.generic.v1beta1.LightningPayment")
+	require.NoError(t, err)
+	require.IsType(t, &generic.LightningPayment{}, v)
+
+	_, err = generic.Resolve("type.googleapis.com/trisa.api.v1beta1.Payload")
+	require.ErrorIs(t, err, generic.ErrUnknownTransaction)
+
+	// Only transaction payloads can be viewed
+	msg, err := anypb.New(&api.Payload{SentAt: "2024-04-12T09:30:00Z"})
+	require.NoError(t, err)
+	_, err = generic.View(msg)
+	require.ErrorIs(t, err, generic.ErrUnknownTransaction)
+
+	_, err = generic.View(nil)
+	require.ErrorIs(t, err, generic.ErrNoTransaction)
+
+	require.ErrorIs(t, generic.Register(&generic.Transaction{}), generic.ErrAlreadyRegistered)
+}
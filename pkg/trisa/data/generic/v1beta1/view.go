@@ -0,0 +1,141 @@
+package generic
+
+import (
+	"math/big"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Number of millisatoshis in a bitcoin.
+const msatPerBTC = 1e11
+
+// View returns a copy of the transaction.
+func (t *Transaction) View() *Transaction {
+	if t == nil {
+		return &Transaction{}
+	}
+	return proto.Clone(t).(*Transaction)
+}
+
+// View returns the original transaction of the pending message.
+func (p *Pending) View() *Transaction {
+	return p.GetTransaction().View()
+}
+
+// View returns the original transaction of the sunrise message.
+func (s *Sunrise) View() *Transaction {
+	return s.GetTransaction().View()
+}
+
+// View returns the reference transaction of the TRP message, filling in the amount of
+// an inquiry, the address of an approval, or the txid of a confirmation if they are
+// not already set on the reference transaction.
+func (t *TRP) View() *Transaction {
+	view := t.GetTransaction().View()
+	if view.Amount == 0 {
+		view.Amount = t.GetInquiry().GetAmount()
+	}
+
+	if address := t.GetApproved().GetAddress(); address != "" && view.Beneficiary == "" {
+		view.Beneficiary = address
+	}
+
+	if txid := t.GetConfirmed().GetTxid(); txid != "" && view.Txid == "" {
+		view.Txid = txid
+	}
+	return view
+}
+
+// View returns the transaction with the address of the first input as the originator,
+// the address of the first non-change output as the beneficiary, and the total of the
+// non-change outputs to the beneficiary as the amount.
+func (t *UTXOTransaction) View() *Transaction {
+	view := &Transaction{
+		Txid:      t.GetTxid(),
+		Network:   t.GetNetwork(),
+		AssetType: t.GetAssetType(),
+		Timestamp: t.GetTimestamp(),
+		ExtraJson: t.GetExtraJson(),
+	}
+
+	for _, input := range t.GetInputs() {
+		if input.Address != "" {
+			view.Originator = input.Address
+			break
+		}
+	}
+
+	for _, output := range t.GetOutputs() {
+		if output.Change || output.Address == "" {
+			continue
+		}
+
+		if view.Beneficiary == "" {
+			view.Beneficiary = output.Address
+		}
+
+		if output.Address == view.Beneficiary {
+			view.Amount += output.Amount
+		}
+	}
+	return view
+}
+
+// View returns the transaction with the amount converted from the smallest unit of the
+// token using the decimals of the token. The asset type is the symbol of the token or
+// the contract address if the symbol is not known.
+func (t *TokenTransfer) View() *Transaction {
+	view := &Transaction{
+		Txid:        t.GetTxid(),
+		Originator:  t.GetOriginator(),
+		Beneficiary: t.GetBeneficiary(),
+		Amount:      t.DisplayAmount(),
+		Network:     t.GetNetwork(),
+		Timestamp:   t.GetTimestamp(),
+		ExtraJson:   t.GetExtraJson(),
+		AssetType:   t.GetSymbol(),
+		Tag:         t.GetMemo(),
+	}
+
+	if view.AssetType == "" {
+		view.AssetType = t.GetContractAddress()
+	}
+	return view
+}
+
+// DisplayAmount converts the amount from the smallest unit of the token using the
+// decimals of the token. Zero is returned if the amount cannot be parsed.
+func (t *TokenTransfer) DisplayAmount() float64 {
+	amount, ok := new(big.Float).SetString(t.GetAmount())
+	if !ok {
+		return 0
+	}
+
+	if decimals := t.GetDecimals(); decimals > 0 {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+		amount.Quo(amount, new(big.Float).SetInt(scale))
+	}
+
+	value, _ := amount.Float64()
+	return value
+}
+
+// View returns the transaction with the payment hash as the txid, the node public keys
+// as the originator and beneficiary, and the amount in bitcoin.
+func (p *LightningPayment) View() *Transaction {
+	view := &Transaction{
+		Txid:        p.GetPaymentHash(),
+		Originator:  p.GetOriginatorNode(),
+		Beneficiary: p.GetBeneficiaryNode(),
+		Amount:      float64(p.GetAmountMsat()) / msatPerBTC,
+		Network:     p.GetNetwork(),
+		Timestamp:   p.GetTimestamp(),
+		ExtraJson:   p.GetExtraJson(),
+		AssetType:   p.GetAssetType(),
+	}
+
+	if view.AssetType == "" {
+		view.AssetType = "BTC"
+	}
+	return view
+}
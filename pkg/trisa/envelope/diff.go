@@ -0,0 +1,333 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Match describes the result of comparing a single component of two envelopes.
+type Match uint8
+
+const (
+	Incomparable Match = iota // The component could not be compared, e.g. the envelope could not be opened
+	Equal                     // The component is identical on both envelopes
+	Different                 // The component differs between the envelopes
+)
+
+var matchNames = []string{"incomparable", "equal", "different"}
+
+func (m Match) String() string {
+	idx := int(m)
+	if idx >= len(matchNames) {
+		idx = 0
+	}
+	return matchNames[idx]
+}
+
+// MarshalText allows the match to be serialized by its name, e.g. in JSON reports.
+func (m Match) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Comparison is the result of comparing two secure envelopes, generally the version of
+// an envelope that was sent and the version the counterparty claims to have received.
+// The ciphertext and HMAC signature are compared directly and are available in any
+// state; the payload is only compared if both envelopes can be opened.
+type Comparison struct {
+	Left        State           `json:"left"`
+	Right       State           `json:"right"`
+	EnvelopeID  Match           `json:"envelope_id"`
+	Ciphertext  Match           `json:"ciphertext"`
+	HMAC        Match           `json:"hmac"`
+	Error       Match           `json:"error"`
+	Payload     Match           `json:"payload"`
+	Identity    []*Difference   `json:"identity,omitempty"`
+	Transaction []*Difference   `json:"transaction,omitempty"`
+	SentAt      *TimestampDelta `json:"sent_at,omitempty"`
+	ReceivedAt  *TimestampDelta `json:"received_at,omitempty"`
+}
+
+// Difference is a single field that differs between the payloads of two envelopes. The
+// path is made up of the protocol buffer field names, e.g. "originator.account_number[0]"
+// and the values are formatted as strings. Empty values indicate the field is not set.
+type Difference struct {
+	Path  string `json:"path"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// TimestampDelta flags a difference between the payload timestamps of two envelopes.
+// The delta is the right timestamp minus the left timestamp and is zero if either of
+// the timestamps is missing or cannot be parsed.
+type TimestampDelta struct {
+	Left  string        `json:"left"`
+	Right string        `json:"right"`
+	Delta time.Duration `json:"delta"`
+}
+
+// Equal returns true if no differences were found between the envelopes and their
+// payloads could be compared.
+func (c *Comparison) Equal() bool {
+	return c.EnvelopeID == Equal && c.Error == Equal && c.Payload == Equal &&
+		c.Ciphertext != Different && c.HMAC != Different
+}
+
+// Compare two envelopes in any state. Sealed envelopes are unsealed if an unsealing
+// key is available on the envelope (e.g. they were wrapped using WithUnsealingKey),
+// otherwise the payloads are reported as incomparable. Because each envelope may have
+// been sealed for a different recipient, the envelopes are opened independently. An
+// error is returned if an envelope has an unsealing key but cannot be opened.
+func Compare(left, right *Envelope) (cmp *Comparison, err error) {
+	if left == nil || right == nil || left.msg == nil || right.msg == nil {
+		return nil, ErrNoMessage
+	}
+
+	cmp = &Comparison{
+		Left:       left.State(),
+		Right:      right.State(),
+		EnvelopeID: matches(left.msg.Id == right.msg.Id),
+		Ciphertext: compareBytes(left.msg.Payload, right.msg.Payload),
+		HMAC:       compareBytes(left.msg.Hmac, right.msg.Hmac),
+		Error:      matches(proto.Equal(left.Error(), right.Error())),
+	}
+
+	var lpayload, rpayload *api.Payload
+	if lpayload, err = openPayload(left); err != nil {
+		return nil, fmt.Errorf("could not open left envelope: %w", err)
+	}
+
+	if rpayload, err = openPayload(right); err != nil {
+		return nil, fmt.Errorf("could not open right envelope: %w", err)
+	}
+
+	// The payloads can only be compared if both envelopes could be opened or if
+	// neither envelope has a payload (e.g. both are error envelopes).
+	switch {
+	case lpayload == nil && rpayload == nil:
+		if cmp.Left == Error && cmp.Right == Error {
+			cmp.Payload = Equal
+		}
+		return cmp, nil
+	case lpayload == nil || rpayload == nil:
+		return cmp, nil
+	}
+
+	cmp.Payload = matches(proto.Equal(lpayload, rpayload))
+	cmp.Identity = diffAny("", lpayload.Identity, rpayload.Identity)
+	cmp.Transaction = diffAny("", lpayload.Transaction, rpayload.Transaction)
+	cmp.SentAt = compareTimestamps(lpayload.SentAt, rpayload.SentAt)
+	cmp.ReceivedAt = compareTimestamps(lpayload.ReceivedAt, rpayload.ReceivedAt)
+	return cmp, nil
+}
+
+// Opens the envelope as far as possible, returning a nil payload without an error if
+// the envelope is sealed and no unsealing key is available or it is an error envelope.
+func openPayload(env *Envelope) (_ *api.Payload, err error) {
+	switch env.State() {
+	case Clear, ClearError:
+		return env.payload, nil
+	case Error:
+		return nil, nil
+	case Sealed, SealedError:
+		if env.seal == nil {
+			return nil, nil
+		}
+
+		if env, _, err = env.Unseal(); err != nil {
+			return nil, err
+		}
+		fallthrough
+	case Unsealed, UnsealedError:
+		if env, _, err = env.Decrypt(); err != nil {
+			return nil, err
+		}
+		return env.payload, nil
+	default:
+		return nil, fmt.Errorf("cannot compare envelope in %q state", env.State())
+	}
+}
+
+func matches(equal bool) Match {
+	if equal {
+		return Equal
+	}
+	return Different
+}
+
+// Byte fields that are missing from both envelopes (e.g. clear or error envelopes)
+// cannot be compared.
+func compareBytes(left, right []byte) Match {
+	if len(left) == 0 && len(right) == 0 {
+		return Incomparable
+	}
+	return matches(bytes.Equal(left, right))
+}
+
+func compareTimestamps(left, right string) *TimestampDelta {
+	if left == right {
+		return nil
+	}
+
+	delta := &TimestampDelta{Left: left, Right: right}
+	lts, lerr := time.Parse(time.RFC3339Nano, left)
+	rts, rerr := time.Parse(time.RFC3339Nano, right)
+	if lerr == nil && rerr == nil {
+		delta.Delta = rts.Sub(lts)
+	}
+	return delta
+}
+
+//===========================================================================
+// Field-Level Payload Differences
+//===========================================================================
+
+// Diffs two Any messages by unmarshaling them into their registered types. If the
+// types differ or are not registered, the messages are compared as opaque values.
+func diffAny(path string, left, right *anypb.Any) (diffs []*Difference) {
+	if proto.Equal(left, right) {
+		return nil
+	}
+
+	if left.GetTypeUrl() != right.GetTypeUrl() {
+		return []*Difference{{Path: join(path, "@type"), Left: left.GetTypeUrl(), Right: right.GetTypeUrl()}}
+	}
+
+	lmsg, lerr := left.UnmarshalNew()
+	rmsg, rerr := right.UnmarshalNew()
+	if lerr != nil || rerr != nil {
+		return []*Difference{{Path: join(path, "value"), Left: formatBytes(left.GetValue()), Right: formatBytes(right.GetValue())}}
+	}
+
+	diffMessage(path, lmsg.ProtoReflect(), rmsg.ProtoReflect(), &diffs)
+	return diffs
+}
+
+func diffMessage(path string, left, right protoreflect.Message, diffs *[]*Difference) {
+	fields := left.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fpath := join(path, string(fd.Name()))
+
+		switch {
+		case fd.IsList():
+			diffList(fpath, fd, left.Get(fd).List(), right.Get(fd).List(), diffs)
+		case fd.IsMap():
+			diffMap(fpath, fd, left.Get(fd).Map(), right.Get(fd).Map(), diffs)
+		default:
+			diffValue(fpath, fd, left.Get(fd), right.Get(fd), diffs)
+		}
+	}
+}
+
+func diffList(path string, fd protoreflect.FieldDescriptor, left, right protoreflect.List, diffs *[]*Difference) {
+	for i := 0; i < left.Len() || i < right.Len(); i++ {
+		ipath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= left.Len():
+			*diffs = append(*diffs, &Difference{Path: ipath, Right: formatValue(fd, right.Get(i))})
+		case i >= right.Len():
+			*diffs = append(*diffs, &Difference{Path: ipath, Left: formatValue(fd, left.Get(i))})
+		default:
+			diffValue(ipath, fd, left.Get(i), right.Get(i), diffs)
+		}
+	}
+}
+
+func diffMap(path string, fd protoreflect.FieldDescriptor, left, right protoreflect.Map, diffs *[]*Difference) {
+	keys := make(map[string]protoreflect.MapKey)
+	collect := func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys[k.String()] = k
+		return true
+	}
+	left.Range(collect)
+	right.Range(collect)
+
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	vd := fd.MapValue()
+	for _, name := range names {
+		key := keys[name]
+		kpath := fmt.Sprintf("%s[%s]", path, strconv.Quote(name))
+		switch {
+		case !left.Has(key):
+			*diffs = append(*diffs, &Difference{Path: kpath, Right: formatValue(vd, right.Get(key))})
+		case !right.Has(key):
+			*diffs = append(*diffs, &Difference{Path: kpath, Left: formatValue(vd, left.Get(key))})
+		default:
+			diffValue(kpath, vd, left.Get(key), right.Get(key), diffs)
+		}
+	}
+}
+
+func diffValue(path string, fd protoreflect.FieldDescriptor, left, right protoreflect.Value, diffs *[]*Difference) {
+	if fd.Message() != nil {
+		// Nested messages are compared field by field; unset messages are treated as
+		// empty so that only the fields that are actually set are reported.
+		lmsg, rmsg := left.Message(), right.Message()
+		if !lmsg.IsValid() && !rmsg.IsValid() {
+			return
+		}
+
+		if !proto.Equal(lmsg.Interface(), rmsg.Interface()) {
+			diffMessage(path, lmsg, rmsg, diffs)
+		}
+		return
+	}
+
+	if !left.Equal(right) {
+		*diffs = append(*diffs, &Difference{Path: path, Left: formatValue(fd, left), Right: formatValue(fd, right)})
+	}
+}
+
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		data, err := protojson.Marshal(v.Message().Interface())
+		if err != nil {
+			return fmt.Sprintf("<%s>", fd.Message().FullName())
+		}
+		return string(data)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.BytesKind:
+		return formatBytes(v.Bytes())
+	case protoreflect.StringKind:
+		if v.String() == "" {
+			return ""
+		}
+		return strconv.Quote(v.String())
+	default:
+		return v.String()
+	}
+}
+
+func formatBytes(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
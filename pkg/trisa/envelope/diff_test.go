@@ -0,0 +1,159 @@
+package envelope_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestCompare(t *testing.T) {
+	payload, err := loadPayloadFixture("testdata/payload.json")
+	require.NoError(t, err, "could not load payload fixture")
+
+	key, err := loadPrivateKey("testdata/sealing_key.pem")
+	require.NoError(t, err, "could not load sealing key")
+
+	sealed, _, err := envelope.Seal(payload, envelope.WithRSAPublicKey(&key.PublicKey))
+	require.NoError(t, err, "could not seal envelope")
+
+	t.Run("Identical", func(t *testing.T) {
+		left, err := envelope.Wrap(sealed.Proto(), envelope.WithRSAPrivateKey(key))
+		require.NoError(t, err)
+
+		// Compare the sealed envelope to an unsealed copy of itself
+		right, _, err := left.Unseal()
+		require.NoError(t, err)
+
+		cmp, err := envelope.Compare(left, right)
+		require.NoError(t, err)
+		require.Equal(t, envelope.Sealed, cmp.Left)
+		require.Equal(t, envelope.Unsealed, cmp.Right)
+		require.Equal(t, envelope.Equal, cmp.EnvelopeID)
+		require.Equal(t, envelope.Equal, cmp.Ciphertext)
+		require.Equal(t, envelope.Equal, cmp.HMAC)
+		require.Equal(t, envelope.Equal, cmp.Error)
+		require.Equal(t, envelope.Equal, cmp.Payload)
+		require.Empty(t, cmp.Identity)
+		require.Empty(t, cmp.Transaction)
+		require.Nil(t, cmp.SentAt)
+		require.Nil(t, cmp.ReceivedAt)
+		require.True(t, cmp.Equal())
+	})
+
+	t.Run("NoUnsealingKey", func(t *testing.T) {
+		left, err := envelope.Wrap(sealed.Proto())
+		require.NoError(t, err)
+
+		right, err := envelope.Wrap(sealed.Proto(), envelope.WithRSAPrivateKey(key))
+		require.NoError(t, err)
+
+		cmp, err := envelope.Compare(left, right)
+		require.NoError(t, err)
+		require.Equal(t, envelope.Equal, cmp.Ciphertext)
+		require.Equal(t, envelope.Equal, cmp.HMAC)
+		require.Equal(t, envelope.Incomparable, cmp.Payload)
+		require.False(t, cmp.Equal())
+	})
+
+	t.Run("Disputed", func(t *testing.T) {
+		// The counterparty claims a different amount, account, and receipt time
+		disputed := proto.Clone(payload).(*api.Payload)
+		disputed.ReceivedAt = "2022-01-27T08:21:43Z"
+
+		identity := &ivms101.IdentityPayload{}
+		require.NoError(t, payload.Identity.UnmarshalTo(identity))
+		identity.Originator.AccountNumbers = append(identity.Originator.AccountNumbers, "forged")
+		identity.Originator.OriginatorPersons[0].GetNaturalPerson().Name.NameIdentifiers[0].PrimaryIdentifier = "Howell"
+		disputed.Identity, err = anypb.New(identity)
+		require.NoError(t, err)
+
+		transaction := &generic.Transaction{}
+		require.NoError(t, payload.Transaction.UnmarshalTo(transaction))
+		transaction.Amount = transaction.Amount * 10
+		disputed.Transaction, err = anypb.New(transaction)
+		require.NoError(t, err)
+
+		right, _, err := envelope.Seal(disputed, envelope.WithEnvelopeID(sealed.ID()), envelope.WithRSAPublicKey(&key.PublicKey))
+		require.NoError(t, err)
+		right, err = envelope.Wrap(right.Proto(), envelope.WithRSAPrivateKey(key))
+		require.NoError(t, err)
+
+		left, err := envelope.Wrap(sealed.Proto(), envelope.WithRSAPrivateKey(key))
+		require.NoError(t, err)
+
+		cmp, err := envelope.Compare(left, right)
+		require.NoError(t, err)
+		require.Equal(t, envelope.Equal, cmp.EnvelopeID)
+		require.Equal(t, envelope.Different, cmp.Ciphertext)
+		require.Equal(t, envelope.Different, cmp.HMAC)
+		require.Equal(t, envelope.Different, cmp.Payload)
+		require.False(t, cmp.Equal())
+
+		require.Equal(t, []*envelope.Difference{
+			{
+				Path:  "originator.originator_persons[0].natural_person.name.name_identifiers[0].primary_identifier",
+				Left:  `"Howard"`,
+				Right: `"Howell"`,
+			},
+			{
+				Path:  "originator.account_numbers[1]",
+				Right: `"forged"`,
+			},
+		}, cmp.Identity)
+
+		require.Len(t, cmp.Transaction, 1)
+		require.Equal(t, "amount", cmp.Transaction[0].Path)
+
+		require.Nil(t, cmp.SentAt)
+		require.NotNil(t, cmp.ReceivedAt)
+		require.Equal(t, disputed.ReceivedAt, cmp.ReceivedAt.Right)
+
+		expected, _ := time.Parse(time.RFC3339, payload.ReceivedAt)
+		actual, _ := time.Parse(time.RFC3339, disputed.ReceivedAt)
+		require.Equal(t, actual.Sub(expected), cmp.ReceivedAt.Delta)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		left, err := envelope.WrapError(api.Errorf(api.ComplianceCheckFail, "rejected"), envelope.WithEnvelopeID(sealed.ID()))
+		require.NoError(t, err)
+
+		right, err := envelope.WrapError(api.Errorf(api.BeneficiaryNameUnmatched, "rejected"), envelope.WithEnvelopeID(sealed.ID()))
+		require.NoError(t, err)
+
+		cmp, err := envelope.Compare(left, right)
+		require.NoError(t, err)
+		require.Equal(t, envelope.Incomparable, cmp.Ciphertext)
+		require.Equal(t, envelope.Different, cmp.Error)
+		require.Equal(t, envelope.Equal, cmp.Payload)
+
+		cmp, err = envelope.Compare(left, left)
+		require.NoError(t, err)
+		require.True(t, cmp.Equal())
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		left, err := envelope.Wrap(sealed.Proto(), envelope.WithRSAPrivateKey(key))
+		require.NoError(t, err)
+
+		right, err := envelope.Wrap(sealed.Proto(), envelope.WithRSAPrivateKey(other))
+		require.NoError(t, err)
+
+		_, err = envelope.Compare(left, right)
+		require.ErrorContains(t, err, "could not open right envelope")
+
+		_, err = envelope.Compare(nil, right)
+		require.ErrorIs(t, err, envelope.ErrNoMessage)
+	})
+}
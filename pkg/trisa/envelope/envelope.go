@@ -69,6 +69,14 @@ type Envelope struct {
 	crypto  crypto.Crypto
 	seal    crypto.Cipher
 	parent  *Envelope
+	guard   ReplayGuard
+}
+
+// ReplayGuard checks that a received secure envelope is fresh and has not been received
+// before, returning a rejection if it is stale or a replay. A guard can be added to
+// Open or OpenPayload using the WithReplayGuard option (see the replay package).
+type ReplayGuard interface {
+	Check(msg *api.SecureEnvelope) *api.Error
 }
 
 //===========================================================================
@@ -177,27 +185,46 @@ func Seal(payload *api.Payload, opts ...Option) (env *Envelope, reject *api.Erro
 // to seal the envelope (provided using the WithUnsealingKey or WithRSAPrivateKey
 // options). The returned envelope has a partent chain that contains the decryption
 // transformations at each step so tha tyou can validate that the payload has been
-// constructed correctly.
+// constructed correctly. If a replay guard is specified with the WithReplayGuard
+// option, stale or replayed envelopes are rejected after they have been decrypted.
 func Open(msg *api.SecureEnvelope, opts ...Option) (env *Envelope, reject *api.Error, err error) {
 	if env, err = Wrap(msg, opts...); err != nil {
 		return nil, nil, err
 	}
 
-	if env, reject, err = env.Unseal(); err != nil {
+	wrapped := env
+	var next *Envelope
+	if next, reject, err = env.Unseal(); err != nil {
 		if reject != nil {
-			env, _ = env.Reject(reject)
+			next, _ = env.Reject(reject)
 		}
-		return env, reject, err
+		return next, reject, err
 	}
 
-	if env, reject, err = env.Decrypt(); err != nil {
+	env = next
+	if next, reject, err = env.Decrypt(); err != nil {
 		if reject != nil {
-			env, _ = env.Reject(reject)
+			next, _ = env.Reject(reject)
 		}
-		return env, reject, err
+		return next, reject, err
 	}
 
-	return env, nil, nil
+	// The received envelope is only checked for replays once the HMAC has been verified
+	// so that envelopes that could not be opened can be resent by the counterparty.
+	if reject = checkReplay(wrapped.guard, msg); reject != nil {
+		next, _ = next.Reject(reject)
+		return next, reject, reject
+	}
+
+	return next, nil, nil
+}
+
+// Checks the original envelope with the replay guard if one was specified.
+func checkReplay(guard ReplayGuard, msg *api.SecureEnvelope) *api.Error {
+	if guard == nil {
+		return nil
+	}
+	return guard.Check(msg)
 }
 
 //===========================================================================
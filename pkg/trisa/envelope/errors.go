@@ -25,4 +25,13 @@ var (
 	ErrCannotSeal               = errors.New("cannot seal envelope: no public key cryptographic handler available")
 	ErrCannotUnseal             = errors.New("cannot unseal envelope: no private key cryptographic handler available")
 	ErrCannotVerify             = errors.New("cannot verify hmac: no cryptographic handler available")
+	ErrNoRecipients             = errors.New("invalid envelope set: no recipients")
+	ErrDuplicateRecipient       = errors.New("invalid envelope set: duplicate recipient")
+	ErrUnknownRecipient         = errors.New("invalid envelope set: unknown recipient")
+	ErrMismatchedSet            = errors.New("invalid envelope set: companion envelopes do not match")
+	ErrNotSigned                = errors.New("envelope does not have a signature")
+	ErrNoSignerCertificate      = errors.New("cannot verify signature: no signer certificate available")
+	ErrSignerMismatch           = errors.New("cannot verify signature: certificate does not match envelope signer")
+	ErrInvalidSignature         = errors.New("envelope signature is invalid")
+	ErrUnsupportedSignature     = errors.New("unsupported envelope signature algorithm")
 )
@@ -1,6 +1,7 @@
 package envelope
 
 import (
+	"crypto/x509"
 	"time"
 
 	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
@@ -73,6 +74,9 @@ func OpenPayload(msg *api.SecureEnvelope, opts ...Option) (payload *api.Payload,
 		return nil, nil, err
 	}
 
+	// The secure envelope is modified in place so keep the fields the guard requires.
+	received := &api.SecureEnvelope{Id: msg.Id, Hmac: msg.Hmac, Timestamp: msg.Timestamp}
+
 	// A rejection here would be related to a sealing key failure
 	if reject, err = env.unsealEnvelope(); reject != nil || err != nil {
 		return nil, reject, err
@@ -83,6 +87,10 @@ func OpenPayload(msg *api.SecureEnvelope, opts ...Option) (payload *api.Payload,
 		return nil, reject, err
 	}
 
+	if reject = checkReplay(env.guard, received); reject != nil {
+		return nil, reject, reject
+	}
+
 	if payload, err = env.Payload(); err != nil {
 		return nil, nil, err
 	}
@@ -142,3 +150,27 @@ func Validate(msg *api.SecureEnvelope) (err error) {
 	}
 	return env.ValidateMessage()
 }
+
+// Sign is a one-liner for Wrap(msg).Sign(signer) and adds a detached signature to a
+// sealed secure envelope immediately before it is sent to the recipient.
+func Sign(msg *api.SecureEnvelope, signer *Signer) (_ *api.SecureEnvelope, err error) {
+	var env *Envelope
+	if env, err = Wrap(msg); err != nil {
+		return nil, err
+	}
+
+	if env, err = env.Sign(signer); err != nil {
+		return nil, err
+	}
+	return env.Proto(), nil
+}
+
+// VerifySignature is a one-liner for Wrap(msg).VerifySignature(cert) and verifies the
+// detached signature of the secure envelope with the identity certificate of the sender.
+func VerifySignature(msg *api.SecureEnvelope, cert *x509.Certificate) (err error) {
+	var env *Envelope
+	if env, err = Wrap(msg); err != nil {
+		return err
+	}
+	return env.VerifySignature(cert)
+}
@@ -0,0 +1,223 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+//===========================================================================
+// Multi-Recipient Envelopes
+//===========================================================================
+
+// Recipient is a party that an envelope is sealed for in a multi-hop transfer, e.g. an
+// intermediary VASP or the beneficiary VASP. The name must be unique in the set and is
+// usually the common name of the certificate of the recipient.
+type Recipient struct {
+	Name       string
+	SealingKey interface{}
+}
+
+// Set is a collection of companion secure envelopes that share a single encrypted
+// payload and HMAC signature; only the encryption key and HMAC secret are sealed
+// separately for each recipient. Intermediary VASPs open their own envelope to perform
+// compliance checks, then forward the remaining envelopes to the next hop without
+// re-encrypting the payload.
+//
+// The set does not protect the payload from the intermediaries: every recipient holds
+// the encryption key and HMAC secret, so an intermediary can re-encrypt the payload,
+// compute a new HMAC, and re-seal the envelopes of the following hops, and the modified
+// set still passes Validate. The transfer path is not authenticated either and may be
+// rewritten by any hop. Intermediaries must therefore be trusted not to modify the set;
+// to bind the payload to the originator, the originator should Sign each companion
+// envelope so that the beneficiary can check it with VerifySignature.
+type Set struct {
+	ID           string
+	Envelopes    map[string]*api.SecureEnvelope
+	TransferPath *ivms101.TransferPath
+}
+
+// SealFor encrypts the payload once and seals the encryption key and HMAC secret for
+// each of the recipients, returning the set of companion envelopes. This method returns
+// a rejection error if the payload could not be encrypted or sealed and an error for
+// the user to handle otherwise.
+func SealFor(payload *api.Payload, recipients []Recipient, opts ...Option) (set *Set, reject *api.Error, err error) {
+	var env *Envelope
+	if env, err = New(payload, opts...); err != nil {
+		return nil, nil, err
+	}
+
+	if env, reject, err = env.Encrypt(); err != nil {
+		return nil, reject, err
+	}
+	return env.SealFor(recipients...)
+}
+
+// SealFor seals the encrypted envelope for each of the recipients. The envelope must
+// be in the unsealed state, e.g. it has been encrypted but not sealed.
+func (e *Envelope) SealFor(recipients ...Recipient) (set *Set, reject *api.Error, err error) {
+	if len(recipients) == 0 {
+		return nil, nil, ErrNoRecipients
+	}
+
+	set = &Set{
+		ID:           e.msg.Id,
+		Envelopes:    make(map[string]*api.SecureEnvelope, len(recipients)),
+		TransferPath: &ivms101.TransferPath{},
+	}
+
+	for _, recipient := range recipients {
+		if _, ok := set.Envelopes[recipient.Name]; ok {
+			return nil, nil, fmt.Errorf("%w: %q", ErrDuplicateRecipient, recipient.Name)
+		}
+
+		var sealed *Envelope
+		if sealed, reject, err = e.Seal(WithSealingKey(recipient.SealingKey)); err != nil {
+			return nil, reject, fmt.Errorf("could not seal envelope for %q: %w", recipient.Name, err)
+		}
+		set.Envelopes[recipient.Name] = sealed.Proto()
+	}
+	return set, nil, nil
+}
+
+// Recipients returns the names of the recipients in the set in sorted order.
+func (s *Set) Recipients() []string {
+	names := make([]string, 0, len(s.Envelopes))
+	for name := range s.Envelopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Envelope returns a copy of the secure envelope sealed for the recipient, e.g. to
+// transfer it to the recipient using the TRISA protocol.
+func (s *Set) Envelope(recipient string) (*api.SecureEnvelope, error) {
+	msg, ok := s.Envelopes[recipient]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRecipient, recipient)
+	}
+	return proto.Clone(msg).(*api.SecureEnvelope), nil
+}
+
+// Open the envelope sealed for the recipient with its private key, decrypting the
+// payload and verifying the HMAC signature.
+func (s *Set) Open(recipient string, opts ...Option) (env *Envelope, reject *api.Error, err error) {
+	var msg *api.SecureEnvelope
+	if msg, err = s.Envelope(recipient); err != nil {
+		return nil, nil, err
+	}
+	return Open(msg, opts...)
+}
+
+// Forward returns the set that the recipient passes on to the next hop: its own
+// envelope is removed and the VASP is appended to the transfer path. The envelopes of
+// the remaining recipients are forwarded without modification. The original set is not
+// modified. ErrNoRecipients is returned if there is no one left to forward the set to.
+func (s *Set) Forward(recipient string, vasp *ivms101.Person) (fwd *Set, err error) {
+	if _, ok := s.Envelopes[recipient]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRecipient, recipient)
+	}
+
+	if len(s.Envelopes) == 1 {
+		return nil, ErrNoRecipients
+	}
+
+	fwd = &Set{
+		ID:           s.ID,
+		Envelopes:    make(map[string]*api.SecureEnvelope, len(s.Envelopes)-1),
+		TransferPath: &ivms101.TransferPath{},
+	}
+
+	for name, msg := range s.Envelopes {
+		if name != recipient {
+			fwd.Envelopes[name] = proto.Clone(msg).(*api.SecureEnvelope)
+		}
+	}
+
+	if s.TransferPath != nil {
+		fwd.TransferPath = proto.Clone(s.TransferPath).(*ivms101.TransferPath)
+	}
+
+	if vasp != nil {
+		fwd.TransferPath.Append(vasp)
+	}
+	return fwd, nil
+}
+
+// Validate that the companion envelopes are sealed and share the same envelope ID,
+// encrypted payload, and HMAC signature. A recipient must validate the set before
+// forwarding it to ensure that all recipients receive the same payload. Validate does
+// not establish who created the payload, see Set for the trust assumptions.
+func (s *Set) Validate() error {
+	if len(s.Envelopes) == 0 {
+		return ErrNoRecipients
+	}
+
+	// All envelopes are compared to the first envelope in the set
+	names := s.Recipients()
+	first := s.Envelopes[names[0]]
+	for _, name := range names {
+		msg := s.Envelopes[name]
+		switch {
+		case msg == nil || !msg.Sealed:
+			return fmt.Errorf("%w: envelope for %q is not sealed", ErrMismatchedSet, name)
+		case msg.Id != s.ID:
+			return fmt.Errorf("%w: envelope for %q has id %q", ErrMismatchedSet, name, msg.Id)
+		}
+
+		if !bytes.Equal(msg.Payload, first.Payload) || !bytes.Equal(msg.Hmac, first.Hmac) ||
+			msg.EncryptionAlgorithm != first.EncryptionAlgorithm || msg.HmacAlgorithm != first.HmacAlgorithm {
+			return fmt.Errorf("%w: envelope for %q has a different payload", ErrMismatchedSet, name)
+		}
+	}
+	return nil
+}
+
+type serialSet struct {
+	ID           string                     `json:"id"`
+	Envelopes    map[string]json.RawMessage `json:"envelopes"`
+	TransferPath *ivms101.TransferPath      `json:"transfer_path,omitempty"`
+}
+
+// MarshalJSON serializes the secure envelopes of the set using protocol buffer JSON.
+func (s *Set) MarshalJSON() (_ []byte, err error) {
+	middle := serialSet{
+		ID:           s.ID,
+		Envelopes:    make(map[string]json.RawMessage, len(s.Envelopes)),
+		TransferPath: s.TransferPath,
+	}
+
+	for name, msg := range s.Envelopes {
+		if middle.Envelopes[name], err = protojson.Marshal(msg); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(middle)
+}
+
+// UnmarshalJSON parses a set serialized with MarshalJSON.
+func (s *Set) UnmarshalJSON(data []byte) (err error) {
+	middle := serialSet{}
+	if err = json.Unmarshal(data, &middle); err != nil {
+		return err
+	}
+
+	s.ID = middle.ID
+	s.TransferPath = middle.TransferPath
+	s.Envelopes = make(map[string]*api.SecureEnvelope, len(middle.Envelopes))
+	for name, raw := range middle.Envelopes {
+		msg := &api.SecureEnvelope{}
+		if err = protojson.Unmarshal(raw, msg); err != nil {
+			return fmt.Errorf("could not parse envelope for %q: %w", name, err)
+		}
+		s.Envelopes[name] = msg
+	}
+	return nil
+}
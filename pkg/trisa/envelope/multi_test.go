@@ -0,0 +1,205 @@
+package envelope_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"google.golang.org/protobuf/proto"
+)
+
+// mockPeer is a VASP in a multi-hop transfer that receives the serialized envelope set
+// from the previous hop, opens its own envelope, and forwards the rest of the set.
+type mockPeer struct {
+	name string
+	key  *rsa.PrivateKey
+	vasp *ivms101.Person
+}
+
+func newMockPeer(t *testing.T, name string) *mockPeer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	vasp := &ivms101.Person{
+		Person: &ivms101.Person_LegalPerson{
+			LegalPerson: &ivms101.LegalPerson{
+				Name: &ivms101.LegalPersonName{
+					NameIdentifiers: []*ivms101.LegalPersonNameId{
+						{LegalPersonName: name, LegalPersonNameIdentifierType: ivms101.LegalPersonLegal},
+					},
+				},
+			},
+		},
+	}
+	return &mockPeer{name: name, key: key, vasp: vasp}
+}
+
+func (p *mockPeer) Recipient() envelope.Recipient {
+	return envelope.Recipient{Name: p.name, SealingKey: &p.key.PublicKey}
+}
+
+// Receive the set, open the envelope sealed for this peer, and return the payload.
+func (p *mockPeer) Receive(t *testing.T, data []byte) (*envelope.Set, *api.Payload) {
+	set := &envelope.Set{}
+	require.NoError(t, json.Unmarshal(data, set))
+	require.NoError(t, set.Validate())
+
+	env, reject, err := set.Open(p.name, envelope.WithRSAPrivateKey(p.key))
+	require.NoError(t, err, "could not open envelope for %s", p.name)
+	require.Nil(t, reject)
+
+	payload, err := env.Payload()
+	require.NoError(t, err)
+	return set, payload
+}
+
+// Forward the set to the next hop without re-encrypting.
+func (p *mockPeer) Forward(t *testing.T, set *envelope.Set) []byte {
+	fwd, err := set.Forward(p.name, p.vasp)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(fwd)
+	require.NoError(t, err)
+	return data
+}
+
+func TestMultiRecipient(t *testing.T) {
+	payload, err := loadPayloadFixture("testdata/payload.json")
+	require.NoError(t, err, "could not load payload fixture")
+
+	first := newMockPeer(t, "first.example.com")
+	second := newMockPeer(t, "second.example.com")
+	beneficiary := newMockPeer(t, "beneficiary.example.com")
+
+	set, reject, err := envelope.SealFor(payload, []envelope.Recipient{first.Recipient(), second.Recipient(), beneficiary.Recipient()})
+	require.NoError(t, err)
+	require.Nil(t, reject)
+	require.NoError(t, set.Validate())
+	require.Equal(t, []string{beneficiary.name, first.name, second.name}, set.Recipients())
+
+	original, err := set.Envelope(beneficiary.name)
+	require.NoError(t, err)
+
+	// Each recipient can only open its own envelope
+	_, _, err = set.Open(second.name, envelope.WithRSAPrivateKey(first.key))
+	require.Error(t, err)
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	// Route the set through both intermediaries to the beneficiary
+	for _, peer := range []*mockPeer{first, second} {
+		var received *api.Payload
+		set, received = peer.Receive(t, data)
+		require.True(t, proto.Equal(payload, received), "payload mismatch at %s", peer.name)
+		data = peer.Forward(t, set)
+	}
+
+	set, received := beneficiary.Receive(t, data)
+	require.True(t, proto.Equal(payload, received), "payload mismatch at beneficiary")
+	require.Equal(t, []string{beneficiary.name}, set.Recipients())
+
+	// The intermediaries forwarded the envelope of the beneficiary without modification
+	forwarded, err := set.Envelope(beneficiary.name)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(original, forwarded))
+
+	// The transfer path was built by the intermediaries while forwarding
+	require.Len(t, set.TransferPath.TransferPath, 2)
+	for i, peer := range []*mockPeer{first, second} {
+		hop := set.TransferPath.TransferPath[i]
+		require.Equal(t, uint64(i+1), hop.Sequence)
+		require.True(t, proto.Equal(peer.vasp, hop.IntermediaryVasp))
+	}
+	require.NoError(t, set.TransferPath.Validate())
+
+	// The beneficiary is the final hop
+	_, err = set.Forward(beneficiary.name, beneficiary.vasp)
+	require.ErrorIs(t, err, envelope.ErrNoRecipients)
+}
+
+func TestInvalidSet(t *testing.T) {
+	payload, err := loadPayloadFixture("testdata/payload.json")
+	require.NoError(t, err, "could not load payload fixture")
+
+	alice := newMockPeer(t, "alice.example.com")
+	bob := newMockPeer(t, "bob.example.com")
+
+	_, _, err = envelope.SealFor(payload, nil)
+	require.ErrorIs(t, err, envelope.ErrNoRecipients)
+
+	_, _, err = envelope.SealFor(payload, []envelope.Recipient{alice.Recipient(), alice.Recipient()})
+	require.ErrorIs(t, err, envelope.ErrDuplicateRecipient)
+
+	set, _, err := envelope.SealFor(payload, []envelope.Recipient{alice.Recipient(), bob.Recipient()})
+	require.NoError(t, err)
+
+	_, err = set.Envelope("eve.example.com")
+	require.ErrorIs(t, err, envelope.ErrUnknownRecipient)
+
+	_, err = set.Forward("eve.example.com", nil)
+	require.ErrorIs(t, err, envelope.ErrUnknownRecipient)
+
+	// An intermediary must not be able to swap the payload of another recipient
+	other, _, err := envelope.SealFor(payload, []envelope.Recipient{bob.Recipient()}, envelope.WithEnvelopeID(set.ID))
+	require.NoError(t, err)
+	set.Envelopes[bob.name] = other.Envelopes[bob.name]
+	require.ErrorIs(t, set.Validate(), envelope.ErrMismatchedSet)
+}
+
+func TestSignedSet(t *testing.T) {
+	payload, err := loadPayloadFixture("testdata/payload.json")
+	require.NoError(t, err, "could not load payload fixture")
+
+	originator := newMockPeer(t, "originator.example.com")
+	intermediary := newMockPeer(t, "intermediary.example.com")
+	beneficiary := newMockPeer(t, "beneficiary.example.com")
+
+	cert := newCertificate(t, originator.key)
+	signer, err := envelope.NewSigner(newProvider(t, cert, originator.key))
+	require.NoError(t, err)
+
+	set, _, err := envelope.SealFor(payload, []envelope.Recipient{intermediary.Recipient(), beneficiary.Recipient()})
+	require.NoError(t, err)
+
+	// The originator signs every companion envelope, the recipient keys are not signed
+	for name, msg := range set.Envelopes {
+		env, err := envelope.Wrap(msg)
+		require.NoError(t, err)
+		env, err = env.Sign(signer)
+		require.NoError(t, err)
+		set.Envelopes[name] = env.Proto()
+	}
+	require.NoError(t, set.Validate())
+
+	fwd, err := set.Forward(intermediary.name, intermediary.vasp)
+	require.NoError(t, err)
+
+	env, err := envelope.Wrap(fwd.Envelopes[beneficiary.name])
+	require.NoError(t, err)
+	require.NoError(t, env.VerifySignature(cert), "forwarded envelope should be signed by the originator")
+
+	// An intermediary holds the keys to re-encrypt and re-seal a modified payload that
+	// is still a valid set, but it cannot produce the signature of the originator.
+	modified := proto.Clone(payload).(*api.Payload)
+	modified.SentAt = "2024-01-01T00:00:00Z"
+	forged, _, err := envelope.SealFor(modified, []envelope.Recipient{beneficiary.Recipient()}, envelope.WithEnvelopeID(set.ID))
+	require.NoError(t, err)
+	require.NoError(t, forged.Validate())
+
+	msg := forged.Envelopes[beneficiary.name]
+	env, err = envelope.Wrap(msg)
+	require.NoError(t, err)
+	require.ErrorIs(t, env.VerifySignature(cert), envelope.ErrNotSigned)
+
+	original := fwd.Envelopes[beneficiary.name]
+	msg.Signature, msg.SignatureAlgorithm, msg.Signer = original.Signature, original.SignatureAlgorithm, original.Signer
+	env, err = envelope.Wrap(msg)
+	require.NoError(t, err)
+	require.ErrorIs(t, env.VerifySignature(cert), envelope.ErrInvalidSignature)
+}
@@ -112,6 +112,16 @@ func WithUnsealingKey(key interface{}) Option {
 	}
 }
 
+// WithReplayGuard rejects stale or replayed envelopes when they are opened with Open or
+// OpenPayload. The guard is not applied when envelopes are unsealed and decrypted
+// directly.
+func WithReplayGuard(guard ReplayGuard) Option {
+	return func(e *Envelope) error {
+		e.guard = guard
+		return nil
+	}
+}
+
 func WithRSAPublicKey(key *rsa.PublicKey) Option {
 	return WithSealingKey(key)
 }
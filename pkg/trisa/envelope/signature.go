@@ -0,0 +1,206 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trust"
+	"google.golang.org/protobuf/proto"
+)
+
+//===========================================================================
+// Envelope Signatures
+//===========================================================================
+
+// Signature algorithms supported for detached envelope signatures.
+const (
+	SignatureRSAPSS = "RSA-PSS-SHA256"
+	SignatureECDSA  = "ECDSA-SHA256"
+)
+
+// Domain separation for the signing input so that envelope signatures cannot be
+// confused with signatures created by the identity key for other purposes.
+const signatureContext = "trisa.api.v1beta1.SecureEnvelope.signature"
+
+// Signer creates detached envelope signatures with the private key of the sender's
+// identity certificate so that the recipient and regulators can verify which VASP
+// authored an envelope. The signer is identified on the envelope by the SHA-256
+// thumbprint of the certificate.
+type Signer struct {
+	key        crypto.Signer
+	algorithm  string
+	thumbprint string
+}
+
+// NewSigner creates a signer from a trust provider that contains a private key. RSA
+// keys are signed with RSA-PSS and ECDSA keys with ECDSA, both using SHA-256.
+func NewSigner(provider *trust.Provider) (_ *Signer, err error) {
+	if !provider.IsPrivate() {
+		return nil, trust.ErrKeyRequired
+	}
+
+	var cert *x509.Certificate
+	if cert, err = provider.GetLeafCertificate(); err != nil {
+		return nil, err
+	}
+
+	signer := &Signer{thumbprint: Thumbprint(cert)}
+	switch key := provider.GetKey().(type) {
+	case *rsa.PrivateKey:
+		signer.key, signer.algorithm = key, SignatureRSAPSS
+	case *ecdsa.PrivateKey:
+		signer.key, signer.algorithm = key, SignatureECDSA
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedSignature, key)
+	}
+	return signer, nil
+}
+
+// Algorithm returns the signature algorithm used by the signer.
+func (s *Signer) Algorithm() string {
+	return s.algorithm
+}
+
+// Thumbprint returns the thumbprint of the signing certificate.
+func (s *Signer) Thumbprint() string {
+	return s.thumbprint
+}
+
+// Sign the envelope with the identity key of the sender. Because the ordering
+// timestamp is signed, the envelope should be signed after it has been sealed and
+// immediately before it is sent. A signature may only be added to a sealed envelope or
+// an error envelope. The original envelope is not modified, the secure envelope is cloned.
+func (e *Envelope) Sign(signer *Signer) (env *Envelope, err error) {
+	state := e.State()
+	if state != Sealed && state != SealedError && state != Error {
+		return nil, fmt.Errorf("cannot sign envelope from %q state", state)
+	}
+
+	env = &Envelope{
+		msg:    proto.Clone(e.msg).(*api.SecureEnvelope),
+		crypto: e.crypto,
+		seal:   e.seal,
+		parent: e,
+	}
+
+	env.msg.SignatureAlgorithm = signer.algorithm
+	env.msg.Signer = signer.thumbprint
+
+	var digest []byte
+	if digest, err = signingDigest(env.msg); err != nil {
+		return nil, err
+	}
+
+	var opts crypto.SignerOpts = crypto.SHA256
+	if signer.algorithm == SignatureRSAPSS {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+
+	if env.msg.Signature, err = signer.key.Sign(rand.Reader, digest, opts); err != nil {
+		return nil, fmt.Errorf("could not sign envelope: %w", err)
+	}
+	return env, nil
+}
+
+// IsSigned returns true if the envelope has a detached signature.
+func (e *Envelope) IsSigned() bool {
+	return len(e.msg.Signature) > 0
+}
+
+// VerifySignature checks the detached signature of the envelope using the public key
+// of the sender's identity certificate, e.g. as returned by a directory lookup. The
+// thumbprint of the certificate must match the signer on the envelope. Returns
+// ErrNotSigned if the envelope does not have a signature.
+func (e *Envelope) VerifySignature(cert *x509.Certificate) (err error) {
+	if !e.IsSigned() {
+		return ErrNotSigned
+	}
+
+	if cert == nil {
+		return ErrNoSignerCertificate
+	}
+
+	if e.msg.Signer != Thumbprint(cert) {
+		return ErrSignerMismatch
+	}
+
+	var digest []byte
+	if digest, err = signingDigest(e.msg); err != nil {
+		return err
+	}
+
+	switch e.msg.SignatureAlgorithm {
+	case SignatureRSAPSS:
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: certificate does not have an rsa public key", ErrInvalidSignature)
+		}
+
+		if err = rsa.VerifyPSS(pub, crypto.SHA256, digest, e.msg.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}); err != nil {
+			return ErrInvalidSignature
+		}
+	case SignatureECDSA:
+		pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: certificate does not have an ecdsa public key", ErrInvalidSignature)
+		}
+
+		if !ecdsa.VerifyASN1(pub, digest, e.msg.Signature) {
+			return ErrInvalidSignature
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedSignature, e.msg.SignatureAlgorithm)
+	}
+	return nil
+}
+
+// Thumbprint returns the base64 URL encoded SHA-256 thumbprint of the certificate,
+// which identifies the signer of an envelope.
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Computes the SHA-256 digest of the signed fields of the envelope. Every field is
+// length prefixed so that bytes cannot be shifted between fields. The sealed keys and
+// public key signature are not signed since they are specific to the recipient.
+func signingDigest(msg *api.SecureEnvelope) (_ []byte, err error) {
+	var rejection []byte
+	if msg.Error != nil && !msg.Error.IsZero() {
+		if rejection, err = (proto.MarshalOptions{Deterministic: true}).Marshal(msg.Error); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fields := [][]byte{
+		[]byte(signatureContext),
+		[]byte(msg.Id),
+		msg.Payload,
+		[]byte(msg.EncryptionAlgorithm),
+		msg.Hmac,
+		[]byte(msg.HmacAlgorithm),
+		rejection,
+		[]byte(msg.Timestamp),
+		binary.BigEndian.AppendUint32(nil, uint32(msg.TransferState)),
+		[]byte(msg.SignatureAlgorithm),
+		[]byte(msg.Signer),
+	}
+
+	for _, field := range fields {
+		binary.Write(&buf, binary.BigEndian, uint64(len(field)))
+		buf.Write(field)
+	}
+
+	digest := sha256.Sum256(buf.Bytes())
+	return digest[:], nil
+}
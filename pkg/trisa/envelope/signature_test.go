@@ -0,0 +1,167 @@
+package envelope_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trust"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignature(t *testing.T) {
+	payload, err := loadPayloadFixture("testdata/payload.json")
+	require.NoError(t, err, "could not load payload fixture")
+
+	recipient, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	msg, reject, err := envelope.SealPayload(payload, envelope.WithRSAPublicKey(&recipient.PublicKey))
+	require.NoError(t, err)
+	require.Nil(t, reject)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		key       crypto.Signer
+		algorithm string
+	}{
+		{"RSA", rsaKey, envelope.SignatureRSAPSS},
+		{"ECDSA", ecKey, envelope.SignatureECDSA},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cert := newCertificate(t, tc.key)
+			signer, err := envelope.NewSigner(newProvider(t, cert, tc.key))
+			require.NoError(t, err)
+			require.Equal(t, tc.algorithm, signer.Algorithm())
+			require.Equal(t, envelope.Thumbprint(cert), signer.Thumbprint())
+
+			env, err := envelope.Wrap(msg)
+			require.NoError(t, err)
+			require.False(t, env.IsSigned())
+			require.ErrorIs(t, env.VerifySignature(cert), envelope.ErrNotSigned)
+
+			signed, err := env.Sign(signer)
+			require.NoError(t, err)
+			require.True(t, signed.IsSigned())
+			require.False(t, env.IsSigned(), "original envelope should not be modified")
+			require.Equal(t, tc.algorithm, signed.Proto().SignatureAlgorithm)
+			require.NoError(t, signed.VerifySignature(cert))
+
+			// The signed envelope can still be opened by the recipient
+			opened, reject, err := envelope.OpenPayload(proto.Clone(signed.Proto()).(*api.SecureEnvelope), envelope.WithRSAPrivateKey(recipient))
+			require.NoError(t, err)
+			require.Nil(t, reject)
+			require.True(t, proto.Equal(payload, opened))
+
+			// The signature must be verified with the certificate of the signer
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			require.NoError(t, err)
+			require.ErrorIs(t, signed.VerifySignature(newCertificate(t, other)), envelope.ErrSignerMismatch)
+			require.ErrorIs(t, signed.VerifySignature(nil), envelope.ErrNoSignerCertificate)
+
+			// Any modification of the signed fields invalidates the signature
+			tamper := []func(*api.SecureEnvelope){
+				func(m *api.SecureEnvelope) { m.Id = "d8e5ef91-e02b-4d3e-ae2f-0d6ee5d7a2c4" },
+				func(m *api.SecureEnvelope) { m.Payload[0] ^= 0xff },
+				func(m *api.SecureEnvelope) { m.Hmac[0] ^= 0xff },
+				func(m *api.SecureEnvelope) { m.Timestamp = time.Now().Add(time.Hour).Format(time.RFC3339Nano) },
+				func(m *api.SecureEnvelope) { m.TransferState = api.TransferRejected },
+				func(m *api.SecureEnvelope) { m.Error = &api.Error{Code: api.ComplianceCheckFail, Message: "forged"} },
+				func(m *api.SecureEnvelope) { m.Signature[0] ^= 0xff },
+			}
+
+			for i, modify := range tamper {
+				forged := proto.Clone(signed.Proto()).(*api.SecureEnvelope)
+				modify(forged)
+				require.ErrorIs(t, envelope.VerifySignature(forged, cert), envelope.ErrInvalidSignature, "tamper case %d was not detected", i)
+			}
+
+			// Replacing the sealed keys does not invalidate the signature since they are
+			// specific to the recipient and are authenticated by the hmac.
+			resealed := proto.Clone(signed.Proto()).(*api.SecureEnvelope)
+			resealed.EncryptionKey = []byte("foo")
+			require.NoError(t, envelope.VerifySignature(resealed, cert))
+
+			// Downgrading the signature algorithm is not possible
+			downgrade := proto.Clone(signed.Proto()).(*api.SecureEnvelope)
+			downgrade.SignatureAlgorithm = "HMAC-SHA256"
+			require.ErrorIs(t, envelope.VerifySignature(downgrade, cert), envelope.ErrUnsupportedSignature)
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		cert := newCertificate(t, rsaKey)
+
+		// A provider without a private key cannot sign envelopes
+		chain, err := trust.PEMEncodeCertificate(cert)
+		require.NoError(t, err)
+		public, err := trust.New(chain)
+		require.NoError(t, err)
+		_, err = envelope.NewSigner(public)
+		require.ErrorIs(t, err, trust.ErrKeyRequired)
+
+		// Only sealed envelopes can be signed
+		signer, err := envelope.NewSigner(newProvider(t, cert, rsaKey))
+		require.NoError(t, err)
+
+		env, err := envelope.New(payload)
+		require.NoError(t, err)
+		_, err = env.Sign(signer)
+		require.Error(t, err)
+
+		// Rejections can be signed so the sender cannot deny them
+		reject, err := envelope.WrapError(&api.Error{Code: api.ComplianceCheckFail, Message: "sanctioned"})
+		require.NoError(t, err)
+		reject, err = reject.Sign(signer)
+		require.NoError(t, err)
+		require.NoError(t, reject.VerifySignature(cert))
+	})
+}
+
+// Creates a self-signed certificate for the key.
+func newCertificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "test.example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().AddDate(0, 0, 7),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err, "could not create certificate")
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// Creates a private provider from the certificate and key.
+func newProvider(t *testing.T, cert *x509.Certificate, key crypto.Signer) *trust.Provider {
+	chain, err := trust.PEMEncodeCertificate(cert)
+	require.NoError(t, err)
+
+	pk, err := trust.PEMEncodePrivateKey(key)
+	require.NoError(t, err)
+
+	provider, err := trust.New(append(chain, pk...))
+	require.NoError(t, err, "could not create provider")
+	return provider
+}
@@ -1,5 +1,7 @@
 package envelope
 
+import "fmt"
+
 type State uint16
 
 const (
@@ -23,3 +25,51 @@ func (s State) String() string {
 	}
 	return stateNames[idx]
 }
+
+// MarshalText allows the state to be serialized by its name, e.g. in JSON reports.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Direction of a secure envelope from the perspective of the local node.
+type Direction uint8
+
+const (
+	Incoming Direction = iota + 1 // the envelope was received from the remote peer
+	Outgoing                      // the envelope is being sent to the remote peer
+)
+
+// String returns a human readable representation of the direction.
+func (d Direction) String() string {
+	switch d {
+	case Incoming:
+		return "incoming"
+	case Outgoing:
+		return "outgoing"
+	default:
+		return fmt.Sprintf("Direction(%d)", d)
+	}
+}
+
+// MarshalText encodes the direction as a string for JSON serialization.
+func (d Direction) MarshalText() ([]byte, error) {
+	switch d {
+	case Incoming, Outgoing:
+		return []byte(d.String()), nil
+	default:
+		return nil, fmt.Errorf("cannot marshal unknown direction %d", d)
+	}
+}
+
+// UnmarshalText parses a direction that was encoded with MarshalText.
+func (d *Direction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "incoming":
+		*d = Incoming
+	case "outgoing":
+		*d = Outgoing
+	default:
+		return fmt.Errorf("cannot parse direction %q", text)
+	}
+	return nil
+}
@@ -159,6 +159,13 @@ func (c *Certificate) Marshal() (_ []byte, err error) {
 // the first certificate and ignore all others. An error is returned if multiple private
 // key blocks are detected in the PEM data.
 func (c *Certificate) Unmarshal(data []byte) (err error) {
+	return c.unmarshal(data, nil)
+}
+
+// Internal unmarshal method that can decrypt encrypted private key blocks if a
+// passphrase func is provided; otherwise ErrEncryptedKey is returned if an encrypted
+// private key block is found in the PEM data.
+func (c *Certificate) unmarshal(data []byte, passphrase PassphraseFunc) (err error) {
 	// Setup block parsing to unmarshal PEM encoded data.
 	chain := make([]*x509.Certificate, 0, 1)
 	keys := make([]interface{}, 0)
@@ -183,6 +190,21 @@ func (c *Certificate) Unmarshal(data []byte) (err error) {
 				return fmt.Errorf("could not parse block %s: %s", block.Type, err)
 			}
 			keys = append(keys, key)
+		case trust.BlockEncryptedKey:
+			if passphrase == nil {
+				return ErrEncryptedKey
+			}
+
+			var secret []byte
+			if secret, err = passphrase(); err != nil {
+				return err
+			}
+
+			var key interface{}
+			if key, err = trust.DecryptPKCS8PrivateKey(block.Bytes, secret); err != nil {
+				return err
+			}
+			keys = append(keys, key)
 		}
 	}
 
@@ -0,0 +1,128 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/pem"
+
+	"github.com/trisacrypto/trisa/pkg/trust"
+)
+
+// PassphraseFunc returns the passphrase used to encrypt or decrypt private keys at
+// rest. It is only called when a passphrase is actually needed, which allows command
+// line tools to lazily prompt the user for a passphrase.
+type PassphraseFunc func() ([]byte, error)
+
+// Passphrase returns a PassphraseFunc that always returns the specified passphrase.
+func Passphrase(passphrase string) PassphraseFunc {
+	return func() ([]byte, error) {
+		return []byte(passphrase), nil
+	}
+}
+
+// Encrypt wraps a Key so that its private key is encrypted with a passphrase when it
+// is marshaled. The key may be nil if the Encrypted wrapper is only used to unmarshal
+// keys from disk.
+func Encrypt(key Key, passphrase PassphraseFunc) *Encrypted {
+	return &Encrypted{Key: key, passphrase: passphrase}
+}
+
+// Unmarshal key data that was serialized using either a Key or an Encrypted marshaler,
+// detecting the format of the data. PEM encoded data is loaded as a Certificate, using
+// the passphrase to decrypt the private key if it is encrypted; otherwise the data is
+// loaded as a protocol buffer Exchange key. The passphrase may be nil if no encrypted
+// keys are expected, in which case ErrEncryptedKey is returned for encrypted keys.
+func Unmarshal(data []byte, passphrase PassphraseFunc) (_ Key, err error) {
+	key := Encrypt(nil, passphrase)
+	if err = key.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return key.Key, nil
+}
+
+// Encrypted implements the KeyMarshaler interface to store keys with an encrypted
+// private key at rest. Private keys are serialized as PKCS#8 ENCRYPTED PRIVATE KEY PEM
+// blocks using scrypt and AES-256-CBC (see trust.EncryptPKCS8PrivateKey). Public keys
+// are marshaled by the wrapped key since they do not need to be protected.
+type Encrypted struct {
+	Key
+	passphrase PassphraseFunc
+}
+
+// Ensure the Encrypted wrapper implements the Key interface.
+var _ Key = &Encrypted{}
+
+// Marshal returns a PEM encoded Certificate block along with an encrypted PEM private
+// key block if the wrapped key is private. If the key is not private, the key is
+// marshaled by the wrapped key without modification.
+func (e *Encrypted) Marshal() (_ []byte, err error) {
+	if e.Key == nil {
+		return nil, ErrNoCertificate
+	}
+
+	var (
+		ok   bool
+		cert *Certificate
+	)
+	if cert, ok = e.Key.(*Certificate); !ok || !cert.IsPrivate() {
+		return e.Key.Marshal()
+	}
+
+	if e.passphrase == nil {
+		return nil, trust.ErrPassphraseRequired
+	}
+
+	var passphrase []byte
+	if passphrase, err = e.passphrase(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err = pem.Encode(&b, &pem.Block{Type: trust.BlockCertificate, Bytes: cert.certs.Raw}); err != nil {
+		return nil, err
+	}
+
+	// Add a new line to separate the certificate and the private key
+	if _, err = b.WriteString("\n"); err != nil {
+		return nil, err
+	}
+
+	var block []byte
+	if block, err = trust.PEMEncodeEncryptedPrivateKey(cert.privateKey, passphrase); err != nil {
+		return nil, err
+	}
+
+	b.Write(block)
+	return b.Bytes(), nil
+}
+
+// Unmarshal detects the format of the key data and loads the wrapped key from it. If
+// the data is PEM encoded, a Certificate is unmarshaled, decrypting any encrypted
+// private key with the passphrase. Unencrypted private keys are also loaded so that
+// keys can be migrated to encrypted storage. Otherwise the data is unmarshaled as a
+// protocol buffer Exchange key.
+func (e *Encrypted) Unmarshal(data []byte) (err error) {
+	if len(data) == 0 {
+		return ErrNoKeyData
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		cert := &Certificate{}
+		if err = cert.unmarshal(data, e.passphrase); err != nil {
+			return err
+		}
+		e.Key = cert
+		return nil
+	}
+
+	exchange := &Exchange{}
+	if err = exchange.Unmarshal(data); err != nil {
+		return ErrUnknownKeyFormat
+	}
+	e.Key = exchange
+	return nil
+}
+
+// Unwrap returns the underlying key.
+func (e *Encrypted) Unwrap() Key {
+	return e.Key
+}
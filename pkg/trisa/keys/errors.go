@@ -10,4 +10,6 @@ var (
 	ErrNoPublicKey           = errors.New("no public keys found in PEM encoded data")
 	ErrTooManyBlocks         = errors.New("too many public key blocks found in PEM encoded data")
 	ErrNoKeyData             = errors.New("cannot parse public key from empty or nil data")
+	ErrEncryptedKey          = errors.New("private key is encrypted: a passphrase is required to unmarshal it")
+	ErrUnknownKeyFormat      = errors.New("could not detect the serialization format of the key data")
 )
@@ -14,12 +14,18 @@ import (
 
 // Config returns the standard TLS configuration for the TRISA network, loading the
 // certificate from the specified provider. Using this TLS configuration ensures that
-// all TRISA peer-to-peer connections are handled and verified correctly.
-func Config(server *trust.Provider, clients trust.ProviderPool) (_ *tls.Config, err error) {
+// all TRISA peer-to-peer connections are handled and verified correctly. By default the
+// Intermediate TLS profile is used, which can be changed by specifying options.
+func Config(server *trust.Provider, clients trust.ProviderPool, opts ...Option) (_ *tls.Config, err error) {
 	if !server.IsPrivate() {
 		return nil, errors.New("server provider must contain a private key to initialize TLS certs")
 	}
 
+	var o *options
+	if o, err = newOptions(opts...); err != nil {
+		return nil, err
+	}
+
 	var crt tls.Certificate
 	if crt, err = server.GetKeyPair(); err != nil {
 		return nil, err
@@ -30,50 +36,34 @@ func Config(server *trust.Provider, clients trust.ProviderPool) (_ *tls.Config,
 		return nil, err
 	}
 
-	return &tls.Config{
+	conf := &tls.Config{
 		Certificates: []tls.Certificate{crt},
-		MinVersion:   tls.VersionTLS12,
-		CurvePreferences: []tls.CurveID{
-			tls.CurveP521,
-			tls.CurveP384,
-			tls.CurveP256,
-		},
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
-		},
-		ClientAuth: tls.RequireAndVerifyClientCert,
-		ClientCAs:  pool,
-	}, nil
-}
-
-// ServerCreds returns the grpc.ServerOption to create a gRPC server with mTLS.
-func ServerCreds(server *trust.Provider, clients trust.ProviderPool) (_ grpc.ServerOption, err error) {
-	var conf *tls.Config
-	if conf, err = Config(server, clients); err != nil {
-		return nil, err
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
 	}
 
-	creds := credentials.NewTLS(conf)
-	return grpc.Creds(creds), nil
+	o.apply(conf, true)
+	return conf, nil
 }
 
-// ClientCreds returns the grpc.DialOption to create a gRPC client with mTLS.
-func ClientCreds(endpoint string, client *trust.Provider, servers trust.ProviderPool) (_ grpc.DialOption, err error) {
+// ClientConfig returns the TLS configuration for a client connecting to a TRISA peer,
+// loading the client certificate from the specified provider and verifying the server
+// with the certificates in the pool. The ServerName must be set on the configuration
+// before it is used unless it is used with ClientCreds. Clients use the Go TLS defaults
+// unless a profile is specified with the WithProfile option.
+func ClientConfig(client *trust.Provider, servers trust.ProviderPool, opts ...Option) (_ *tls.Config, err error) {
 	if !client.IsPrivate() {
 		return nil, errors.New("client provider must contain a private key to initialize TLS certs")
 	}
 
-	var crt tls.Certificate
-	if crt, err = client.GetKeyPair(); err != nil {
+	var o *options
+	if o, err = newOptions(opts...); err != nil {
 		return nil, err
 	}
 
-	var u *url.URL
-	if u, err = url.Parse(endpoint); err != nil {
-		return nil, fmt.Errorf("invalid endpoint: %q", err)
+	var crt tls.Certificate
+	if crt, err = client.GetKeyPair(); err != nil {
+		return nil, err
 	}
 
 	var pool *x509.CertPool
@@ -82,9 +72,37 @@ func ClientCreds(endpoint string, client *trust.Provider, servers trust.Provider
 	}
 
 	conf := &tls.Config{
-		ServerName:   u.Host,
 		Certificates: []tls.Certificate{crt},
 		RootCAs:      pool,
 	}
+
+	o.apply(conf, false)
+	return conf, nil
+}
+
+// ServerCreds returns the grpc.ServerOption to create a gRPC server with mTLS.
+func ServerCreds(server *trust.Provider, clients trust.ProviderPool, opts ...Option) (_ grpc.ServerOption, err error) {
+	var conf *tls.Config
+	if conf, err = Config(server, clients, opts...); err != nil {
+		return nil, err
+	}
+
+	creds := credentials.NewTLS(conf)
+	return grpc.Creds(creds), nil
+}
+
+// ClientCreds returns the grpc.DialOption to create a gRPC client with mTLS.
+func ClientCreds(endpoint string, client *trust.Provider, servers trust.ProviderPool, opts ...Option) (_ grpc.DialOption, err error) {
+	var conf *tls.Config
+	if conf, err = ClientConfig(client, servers, opts...); err != nil {
+		return nil, err
+	}
+
+	var u *url.URL
+	if u, err = url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %q", err)
+	}
+
+	conf.ServerName = u.Host
 	return grpc.WithTransportCredentials(credentials.NewTLS(conf)), nil
 }
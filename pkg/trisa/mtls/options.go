@@ -0,0 +1,163 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// Profile is a TLS policy that determines the minimum TLS version, cipher suites, and
+// curves that are negotiated for TRISA mTLS connections. The profiles are loosely based
+// on the Mozilla server side TLS recommendations.
+type Profile uint8
+
+const (
+	// Intermediate requires TLS 1.2 or later and only allows AEAD cipher suites with
+	// forward secrecy (ECDHE). This is the default profile.
+	Intermediate Profile = iota
+
+	// Modern requires TLS 1.3; cipher suites are not configurable and are all AEAD
+	// suites with forward secrecy.
+	Modern
+
+	// Legacy requires TLS 1.2 or later but also allows the RSA key exchange cipher
+	// suites without forward secrecy for compatibility with older TRISA peers. This
+	// profile should only be used when connecting to peers that cannot be upgraded.
+	Legacy
+)
+
+// String returns the name of the profile.
+func (p Profile) String() string {
+	switch p {
+	case Intermediate:
+		return "intermediate"
+	case Modern:
+		return "modern"
+	case Legacy:
+		return "legacy"
+	default:
+		return fmt.Sprintf("Profile(%d)", p)
+	}
+}
+
+// Option configures the TLS policy of the mTLS configuration.
+type Option func(o *options) error
+
+// WithProfile specifies the TLS policy profile to use. Servers use the Intermediate
+// profile by default; clients use the Go TLS defaults unless a profile is specified.
+func WithProfile(profile Profile) Option {
+	return func(o *options) error {
+		if profile > Legacy {
+			return fmt.Errorf("unknown tls profile %s", profile)
+		}
+		o.profile = profile
+		o.explicit = true
+		return nil
+	}
+}
+
+// WithALPN specifies the application protocols to negotiate in preference order. Note
+// that gRPC transport credentials always add "h2" to the negotiated protocols.
+func WithALPN(protocols ...string) Option {
+	return func(o *options) error {
+		o.alpn = protocols
+		return nil
+	}
+}
+
+// WithSessionTicketKeys specifies the keys used by servers to encrypt and decrypt TLS
+// session tickets; the first key is used to encrypt new tickets. Sharing keys between
+// servers allows clients to resume sessions across a cluster of TRISA nodes. If no keys
+// are specified, then session tickets are disabled. This option has no effect on
+// client configurations.
+func WithSessionTicketKeys(keys ...[32]byte) Option {
+	return func(o *options) error {
+		o.ticketKeys = keys
+		o.disableTickets = len(keys) == 0
+		return nil
+	}
+}
+
+// WithECDSA enables the ECDHE-ECDSA cipher suites so that certificates with ECDSA keys
+// can be used with TLS 1.2 connections. ECDSA certificates are always supported with
+// TLS 1.3 and therefore by the Modern profile.
+func WithECDSA() Option {
+	return func(o *options) error {
+		o.ecdsa = true
+		return nil
+	}
+}
+
+type options struct {
+	profile        Profile
+	explicit       bool
+	alpn           []string
+	ticketKeys     [][32]byte
+	disableTickets bool
+	ecdsa          bool
+}
+
+func newOptions(opts ...Option) (o *options, err error) {
+	o = &options{profile: Intermediate}
+	for _, opt := range opts {
+		if err = opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// Apply the TLS policy to the configuration. The profile is only applied to client
+// configurations if it was explicitly selected so that clients negotiate with the Go
+// defaults, which support both RSA and ECDSA keyed peers.
+func (o *options) apply(conf *tls.Config, server bool) {
+	if server || o.explicit {
+		o.applyProfile(conf)
+	}
+
+	if len(o.alpn) > 0 {
+		conf.NextProtos = o.alpn
+	}
+
+	if server {
+		if len(o.ticketKeys) > 0 {
+			conf.SetSessionTicketKeys(o.ticketKeys)
+		}
+		conf.SessionTicketsDisabled = o.disableTickets
+	}
+}
+
+// Set the minimum version, curves, and cipher suites of the profile.
+func (o *options) applyProfile(conf *tls.Config) {
+	switch o.profile {
+	case Modern:
+		conf.MinVersion = tls.VersionTLS13
+		conf.CurvePreferences = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}
+		conf.CipherSuites = nil
+	case Intermediate:
+		conf.MinVersion = tls.VersionTLS12
+		conf.CurvePreferences = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+		conf.CipherSuites = []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		}
+	case Legacy:
+		conf.MinVersion = tls.VersionTLS12
+		conf.CurvePreferences = []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256, tls.X25519}
+		conf.CipherSuites = []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		}
+	}
+
+	if o.ecdsa && conf.CipherSuites != nil {
+		conf.CipherSuites = append(conf.CipherSuites,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		)
+	}
+}
@@ -0,0 +1,235 @@
+package mtls_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/trisa/mtls"
+	"github.com/trisacrypto/trisa/pkg/trust"
+)
+
+func TestProfiles(t *testing.T) {
+	certs := newTestCerts(t)
+
+	testCases := []struct {
+		name    string
+		server  []mtls.Option
+		client  []mtls.Option
+		modify  func(*tls.Config)
+		key     string
+		version uint16
+		err     bool
+	}{
+		{"intermediate default", nil, nil, nil, "rsa", tls.VersionTLS13, false},
+		{"modern", []mtls.Option{mtls.WithProfile(mtls.Modern)}, []mtls.Option{mtls.WithProfile(mtls.Modern)}, nil, "rsa", tls.VersionTLS13, false},
+		{"legacy", []mtls.Option{mtls.WithProfile(mtls.Legacy)}, []mtls.Option{mtls.WithProfile(mtls.Legacy)}, nil, "rsa", tls.VersionTLS13, false},
+		{"intermediate tls 1.2", nil, nil, maxTLS12, "rsa", tls.VersionTLS12, false},
+		{"modern rejects tls 1.2", []mtls.Option{mtls.WithProfile(mtls.Modern)}, nil, maxTLS12, "rsa", 0, true},
+		{"intermediate rejects non-pfs", nil, nil, rsaKeyExchange, "rsa", 0, true},
+		{"legacy allows non-pfs", []mtls.Option{mtls.WithProfile(mtls.Legacy)}, nil, rsaKeyExchange, "rsa", tls.VersionTLS12, false},
+		{"ecdsa tls 1.3", nil, nil, nil, "ecdsa", tls.VersionTLS13, false},
+		{"ecdsa tls 1.2 requires option", nil, nil, maxTLS12, "ecdsa", 0, true},
+		{"ecdsa tls 1.2", []mtls.Option{mtls.WithECDSA()}, []mtls.Option{mtls.WithECDSA()}, maxTLS12, "ecdsa", tls.VersionTLS12, false},
+		{"ecdsa tls 1.2 client defaults", []mtls.Option{mtls.WithECDSA()}, nil, maxTLS12, "ecdsa", tls.VersionTLS12, false},
+		{"ecdsa tls 1.2 client profile", []mtls.Option{mtls.WithECDSA()}, []mtls.Option{mtls.WithProfile(mtls.Intermediate)}, maxTLS12, "ecdsa", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			serverConf, err := mtls.Config(certs[tc.key], certs.pool(), tc.server...)
+			require.NoError(t, err)
+
+			clientConf, err := mtls.ClientConfig(certs[tc.key], certs.pool(), tc.client...)
+			require.NoError(t, err)
+			clientConf.ServerName = "localhost"
+			if tc.modify != nil {
+				tc.modify(clientConf)
+			}
+
+			state, err := handshake(serverConf, clientConf)
+			if tc.err {
+				require.Error(t, err, "expected handshake to fail")
+				return
+			}
+
+			require.NoError(t, err, "expected handshake to succeed")
+			require.Equal(t, tc.version, state.Version)
+		})
+	}
+}
+
+func TestALPN(t *testing.T) {
+	certs := newTestCerts(t)
+
+	serverConf, err := mtls.Config(certs["rsa"], certs.pool(), mtls.WithALPN("h2", "trisa/1"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"h2", "trisa/1"}, serverConf.NextProtos)
+
+	clientConf, err := mtls.ClientConfig(certs["rsa"], certs.pool(), mtls.WithALPN("trisa/1"))
+	require.NoError(t, err)
+	clientConf.ServerName = "localhost"
+
+	state, err := handshake(serverConf, clientConf)
+	require.NoError(t, err)
+	require.Equal(t, "trisa/1", state.NegotiatedProtocol)
+}
+
+func TestSessionTicketKeys(t *testing.T) {
+	certs := newTestCerts(t)
+
+	var key [32]byte
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	// Two servers that share session ticket keys
+	serverA, err := mtls.Config(certs["rsa"], certs.pool(), mtls.WithSessionTicketKeys(key))
+	require.NoError(t, err)
+	serverB, err := mtls.Config(certs["rsa"], certs.pool(), mtls.WithSessionTicketKeys(key))
+	require.NoError(t, err)
+
+	clientConf, err := mtls.ClientConfig(certs["rsa"], certs.pool())
+	require.NoError(t, err)
+	require.Nil(t, clientConf.CipherSuites, "clients should use the default cipher suites")
+	clientConf.ServerName = "localhost"
+	clientConf.MaxVersion = tls.VersionTLS12
+	clientConf.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+
+	state, err := handshake(serverA, clientConf)
+	require.NoError(t, err)
+	require.False(t, state.DidResume)
+
+	state, err = handshake(serverB, clientConf)
+	require.NoError(t, err)
+	require.True(t, state.DidResume, "expected session to be resumed with shared ticket keys")
+
+	// No keys disables session tickets
+	serverC, err := mtls.Config(certs["rsa"], certs.pool(), mtls.WithSessionTicketKeys())
+	require.NoError(t, err)
+	require.True(t, serverC.SessionTicketsDisabled)
+
+	_, err = mtls.Config(certs["rsa"], certs.pool(), mtls.WithProfile(mtls.Profile(42)))
+	require.Error(t, err, "expected unknown profile to fail")
+}
+
+func maxTLS12(conf *tls.Config) {
+	conf.MaxVersion = tls.VersionTLS12
+}
+
+func rsaKeyExchange(conf *tls.Config) {
+	conf.MaxVersion = tls.VersionTLS12
+	conf.CipherSuites = []uint16{tls.TLS_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_RSA_WITH_AES_128_GCM_SHA256}
+}
+
+// Perform a TLS handshake over an in-memory connection, returning the client state.
+func handshake(serverConf, clientConf *tls.Config) (_ tls.ConnectionState, err error) {
+	sconn, cconn := net.Pipe()
+	defer sconn.Close()
+	defer cconn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	sconn.SetDeadline(deadline)
+	cconn.SetDeadline(deadline)
+
+	errc := make(chan error, 1)
+	go func() {
+		server := tls.Server(sconn, serverConf)
+		err := server.Handshake()
+		if err == nil {
+			// Read to process client messages until the client closes the connection
+			server.Read(make([]byte, 1))
+		}
+		sconn.Close()
+		errc <- err
+	}()
+
+	client := tls.Client(cconn, clientConf)
+	if err = client.Handshake(); err != nil {
+		cconn.Close()
+		<-errc
+		return tls.ConnectionState{}, err
+	}
+
+	state := client.ConnectionState()
+	client.Close()
+	if err = <-errc; err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return state, nil
+}
+
+// Test certificates with a root CA and RSA and ECDSA leaf certificates for localhost.
+type testCerts map[string]*trust.Provider
+
+func (c testCerts) pool() trust.ProviderPool {
+	return trust.NewPool(c["rsa"], c["ecdsa"])
+}
+
+func newTestCerts(t *testing.T) testCerts {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return testCerts{
+		"rsa":   newLeaf(t, 2, "rsa.localhost", rsaKey, ca, caKey),
+		"ecdsa": newLeaf(t, 3, "ecdsa.localhost", ecKey, ca, caKey),
+	}
+}
+
+func newLeaf(t *testing.T, serial int64, name string, key crypto.Signer, ca *x509.Certificate, caKey crypto.Signer) *trust.Provider {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost", name},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, key.Public(), caKey)
+	require.NoError(t, err)
+
+	crt, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	leafPEM, err := trust.PEMEncodeCertificate(crt)
+	require.NoError(t, err)
+	caPEM, err := trust.PEMEncodeCertificate(ca)
+	require.NoError(t, err)
+	keyPEM, err := trust.PEMEncodePrivateKey(key)
+	require.NoError(t, err)
+
+	provider, err := trust.New(append(append(leafPEM, caPEM...), keyPEM...))
+	require.NoError(t, err)
+	return provider
+}
@@ -36,6 +36,7 @@ type PeerInfo struct {
 	CommonName          string
 	Endpoint            string
 	SigningKey          *rsa.PublicKey
+	IdentityCertificate *x509.Certificate
 }
 
 // SigningKey returns the current signing key of the remote peer, if it's available
@@ -47,6 +48,15 @@ func (p *Peer) SigningKey() *rsa.PublicKey {
 	return p.info.SigningKey
 }
 
+// IdentityCertificate returns the identity certificate of the remote peer from the
+// directory service if it's available (otherwise returns nil). The certificate is used
+// to verify detached signatures on secure envelopes sent by the peer.
+func (p *Peer) IdentityCertificate() *x509.Certificate {
+	p.RLock()
+	defer p.RUnlock()
+	return p.info.IdentityCertificate
+}
+
 // UpdateSigningKey if the key exchange was initiated from a remote TRISA peer.
 func (p *Peer) UpdateSigningKey(key interface{}) error {
 	p.Lock()
@@ -10,6 +10,8 @@ import (
 	"sync"
 	"time"
 
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
 	gds "github.com/trisacrypto/trisa/pkg/trisa/gds/api/v1beta1"
 	"github.com/trisacrypto/trisa/pkg/trust"
 	"google.golang.org/grpc"
@@ -55,7 +57,9 @@ func (p *Peers) Add(info *PeerInfo) (err error) {
 	// overwriting existing data. This means that this method will not correct bad data
 	// from a GDS Lookup but will always retain the original data. This could create a
 	// problem if the peer info is partially updated so callers should ensure that the
-	// info struct is always completely populated.
+	// info struct is always completely populated. The identity certificate is the
+	// exception: it is always replaced so that envelope signatures can be verified after
+	// the peer has rotated its certificate.
 	peer.Lock()
 	if peer.info.ID == "" && info.ID != "" {
 		peer.info.ID = info.ID
@@ -69,6 +73,9 @@ func (p *Peers) Add(info *PeerInfo) (err error) {
 	if peer.info.SigningKey == nil && info.SigningKey != nil {
 		peer.info.SigningKey = info.SigningKey
 	}
+	if info.IdentityCertificate != nil {
+		peer.info.IdentityCertificate = info.IdentityCertificate
+	}
 	peer.Unlock()
 	return nil
 }
@@ -184,6 +191,17 @@ func (p *Peers) Lookup(commonName string) (peer *Peer, err error) {
 		}
 	}
 
+	// Store the identity certificate to verify envelope signatures from the peer. If the
+	// directory only returned the public key of the certificate (as used for the signing
+	// key above) signatures cannot be verified, but any other data is an error.
+	if rep.IdentityCertificate != nil && len(rep.IdentityCertificate.Data) > 0 {
+		if info.IdentityCertificate, err = x509.ParseCertificate(rep.IdentityCertificate.Data); err != nil {
+			if _, perr := x509.ParsePKIXPublicKey(rep.IdentityCertificate.Data); perr != nil {
+				return nil, fmt.Errorf("could not parse identity certificate of %q: %w", commonName, err)
+			}
+		}
+	}
+
 	// Update the info on the peers
 	if err = p.Add(info); err != nil {
 		return nil, err
@@ -192,6 +210,34 @@ func (p *Peers) Lookup(commonName string) (peer *Peer, err error) {
 	return peer, nil
 }
 
+// VerifySignature checks the detached signature on a secure envelope received from the
+// remote peer against the identity certificate of the peer. If the certificate is not
+// cached, it is retrieved from the directory service using Lookup. If the envelope was
+// signed by a different certificate than the cached one, the peer may have rotated its
+// certificate, so the certificate is looked up again once before the signature fails.
+func (p *Peers) VerifySignature(commonName string, msg *api.SecureEnvelope) (err error) {
+	var peer *Peer
+	if peer, err = p.Get(commonName); err != nil {
+		return err
+	}
+
+	if peer.IdentityCertificate() == nil {
+		if peer, err = p.Lookup(commonName); err != nil {
+			return err
+		}
+		return envelope.VerifySignature(msg, peer.IdentityCertificate())
+	}
+
+	if err = envelope.VerifySignature(msg, peer.IdentityCertificate()); !errors.Is(err, envelope.ErrSignerMismatch) {
+		return err
+	}
+
+	if peer, err = p.Lookup(commonName); err != nil {
+		return err
+	}
+	return envelope.VerifySignature(msg, peer.IdentityCertificate())
+}
+
 // Search uses the directory service to find a remote peer by name
 func (p *Peers) Search(name string) (_ *Peer, err error) {
 	// Ensure we're connected to the directory service
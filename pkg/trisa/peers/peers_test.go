@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
 	apimock "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1/mock"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
 	gds "github.com/trisacrypto/trisa/pkg/trisa/gds/api/v1beta1"
 	gdsmock "github.com/trisacrypto/trisa/pkg/trisa/gds/api/v1beta1/mock"
 	models "github.com/trisacrypto/trisa/pkg/trisa/gds/models/v1beta1"
@@ -408,6 +409,86 @@ func TestSearch(t *testing.T) {
 	require.Equal(t, "donatello.example.com:443", donatello.Info().Endpoint)
 }
 
+// Test that envelope signatures are verified with the identity certificate of the peer.
+func TestVerifySignature(t *testing.T) {
+	cache, mgds, err := makePeersCache()
+	require.NoError(t, err, "could not create mocked peers cache")
+	defer mgds.Shutdown()
+
+	// The remote peer signs envelopes with the key of its identity certificate
+	certs, _, err := loadCertificates("testdata/server.pem")
+	require.NoError(t, err, "could not load server certificates")
+	leaf, err := certs.GetLeafCertificate()
+	require.NoError(t, err)
+
+	signer, err := envelope.NewSigner(certs)
+	require.NoError(t, err, "could not create envelope signer")
+
+	reply := &gds.LookupReply{}
+	require.NoError(t, loadGRPCFixture("testdata/leonardo.trisa.dev.pb.json", reply))
+	reply.IdentityCertificate = &models.Certificate{Data: leaf.Raw}
+	mgds.OnLookup = func(context.Context, *gds.LookupRequest) (*gds.LookupReply, error) {
+		return reply, nil
+	}
+
+	reject, err := envelope.Reject(&api.Error{Code: api.ComplianceCheckFail, Message: "sanctioned"})
+	require.NoError(t, err)
+	msg, err := envelope.Sign(reject, signer)
+	require.NoError(t, err)
+
+	// The identity certificate is retrieved from the directory the first time
+	require.NoError(t, cache.VerifySignature("leonardo.trisa.dev", msg))
+	require.Equal(t, 1, mgds.Calls[gdsmock.LookupRPC])
+
+	peer, err := cache.Get("leonardo.trisa.dev")
+	require.NoError(t, err)
+	require.True(t, leaf.Equal(peer.IdentityCertificate()), "identity certificate should be cached")
+
+	// Subsequent verifications use the cached certificate
+	require.NoError(t, cache.VerifySignature("leonardo.trisa.dev", msg))
+	require.Equal(t, 1, mgds.Calls[gdsmock.LookupRPC])
+
+	// A forged rejection cannot be attributed to the peer
+	forged := proto.Clone(msg).(*api.SecureEnvelope)
+	forged.Error.Message = "approved"
+	require.ErrorIs(t, cache.VerifySignature("leonardo.trisa.dev", forged), envelope.ErrInvalidSignature)
+
+	// Unsigned envelopes cannot be verified
+	require.ErrorIs(t, cache.VerifySignature("leonardo.trisa.dev", reject), envelope.ErrNotSigned)
+
+	// After the peer rotates its identity certificate the new certificate is looked up
+	rotated, _, err := loadCertificates("testdata/client.pem")
+	require.NoError(t, err, "could not load rotated certificates")
+	rotatedLeaf, err := rotated.GetLeafCertificate()
+	require.NoError(t, err)
+
+	rotatedSigner, err := envelope.NewSigner(rotated)
+	require.NoError(t, err, "could not create rotated envelope signer")
+	rotatedMsg, err := envelope.Sign(reject, rotatedSigner)
+	require.NoError(t, err)
+
+	reply.IdentityCertificate = &models.Certificate{Data: rotatedLeaf.Raw}
+	require.NoError(t, cache.VerifySignature("leonardo.trisa.dev", rotatedMsg))
+	require.Equal(t, 2, mgds.Calls[gdsmock.LookupRPC])
+	require.True(t, rotatedLeaf.Equal(peer.IdentityCertificate()), "rotated identity certificate should be cached")
+
+	// Envelopes signed with the retired certificate no longer verify
+	require.ErrorIs(t, cache.VerifySignature("leonardo.trisa.dev", msg), envelope.ErrSignerMismatch)
+	require.Equal(t, 3, mgds.Calls[gdsmock.LookupRPC])
+
+	// The envelope must have been signed by the peer
+	reply.CommonName = "donatello.example.com"
+	reply.IdentityCertificate, _, err = generateCertificate()
+	require.NoError(t, err)
+	require.ErrorIs(t, cache.VerifySignature("donatello.example.com", msg), envelope.ErrNoSignerCertificate)
+
+	// An identity certificate that cannot be parsed is returned as an error
+	reply.CommonName = "raphael.example.com"
+	reply.IdentityCertificate = &models.Certificate{Data: []byte("not a certificate")}
+	err = cache.VerifySignature("raphael.example.com", msg)
+	require.ErrorContains(t, err, "could not parse identity certificate")
+}
+
 // Helper function to create a new Peers manager (e.g. cached peers) connected to a mock
 // directory service for testing interactions with the directory service and TRISA network.
 func makePeersCache() (cache *peers.Peers, mgds *gdsmock.GDS, err error) {
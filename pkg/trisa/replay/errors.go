@@ -0,0 +1,9 @@
+package replay
+
+import "errors"
+
+var (
+	ErrInvalidWindow    = errors.New("replay window must be positive")
+	ErrInvalidRetention = errors.New("nonce retention must be at least twice the replay window")
+	ErrNoStore          = errors.New("a nonce store is required to guard against replays")
+)
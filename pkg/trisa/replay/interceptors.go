@@ -0,0 +1,91 @@
+package replay
+
+import (
+	"context"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"google.golang.org/grpc"
+)
+
+// UnaryInterceptor checks the secure envelope of incoming Transfer requests, returning
+// a rejection envelope to the counterparty without calling the handler if the envelope
+// is stale or a replay. If the handler returns an error or a rejection that asks the
+// counterparty to retry, the nonce is forgotten so that the envelope can be resent.
+func (g *Guard) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (rep interface{}, err error) {
+		msg, ok := req.(*api.SecureEnvelope)
+		if !ok || info.FullMethod != api.TRISANetwork_Transfer_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		if reject := g.Check(msg); reject != nil {
+			return g.rejection(msg.Id, reject), nil
+		}
+
+		if rep, err = handler(ctx, req); err != nil {
+			g.Forget(msg)
+			return rep, err
+		}
+
+		if out, ok := rep.(*api.SecureEnvelope); ok && out.Error != nil && out.Error.Retry {
+			g.Forget(msg)
+		}
+		return rep, nil
+	}
+}
+
+// StreamInterceptor checks every secure envelope received on the TransferStream RPC.
+// Stale or replayed envelopes are not delivered to the handler; instead a rejection
+// envelope is sent to the counterparty and the next envelope is received.
+func (g *Guard) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.FullMethod != api.TRISANetwork_TransferStream_FullMethodName {
+			return handler(srv, stream)
+		}
+		return handler(srv, &guardedStream{ServerStream: stream, guard: g})
+	}
+}
+
+type guardedStream struct {
+	grpc.ServerStream
+	guard *Guard
+}
+
+// RecvMsg receives the next envelope that passes the replay guard.
+func (s *guardedStream) RecvMsg(m interface{}) (err error) {
+	for {
+		if err = s.ServerStream.RecvMsg(m); err != nil {
+			return err
+		}
+
+		msg, ok := m.(*api.SecureEnvelope)
+		if !ok {
+			return nil
+		}
+
+		reject := s.guard.Check(msg)
+		if reject == nil {
+			return nil
+		}
+
+		if err = s.ServerStream.SendMsg(s.guard.rejection(msg.Id, reject)); err != nil {
+			return err
+		}
+	}
+}
+
+// Creates an error envelope to return the rejection to the counterparty.
+func (g *Guard) rejection(envelopeID string, reject *api.Error) *api.SecureEnvelope {
+	msg := &api.SecureEnvelope{
+		Id:            envelopeID,
+		Error:         reject,
+		Timestamp:     g.clock.Now().UTC().Format(time.RFC3339Nano),
+		TransferState: api.TransferRejected,
+	}
+
+	if reject.Retry {
+		msg.TransferState = api.TransferRepair
+	}
+	return msg
+}
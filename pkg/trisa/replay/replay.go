@@ -0,0 +1,212 @@
+/*
+Package replay protects TRISA nodes from replayed or stale secure envelopes. A Guard
+checks the ordering timestamp of every received envelope against a clock skew window
+and records a nonce composed of the envelope ID and HMAC so that an envelope cannot be
+received twice. The envelope ID alone cannot be used as a nonce because every message
+in an exchange shares the same envelope ID; the HMAC is unique to each payload.
+
+The ordering timestamp is not covered by the HMAC, so anyone who intercepts an envelope
+can resend it with a current timestamp once the original timestamp has left the window.
+Nonces are therefore retained for a fixed period after the envelope was received
+(DefaultRetention unless otherwise specified) rather than for the window after the
+timestamp claimed by the sender; an envelope replayed after its nonce has been expired
+will be accepted again. Rejections are returned as *api.Error so that they can be sent
+directly back to the counterparty.
+
+A guard can be applied when opening envelopes with the envelope.WithReplayGuard option
+or to all incoming envelopes on a TRISA server using the UnaryInterceptor and
+StreamInterceptor. The same guard should not be used both as an envelope option and as
+an interceptor on the same server, otherwise every envelope will be rejected as a
+replay when it is opened.
+*/
+package replay
+
+import (
+	"encoding/base64"
+	"sync"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+)
+
+// DefaultWindow is the maximum clock skew between the envelope timestamp and the local
+// clock unless otherwise specified.
+const DefaultWindow = 5 * time.Minute
+
+// DefaultRetention is how long the nonce of an envelope is retained after it was
+// received unless otherwise specified.
+const DefaultRetention = 24 * time.Hour
+
+// Guard rejects secure envelopes whose timestamps are outside of the clock skew window
+// or that have already been received. It is safe for concurrent use.
+type Guard struct {
+	sync.Mutex
+	window    time.Duration
+	retention time.Duration
+	store     Store
+	clock     clock.Clock
+	expired   time.Time
+}
+
+var _ envelope.ReplayGuard = &Guard{}
+
+// New creates a replay guard with an in-memory nonce store and the default window and
+// retention period.
+func New(opts ...Option) (g *Guard, err error) {
+	g = &Guard{
+		window:    DefaultWindow,
+		retention: DefaultRetention,
+		clock:     clock.System{},
+	}
+
+	for _, opt := range opts {
+		if err = opt(g); err != nil {
+			return nil, err
+		}
+	}
+
+	// An unmodified envelope is accepted until its timestamp is a window in the past,
+	// which may be up to twice the window after it was first received.
+	if g.retention < 2*g.window {
+		return nil, ErrInvalidRetention
+	}
+
+	if g.store == nil {
+		g.store = NewMemoryStore()
+	}
+	return g, nil
+}
+
+// Check that the envelope timestamp is within the clock skew window and that the
+// envelope has not been received before, recording the envelope nonce. Envelopes
+// without an HMAC (e.g. rejections and attachment segments) are only checked for
+// freshness. A rejection is returned if the envelope is stale or a replay.
+func (g *Guard) Check(msg *api.SecureEnvelope) *api.Error {
+	if msg == nil || msg.Id == "" {
+		return api.Errorf(api.BadRequest, "missing envelope id")
+	}
+
+	if msg.Timestamp == "" {
+		return api.Errorf(api.BadRequest, "missing ordering timestamp on envelope")
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+	if err != nil {
+		return api.Errorf(api.BadRequest, "could not parse ordering timestamp in RFC3339 format")
+	}
+
+	now := g.clock.Now()
+	if skew := now.Sub(ts); skew > g.window || skew < -g.window {
+		return &api.Error{
+			Code:    api.BadRequest,
+			Message: "envelope timestamp is outside of the accepted window, resend with a current timestamp",
+			Retry:   true,
+		}
+	}
+
+	if len(msg.Hmac) == 0 {
+		return nil
+	}
+
+	g.expire(now)
+	added, err := g.store.Add(nonce(msg), now.Add(g.retention))
+	if err != nil {
+		return api.Errorf(api.Unavailable, "could not check envelope for replays").WithRetry()
+	}
+
+	if !added {
+		return &api.Error{
+			Code:    api.BadRequest,
+			Message: "envelope has already been received",
+		}
+	}
+	return nil
+}
+
+// Forget the nonce of the envelope so that it can be received again, e.g. if the
+// envelope could not be opened and the counterparty has been asked to resend it.
+func (g *Guard) Forget(msg *api.SecureEnvelope) error {
+	if msg == nil || len(msg.Hmac) == 0 {
+		return nil
+	}
+	return g.store.Delete(nonce(msg))
+}
+
+// Window returns the clock skew window of the guard.
+func (g *Guard) Window() time.Duration {
+	return g.window
+}
+
+// Retention returns how long nonces are retained after an envelope is received.
+func (g *Guard) Retention() time.Duration {
+	return g.retention
+}
+
+// Expires nonces from the store at most once per window.
+func (g *Guard) expire(now time.Time) {
+	g.Lock()
+	if now.Sub(g.expired) < g.window {
+		g.Unlock()
+		return
+	}
+	g.expired = now
+	g.Unlock()
+
+	// Nonces are only used to detect replays so an error expiring them is not fatal
+	g.store.Expire(now)
+}
+
+// The nonce of an envelope is composed of the envelope ID and the HMAC of its payload.
+func nonce(msg *api.SecureEnvelope) string {
+	return msg.Id + ":" + base64.RawStdEncoding.EncodeToString(msg.Hmac)
+}
+
+//===========================================================================
+// Guard Options
+//===========================================================================
+
+// Option configures a replay guard.
+type Option func(g *Guard) error
+
+// Specify the maximum clock skew between envelope timestamps and the local clock.
+func WithWindow(window time.Duration) Option {
+	return func(g *Guard) error {
+		if window <= 0 {
+			return ErrInvalidWindow
+		}
+		g.window = window
+		return nil
+	}
+}
+
+// Specify how long nonces are retained after an envelope is received. Envelopes that
+// are replayed with a rewritten timestamp after the retention period are not detected,
+// so it should be at least as long as a transfer may remain open. The retention period
+// must be at least twice the window.
+func WithRetention(retention time.Duration) Option {
+	return func(g *Guard) error {
+		g.retention = retention
+		return nil
+	}
+}
+
+// Specify the store used to record nonces, e.g. a store that is shared by replicas.
+func WithStore(store Store) Option {
+	return func(g *Guard) error {
+		if store == nil {
+			return ErrNoStore
+		}
+		g.store = store
+		return nil
+	}
+}
+
+// Specify the clock used to check envelope timestamps.
+func WithClock(c clock.Clock) Option {
+	return func(g *Guard) error {
+		g.clock = c
+		return nil
+	}
+}
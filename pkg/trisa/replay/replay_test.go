@@ -0,0 +1,282 @@
+package replay_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/bufconn"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trisa/replay"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+var epoch = time.Date(2024, 4, 12, 9, 30, 0, 0, time.UTC)
+
+func TestCheck(t *testing.T) {
+	clock := clock.NewManual(epoch)
+	store := replay.NewMemoryStore()
+	guard, err := replay.New(replay.WithClock(clock), replay.WithStore(store), replay.WithWindow(time.Minute), replay.WithRetention(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, guard.Window())
+	require.Equal(t, time.Hour, guard.Retention())
+
+	msg := &api.SecureEnvelope{
+		Id:        uuid.NewString(),
+		Hmac:      []byte("hmac signature"),
+		Timestamp: epoch.Add(-30 * time.Second).Format(time.RFC3339Nano),
+	}
+
+	require.Nil(t, guard.Check(msg))
+	require.Equal(t, 1, store.Len())
+
+	// The same envelope cannot be received twice
+	reject := guard.Check(msg)
+	require.NotNil(t, reject)
+	require.Equal(t, api.BadRequest, reject.Code)
+	require.False(t, reject.Retry)
+
+	// A reply in the same exchange has the same envelope ID but a different HMAC
+	reply := proto.Clone(msg).(*api.SecureEnvelope)
+	reply.Hmac = []byte("another hmac signature")
+	require.Nil(t, guard.Check(reply))
+
+	// A forgotten envelope can be received again
+	require.NoError(t, guard.Forget(msg))
+	require.Nil(t, guard.Check(msg))
+
+	// Envelopes without an HMAC are only checked for freshness
+	rejection := &api.SecureEnvelope{Id: msg.Id, Timestamp: msg.Timestamp, Error: &api.Error{Code: api.Rejected}}
+	require.Nil(t, guard.Check(rejection))
+	require.Nil(t, guard.Check(rejection))
+
+	// Envelopes outside of the window are rejected
+	for _, offset := range []time.Duration{-61 * time.Second, 61 * time.Second} {
+		stale := &api.SecureEnvelope{Id: uuid.NewString(), Hmac: []byte("hmac"), Timestamp: epoch.Add(offset).Format(time.RFC3339Nano)}
+		reject = guard.Check(stale)
+		require.NotNil(t, reject, "expected envelope at offset %s to be rejected", offset)
+		require.True(t, reject.Retry, "stale envelopes can be resent with a current timestamp")
+	}
+
+	// Invalid envelopes are rejected
+	for _, invalid := range []*api.SecureEnvelope{
+		nil,
+		{Timestamp: msg.Timestamp},
+		{Id: msg.Id},
+		{Id: msg.Id, Timestamp: "yesterday"},
+	} {
+		require.NotNil(t, guard.Check(invalid))
+	}
+
+	// Once the window has passed the envelope is stale
+	clock.Advance(2 * time.Minute)
+	reject = guard.Check(msg)
+	require.NotNil(t, reject)
+	require.True(t, reject.Retry)
+
+	// The timestamp is not authenticated so a replay with a rewritten timestamp must
+	// still be detected by its nonce after the window has passed
+	rewritten := proto.Clone(msg).(*api.SecureEnvelope)
+	rewritten.Timestamp = clock.Now().Format(time.RFC3339Nano)
+	reject = guard.Check(rewritten)
+	require.NotNil(t, reject)
+	require.Equal(t, api.BadRequest, reject.Code)
+	require.False(t, reject.Retry, "replays cannot be resent")
+
+	fresh := &api.SecureEnvelope{Id: uuid.NewString(), Hmac: []byte("hmac"), Timestamp: clock.Now().Format(time.RFC3339Nano)}
+	require.Nil(t, guard.Check(fresh))
+	require.Equal(t, 3, store.Len())
+
+	// Nonces are expired once the retention period has passed since they were received
+	clock.Advance(2 * time.Hour)
+	fresh = &api.SecureEnvelope{Id: uuid.NewString(), Hmac: []byte("hmac"), Timestamp: clock.Now().Format(time.RFC3339Nano)}
+	require.Nil(t, guard.Check(fresh))
+	require.Equal(t, 1, store.Len())
+
+	_, err = replay.New(replay.WithWindow(0))
+	require.ErrorIs(t, err, replay.ErrInvalidWindow)
+	_, err = replay.New(replay.WithWindow(time.Minute), replay.WithRetention(time.Minute))
+	require.ErrorIs(t, err, replay.ErrInvalidRetention)
+	_, err = replay.New(replay.WithStore(nil))
+	require.ErrorIs(t, err, replay.ErrNoStore)
+}
+
+func TestEnvelopeOption(t *testing.T) {
+	guard, err := replay.New()
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	msg, reject, err := envelope.SealPayload(makePayload(t), envelope.WithRSAPublicKey(&key.PublicKey))
+	require.NoError(t, err)
+	require.Nil(t, reject)
+
+	// The envelope cannot be opened twice
+	env, reject, err := envelope.Open(msg, envelope.WithRSAPrivateKey(key), envelope.WithReplayGuard(guard))
+	require.NoError(t, err)
+	require.Nil(t, reject)
+	require.Equal(t, envelope.Clear, env.State())
+
+	env, reject, err = envelope.Open(msg, envelope.WithRSAPrivateKey(key), envelope.WithReplayGuard(guard))
+	require.ErrorIs(t, err, reject)
+	require.NotNil(t, reject)
+	require.Equal(t, envelope.Error, env.State(), "a rejection envelope should be returned")
+	require.Equal(t, msg.Id, env.ID())
+
+	// OpenPayload modifies the secure envelope so use a copy
+	clone := proto.Clone(msg).(*api.SecureEnvelope)
+	_, reject, err = envelope.OpenPayload(clone, envelope.WithRSAPrivateKey(key), envelope.WithReplayGuard(guard))
+	require.Error(t, err)
+	require.NotNil(t, reject)
+
+	// An envelope that could not be opened is not recorded so it can be resent
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	msg, _, err = envelope.SealPayload(makePayload(t), envelope.WithRSAPublicKey(&key.PublicKey))
+	require.NoError(t, err)
+
+	_, reject, err = envelope.Open(msg, envelope.WithRSAPrivateKey(other), envelope.WithReplayGuard(guard))
+	require.Error(t, err)
+	require.Equal(t, api.InvalidKey, reject.Code)
+
+	payload, reject, err := envelope.OpenPayload(msg, envelope.WithRSAPrivateKey(key), envelope.WithReplayGuard(guard))
+	require.NoError(t, err)
+	require.Nil(t, reject)
+	require.NotNil(t, payload)
+}
+
+func TestInterceptors(t *testing.T) {
+	guard, err := replay.New()
+	require.NoError(t, err)
+
+	remote := &mockServer{}
+	bufnet := bufconn.New()
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(guard.UnaryInterceptor()),
+		grpc.StreamInterceptor(guard.StreamInterceptor()),
+	)
+	api.RegisterTRISANetworkServer(srv, remote)
+	go srv.Serve(bufnet.Sock())
+	defer srv.GracefulStop()
+
+	cc, err := bufnet.Connect(context.Background(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+	client := api.NewTRISANetworkClient(cc)
+
+	msg := &api.SecureEnvelope{
+		Id:        uuid.NewString(),
+		Payload:   []byte("encrypted payload"),
+		Hmac:      []byte("hmac signature"),
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	t.Run("Unary", func(t *testing.T) {
+		rep, err := client.Transfer(context.Background(), msg)
+		require.NoError(t, err)
+		require.Nil(t, rep.Error)
+
+		rep, err = client.Transfer(context.Background(), msg)
+		require.NoError(t, err)
+		require.NotNil(t, rep.Error, "expected a rejection envelope")
+		require.Equal(t, msg.Id, rep.Id)
+		require.Equal(t, api.TransferRejected, rep.TransferState)
+		require.Equal(t, 1, remote.transfers, "handler should not be called for a replay")
+
+		// If the handler asks the counterparty to retry the envelope can be resent
+		retry := proto.Clone(msg).(*api.SecureEnvelope)
+		retry.Hmac = []byte("retry")
+		remote.retry = true
+		rep, err = client.Transfer(context.Background(), retry)
+		require.NoError(t, err)
+		require.True(t, rep.Error.Retry)
+
+		remote.retry = false
+		rep, err = client.Transfer(context.Background(), retry)
+		require.NoError(t, err)
+		require.Nil(t, rep.Error)
+		require.Equal(t, 3, remote.transfers)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream, err := client.TransferStream(context.Background())
+		require.NoError(t, err)
+
+		stale := proto.Clone(msg).(*api.SecureEnvelope)
+		stale.Id = uuid.NewString()
+		stale.Timestamp = time.Now().Add(-time.Hour).Format(time.RFC3339Nano)
+
+		fresh := proto.Clone(msg).(*api.SecureEnvelope)
+		fresh.Id = uuid.NewString()
+
+		// The replay and the stale envelope are rejected, the fresh envelope is echoed
+		for _, m := range []*api.SecureEnvelope{msg, stale, fresh} {
+			require.NoError(t, stream.Send(m))
+		}
+		require.NoError(t, stream.CloseSend())
+
+		for _, expected := range []struct {
+			id     string
+			reject bool
+		}{{msg.Id, true}, {stale.Id, true}, {fresh.Id, false}} {
+			rep, err := stream.Recv()
+			require.NoError(t, err)
+			require.Equal(t, expected.id, rep.Id)
+			require.Equal(t, expected.reject, rep.Error != nil)
+		}
+	})
+}
+
+type mockServer struct {
+	api.UnimplementedTRISANetworkServer
+	transfers int
+	retry     bool
+}
+
+func (s *mockServer) Transfer(ctx context.Context, in *api.SecureEnvelope) (*api.SecureEnvelope, error) {
+	s.transfers++
+	out := &api.SecureEnvelope{Id: in.Id, Timestamp: time.Now().UTC().Format(time.RFC3339Nano)}
+	if s.retry {
+		out.Error = api.Errorf(api.InvalidKey, "could not unseal envelope").WithRetry()
+	}
+	return out, nil
+}
+
+func (s *mockServer) TransferStream(stream api.TRISANetwork_TransferStreamServer) error {
+	for {
+		in, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+
+		if err = stream.Send(&api.SecureEnvelope{Id: in.Id}); err != nil {
+			return err
+		}
+	}
+}
+
+func makePayload(t *testing.T) *api.Payload {
+	identity, err := anypb.New(&generic.Transaction{Txid: "1234"})
+	require.NoError(t, err)
+
+	transaction, err := anypb.New(&generic.Transaction{Txid: "1234", Amount: 42.99})
+	require.NoError(t, err)
+
+	return &api.Payload{
+		Identity:    identity,
+		Transaction: transaction,
+		SentAt:      time.Now().UTC().Format(time.RFC3339),
+	}
+}
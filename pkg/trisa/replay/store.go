@@ -0,0 +1,74 @@
+package replay
+
+import (
+	"sync"
+	"time"
+)
+
+// Store records the nonces of received envelopes until they expire at the end of the
+// retention period of the guard. Implementations must be safe for concurrent use;
+// stores that expire keys natively (e.g. with a TTL) may implement Expire as a no-op.
+type Store interface {
+	// Add the nonce if it has not already been recorded, returning false if it has.
+	Add(nonce string, expires time.Time) (bool, error)
+
+	// Delete the nonce so that the envelope can be received again.
+	Delete(nonce string) error
+
+	// Expire removes all nonces that expire before the specified time.
+	Expire(now time.Time) error
+}
+
+// MemoryStore keeps the nonces of received envelopes in memory and is the store the
+// guard uses unless WithStore is specified. Nonces are lost when the process exits, so
+// an envelope received shortly before a restart can be replayed until its timestamp is
+// outside of the window; a shared store is needed to detect replays across a cluster.
+type MemoryStore struct {
+	sync.Mutex
+	nonces map[string]time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns an empty in-memory store of envelope nonces.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nonces: make(map[string]time.Time)}
+}
+
+// Add the nonce with its expiration, returning false if it has already been recorded.
+func (s *MemoryStore) Add(nonce string, expires time.Time) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.nonces[nonce]; ok {
+		return false, nil
+	}
+	s.nonces[nonce] = expires
+	return true, nil
+}
+
+// Delete the nonce so that the envelope it identifies can be received again.
+func (s *MemoryStore) Delete(nonce string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.nonces, nonce)
+	return nil
+}
+
+// Expire removes all nonces whose retention period ended before now.
+func (s *MemoryStore) Expire(now time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	for nonce, expires := range s.nonces {
+		if expires.Before(now) {
+			delete(s.nonces, nonce)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of nonces in the store.
+func (s *MemoryStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.nonces)
+}
@@ -0,0 +1,19 @@
+package sunrise
+
+import "errors"
+
+var (
+	ErrNotFound       = errors.New("no sunrise exchange found for the envelope id")
+	ErrNoBaseURL      = errors.New("a base url is required to create sunrise links")
+	ErrNoMailer       = errors.New("a mailer is required to send sunrise messages")
+	ErrNoContacts     = errors.New("no contacts with an email address to send sunrise messages to")
+	ErrNoEnvelopeID   = errors.New("an envelope id is required to record a sunrise exchange")
+	ErrNotSunrise     = errors.New("exchange payload does not contain a sunrise transaction")
+	ErrInvalidToken   = errors.New("sunrise token is invalid")
+	ErrTokenExpired   = errors.New("sunrise token has expired")
+	ErrTokenVerified  = errors.New("sunrise token has already been used")
+	ErrNoSMTPAddr     = errors.New("an smtp server address is required")
+	ErrNoSender       = errors.New("a from address is required to send email")
+	ErrNoRecipient    = errors.New("a to address is required to send email")
+	ErrInvalidSubject = errors.New("email subject must not contain newlines")
+)
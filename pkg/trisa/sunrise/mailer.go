@@ -0,0 +1,150 @@
+package sunrise
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email is a plain text message delivered by a Mailer.
+type Email struct {
+	From    string // If empty the default sender of the mailer is used
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers sunrise messages to counterparty contacts.
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// SMTPMailer delivers email through an SMTP server, upgrading the connection with
+// STARTTLS if the server supports it.
+type SMTPMailer struct {
+	Addr      string      // host:port of the SMTP server
+	From      string      // the default sender of email
+	Auth      smtp.Auth   // optional authentication, e.g. smtp.PlainAuth
+	TLSConfig *tls.Config // optional configuration for STARTTLS
+}
+
+var _ Mailer = &SMTPMailer{}
+
+// NewSMTPMailer returns a mailer that sends email from the address using the server.
+func NewSMTPMailer(addr, from string, auth smtp.Auth) (*SMTPMailer, error) {
+	if addr == "" {
+		return nil, ErrNoSMTPAddr
+	}
+	return &SMTPMailer{Addr: addr, From: from, Auth: auth}, nil
+}
+
+// Send the email, honoring the deadline and cancellation of the context.
+func (m *SMTPMailer) Send(ctx context.Context, email *Email) (err error) {
+	from := email.From
+	if from == "" {
+		from = m.From
+	}
+
+	var msg []byte
+	if msg, err = m.message(from, email); err != nil {
+		return err
+	}
+
+	var host string
+	if host, _, err = net.SplitHostPort(m.Addr); err != nil {
+		return fmt.Errorf("could not parse smtp address: %w", err)
+	}
+
+	var conn net.Conn
+	dialer := &net.Dialer{}
+	if conn, err = dialer.DialContext(ctx, "tcp", m.Addr); err != nil {
+		return fmt.Errorf("could not connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Close the connection if the context is canceled during the exchange
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var client *smtp.Client
+	if client, err = smtp.NewClient(conn, host); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		conf := m.TLSConfig
+		if conf == nil {
+			conf = &tls.Config{ServerName: host}
+		}
+
+		if err = client.StartTLS(conf); err != nil {
+			return err
+		}
+	}
+
+	if m.Auth != nil {
+		if err = client.Auth(m.Auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(email.To); err != nil {
+		return err
+	}
+
+	var w io.WriteCloser
+	if w, err = client.Data(); err != nil {
+		return err
+	}
+
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Compose the RFC 5322 message with the headers of the email.
+func (m *SMTPMailer) message(from string, email *Email) (_ []byte, err error) {
+	switch {
+	case from == "":
+		return nil, ErrNoSender
+	case email.To == "":
+		return nil, ErrNoRecipient
+	case strings.ContainsAny(email.Subject, "\r\n"):
+		return nil, ErrInvalidSubject
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", email.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+
+	// SMTP requires CRLF line endings in the message body
+	body := strings.ReplaceAll(email.Body, "\r\n", "\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
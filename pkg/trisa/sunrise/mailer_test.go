@@ -0,0 +1,169 @@
+package sunrise_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/trisa/sunrise"
+)
+
+func TestSMTPMailer(t *testing.T) {
+	srv := newFakeSMTP(t)
+	defer srv.Close()
+
+	mailer, err := sunrise.NewSMTPMailer(srv.Addr(), "compliance@alice.example.com", nil)
+	require.NoError(t, err)
+
+	email := &sunrise.Email{
+		To:      "tech@bob.example.com",
+		Subject: "Travel rule information request",
+		Body:    "Hello,\nPlease follow the link.\n",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mailer.Send(ctx, email))
+
+	msgs := srv.Messages()
+	require.Len(t, msgs, 1)
+	require.Equal(t, "<compliance@alice.example.com>", msgs[0].from)
+	require.Equal(t, []string{"<tech@bob.example.com>"}, msgs[0].to)
+	require.Contains(t, msgs[0].data, "From: compliance@alice.example.com\r\n")
+	require.Contains(t, msgs[0].data, "To: tech@bob.example.com\r\n")
+	require.Contains(t, msgs[0].data, "Subject: Travel rule information request\r\n")
+	require.Contains(t, msgs[0].data, "\r\n\r\nHello,\r\nPlease follow the link.\r\n")
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := sunrise.NewSMTPMailer("", "", nil)
+		require.ErrorIs(t, err, sunrise.ErrNoSMTPAddr)
+
+		err = mailer.Send(ctx, &sunrise.Email{Subject: "no recipient"})
+		require.ErrorIs(t, err, sunrise.ErrNoRecipient)
+
+		err = mailer.Send(ctx, &sunrise.Email{To: "tech@bob.example.com", Subject: "injected\r\nBcc: eve@example.com"})
+		require.ErrorIs(t, err, sunrise.ErrInvalidSubject)
+
+		noSender := &sunrise.SMTPMailer{Addr: srv.Addr()}
+		err = noSender.Send(ctx, email)
+		require.ErrorIs(t, err, sunrise.ErrNoSender)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.Error(t, mailer.Send(ctx, email))
+	})
+}
+
+// fakeSMTP is a minimal SMTP server that accepts every message without TLS or auth.
+type fakeSMTP struct {
+	sync.Mutex
+	ln       net.Listener
+	messages []fakeMessage
+	wg       sync.WaitGroup
+}
+
+type fakeMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &fakeSMTP{ln: ln}
+	srv.wg.Add(1)
+	go srv.serve()
+	return srv
+}
+
+func (s *fakeSMTP) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeSMTP) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *fakeSMTP) Messages() []fakeMessage {
+	s.Lock()
+	defer s.Unlock()
+	return append([]fakeMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTP) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 localhost fake smtp")
+
+	msg := fakeMessage{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = append(msg.to, line[len("RCPT TO:"):])
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dl, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dl == ".\r\n" {
+					break
+				}
+				data.WriteString(dl)
+			}
+
+			msg.data = data.String()
+			s.Lock()
+			s.messages = append(s.messages, msg)
+			s.Unlock()
+			msg = fakeMessage{}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sunrise
+
+import (
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/internal/memstore"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Exchange is a sunrise transfer with a counterparty that is not a TRISA member. The
+// payload contains the IVMS101 identity of the original transfer and a generic.Sunrise
+// transaction that records the messages sent to the contacts of the counterparty.
+type Exchange struct {
+	EnvelopeID string
+	Payload    *api.Payload
+	Tokens     []*Token
+	Created    time.Time
+	Modified   time.Time
+}
+
+// Token is the record of a one-time link sent to a contact. Only the SHA-256 digest of
+// the secret in the link is stored so that links cannot be recreated from the store.
+type Token struct {
+	Recipient string    // email address the link was sent to
+	Digest    []byte    // SHA-256 digest of the secret
+	Expires   time.Time // time after which the link is no longer valid
+	Verified  time.Time // zero until the link is used
+}
+
+// Sunrise returns the sunrise transaction of the exchange.
+func (e *Exchange) Sunrise() (msg *generic.Sunrise, err error) {
+	if e.Payload == nil || e.Payload.Transaction == nil {
+		return nil, ErrNotSunrise
+	}
+
+	msg = &generic.Sunrise{}
+	if !e.Payload.Transaction.MessageIs(msg) {
+		return nil, ErrNotSunrise
+	}
+
+	if err = e.Payload.Transaction.UnmarshalTo(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Returns a deep copy of the exchange.
+func (e *Exchange) clone() *Exchange {
+	out := *e
+	if e.Payload != nil {
+		out.Payload = proto.Clone(e.Payload).(*api.Payload)
+	}
+
+	out.Tokens = make([]*Token, 0, len(e.Tokens))
+	for _, token := range e.Tokens {
+		cpy := *token
+		cpy.Digest = append([]byte(nil), token.Digest...)
+		out.Tokens = append(out.Tokens, &cpy)
+	}
+	return &out
+}
+
+// Store persists sunrise exchanges so that tokens can be verified when the links are
+// followed. Implementations must be safe for concurrent use and should return
+// ErrNotFound if an exchange does not exist.
+type Store interface {
+	Get(envelopeID string) (*Exchange, error)
+	Put(*Exchange) error
+	Delete(envelopeID string) error
+	List() ([]*Exchange, error)
+}
+
+// MemoryStore keeps sunrise exchanges in memory and is the store the service uses
+// unless WithStore is specified. Exchanges are lost when the process exits, so links
+// sent before a restart can no longer be verified.
+type MemoryStore struct {
+	exchanges *memstore.Store[*Exchange]
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns an empty in-memory store of sunrise exchanges.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		exchanges: memstore.New(memstore.Config[*Exchange]{
+			Key:      func(e *Exchange) string { return e.EnvelopeID },
+			Clone:    (*Exchange).clone,
+			Created:  func(e *Exchange) time.Time { return e.Created },
+			NotFound: ErrNotFound,
+			NoKey:    ErrNoEnvelopeID,
+		}),
+	}
+}
+
+// Get returns a copy of the exchange with the specified envelope ID.
+func (s *MemoryStore) Get(envelopeID string) (*Exchange, error) {
+	return s.exchanges.Get(envelopeID)
+}
+
+// Put stores a copy of the exchange and its tokens under its envelope ID.
+func (s *MemoryStore) Put(e *Exchange) error {
+	return s.exchanges.Put(e)
+}
+
+// Delete the exchange with the specified envelope ID.
+func (s *MemoryStore) Delete(envelopeID string) error {
+	return s.exchanges.Delete(envelopeID)
+}
+
+// List returns copies of all exchanges ordered by when the sunrise message was sent.
+func (s *MemoryStore) List() ([]*Exchange, error) {
+	return s.exchanges.List()
+}
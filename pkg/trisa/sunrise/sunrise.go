@@ -0,0 +1,422 @@
+/*
+Package sunrise implements the TRISA sunrise workflow for sending travel rule data to
+counterparties that are not TRISA members. Rather than sending the IVMS101 payload in
+a secure envelope, each contact of the counterparty (e.g. from the GDS Contacts record
+of the VASP) is sent a message with a secure, one-time link. When the link is followed
+the token in the link is verified and the payload of the exchange can be displayed to
+the counterparty. Every message sent is recorded in a generic.Sunrise transaction that
+is persisted with the identity payload of the transfer so that the exchange can be
+archived like any other TRISA transfer.
+*/
+package sunrise
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	models "github.com/trisacrypto/trisa/pkg/trisa/gds/models/v1beta1"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// DefaultTTL is how long sunrise links are valid for unless otherwise specified.
+	DefaultTTL = 7 * 24 * time.Hour
+
+	// ChannelEmail is the channel recorded on sunrise messages sent by a Mailer.
+	ChannelEmail = "email"
+
+	// TokenParam is the query parameter of the sunrise link that contains the token.
+	TokenParam = "token"
+
+	secretLength = 32
+)
+
+// Default templates for sunrise messages that can be replaced with WithTemplates.
+var (
+	DefaultSubject = template.Must(template.New("subject").Parse(`Travel rule information request from {{ .Originator }}`))
+	DefaultBody    = template.Must(template.New("body").Parse(`Hello {{ .Recipient }},
+
+{{ .Originator }} is sending a virtual asset transfer to a customer of {{ .Counterparty }}
+and is required by the travel rule to share information about the transfer with you.
+
+Please review the transfer using the secure link below. The link can only be used
+once and expires on {{ .Expires.Format "January 2, 2006 at 15:04 MST" }}.
+
+{{ .Link }}
+
+Reference: {{ .EnvelopeID }}
+`))
+)
+
+// Message is the data used to render the templates of a sunrise message.
+type Message struct {
+	EnvelopeID   string
+	Recipient    string
+	Counterparty string
+	Originator   string
+	Link         string
+	Expires      time.Time
+}
+
+// Sunrise sends tokenized links to counterparty contacts and verifies the tokens when
+// the links are followed.
+type Sunrise struct {
+	sync.Mutex
+	store      Store
+	mailer     Mailer
+	clock      clock.Clock
+	baseURL    *url.URL
+	originator string
+	ttl        time.Duration
+	subject    *template.Template
+	body       *template.Template
+}
+
+// New creates a sunrise service that sends messages with the mailer. The WithBaseURL
+// option is required so that links can be created.
+func New(mailer Mailer, opts ...Option) (s *Sunrise, err error) {
+	if mailer == nil {
+		return nil, ErrNoMailer
+	}
+
+	s = &Sunrise{
+		mailer:     mailer,
+		clock:      clock.System{},
+		originator: "A TRISA member VASP",
+		ttl:        DefaultTTL,
+		subject:    DefaultSubject,
+		body:       DefaultBody,
+	}
+
+	for _, opt := range opts {
+		if err = opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.baseURL == nil {
+		return nil, ErrNoBaseURL
+	}
+
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	return s, nil
+}
+
+// Send a sunrise message to each contact of the counterparty with an email address,
+// recording the messages in a generic.Sunrise transaction that is persisted with the
+// identity payload of the envelope. The envelope must be in the clear so that its
+// payload can be read; if its transaction is a generic.Transaction it is included in
+// the sunrise transaction for reference. Contacts that share an email address are
+// only sent a single message. If any message cannot be sent, the messages that were
+// sent are recorded and the error is returned.
+func (s *Sunrise) Send(ctx context.Context, env *envelope.Envelope, counterparty string, contacts *models.Contacts) (exchange *Exchange, err error) {
+	var payload *api.Payload
+	if payload, err = env.Payload(); err != nil {
+		return nil, err
+	}
+
+	recipients := Recipients(contacts)
+	if len(recipients) == 0 {
+		return nil, ErrNoContacts
+	}
+
+	now := s.clock.Now()
+	exchange = &Exchange{
+		EnvelopeID: env.ID(),
+		Payload: &api.Payload{
+			Identity:   payload.Identity,
+			SentAt:     payload.SentAt,
+			ReceivedAt: payload.ReceivedAt,
+		},
+		Created:  now,
+		Modified: now,
+	}
+
+	msg := &generic.Sunrise{
+		EnvelopeId:   exchange.EnvelopeID,
+		Counterparty: counterparty,
+	}
+
+	// Record the generic view of the transaction if it is a registered transaction type
+	if payload.Transaction != nil {
+		if msg.Transaction, err = generic.View(payload.Transaction); err != nil && !errors.Is(err, generic.ErrUnknownTransaction) {
+			return nil, fmt.Errorf("could not unmarshal transaction: %w", err)
+		}
+	}
+
+	var serr error
+	for _, contact := range recipients {
+		var sent *generic.SunriseMessage
+		if sent, serr = s.send(ctx, exchange, counterparty, contact); serr != nil {
+			serr = fmt.Errorf("could not send sunrise message to %s: %w", contact.Email, serr)
+			break
+		}
+		msg.Messages = append(msg.Messages, sent)
+	}
+
+	if len(msg.Messages) == 0 {
+		return nil, serr
+	}
+
+	if exchange.Payload.Transaction, err = anypb.New(msg); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if err = s.store.Put(exchange); err != nil {
+		return nil, err
+	}
+	return exchange, serr
+}
+
+func (s *Sunrise) send(ctx context.Context, exchange *Exchange, counterparty string, contact *models.Contact) (_ *generic.SunriseMessage, err error) {
+	var token string
+	var record *Token
+	if token, record, err = s.newToken(exchange.EnvelopeID, contact.Email); err != nil {
+		return nil, err
+	}
+
+	data := &Message{
+		EnvelopeID:   exchange.EnvelopeID,
+		Recipient:    contact.Name,
+		Counterparty: counterparty,
+		Originator:   s.originator,
+		Link:         s.Link(token),
+		Expires:      record.Expires,
+	}
+
+	email := &Email{To: contact.Email}
+	if email.Subject, err = render(s.subject, data); err != nil {
+		return nil, err
+	}
+
+	if email.Body, err = render(s.body, data); err != nil {
+		return nil, err
+	}
+
+	if err = s.mailer.Send(ctx, email); err != nil {
+		return nil, err
+	}
+
+	exchange.Tokens = append(exchange.Tokens, record)
+	return &generic.SunriseMessage{
+		Recipient:      contact.Name,
+		Email:          contact.Email,
+		Phone:          contact.Phone,
+		Channel:        ChannelEmail,
+		SentAt:         s.clock.Now().Format(time.RFC3339),
+		ReplyNotBefore: record.Expires.Format(time.RFC3339),
+	}, nil
+}
+
+// Verify the token from a sunrise link, returning the exchange so that its payload can
+// be displayed to the counterparty. Tokens can only be verified once and only before
+// they expire.
+func (s *Sunrise) Verify(token string) (exchange *Exchange, err error) {
+	var envelopeID string
+	var secret []byte
+	if envelopeID, secret, err = ParseToken(token); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if exchange, err = s.store.Get(envelopeID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	digest := sha256.Sum256(secret)
+	for _, record := range exchange.Tokens {
+		if subtle.ConstantTimeCompare(digest[:], record.Digest) != 1 {
+			continue
+		}
+
+		now := s.clock.Now()
+		if !record.Verified.IsZero() {
+			return nil, ErrTokenVerified
+		}
+
+		if now.After(record.Expires) {
+			return nil, ErrTokenExpired
+		}
+
+		record.Verified = now
+		exchange.Modified = now
+		if err = s.store.Put(exchange); err != nil {
+			return nil, err
+		}
+		return exchange, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+// Get the sunrise exchange with the specified envelope ID.
+func (s *Sunrise) Get(envelopeID string) (*Exchange, error) {
+	return s.store.Get(envelopeID)
+}
+
+// Link returns the sunrise link for the token.
+func (s *Sunrise) Link(token string) string {
+	link := *s.baseURL
+	query := link.Query()
+	query.Set(TokenParam, token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// Create a random token for the envelope, returning the token to send in the link and
+// the record of its digest to store in the exchange.
+func (s *Sunrise) newToken(envelopeID, recipient string) (token string, record *Token, err error) {
+	secret := make([]byte, secretLength)
+	if _, err = rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("could not generate sunrise token: %w", err)
+	}
+
+	digest := sha256.Sum256(secret)
+	record = &Token{
+		Recipient: recipient,
+		Digest:    digest[:],
+		Expires:   s.clock.Now().Add(s.ttl),
+	}
+
+	token = base64.RawURLEncoding.EncodeToString([]byte(envelopeID)) + "." + base64.RawURLEncoding.EncodeToString(secret)
+	return token, record, nil
+}
+
+// ParseToken returns the envelope ID and secret of a sunrise token.
+func ParseToken(token string) (envelopeID string, secret []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", nil, ErrInvalidToken
+	}
+
+	var id []byte
+	if id, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil || len(id) == 0 {
+		return "", nil, ErrInvalidToken
+	}
+
+	if secret, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil || len(secret) != secretLength {
+		return "", nil, ErrInvalidToken
+	}
+	return string(id), secret, nil
+}
+
+// Recipients returns the contacts with an email address in the order technical,
+// administrative, legal, and billing, skipping contacts with duplicate addresses.
+func Recipients(contacts *models.Contacts) []*models.Contact {
+	if contacts == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, 4)
+	recipients := make([]*models.Contact, 0, 4)
+	for _, contact := range []*models.Contact{contacts.Technical, contacts.Administrative, contacts.Legal, contacts.Billing} {
+		if contact == nil || contact.Email == "" {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(contact.Email))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		recipients = append(recipients, contact)
+	}
+	return recipients
+}
+
+func render(tmpl *template.Template, data *Message) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render %s template: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+//===========================================================================
+// Sunrise Options
+//===========================================================================
+
+// Option configures the sunrise service when it is created.
+type Option func(s *Sunrise) error
+
+// Specify the URL of the page that verifies tokens, which are appended as a query.
+func WithBaseURL(base string) Option {
+	return func(s *Sunrise) (err error) {
+		if s.baseURL, err = url.Parse(base); err != nil {
+			return fmt.Errorf("could not parse base url: %w", err)
+		}
+
+		if s.baseURL.Scheme == "" || s.baseURL.Host == "" {
+			return fmt.Errorf("base url must be absolute: %q", base)
+		}
+		return nil
+	}
+}
+
+// Specify the store used to persist sunrise exchanges.
+func WithStore(store Store) Option {
+	return func(s *Sunrise) error {
+		s.store = store
+		return nil
+	}
+}
+
+// Specify the clock used to timestamp messages and expire tokens.
+func WithClock(c clock.Clock) Option {
+	return func(s *Sunrise) error {
+		s.clock = c
+		return nil
+	}
+}
+
+// Specify the name of the originating VASP that is used in messages.
+func WithOriginator(name string) Option {
+	return func(s *Sunrise) error {
+		s.originator = name
+		return nil
+	}
+}
+
+// Specify how long sunrise links are valid for.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Sunrise) error {
+		if ttl <= 0 {
+			return fmt.Errorf("sunrise link ttl must be positive")
+		}
+		s.ttl = ttl
+		return nil
+	}
+}
+
+// Specify the templates used to render the subject and body of messages; both are
+// executed with a *Message.
+func WithTemplates(subject, body *template.Template) Option {
+	return func(s *Sunrise) error {
+		s.subject = subject
+		s.body = body
+		return nil
+	}
+}
@@ -0,0 +1,210 @@
+package sunrise_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	models "github.com/trisacrypto/trisa/pkg/trisa/gds/models/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/sunrise"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const baseURL = "https://vasp.example.com/sunrise/verify"
+
+var linkRE = regexp.MustCompile(`https://vasp\.example\.com/sunrise/verify\?token=\S+`)
+
+func TestSunrise(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := clock.NewManual(now)
+	mailer := &mockMailer{}
+
+	svc, err := sunrise.New(mailer,
+		sunrise.WithBaseURL(baseURL),
+		sunrise.WithClock(clock),
+		sunrise.WithOriginator("Alice VASP"),
+		sunrise.WithTTL(24*time.Hour),
+	)
+	require.NoError(t, err)
+
+	env := newEnvelope(t)
+	contacts := &models.Contacts{
+		Technical:      &models.Contact{Name: "Tech Contact", Email: "tech@bob.example.com", Phone: "+15555550100"},
+		Administrative: &models.Contact{Name: "Admin Contact", Email: "admin@bob.example.com"},
+		Legal:          &models.Contact{Name: "Legal Contact", Email: "TECH@bob.example.com"},
+		Billing:        &models.Contact{Name: "No Email"},
+	}
+
+	exchange, err := svc.Send(context.Background(), env, "Bob VASP", contacts)
+	require.NoError(t, err)
+	require.Equal(t, env.ID(), exchange.EnvelopeID)
+	require.Len(t, exchange.Tokens, 2, "duplicate and empty email addresses should be skipped")
+	require.NotNil(t, exchange.Payload.Identity, "identity payload should be persisted")
+
+	msg, err := exchange.Sunrise()
+	require.NoError(t, err)
+	require.Equal(t, env.ID(), msg.EnvelopeId)
+	require.Equal(t, "Bob VASP", msg.Counterparty)
+	require.Equal(t, "bc1qbeneficiary", msg.Transaction.Beneficiary)
+	require.Len(t, msg.Messages, 2)
+	require.Equal(t, "Tech Contact", msg.Messages[0].Recipient)
+	require.Equal(t, "+15555550100", msg.Messages[0].Phone)
+	require.Equal(t, sunrise.ChannelEmail, msg.Messages[0].Channel)
+	require.Equal(t, now.Add(24*time.Hour).Format(time.RFC3339), msg.Messages[0].ReplyNotBefore)
+
+	// Check the rendered messages
+	require.Len(t, mailer.sent, 2)
+	email := mailer.sent[0]
+	require.Equal(t, "tech@bob.example.com", email.To)
+	require.Equal(t, "Travel rule information request from Alice VASP", email.Subject)
+	require.Contains(t, email.Body, "Hello Tech Contact")
+	require.Contains(t, email.Body, env.ID())
+	require.NotContains(t, email.Body, "Alice Originator", "personal information must not be sent in the message")
+
+	// The exchange should be persisted
+	stored, err := svc.Get(env.ID())
+	require.NoError(t, err)
+	require.Len(t, stored.Tokens, 2)
+
+	t.Run("Verify", func(t *testing.T) {
+		token := extractToken(t, mailer.sent[0].Body)
+		verified, err := svc.Verify(token)
+		require.NoError(t, err)
+		require.Equal(t, env.ID(), verified.EnvelopeID)
+		require.Equal(t, now, verified.Tokens[0].Verified)
+		require.True(t, verified.Tokens[1].Verified.IsZero())
+
+		identity := &ivms101.IdentityPayload{}
+		require.NoError(t, verified.Payload.Identity.UnmarshalTo(identity))
+
+		// Tokens are one-time use
+		_, err = svc.Verify(token)
+		require.ErrorIs(t, err, sunrise.ErrTokenVerified)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		token := extractToken(t, mailer.sent[1].Body)
+		clock.Advance(25 * time.Hour)
+		defer clock.Set(now)
+
+		_, err := svc.Verify(token)
+		require.ErrorIs(t, err, sunrise.ErrTokenExpired)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		token := extractToken(t, mailer.sent[1].Body)
+		id, _, err := sunrise.ParseToken(token)
+		require.NoError(t, err)
+
+		for _, invalid := range []string{
+			"",
+			"foo",
+			token + "x",
+			token[:len(token)-4] + "AAAA",
+			"bm90LWFuLWVudmVsb3Bl." + token[len(token)-43:],
+		} {
+			_, err := svc.Verify(invalid)
+			require.ErrorIs(t, err, sunrise.ErrInvalidToken, invalid)
+		}
+		require.Equal(t, env.ID(), id)
+	})
+}
+
+func TestSendErrors(t *testing.T) {
+	_, err := sunrise.New(nil, sunrise.WithBaseURL(baseURL))
+	require.ErrorIs(t, err, sunrise.ErrNoMailer)
+
+	_, err = sunrise.New(&mockMailer{})
+	require.ErrorIs(t, err, sunrise.ErrNoBaseURL)
+
+	_, err = sunrise.New(&mockMailer{}, sunrise.WithBaseURL("/relative"))
+	require.Error(t, err)
+
+	mailer := &mockMailer{failAfter: 1}
+	svc, err := sunrise.New(mailer, sunrise.WithBaseURL(baseURL))
+	require.NoError(t, err)
+
+	env := newEnvelope(t)
+	_, err = svc.Send(context.Background(), env, "Bob VASP", &models.Contacts{})
+	require.ErrorIs(t, err, sunrise.ErrNoContacts)
+
+	// Messages that were sent before a failure are recorded
+	exchange, err := svc.Send(context.Background(), env, "Bob VASP", &models.Contacts{
+		Technical: &models.Contact{Name: "Tech Contact", Email: "tech@bob.example.com"},
+		Legal:     &models.Contact{Name: "Legal Contact", Email: "legal@bob.example.com"},
+	})
+	require.ErrorIs(t, err, errMailer)
+	require.Len(t, exchange.Tokens, 1)
+
+	msg, err := exchange.Sunrise()
+	require.NoError(t, err)
+	require.Len(t, msg.Messages, 1)
+}
+
+type mockMailer struct {
+	sync.Mutex
+	sent      []*sunrise.Email
+	failAfter int
+}
+
+var errMailer = errors.New("mailer unavailable")
+
+func (m *mockMailer) Send(_ context.Context, email *sunrise.Email) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.failAfter > 0 && len(m.sent) >= m.failAfter {
+		return errMailer
+	}
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+func extractToken(t *testing.T, body string) string {
+	link := linkRE.FindString(body)
+	require.NotEmpty(t, link, "could not find sunrise link in message")
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	return u.Query().Get(sunrise.TokenParam)
+}
+
+func newEnvelope(t *testing.T) *envelope.Envelope {
+	identity := &ivms101.IdentityPayload{
+		Originator: &ivms101.Originator{
+			OriginatorPersons: []*ivms101.Person{
+				{
+					Person: &ivms101.Person_NaturalPerson{
+						NaturalPerson: &ivms101.NaturalPerson{
+							Name: &ivms101.NaturalPersonName{
+								NameIdentifiers: []*ivms101.NaturalPersonNameId{
+									{PrimaryIdentifier: "Originator", SecondaryIdentifier: "Alice", NameIdentifierType: ivms101.NaturalPersonLegal},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	payload := &api.Payload{SentAt: time.Now().Format(time.RFC3339)}
+	var err error
+	payload.Identity, err = anypb.New(identity)
+	require.NoError(t, err)
+	payload.Transaction, err = anypb.New(&generic.Transaction{Originator: "bc1qoriginator", Beneficiary: "bc1qbeneficiary", Amount: 0.5})
+	require.NoError(t, err)
+
+	env, err := envelope.New(payload)
+	require.NoError(t, err)
+	return env
+}
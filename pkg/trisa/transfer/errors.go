@@ -0,0 +1,14 @@
+package transfer
+
+import "errors"
+
+var (
+	ErrNotFound    = errors.New("no transfer found for the envelope id")
+	ErrNoEnvelope  = errors.New("a secure envelope is required to track a transfer")
+	ErrNoID        = errors.New("an envelope id is required to track a transfer")
+	ErrUnspecified = errors.New("secure envelope does not specify a transfer state")
+
+	ErrNotPending         = errors.New("envelope payload does not contain a pending message")
+	ErrInvalidDeadline    = errors.New("reply_not_after must not be before reply_not_before")
+	ErrInvalidExpiryError = errors.New("a valid trisa error is required to reject expired transfers")
+)
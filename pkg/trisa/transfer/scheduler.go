@@ -0,0 +1,291 @@
+package transfer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+)
+
+// DefaultExpiredMessage is the message of the rejection sent when a counterparty does
+// not reply before the reply_not_after deadline of its pending message.
+const DefaultExpiredMessage = "counterparty did not reply before the pending deadline"
+
+// Scheduled is a transfer that is waiting on a reply from a counterparty that
+// responded with a generic.Pending message.
+type Scheduled struct {
+	EnvelopeID     string
+	ReplyNotBefore time.Time // Zero if the counterparty may reply at any time
+	ReplyNotAfter  time.Time // Zero if the transfer does not expire
+	Pending        *generic.Pending
+	Registered     time.Time
+
+	ready  clock.Timer
+	expiry clock.Timer
+}
+
+// ReadyHook is called when the reply_not_before time of a pending transfer passes.
+type ReadyHook func(scheduled *Scheduled)
+
+// ExpiredHook is called when the reply_not_after time of a pending transfer passes
+// with the follow-up envelope that should be sent to the counterparty. The follow-up
+// envelope is in the repair state if the expiry error can be retried and otherwise in
+// the rejected state. The transfer is no longer scheduled when the hook is called.
+type ExpiredHook func(scheduled *Scheduled, followup *envelope.Envelope)
+
+// Scheduler tracks the deadlines of pending messages received from counterparties and
+// calls hooks when the counterparty may reply and when its deadline expires. Hooks are
+// called from the timer goroutines of the clock and must be safe for concurrent use.
+type Scheduler struct {
+	sync.Mutex
+	clock     clock.Clock
+	expired   *api.Error
+	timeout   time.Duration
+	scheduled map[string]*Scheduled
+	onReady   []ReadyHook
+	onExpired []ExpiredHook
+}
+
+// NewScheduler creates a scheduler that uses the system clock and rejects transfers
+// whose deadline has passed unless otherwise specified by the options.
+func NewScheduler(opts ...SchedulerOption) (scheduler *Scheduler, err error) {
+	scheduler = &Scheduler{
+		clock:     clock.System{},
+		expired:   &api.Error{Code: api.CompliancePeriodExceeded, Message: DefaultExpiredMessage},
+		scheduled: make(map[string]*Scheduled),
+	}
+
+	for _, opt := range opts {
+		if err = opt(scheduler); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = scheduler.FollowUp(uuid.NewString()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExpiryError, err)
+	}
+	return scheduler, nil
+}
+
+// Schedule the deadlines of a pending message received from a counterparty. If the
+// transfer is already scheduled its deadlines are replaced. Pending messages without a
+// reply_not_after timestamp expire after the default timeout if one is configured.
+func (s *Scheduler) Schedule(pending *generic.Pending) (_ *Scheduled, err error) {
+	if pending.EnvelopeId == "" {
+		return nil, ErrNoID
+	}
+
+	scheduled := &Scheduled{
+		EnvelopeID: pending.EnvelopeId,
+		Pending:    pending,
+		Registered: s.clock.Now(),
+	}
+
+	if scheduled.ReplyNotBefore, err = parseTimestamp(pending.ReplyNotBefore); err != nil {
+		return nil, fmt.Errorf("could not parse reply_not_before: %w", err)
+	}
+
+	if scheduled.ReplyNotAfter, err = parseTimestamp(pending.ReplyNotAfter); err != nil {
+		return nil, fmt.Errorf("could not parse reply_not_after: %w", err)
+	}
+
+	if scheduled.ReplyNotAfter.IsZero() && s.timeout > 0 {
+		scheduled.ReplyNotAfter = scheduled.Registered.Add(s.timeout)
+	}
+
+	if !scheduled.ReplyNotBefore.IsZero() && !scheduled.ReplyNotAfter.IsZero() && scheduled.ReplyNotAfter.Before(scheduled.ReplyNotBefore) {
+		return nil, ErrInvalidDeadline
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if prev, ok := s.scheduled[scheduled.EnvelopeID]; ok {
+		prev.stop()
+	}
+
+	if !scheduled.ReplyNotBefore.IsZero() {
+		scheduled.ready = s.clock.AfterFunc(scheduled.ReplyNotBefore.Sub(scheduled.Registered), func() { s.fireReady(scheduled) })
+	}
+
+	if !scheduled.ReplyNotAfter.IsZero() {
+		scheduled.expiry = s.clock.AfterFunc(scheduled.ReplyNotAfter.Sub(scheduled.Registered), func() { s.fireExpired(scheduled) })
+	}
+
+	s.scheduled[scheduled.EnvelopeID] = scheduled
+	return scheduled, nil
+}
+
+// ScheduleEnvelope schedules the deadlines of an envelope in the clear whose payload
+// transaction is a generic.Pending message. The envelope ID is used if the pending
+// message does not specify one.
+func (s *Scheduler) ScheduleEnvelope(env *envelope.Envelope) (_ *Scheduled, err error) {
+	var payload *api.Payload
+	if payload, err = env.Payload(); err != nil {
+		return nil, err
+	}
+
+	pending := &generic.Pending{}
+	if payload.Transaction == nil || !payload.Transaction.MessageIs(pending) {
+		return nil, ErrNotPending
+	}
+
+	if err = payload.Transaction.UnmarshalTo(pending); err != nil {
+		return nil, err
+	}
+
+	if pending.EnvelopeId == "" {
+		pending.EnvelopeId = env.ID()
+	}
+	return s.Schedule(pending)
+}
+
+// Resolve stops tracking the deadlines of the transfer, e.g. when the counterparty
+// replies. Returns ErrNotFound if the transfer is not scheduled.
+func (s *Scheduler) Resolve(envelopeID string) error {
+	s.Lock()
+	defer s.Unlock()
+	scheduled, ok := s.scheduled[envelopeID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	scheduled.stop()
+	delete(s.scheduled, envelopeID)
+	return nil
+}
+
+// Get the scheduled transfer with the specified envelope ID.
+func (s *Scheduler) Get(envelopeID string) (*Scheduled, error) {
+	s.Lock()
+	defer s.Unlock()
+	if scheduled, ok := s.scheduled[envelopeID]; ok {
+		return scheduled, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Len returns the number of scheduled transfers.
+func (s *Scheduler) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.scheduled)
+}
+
+// Stop all timers and clear the scheduled transfers.
+func (s *Scheduler) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	for id, scheduled := range s.scheduled {
+		scheduled.stop()
+		delete(s.scheduled, id)
+	}
+}
+
+// FollowUp returns the envelope that should be sent to the counterparty when the
+// transfer expires: an error envelope with the expiry error of the scheduler.
+func (s *Scheduler) FollowUp(envelopeID string) (*envelope.Envelope, error) {
+	return envelope.WrapError(s.expired, envelope.WithEnvelopeID(envelopeID))
+}
+
+func (s *Scheduler) fireReady(scheduled *Scheduled) {
+	s.Lock()
+	current, ok := s.scheduled[scheduled.EnvelopeID]
+	s.Unlock()
+
+	// Do not fire hooks for transfers that have been resolved or replaced
+	if !ok || current != scheduled {
+		return
+	}
+
+	for _, hook := range s.onReady {
+		hook(scheduled)
+	}
+}
+
+func (s *Scheduler) fireExpired(scheduled *Scheduled) {
+	s.Lock()
+	if current, ok := s.scheduled[scheduled.EnvelopeID]; !ok || current != scheduled {
+		s.Unlock()
+		return
+	}
+
+	scheduled.stop()
+	delete(s.scheduled, scheduled.EnvelopeID)
+	s.Unlock()
+
+	// The expiry error is validated when the scheduler is created so the follow-up
+	// envelope can always be created from the envelope ID of a scheduled transfer.
+	followup, _ := s.FollowUp(scheduled.EnvelopeID)
+
+	for _, hook := range s.onExpired {
+		hook(scheduled, followup)
+	}
+}
+
+func (s *Scheduled) stop() {
+	if s.ready != nil {
+		s.ready.Stop()
+	}
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+}
+
+func parseTimestamp(ts string) (time.Time, error) {
+	if ts == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, ts)
+}
+
+// SchedulerOption configures the scheduler when it is created.
+type SchedulerOption func(s *Scheduler) error
+
+// Specify the clock used to schedule deadlines, e.g. a manual clock for tests.
+func WithClock(c clock.Clock) SchedulerOption {
+	return func(s *Scheduler) error {
+		s.clock = c
+		return nil
+	}
+}
+
+// Specify the error sent to the counterparty when a deadline passes. If the error can
+// be retried the follow-up envelope requests a repair, otherwise it is a rejection.
+func WithExpiryError(reject *api.Error) SchedulerOption {
+	return func(s *Scheduler) error {
+		if reject == nil || reject.IsZero() {
+			return ErrInvalidExpiryError
+		}
+		s.expired = reject
+		return nil
+	}
+}
+
+// Specify a timeout for pending messages that do not have a reply_not_after timestamp.
+func WithDefaultTimeout(timeout time.Duration) SchedulerOption {
+	return func(s *Scheduler) error {
+		s.timeout = timeout
+		return nil
+	}
+}
+
+// Register a hook that is called when a counterparty may reply to a pending transfer.
+func OnReady(hook ReadyHook) SchedulerOption {
+	return func(s *Scheduler) error {
+		s.onReady = append(s.onReady, hook)
+		return nil
+	}
+}
+
+// Register a hook that is called when a pending transfer expires.
+func OnExpired(hook ExpiredHook) SchedulerOption {
+	return func(s *Scheduler) error {
+		s.onExpired = append(s.onExpired, hook)
+		return nil
+	}
+}
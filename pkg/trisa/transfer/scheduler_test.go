@@ -0,0 +1,217 @@
+package transfer_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/ivms101"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/clock"
+	generic "github.com/trisacrypto/trisa/pkg/trisa/data/generic/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trisa/transfer"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestScheduler(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := clock.NewManual(now)
+	events := &recorder{}
+
+	scheduler, err := transfer.NewScheduler(
+		transfer.WithClock(clock),
+		transfer.OnReady(events.ready),
+		transfer.OnExpired(events.expired),
+	)
+	require.NoError(t, err)
+
+	t.Run("Expired", func(t *testing.T) {
+		defer events.reset()
+		pending := newPending(now.Add(time.Hour), now.Add(2*time.Hour))
+
+		scheduled, err := scheduler.Schedule(pending)
+		require.NoError(t, err)
+		require.Equal(t, pending.EnvelopeId, scheduled.EnvelopeID)
+		require.Equal(t, 2, clock.Timers())
+
+		clock.Advance(59 * time.Minute)
+		require.Empty(t, events.readied)
+
+		clock.Advance(time.Minute)
+		require.Equal(t, []string{pending.EnvelopeId}, events.readied)
+		require.Empty(t, events.followups)
+
+		clock.Advance(time.Hour)
+		require.Len(t, events.followups, 1)
+		require.Zero(t, scheduler.Len())
+		require.Zero(t, clock.Timers())
+
+		followup := events.followups[0]
+		require.Equal(t, pending.EnvelopeId, followup.ID())
+		require.True(t, followup.IsError())
+		require.Equal(t, api.TransferRejected, followup.TransferState())
+		require.Equal(t, api.CompliancePeriodExceeded, followup.Error().Code)
+	})
+
+	t.Run("Resolved", func(t *testing.T) {
+		defer events.reset()
+		pending := newPending(now.Add(time.Hour), now.Add(2*time.Hour))
+
+		_, err := scheduler.Schedule(pending)
+		require.NoError(t, err)
+		require.NoError(t, scheduler.Resolve(pending.EnvelopeId))
+		require.ErrorIs(t, scheduler.Resolve(pending.EnvelopeId), transfer.ErrNotFound)
+
+		clock.Set(now.Add(3 * time.Hour))
+		require.Empty(t, events.readied)
+		require.Empty(t, events.followups)
+		clock.Set(now)
+	})
+
+	t.Run("Rescheduled", func(t *testing.T) {
+		defer events.reset()
+		pending := newPending(time.Time{}, now.Add(time.Hour))
+
+		_, err := scheduler.Schedule(pending)
+		require.NoError(t, err)
+
+		// The counterparty sends another pending message extending the deadline
+		pending.ReplyNotAfter = now.Add(2 * time.Hour).Format(time.RFC3339)
+		_, err = scheduler.Schedule(pending)
+		require.NoError(t, err)
+		require.Equal(t, 1, clock.Timers())
+
+		clock.Advance(90 * time.Minute)
+		require.Empty(t, events.followups)
+
+		clock.Advance(30 * time.Minute)
+		require.Len(t, events.followups, 1)
+		clock.Set(now)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := scheduler.Schedule(&generic.Pending{})
+		require.ErrorIs(t, err, transfer.ErrNoID)
+
+		_, err = scheduler.Schedule(newPending(now.Add(2*time.Hour), now.Add(time.Hour)))
+		require.ErrorIs(t, err, transfer.ErrInvalidDeadline)
+
+		_, err = scheduler.Schedule(&generic.Pending{EnvelopeId: uuid.NewString(), ReplyNotAfter: "tomorrow"})
+		require.Error(t, err)
+	})
+}
+
+func TestSchedulerOptions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := clock.NewManual(now)
+	events := &recorder{}
+
+	scheduler, err := transfer.NewScheduler(
+		transfer.WithClock(clock),
+		transfer.WithDefaultTimeout(time.Hour),
+		transfer.WithExpiryError(api.Errorf(api.CompliancePeriodExceeded, "please resend").WithRetry()),
+		transfer.OnExpired(events.expired),
+	)
+	require.NoError(t, err)
+
+	// Pending messages without a deadline expire after the default timeout
+	scheduled, err := scheduler.Schedule(newPending(time.Time{}, time.Time{}))
+	require.NoError(t, err)
+	require.Equal(t, now.Add(time.Hour), scheduled.ReplyNotAfter)
+
+	clock.Advance(time.Hour)
+	require.Len(t, events.followups, 1)
+	require.Equal(t, api.TransferRepair, events.followups[0].TransferState())
+
+	_, err = transfer.NewScheduler(transfer.WithExpiryError(nil))
+	require.ErrorIs(t, err, transfer.ErrInvalidExpiryError)
+}
+
+func TestScheduleEnvelope(t *testing.T) {
+	scheduler, err := transfer.NewScheduler(transfer.WithClock(clock.NewManual(time.Now())))
+	require.NoError(t, err)
+
+	pending := newPending(time.Time{}, time.Now().Add(time.Hour))
+	pending.EnvelopeId = ""
+
+	payload := &api.Payload{SentAt: time.Now().Format(time.RFC3339)}
+	payload.Identity, err = anypb.New(&ivms101.IdentityPayload{})
+	require.NoError(t, err)
+	payload.Transaction, err = anypb.New(pending)
+	require.NoError(t, err)
+
+	env, err := envelope.New(payload)
+	require.NoError(t, err)
+
+	scheduled, err := scheduler.ScheduleEnvelope(env)
+	require.NoError(t, err)
+	require.Equal(t, env.ID(), scheduled.EnvelopeID)
+
+	payload.Transaction, err = anypb.New(&generic.Transaction{})
+	require.NoError(t, err)
+	env, err = envelope.New(payload)
+	require.NoError(t, err)
+
+	_, err = scheduler.ScheduleEnvelope(env)
+	require.ErrorIs(t, err, transfer.ErrNotPending)
+}
+
+func TestSystemClock(t *testing.T) {
+	expired := make(chan *envelope.Envelope, 1)
+	scheduler, err := transfer.NewScheduler(transfer.OnExpired(func(_ *transfer.Scheduled, followup *envelope.Envelope) {
+		expired <- followup
+	}))
+	require.NoError(t, err)
+	defer scheduler.Stop()
+
+	pending := newPending(time.Time{}, time.Time{})
+	pending.ReplyNotAfter = time.Now().Add(-time.Second).Format(time.RFC3339)
+	_, err = scheduler.Schedule(pending)
+	require.NoError(t, err)
+
+	select {
+	case followup := <-expired:
+		require.Equal(t, pending.EnvelopeId, followup.ID())
+	case <-time.After(5 * time.Second):
+		t.Fatal("expired hook was not called")
+	}
+}
+
+func newPending(notBefore, notAfter time.Time) *generic.Pending {
+	pending := &generic.Pending{EnvelopeId: uuid.NewString(), ReceivedBy: "Beneficiary VASP"}
+	if !notBefore.IsZero() {
+		pending.ReplyNotBefore = notBefore.Format(time.RFC3339)
+	}
+	if !notAfter.IsZero() {
+		pending.ReplyNotAfter = notAfter.Format(time.RFC3339)
+	}
+	return pending
+}
+
+type recorder struct {
+	sync.Mutex
+	readied   []string
+	followups []*envelope.Envelope
+}
+
+func (r *recorder) ready(s *transfer.Scheduled) {
+	r.Lock()
+	defer r.Unlock()
+	r.readied = append(r.readied, s.EnvelopeID)
+}
+
+func (r *recorder) expired(_ *transfer.Scheduled, followup *envelope.Envelope) {
+	r.Lock()
+	defer r.Unlock()
+	r.followups = append(r.followups, followup)
+}
+
+func (r *recorder) reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.readied = nil
+	r.followups = nil
+}
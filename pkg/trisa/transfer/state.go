@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"fmt"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+)
+
+// Party identifies a counterparty in a TRISA transfer.
+type Party uint8
+
+const (
+	Nobody      Party = iota // no further action is required by either counterparty
+	Originator               // the VASP sending the virtual asset transaction
+	Beneficiary              // the VASP receiving the virtual asset transaction
+)
+
+var partyNames = [...]string{"nobody", "originator", "beneficiary"}
+
+// String returns a human readable representation of the party.
+func (p Party) String() string {
+	if int(p) < len(partyNames) {
+		return partyNames[p]
+	}
+	return fmt.Sprintf("Party(%d)", p)
+}
+
+// Counterparty returns the other party in the transfer.
+func (p Party) Counterparty() Party {
+	switch p {
+	case Originator:
+		return Beneficiary
+	case Beneficiary:
+		return Originator
+	default:
+		return Nobody
+	}
+}
+
+// Transitions is the table of legal transfer states of an envelope given the current
+// state of the transfer. A transfer begins in the unspecified state and must be
+// started by the originator; rejected transfers are terminal and completed transfers
+// may only be acknowledged by the beneficiary. States may be repeated so that the
+// counterparty can acknowledge an envelope (e.g. an accepted reply from the originator
+// or subsequent pending messages while the beneficiary is reviewing the transfer).
+//
+//	STARTED -> PENDING, REVIEW -> ACCEPTED -> COMPLETED
+//	   |         |                  |-----> REJECTED
+//	   |         |-> REPAIR -> STARTED
+//	   |-------------> ACCEPTED, REPAIR, REJECTED
+var Transitions = map[api.TransferState][]api.TransferState{
+	api.TransferStateUnspecified: {api.TransferStarted},
+	api.TransferStarted:          {api.TransferPending, api.TransferReview, api.TransferRepair, api.TransferAccepted, api.TransferRejected},
+	api.TransferPending:          {api.TransferPending, api.TransferReview, api.TransferRepair, api.TransferAccepted, api.TransferRejected},
+	api.TransferReview:           {api.TransferPending, api.TransferReview, api.TransferRepair, api.TransferAccepted, api.TransferRejected},
+	api.TransferRepair:           {api.TransferStarted, api.TransferPending, api.TransferReview, api.TransferRepair, api.TransferRejected},
+	api.TransferAccepted:         {api.TransferAccepted, api.TransferCompleted, api.TransferRejected},
+	api.TransferCompleted:        {api.TransferCompleted},
+	api.TransferRejected:         {},
+}
+
+// CanTransition returns true if an envelope with the next state may follow the current
+// state of the transfer according to the Transitions table.
+func CanTransition(from, to api.TransferState) bool {
+	for _, state := range Transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Terminal returns true if no further envelopes may be exchanged in the state.
+func Terminal(state api.TransferState) bool {
+	return len(Transitions[state]) == 0
+}
+
+// CanSend returns true if the party is allowed to send an envelope with the next
+// state. Only the originator may start or complete a transfer and only the beneficiary
+// may accept it, unless the envelope acknowledges the current state of the transfer.
+func CanSend(sender Party, from, to api.TransferState) bool {
+	if from == to {
+		return true
+	}
+
+	switch to {
+	case api.TransferStarted, api.TransferCompleted:
+		return sender == Originator
+	case api.TransferAccepted:
+		return sender == Beneficiary
+	default:
+		return true
+	}
+}
+
+// NextActor returns the party that must act after the sender sends an envelope with
+// the specified state. A pending envelope indicates that the sender will follow up
+// while started, review, and repair envelopes require the recipient to act. Once the
+// transfer is accepted the originator must send the transaction and the beneficiary
+// must acknowledge its completion.
+func NextActor(sender Party, state api.TransferState) Party {
+	switch state {
+	case api.TransferPending:
+		return sender
+	case api.TransferStarted, api.TransferReview, api.TransferRepair:
+		return sender.Counterparty()
+	case api.TransferAccepted:
+		return Originator
+	case api.TransferCompleted:
+		if sender == Originator {
+			return Beneficiary
+		}
+		return Nobody
+	default:
+		return Nobody
+	}
+}
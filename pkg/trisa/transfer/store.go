@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"time"
+
+	"github.com/trisacrypto/trisa/pkg/internal/memstore"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+)
+
+// Transfer is the tracked state of a TRISA exchange between two peers, correlated by
+// the ID of the secure envelopes, which is shared by every envelope of the exchange.
+type Transfer struct {
+	EnvelopeID string
+	Role       Party             // The role of the local node in the transfer
+	State      api.TransferState // The state of the last envelope exchanged
+	NextActor  Party             // The party that must send the next envelope
+	Created    time.Time
+	Modified   time.Time
+	History    []Transition // All envelopes exchanged in the transfer in order
+}
+
+// Transition records an envelope that changed or acknowledged the state of a transfer.
+type Transition struct {
+	From      api.TransferState
+	To        api.TransferState
+	Direction envelope.Direction
+	Sender    Party
+	Timestamp time.Time
+}
+
+// Done returns true if neither party is expected to send another envelope.
+func (t *Transfer) Done() bool {
+	return t.NextActor == Nobody
+}
+
+// MustAct returns true if the local node must send the next envelope.
+func (t *Transfer) MustAct() bool {
+	return t.NextActor != Nobody && t.NextActor == t.Role
+}
+
+// Returns a copy of the transfer that does not share its history.
+func (t *Transfer) clone() *Transfer {
+	out := *t
+	out.History = append([]Transition(nil), t.History...)
+	return &out
+}
+
+// Store persists tracked transfers so that envelopes can be correlated across process
+// restarts or between the nodes of a cluster. Implementations must be safe for
+// concurrent use and should return ErrNotFound if a transfer does not exist.
+type Store interface {
+	Get(envelopeID string) (*Transfer, error)
+	Put(*Transfer) error
+	Delete(envelopeID string) error
+	List() ([]*Transfer, error)
+}
+
+// MemoryStore keeps the state of TRISA transfers in memory and is the store the
+// workflow uses unless WithStore is specified. Transfers are lost when the process
+// exits, so an envelope that continues a transfer started before a restart is treated
+// as the first envelope of a new transfer.
+type MemoryStore struct {
+	transfers *memstore.Store[*Transfer]
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns an empty in-memory store of TRISA transfers.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		transfers: memstore.New(memstore.Config[*Transfer]{
+			Key:      func(t *Transfer) string { return t.EnvelopeID },
+			Clone:    (*Transfer).clone,
+			Created:  func(t *Transfer) time.Time { return t.Created },
+			NotFound: ErrNotFound,
+			NoKey:    ErrNoID,
+		}),
+	}
+}
+
+// Get returns a copy of the transfer with the specified envelope ID.
+func (s *MemoryStore) Get(envelopeID string) (*Transfer, error) {
+	return s.transfers.Get(envelopeID)
+}
+
+// Put stores a copy of the transfer under its envelope ID.
+func (s *MemoryStore) Put(t *Transfer) error {
+	return s.transfers.Put(t)
+}
+
+// Delete the transfer with the specified envelope ID.
+func (s *MemoryStore) Delete(envelopeID string) error {
+	return s.transfers.Delete(envelopeID)
+}
+
+// List returns copies of all transfers ordered by when their first envelope was seen.
+func (s *MemoryStore) List() ([]*Transfer, error) {
+	return s.transfers.List()
+}
@@ -0,0 +1,184 @@
+/*
+Package transfer implements a state machine for TRISA transfers. Every secure envelope
+of a TRISA exchange shares the same envelope ID and carries a transfer state that
+describes the progress of the travel rule exchange (started, pending, review, repair,
+accepted, completed, or rejected). The workflow keys transfers by envelope ID and
+validates the state of each incoming and outgoing envelope against the Transitions
+table, tracking which counterparty must act next. Illegal transitions are returned as
+TRISA rejection errors so that they can be sent to the remote peer in an error envelope.
+*/
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+)
+
+// Workflow validates and records the transfer state of secure envelopes.
+type Workflow struct {
+	sync.Mutex
+	store Store
+}
+
+// New creates a workflow with an in-memory store unless otherwise specified.
+func New(opts ...Option) (workflow *Workflow, err error) {
+	workflow = &Workflow{}
+	for _, opt := range opts {
+		if err = opt(workflow); err != nil {
+			return nil, err
+		}
+	}
+
+	if workflow.store == nil {
+		workflow.store = NewMemoryStore()
+	}
+	return workflow, nil
+}
+
+// Receive validates and records a secure envelope received from the remote peer. If
+// the transfer state of the envelope is not legal a rejection is returned that should
+// be sent back to the peer; the state of the transfer is not modified. Errors are only
+// returned if the transfer could not be loaded or saved.
+func (w *Workflow) Receive(msg *api.SecureEnvelope) (*Transfer, *api.Error, error) {
+	return w.record(envelope.Incoming, msg)
+}
+
+// Send validates and records a secure envelope that is being sent to the remote peer.
+// A rejection is returned if the local node is not allowed to send the envelope in the
+// current state of the transfer; the envelope should not be sent.
+func (w *Workflow) Send(msg *api.SecureEnvelope) (*Transfer, *api.Error, error) {
+	return w.record(envelope.Outgoing, msg)
+}
+
+// Get the tracked transfer with the specified envelope ID.
+func (w *Workflow) Get(envelopeID string) (*Transfer, error) {
+	return w.store.Get(envelopeID)
+}
+
+// Delete the tracked transfer with the specified envelope ID.
+func (w *Workflow) Delete(envelopeID string) error {
+	return w.store.Delete(envelopeID)
+}
+
+func (w *Workflow) record(direction envelope.Direction, msg *api.SecureEnvelope) (_ *Transfer, reject *api.Error, err error) {
+	if msg == nil {
+		return nil, rejectf("%s", ErrNoEnvelope), nil
+	}
+
+	if msg.Id == "" {
+		return nil, rejectf("%s", ErrNoID), nil
+	}
+
+	var state api.TransferState
+	if state, err = State(msg); err != nil {
+		return nil, rejectf("%s", err), nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	now := time.Now()
+	var transfer *Transfer
+	if transfer, err = w.store.Get(msg.Id); err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, nil, err
+		}
+
+		// The party that starts the transfer is the originator
+		transfer = &Transfer{
+			EnvelopeID: msg.Id,
+			Role:       Originator,
+			State:      api.TransferStateUnspecified,
+			NextActor:  Originator,
+			Created:    now,
+		}
+
+		if direction == envelope.Incoming {
+			transfer.Role = Beneficiary
+		}
+	}
+
+	sender := transfer.Role
+	if direction == envelope.Incoming {
+		sender = sender.Counterparty()
+	}
+
+	if reject = Validate(transfer, sender, state); reject != nil {
+		return nil, reject, nil
+	}
+
+	transfer.History = append(transfer.History, Transition{
+		From:      transfer.State,
+		To:        state,
+		Direction: direction,
+		Sender:    sender,
+		Timestamp: now,
+	})
+	transfer.State = state
+	transfer.NextActor = NextActor(sender, state)
+	transfer.Modified = now
+
+	if err = w.store.Put(transfer); err != nil {
+		return nil, nil, err
+	}
+	return transfer, nil, nil
+}
+
+// Validate returns a rejection if the sender is not allowed to send an envelope with
+// the specified state in the current state of the transfer. Either party may reject a
+// transfer that is not in a terminal state, otherwise only the party that is expected
+// to act next may send an envelope.
+func Validate(transfer *Transfer, sender Party, state api.TransferState) *api.Error {
+	if !CanTransition(transfer.State, state) {
+		return rejectf("cannot transition transfer from %s to %s", transfer.State, state)
+	}
+
+	if state != api.TransferRejected && transfer.NextActor != sender {
+		return rejectf("the %s cannot send a %s envelope: waiting on the %s", sender, state, transfer.NextActor)
+	}
+
+	if !CanSend(sender, transfer.State, state) {
+		return rejectf("the %s cannot send a %s envelope", sender, state)
+	}
+	return nil
+}
+
+// State returns the transfer state of the secure envelope. Error envelopes that do not
+// specify a transfer state are in the rejected state unless the error can be retried,
+// in which case they are in the repair state. An error is returned if any other
+// envelope does not specify a transfer state.
+func State(msg *api.SecureEnvelope) (api.TransferState, error) {
+	if msg.TransferState != api.TransferStateUnspecified {
+		return msg.TransferState, nil
+	}
+
+	if msg.Error != nil && !msg.Error.IsZero() {
+		if msg.Error.Retry {
+			return api.TransferRepair, nil
+		}
+		return api.TransferRejected, nil
+	}
+	return api.TransferStateUnspecified, ErrUnspecified
+}
+
+// Illegal envelopes are rejected with a bad request that cannot be retried, since the
+// envelope would not be legal in the current state of the transfer if it were resent.
+func rejectf(format string, a ...interface{}) *api.Error {
+	return &api.Error{Code: api.BadRequest, Message: fmt.Sprintf(format, a...)}
+}
+
+// Option configures the workflow when it is created.
+type Option func(w *Workflow) error
+
+// Specify the store used to persist transfers.
+func WithStore(store Store) Option {
+	return func(w *Workflow) error {
+		w.store = store
+		return nil
+	}
+}
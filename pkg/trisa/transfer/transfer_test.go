@@ -0,0 +1,251 @@
+package transfer_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	api "github.com/trisacrypto/trisa/pkg/trisa/api/v1beta1"
+	"github.com/trisacrypto/trisa/pkg/trisa/envelope"
+	"github.com/trisacrypto/trisa/pkg/trisa/transfer"
+)
+
+const (
+	in  = envelope.Incoming
+	out = envelope.Outgoing
+)
+
+type step struct {
+	direction envelope.Direction
+	state     api.TransferState
+	illegal   bool
+}
+
+func TestWorkflow(t *testing.T) {
+	testCases := []struct {
+		name      string
+		steps     []step
+		role      transfer.Party
+		final     api.TransferState
+		nextActor transfer.Party
+	}{
+		{
+			name: "SynchronousCompletion",
+			steps: []step{
+				{out, api.TransferStarted, false},
+				{in, api.TransferAccepted, false},
+				{out, api.TransferCompleted, false},
+				{in, api.TransferCompleted, false},
+			},
+			role:      transfer.Originator,
+			final:     api.TransferCompleted,
+			nextActor: transfer.Nobody,
+		},
+		{
+			name: "AsynchronousAcceptance",
+			steps: []step{
+				{in, api.TransferStarted, false},
+				{out, api.TransferPending, false},
+				{out, api.TransferPending, false},
+				{out, api.TransferAccepted, false},
+				{in, api.TransferAccepted, false},
+				{in, api.TransferCompleted, false},
+			},
+			role:      transfer.Beneficiary,
+			final:     api.TransferCompleted,
+			nextActor: transfer.Beneficiary,
+		},
+		{
+			name: "Repair",
+			steps: []step{
+				{out, api.TransferStarted, false},
+				{in, api.TransferRepair, false},
+				{out, api.TransferStarted, false},
+				{in, api.TransferReview, false},
+				{out, api.TransferPending, false},
+				{out, api.TransferReview, false},
+			},
+			role:      transfer.Originator,
+			final:     api.TransferReview,
+			nextActor: transfer.Beneficiary,
+		},
+		{
+			name: "RejectedIsTerminal",
+			steps: []step{
+				{out, api.TransferStarted, false},
+				{in, api.TransferRejected, false},
+				{out, api.TransferStarted, true},
+				{out, api.TransferRejected, true},
+				{in, api.TransferAccepted, true},
+			},
+			role:      transfer.Originator,
+			final:     api.TransferRejected,
+			nextActor: transfer.Nobody,
+		},
+		{
+			name: "EitherPartyMayReject",
+			steps: []step{
+				{in, api.TransferStarted, false},
+				{out, api.TransferPending, false},
+				{in, api.TransferRejected, false},
+			},
+			role:      transfer.Beneficiary,
+			final:     api.TransferRejected,
+			nextActor: transfer.Nobody,
+		},
+		{
+			name: "MustStart",
+			steps: []step{
+				{in, api.TransferPending, true},
+				{in, api.TransferAccepted, true},
+				{in, api.TransferCompleted, true},
+				{in, api.TransferStarted, false},
+			},
+			role:      transfer.Beneficiary,
+			final:     api.TransferStarted,
+			nextActor: transfer.Beneficiary,
+		},
+		{
+			name: "OutOfTurn",
+			steps: []step{
+				{out, api.TransferStarted, false},
+				{out, api.TransferPending, true},
+				{in, api.TransferPending, false},
+				{out, api.TransferReview, true},
+				{in, api.TransferAccepted, false},
+				{in, api.TransferAccepted, true},
+			},
+			role:      transfer.Originator,
+			final:     api.TransferAccepted,
+			nextActor: transfer.Originator,
+		},
+		{
+			name: "WrongRole",
+			steps: []step{
+				{in, api.TransferStarted, false},
+				{out, api.TransferCompleted, true},
+				{out, api.TransferReview, false},
+				{in, api.TransferAccepted, true},
+				{in, api.TransferStarted, true},
+				{in, api.TransferPending, false},
+			},
+			role:      transfer.Beneficiary,
+			final:     api.TransferPending,
+			nextActor: transfer.Originator,
+		},
+		{
+			name: "CompletedRequiresAcceptance",
+			steps: []step{
+				{out, api.TransferStarted, false},
+				{in, api.TransferPending, false},
+				{out, api.TransferCompleted, true},
+				{in, api.TransferCompleted, true},
+			},
+			role:      transfer.Originator,
+			final:     api.TransferPending,
+			nextActor: transfer.Beneficiary,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workflow, err := transfer.New()
+			require.NoError(t, err)
+
+			id := uuid.NewString()
+			legal := 0
+			for i, s := range tc.steps {
+				msg := &api.SecureEnvelope{Id: id, TransferState: s.state}
+
+				var reject *api.Error
+				switch s.direction {
+				case in:
+					_, reject, err = workflow.Receive(msg)
+				case out:
+					_, reject, err = workflow.Send(msg)
+				}
+				require.NoError(t, err, "step %d", i)
+
+				if s.illegal {
+					require.NotNil(t, reject, "expected step %d (%s %s) to be illegal", i, s.direction, s.state)
+					require.Equal(t, api.BadRequest, reject.Code)
+					require.False(t, reject.Retry)
+				} else {
+					require.Nil(t, reject, "expected step %d (%s %s) to be legal", i, s.direction, s.state)
+					legal++
+				}
+			}
+
+			actual, err := workflow.Get(id)
+			require.NoError(t, err)
+			require.Equal(t, tc.role, actual.Role)
+			require.Equal(t, tc.final, actual.State)
+			require.Equal(t, tc.nextActor, actual.NextActor)
+			require.Len(t, actual.History, legal, "only legal transitions should be recorded")
+		})
+	}
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	workflow, err := transfer.New()
+	require.NoError(t, err)
+
+	id := uuid.NewString()
+	_, reject, err := workflow.Send(&api.SecureEnvelope{Id: id, TransferState: api.TransferStarted})
+	require.NoError(t, err)
+	require.Nil(t, reject)
+
+	// An error envelope that can be retried without a transfer state requires repair
+	actual, reject, err := workflow.Receive(&api.SecureEnvelope{Id: id, Error: api.Errorf(api.MissingFields, "missing beneficiary").WithRetry()})
+	require.NoError(t, err)
+	require.Nil(t, reject)
+	require.Equal(t, api.TransferRepair, actual.State)
+	require.True(t, actual.MustAct())
+
+	// An error envelope that cannot be retried rejects the transfer
+	actual, reject, err = workflow.Receive(&api.SecureEnvelope{Id: id, Error: api.Errorf(api.ComplianceCheckFail, "sanctioned")})
+	require.NoError(t, err)
+	require.Nil(t, reject)
+	require.Equal(t, api.TransferRejected, actual.State)
+	require.True(t, actual.Done())
+}
+
+func TestInvalidEnvelopes(t *testing.T) {
+	workflow, err := transfer.New()
+	require.NoError(t, err)
+
+	testCases := []*api.SecureEnvelope{
+		nil,
+		{TransferState: api.TransferStarted},
+		{Id: uuid.NewString()},
+	}
+
+	for i, msg := range testCases {
+		_, reject, err := workflow.Receive(msg)
+		require.NoError(t, err, "test case %d", i)
+		require.NotNil(t, reject, "test case %d", i)
+		require.Equal(t, api.BadRequest, reject.Code, "test case %d", i)
+	}
+
+	_, err = workflow.Get(uuid.NewString())
+	require.ErrorIs(t, err, transfer.ErrNotFound)
+}
+
+func TestTransitions(t *testing.T) {
+	states := []api.TransferState{
+		api.TransferStateUnspecified, api.TransferStarted, api.TransferPending, api.TransferReview,
+		api.TransferRepair, api.TransferAccepted, api.TransferCompleted, api.TransferRejected,
+	}
+
+	// Every state must be in the table and only rejected is terminal
+	for _, state := range states {
+		_, ok := transfer.Transitions[state]
+		require.True(t, ok, "%s is missing from the transitions table", state)
+		require.Equal(t, state == api.TransferRejected, transfer.Terminal(state), state.String())
+	}
+
+	// Nothing may transition into the unspecified state
+	for _, state := range states {
+		require.False(t, transfer.CanTransition(state, api.TransferStateUnspecified))
+	}
+}
@@ -0,0 +1,231 @@
+package trust
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// EncodeBundle writes the certificate chains of the providers as a PEM bundle where
+// each certificate is annotated with comments describing its subject, issuer, serial
+// number, validity period, and fingerprint so that operators can inspect the bundle
+// without decoding it. Comments are ignored by PEM decoders so the bundle can be read
+// by any tool that accepts concatenated PEM certificates. Private keys are not written.
+func EncodeBundle(providers ...*Provider) (_ []byte, err error) {
+	var b bytes.Buffer
+	for _, p := range providers {
+		fmt.Fprintf(&b, "# %s\n", p.String())
+		for i, asn1Data := range p.chain.Certificate {
+			var crt *x509.Certificate
+			if crt, err = x509.ParseCertificate(asn1Data); err != nil {
+				return nil, fmt.Errorf("could not parse certificate %d of %s: %s", i, p, err)
+			}
+
+			fmt.Fprintf(&b, "# Subject: %s\n", crt.Subject)
+			fmt.Fprintf(&b, "# Issuer: %s\n", crt.Issuer)
+			fmt.Fprintf(&b, "# Serial: %X\n", crt.SerialNumber)
+			fmt.Fprintf(&b, "# Not Before: %s\n", crt.NotBefore.UTC().Format(time.RFC3339))
+			fmt.Fprintf(&b, "# Not After: %s\n", crt.NotAfter.UTC().Format(time.RFC3339))
+			fmt.Fprintf(&b, "# SHA256 Fingerprint: %s\n", Fingerprint(crt))
+
+			if err = pem.Encode(&b, &pem.Block{Type: BlockCertificate, Bytes: crt.Raw}); err != nil {
+				return nil, fmt.Errorf("could not encode certificate %d of %s: %s", i, p, err)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeBundle reads a PEM bundle of one or more certificate chains and returns a
+// public provider for each chain. Chains are expected to be ordered from the leaf
+// certificate to the root; a new chain is started whenever a certificate is not the
+// issuer of the certificate before it. Comments and private keys are ignored.
+func DecodeBundle(data []byte) (_ []*Provider, err error) {
+	certs := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+
+		switch block.Type {
+		case BlockCertificate:
+			var crt *x509.Certificate
+			if crt, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, fmt.Errorf("could not parse certificate %d: %s", len(certs), err)
+			}
+			certs = append(certs, crt)
+		case BlockPrivateKey, BlockECPrivateKey, BlockRSAPrivateKey, BlockEncryptedKey:
+			// Bundles only contain public certificates
+			continue
+		default:
+			return nil, fmt.Errorf("unhandled block type %q", block.Type)
+		}
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrNoCertificates
+	}
+	return splitChains(certs), nil
+}
+
+// EncodeTruststore writes the certificates of the providers in the pool as a PKCS#12
+// truststore that contains only trusted certificate entries (no private keys), which
+// can be loaded directly by Java services as a PKCS12 keystore. The password protects
+// the integrity of the truststore; if empty, pkcs12.DefaultPassword is used.
+func EncodeTruststore(pool ProviderPool, password string) (_ []byte, err error) {
+	if password == "" {
+		password = pkcs12.DefaultPassword
+	}
+
+	entries := make([]pkcs12.TrustStoreEntry, 0, len(pool))
+	seen := make(map[[32]byte]struct{})
+	aliases := make(map[string]int)
+
+	for _, p := range pool.sorted() {
+		for i, asn1Data := range p.chain.Certificate {
+			// Intermediate and root certificates are often shared between chains
+			fingerprint := sha256.Sum256(asn1Data)
+			if _, ok := seen[fingerprint]; ok {
+				continue
+			}
+			seen[fingerprint] = struct{}{}
+
+			var crt *x509.Certificate
+			if crt, err = x509.ParseCertificate(asn1Data); err != nil {
+				return nil, fmt.Errorf("could not parse certificate %d of %s: %s", i, p, err)
+			}
+
+			// Java requires aliases to be unique in the keystore
+			alias := strings.ReplaceAll(strings.ToLower(crt.Subject.CommonName), " ", "_")
+			if n := aliases[alias]; n > 0 {
+				aliases[alias]++
+				alias = fmt.Sprintf("%s-%d", alias, n)
+			} else {
+				aliases[alias] = 1
+			}
+
+			entries = append(entries, pkcs12.TrustStoreEntry{Cert: crt, FriendlyName: alias})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoCertificates
+	}
+	return pkcs12.Modern.EncodeTrustStoreEntries(entries, password)
+}
+
+// DecodeTruststore reads a PKCS#12 truststore and returns a pool of public providers.
+// Because EncodeTruststore stores certificates that are shared between chains only
+// once, the chains are rebuilt by looking up the issuer of each leaf certificate among
+// the certificates in the truststore rather than by their order. If the password is
+// empty, pkcs12.DefaultPassword is used.
+func DecodeTruststore(pfxData []byte, password string) (_ ProviderPool, err error) {
+	if password == "" {
+		password = pkcs12.DefaultPassword
+	}
+
+	var certs []*x509.Certificate
+	if certs, err = pkcs12.DecodeTrustStore(pfxData, password); err != nil {
+		return nil, err
+	}
+
+	if len(certs) == 0 {
+		return nil, ErrNoCertificates
+	}
+	return NewPool(buildChains(certs)...), nil
+}
+
+// Group an ordered list of certificates into chains, starting a new chain whenever a
+// certificate did not issue the certificate before it.
+func splitChains(certs []*x509.Certificate) []*Provider {
+	providers := make([]*Provider, 0, 1)
+	var chain tls.Certificate
+	for i, crt := range certs {
+		if i > 0 && !bytes.Equal(certs[i-1].RawIssuer, crt.RawSubject) {
+			providers = append(providers, &Provider{chain: chain})
+			chain = tls.Certificate{}
+		}
+		chain.Certificate = append(chain.Certificate, crt.Raw)
+	}
+	return append(providers, &Provider{chain: chain})
+}
+
+// Group an unordered set of certificates into chains, one for each leaf certificate
+// (a certificate that did not issue any other certificate in the set), following the
+// issuer of each certificate until a self-signed certificate is reached or the issuer
+// is not in the set. Certificates may be shared by more than one chain.
+func buildChains(certs []*x509.Certificate) []*Provider {
+	issuers := make(map[*x509.Certificate]*x509.Certificate, len(certs))
+	issued := make(map[*x509.Certificate]bool, len(certs))
+	for _, crt := range certs {
+		if issuer := findIssuer(crt, certs); issuer != nil {
+			issuers[crt] = issuer
+			issued[issuer] = true
+		}
+	}
+
+	providers := make([]*Provider, 0, len(certs))
+	for _, crt := range certs {
+		if issued[crt] {
+			continue
+		}
+
+		var chain tls.Certificate
+		seen := make(map[*x509.Certificate]bool)
+		for next := crt; next != nil && !seen[next]; next = issuers[next] {
+			seen[next] = true
+			chain.Certificate = append(chain.Certificate, next.Raw)
+		}
+		providers = append(providers, &Provider{chain: chain})
+	}
+
+	// Every certificate issued another one, e.g. a cross-signed pair; keep them together
+	if len(providers) == 0 {
+		return splitChains(certs)
+	}
+	return providers
+}
+
+// Returns the certificate in the set that issued crt, preferring a certificate whose
+// signature can be verified over one that only matches by subject name.
+func findIssuer(crt *x509.Certificate, certs []*x509.Certificate) (issuer *x509.Certificate) {
+	for _, candidate := range certs {
+		if candidate == crt || !bytes.Equal(candidate.RawSubject, crt.RawIssuer) {
+			continue
+		}
+
+		if candidate.CheckSignature(crt.SignatureAlgorithm, crt.RawTBSCertificate, crt.Signature) == nil {
+			return candidate
+		}
+
+		if issuer == nil {
+			issuer = candidate
+		}
+	}
+	return issuer
+}
+
+// Returns the providers in the pool sorted by name for deterministic serialization.
+func (pool ProviderPool) sorted() []*Provider {
+	names := make([]string, 0, len(pool))
+	for name := range pool {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providers := make([]*Provider, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, pool[name])
+	}
+	return providers
+}
@@ -0,0 +1,283 @@
+package trust_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/trust"
+	"github.com/trisacrypto/trisa/pkg/trust/mock"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestBundle(t *testing.T) {
+	pool := loadPoolFixture(t)
+
+	data, err := trust.EncodeBundle(pool["docs.trisa.dev"], pool["Test"])
+	require.NoError(t, err, "could not encode bundle")
+	require.Contains(t, string(data), "# Subject: CN=docs.trisa.dev")
+	require.Contains(t, string(data), "# Not After: ")
+	require.Contains(t, string(data), "# SHA256 Fingerprint: ")
+	require.NotContains(t, string(data), trust.BlockPrivateKey)
+
+	providers, err := trust.DecodeBundle(data)
+	require.NoError(t, err, "could not decode bundle")
+	requirePoolsEqual(t, pool, trust.NewPool(providers...))
+
+	_, err = trust.DecodeBundle([]byte("# no certificates here\n"))
+	require.ErrorIs(t, err, trust.ErrNoCertificates)
+}
+
+func TestTruststore(t *testing.T) {
+	pool := loadPoolFixture(t)
+
+	data, err := trust.EncodeTruststore(pool, "")
+	require.NoError(t, err, "could not encode truststore")
+
+	// Should be able to decode the truststore as a plain PKCS12 truststore
+	certs, err := pkcs12.DecodeTrustStore(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decode truststore with pkcs12")
+	require.Len(t, certs, 5, "expected the fixture and mock chain certificates")
+
+	decoded, err := trust.DecodeTruststore(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decode truststore")
+	requirePoolsEqual(t, pool, decoded)
+
+	_, err = trust.DecodeTruststore(data, "wrongpassword")
+	require.Error(t, err, "should not be able to decode truststore with wrong password")
+
+	_, err = trust.EncodeTruststore(trust.NewPool(), "")
+	require.ErrorIs(t, err, trust.ErrNoCertificates)
+}
+
+func TestTruststoreSharedIntermediate(t *testing.T) {
+	// The intermediate and root are only stored once in the truststore
+	pool := sharedIntermediatePool(t, "alpha.example.com", "bravo.example.com")
+
+	data, err := trust.EncodeTruststore(pool, "")
+	require.NoError(t, err, "could not encode truststore")
+
+	certs, err := pkcs12.DecodeTrustStore(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decode truststore with pkcs12")
+	require.Len(t, certs, 4, "expected two leaves and a shared intermediate and root")
+
+	decoded, err := trust.DecodeTruststore(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decode truststore")
+	requirePoolsEqual(t, pool, decoded)
+}
+
+func TestSerializerDirectoryNames(t *testing.T) {
+	// Common names that differ only by case map to the same file name
+	pool := sharedIntermediatePool(t, "Leaf Node", "leaf node", "../escape")
+	path := filepath.Join(t.TempDir(), "pool")
+
+	serializer, err := trust.NewSerializer(false, "", trust.FormatDirectory)
+	require.NoError(t, err)
+	require.NoError(t, serializer.WritePoolFile(pool, path))
+
+	require.FileExists(t, filepath.Join(path, "leaf_node.pem"))
+	require.FileExists(t, filepath.Join(path, "leaf_node-1.pem"))
+	require.FileExists(t, filepath.Join(path, ".._escape.pem"))
+	require.NoFileExists(t, filepath.Join(filepath.Dir(path), "escape.pem"))
+
+	serializer, err = trust.NewSerializer(false)
+	require.NoError(t, err)
+	actual, err := serializer.ReadPoolFile(path)
+	require.NoError(t, err)
+	requirePoolsEqual(t, pool, actual)
+}
+
+func TestSerializerPoolFormats(t *testing.T) {
+	pool := loadPoolFixture(t)
+	tmpdir := t.TempDir()
+
+	for _, path := range []string{"pool.bundle", "pool.pfx", "pool.zip", "pool" + string(filepath.Separator)} {
+		path = filepath.Join(tmpdir, path)
+
+		serializer, err := trust.NewSerializer(false)
+		require.NoError(t, err)
+		require.NoError(t, serializer.WritePoolFile(pool, path), "could not write pool to %s", path)
+
+		serializer, err = trust.NewSerializer(false)
+		require.NoError(t, err)
+		actual, err := serializer.ReadPoolFile(path)
+		require.NoError(t, err, "could not read pool from %s", path)
+		requirePoolsEqual(t, pool, actual)
+
+		if info, _ := os.Stat(path); info.IsDir() {
+			require.FileExists(t, filepath.Join(path, "docs.trisa.dev.pem"))
+			require.FileExists(t, filepath.Join(path, "test.pem"))
+			continue
+		}
+
+		// Format should be detected from the data if read without a path
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		serializer, err = trust.NewSerializer(false)
+		require.NoError(t, err)
+		actual, err = serializer.ExtractPool(data)
+		require.NoError(t, err, "could not detect format of %s", path)
+		requirePoolsEqual(t, pool, actual)
+	}
+
+	// Java KeyStore files are not supported
+	serializer, err := trust.NewSerializer(false)
+	require.NoError(t, err)
+	_, err = serializer.ReadPoolFile(filepath.Join(tmpdir, "pool.jks"))
+	require.Error(t, err)
+
+	// Cannot write a directory without a path
+	serializer, err = trust.NewSerializer(false, "", trust.FormatDirectory)
+	require.NoError(t, err)
+	_, err = serializer.CompressPool(pool)
+	require.ErrorIs(t, err, trust.ErrDirectoryPath)
+
+	// Cannot write multiple providers to a single PEM file
+	serializer, err = trust.NewSerializer(false, "", trust.CompressionNone)
+	require.NoError(t, err)
+	_, err = serializer.CompressPool(pool)
+	require.Error(t, err)
+}
+
+func TestReadPoolPEM(t *testing.T) {
+	data, err := mock.Chain()
+	require.NoError(t, err, "could not create mock chain")
+
+	chain, err := trust.Decrypt(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decrypt mock chain")
+
+	// Create a PEM file with the leaf and root certificates but not the intermediate.
+	encoded, err := chain.Encode()
+	require.NoError(t, err)
+
+	var certs [][]byte
+	for block, rest := pem.Decode(encoded); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == trust.BlockCertificate {
+			certs = append(certs, pem.EncodeToMemory(block))
+		}
+	}
+	require.Len(t, certs, 3, "expected leaf, intermediate, and root certificates")
+	data = append(certs[0], certs[2]...)
+
+	// A PEM file in a pool is read as a single provider as written
+	serializer, err := trust.NewSerializer(false, "", trust.CompressionNone)
+	require.NoError(t, err)
+
+	pool, err := serializer.ReadPool(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, pool, 1)
+
+	actual, err := pool[chain.String()].Encode()
+	require.NoError(t, err)
+	require.Equal(t, data, actual)
+
+	// Only annotated bundles are split into chains by issuer
+	serializer, err = trust.NewSerializer(false, "", trust.FormatBundle)
+	require.NoError(t, err)
+
+	pool, err = serializer.ReadPool(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, pool, 2)
+}
+
+func loadPoolFixture(t *testing.T) trust.ProviderPool {
+	serializer, err := trust.NewSerializer(true, "supersecretsquirrel")
+	require.NoError(t, err)
+
+	provider, err := serializer.ReadFile("testdata/110831.zip")
+	require.NoError(t, err, "could not load provider fixture")
+
+	data, err := mock.Chain()
+	require.NoError(t, err, "could not create mock chain")
+
+	chain, err := trust.Decrypt(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decrypt mock chain")
+
+	pool := trust.NewPool(provider, chain)
+	require.Len(t, pool, 2)
+	return pool
+}
+
+// Creates a pool with a provider for each common name whose leaf certificates are all
+// issued by the same intermediate and root certificate authorities.
+func sharedIntermediatePool(t *testing.T, names ...string) trust.ProviderPool {
+	root, rootKey := issueCert(t, "Test Root CA", true, nil, nil)
+	intermediate, intermediateKey := issueCert(t, "Test Intermediate CA", true, root, rootKey)
+
+	pool := trust.NewPool()
+	for _, name := range names {
+		leaf, _ := issueCert(t, name, false, intermediate, intermediateKey)
+
+		var data []byte
+		for _, crt := range []*x509.Certificate{leaf, intermediate, root} {
+			data = append(data, pem.EncodeToMemory(&pem.Block{Type: trust.BlockCertificate, Bytes: crt.Raw})...)
+		}
+
+		providers, err := trust.DecodeBundle(data)
+		require.NoError(t, err)
+		require.Len(t, providers, 1)
+		pool.Add(providers[0])
+	}
+
+	require.Len(t, pool, len(names))
+	return pool
+}
+
+// Issues a certificate with the common name signed by the parent, or a self-signed
+// certificate if the parent is nil. Certificate authorities may sign other certificates.
+func issueCert(t *testing.T, name string, ca bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+	}
+	if ca {
+		tpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	if parent == nil {
+		parent, parentKey = tpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	crt, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return crt, key
+}
+
+func requirePoolsEqual(t *testing.T, expected, actual trust.ProviderPool) {
+	require.Len(t, actual, len(expected))
+	for name, p := range expected {
+		require.Contains(t, actual, name)
+		require.False(t, actual[name].IsPrivate())
+
+		pb, err := p.Encode()
+		require.NoError(t, err)
+		ab, err := actual[name].Encode()
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(pb, ab), "chain for %s does not match", name)
+	}
+}
@@ -4,12 +4,17 @@ import "errors"
 
 // Standard errors for error type checking
 var (
-	ErrDecodePrivateKey  = errors.New("could not decode PEM private key")
-	ErrDecodePublicKey   = errors.New("could not decode PEM public key")
-	ErrDecodeCertificate = errors.New("could not decode PEM certificate")
-	ErrDecodeCSR         = errors.New("could not decode PEM certificate request")
-	ErrNoCertificates    = errors.New("provider does not contain any certificates")
-	ErrKeyRequired       = errors.New("private key required")
-	ErrZipEmpty          = errors.New("zip archive contains no providers")
-	ErrZipTooMany        = errors.New("multiple providers in zip, is this a provider pool?")
+	ErrDecodePrivateKey    = errors.New("could not decode PEM private key")
+	ErrDecodePublicKey     = errors.New("could not decode PEM public key")
+	ErrDecodeCertificate   = errors.New("could not decode PEM certificate")
+	ErrDecodeCSR           = errors.New("could not decode PEM certificate request")
+	ErrNoCertificates      = errors.New("provider does not contain any certificates")
+	ErrKeyRequired         = errors.New("private key required")
+	ErrZipEmpty            = errors.New("zip archive contains no providers")
+	ErrZipTooMany          = errors.New("multiple providers in zip, is this a provider pool?")
+	ErrDirectoryPath       = errors.New("a path is required to write a provider pool to a directory")
+	ErrPoolConflict        = errors.New("provider pools contain different chains for the same provider")
+	ErrPassphraseRequired  = errors.New("a passphrase is required to encrypt or decrypt the private key")
+	ErrIncorrectPassphrase = errors.New("could not decrypt private key: incorrect passphrase")
+	ErrKDFParameters       = errors.New("private key encryption parameters exceed the supported limits")
 )
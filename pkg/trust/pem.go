@@ -10,6 +10,7 @@ import (
 const (
 	BlockPublicKey          = "PUBLIC KEY"
 	BlockPrivateKey         = "PRIVATE KEY"
+	BlockEncryptedKey       = "ENCRYPTED PRIVATE KEY"
 	BlockRSAPublicKey       = "RSA PUBLIC KEY"
 	BlockRSAPrivateKey      = "RSA PRIVATE KEY"
 	BlockECPrivateKey       = "EC PRIVATE KEY"
@@ -32,6 +33,32 @@ func PEMEncodePrivateKey(key interface{}) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// PEMEncodeEncryptedPrivateKey as a PKCS8 ASN.1 DER key encrypted with the passphrase
+// and write a PEM block with type "ENCRYPTED PRIVATE KEY"
+func PEMEncodeEncryptedPrivateKey(key interface{}, passphrase []byte) ([]byte, error) {
+	der, err := EncryptPKCS8PrivateKey(key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := pem.Encode(&b, &pem.Block{Type: BlockEncryptedKey, Bytes: der}); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// PEMDecodeEncryptedPrivateKey from a PEM encoded block with type "ENCRYPTED PRIVATE
+// KEY" using the passphrase to decrypt the PKCS8 ASN.1 DER key.
+func PEMDecodeEncryptedPrivateKey(in, passphrase []byte) (interface{}, error) {
+	block, _ := pem.Decode(in)
+	if block == nil || block.Type != BlockEncryptedKey {
+		return nil, ErrDecodePrivateKey
+	}
+	return DecryptPKCS8PrivateKey(block.Bytes, passphrase)
+}
+
 // PEMDecodePrivateKey from a PEM encoded block. If the block type is "EC PRIVATE KEY",
 // then the block is parsed as an EC private key in SEC 1, ASN.1 DER form. If the block
 // is "RSA PRIVATE KEY" then it is decoded as a PKCS 1, ASN.1 DER form. If the block
@@ -0,0 +1,293 @@
+package trust
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Default scrypt parameters used when encrypting private keys. These match the
+// parameters used by OpenSSL when running `openssl pkcs8 -topk8 -scrypt`.
+const (
+	ScryptN       = 1 << 14
+	ScryptR       = 8
+	ScryptP       = 1
+	scryptSaltLen = 16
+	aes256KeyLen  = 32
+)
+
+// Limits on the key derivation parameters read from an encrypted private key so that a
+// crafted key cannot use gigabytes of memory or hours of CPU before the passphrase is
+// checked. The scrypt limits allow up to 1GiB of memory with the OpenSSL block size.
+const (
+	maxScryptN          = 1 << 20
+	maxScryptRP         = 8
+	maxPBKDF2Iterations = 10000000
+)
+
+// Object identifiers for PKCS#5 v2.0 password based encryption (RFC 8018) and the
+// scrypt key derivation function (RFC 7914).
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// ASN.1 structures for PKCS#8 EncryptedPrivateKeyInfo (RFC 5958) and its parameters.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// EncryptPKCS8PrivateKey marshals the private key as a PKCS#8 ASN.1 DER key and then
+// encrypts it with the passphrase, returning a DER encoded EncryptedPrivateKeyInfo.
+// The encryption scheme is PBES2 using scrypt to derive an AES-256-CBC key, which is
+// the same scheme OpenSSL uses with the -scrypt flag so keys can be inspected with
+// standard tooling. An empty passphrase returns an error.
+func EncryptPKCS8PrivateKey(key interface{}, passphrase []byte) (_ []byte, err error) {
+	if len(passphrase) == 0 {
+		return nil, ErrPassphraseRequired
+	}
+
+	var pkcs8 []byte
+	if pkcs8, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, scryptSaltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("could not generate salt: %w", err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("could not generate iv: %w", err)
+	}
+
+	var dk []byte
+	if dk, err = scrypt.Key(passphrase, salt, ScryptN, ScryptR, ScryptP, aes256KeyLen); err != nil {
+		return nil, fmt.Errorf("could not derive encryption key: %w", err)
+	}
+
+	var block cipher.Block
+	if block, err = aes.NewCipher(dk); err != nil {
+		return nil, err
+	}
+
+	plaintext := pkcs7Pad(pkcs8, aes.BlockSize)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	// Marshal the algorithm parameters from the inside out.
+	var kdfParams, encParams, params []byte
+	if kdfParams, err = asn1.Marshal(scryptParams{
+		Salt:                     salt,
+		CostParameter:            ScryptN,
+		BlockSize:                ScryptR,
+		ParallelizationParameter: ScryptP,
+		KeyLength:                aes256KeyLen,
+	}); err != nil {
+		return nil, err
+	}
+
+	if encParams, err = asn1.Marshal(iv); err != nil {
+		return nil, err
+	}
+
+	if params, err = asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidScrypt, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: encParams}},
+	}); err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: ciphertext,
+	})
+}
+
+// DecryptPKCS8PrivateKey decrypts a DER encoded EncryptedPrivateKeyInfo with the
+// passphrase and parses the PKCS#8 private key inside of it. PBES2 with either scrypt
+// or PBKDF2 (HMAC-SHA1, HMAC-SHA256, or HMAC-SHA512) key derivation and AES-CBC
+// encryption is supported, which covers the keys produced by this package and the
+// OpenSSL defaults. If the passphrase is incorrect, ErrIncorrectPassphrase is returned
+// and if the key derivation parameters exceed the supported limits, ErrKDFParameters is
+// returned without deriving the key.
+func DecryptPKCS8PrivateKey(der, passphrase []byte) (_ interface{}, err error) {
+	var info encryptedPrivateKeyInfo
+	if _, err = asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("could not parse encrypted private key info: %w", err)
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption algorithm %s", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err = asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("could not parse pbes2 parameters: %w", err)
+	}
+
+	// Determine the length of the key from the encryption scheme
+	var keyLen int
+	switch enc := params.EncryptionScheme.Algorithm; {
+	case enc.Equal(oidAES128CBC):
+		keyLen = 16
+	case enc.Equal(oidAES192CBC):
+		keyLen = 24
+	case enc.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported private key encryption scheme %s", enc)
+	}
+
+	var iv []byte
+	if _, err = asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("could not parse encryption scheme iv: %w", err)
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid iv length %d", len(iv))
+	}
+
+	var dk []byte
+	if dk, err = deriveKey(params.KeyDerivationFunc, passphrase, keyLen); err != nil {
+		return nil, err
+	}
+
+	var block cipher.Block
+	if block, err = aes.NewCipher(dk); err != nil {
+		return nil, err
+	}
+
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted private key data length %d", len(info.EncryptedData))
+	}
+
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+	// A padding or parsing failure almost certainly means the passphrase was wrong
+	if plaintext, err = pkcs7Unpad(plaintext, aes.BlockSize); err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	var key interface{}
+	if key, err = x509.ParsePKCS8PrivateKey(plaintext); err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+	return key, nil
+}
+
+func deriveKey(kdf pkix.AlgorithmIdentifier, passphrase []byte, keyLen int) (_ []byte, err error) {
+	switch {
+	case kdf.Algorithm.Equal(oidScrypt):
+		var params scryptParams
+		if _, err = asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("could not parse scrypt parameters: %w", err)
+		}
+
+		if params.KeyLength != 0 && params.KeyLength != keyLen {
+			return nil, fmt.Errorf("scrypt key length %d does not match cipher", params.KeyLength)
+		}
+
+		n, r, p := params.CostParameter, params.BlockSize, params.ParallelizationParameter
+		if n <= 1 || n > maxScryptN || r <= 0 || p <= 0 || r > maxScryptRP/p {
+			return nil, fmt.Errorf("%w: scrypt N=%d r=%d p=%d", ErrKDFParameters, n, r, p)
+		}
+		return scrypt.Key(passphrase, params.Salt, params.CostParameter, params.BlockSize, params.ParallelizationParameter, keyLen)
+
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var params pbkdf2Params
+		if _, err = asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("could not parse pbkdf2 parameters: %w", err)
+		}
+
+		if params.KeyLength != 0 && params.KeyLength != keyLen {
+			return nil, fmt.Errorf("pbkdf2 key length %d does not match cipher", params.KeyLength)
+		}
+
+		if params.IterationCount <= 0 || params.IterationCount > maxPBKDF2Iterations {
+			return nil, fmt.Errorf("%w: pbkdf2 iteration count %d", ErrKDFParameters, params.IterationCount)
+		}
+
+		// The default PRF is HMAC-SHA1 if not specified
+		var prf func() hash.Hash
+		switch alg := params.PRF.Algorithm; {
+		case len(alg) == 0, alg.Equal(oidHMACSHA1):
+			prf = sha1.New
+		case alg.Equal(oidHMACSHA256):
+			prf = sha256.New
+		case alg.Equal(oidHMACSHA512):
+			prf = sha512.New
+		default:
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %s", alg)
+		}
+		return pbkdf2.Key(passphrase, params.Salt, params.IterationCount, keyLen, prf), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function %s", kdf.Algorithm)
+	}
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, ErrIncorrectPassphrase
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -1,8 +1,12 @@
 package trust
 
 import (
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"fmt"
+	"sort"
+	"time"
 )
 
 // ProviderPool is a collection of provider objects, used to collectively manage the
@@ -50,3 +54,250 @@ func (pool ProviderPool) GetCertPool(includeSystem bool) (_ *x509.CertPool, err
 
 	return certPool, nil
 }
+
+// Fingerprint returns the hex encoded SHA-256 hash of the raw certificate, which is used
+// to identify certificates when comparing provider pools.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+//===========================================================================
+// Pool Diffs
+//===========================================================================
+
+// PoolDiff describes the changes between an original and an updated provider pool.
+// Providers are matched by name (the common name of the leaf certificate) and their
+// chains are compared by certificate fingerprint.
+type PoolDiff struct {
+	Added   ProviderPool          // Providers in the updated pool but not the original
+	Removed ProviderPool          // Providers in the original pool but not the updated
+	Changed map[string]*ChainDiff // Providers in both pools with different certificates
+}
+
+// ChainDiff describes the certificates that were added to or removed from a chain.
+type ChainDiff struct {
+	Added   []*x509.Certificate
+	Removed []*x509.Certificate
+}
+
+// Diff returns the changes required to go from the pool to the updated pool.
+func (pool ProviderPool) Diff(updated ProviderPool) (diff *PoolDiff, err error) {
+	diff = &PoolDiff{
+		Added:   make(ProviderPool),
+		Removed: make(ProviderPool),
+		Changed: make(map[string]*ChainDiff),
+	}
+
+	for name, p := range pool {
+		if _, ok := updated[name]; !ok {
+			diff.Removed[name] = p
+		}
+	}
+
+	for name, p := range updated {
+		var original *Provider
+		if original = pool[name]; original == nil {
+			diff.Added[name] = p
+			continue
+		}
+
+		var chain *ChainDiff
+		if chain, err = diffChains(original, p); err != nil {
+			return nil, fmt.Errorf("could not compare %s: %w", name, err)
+		}
+
+		if chain != nil {
+			diff.Changed[name] = chain
+		}
+	}
+	return diff, nil
+}
+
+// IsEmpty returns true if there are no differences between the pools.
+func (d *PoolDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Returns nil if the chains contain the same certificates.
+func diffChains(original, updated *Provider) (_ *ChainDiff, err error) {
+	var a, b []*x509.Certificate
+	if a, err = original.GetCertificates(); err != nil {
+		return nil, err
+	}
+	if b, err = updated.GetCertificates(); err != nil {
+		return nil, err
+	}
+
+	diff := &ChainDiff{
+		Added:   subtractCertificates(b, a),
+		Removed: subtractCertificates(a, b),
+	}
+
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// Returns the certificates in a that are not in b.
+func subtractCertificates(a, b []*x509.Certificate) []*x509.Certificate {
+	fingerprints := make(map[string]struct{}, len(b))
+	for _, cert := range b {
+		fingerprints[Fingerprint(cert)] = struct{}{}
+	}
+
+	out := make([]*x509.Certificate, 0)
+	for _, cert := range a {
+		if _, ok := fingerprints[Fingerprint(cert)]; !ok {
+			out = append(out, cert)
+		}
+	}
+	return out
+}
+
+//===========================================================================
+// Merging and Filtering Pools
+//===========================================================================
+
+// ConflictPolicy determines how to handle providers with the same name but different
+// certificate chains when merging provider pools.
+type ConflictPolicy uint8
+
+const (
+	KeepExisting    ConflictPolicy = iota // Keep the provider from the original pool
+	ReplaceExisting                       // Replace with the provider from the other pool
+	KeepNewest                            // Keep the provider whose chain expires last
+	FailOnConflict                        // Return ErrPoolConflict
+)
+
+// Merge the other pool into a copy of this pool, resolving providers that have the same
+// name but different certificates using the conflict policy. Neither pool is modified.
+func (pool ProviderPool) Merge(other ProviderPool, policy ConflictPolicy) (merged ProviderPool, err error) {
+	merged = make(ProviderPool, len(pool))
+	for name, p := range pool {
+		merged[name] = p
+	}
+
+	for name, p := range other {
+		var existing *Provider
+		if existing = merged[name]; existing == nil {
+			merged[name] = p
+			continue
+		}
+
+		var diff *ChainDiff
+		if diff, err = diffChains(existing, p); err != nil {
+			return nil, fmt.Errorf("could not compare %s: %w", name, err)
+		}
+
+		if diff == nil {
+			continue
+		}
+
+		switch policy {
+		case KeepExisting:
+			continue
+		case ReplaceExisting:
+			merged[name] = p
+		case KeepNewest:
+			var a, b time.Time
+			if a, err = existing.Expires(); err != nil {
+				return nil, err
+			}
+			if b, err = p.Expires(); err != nil {
+				return nil, err
+			}
+
+			if b.After(a) {
+				merged[name] = p
+			}
+		case FailOnConflict:
+			return nil, fmt.Errorf("%w: %s", ErrPoolConflict, name)
+		default:
+			return nil, fmt.Errorf("unknown conflict policy %d", policy)
+		}
+	}
+	return merged, nil
+}
+
+// ProviderFilter returns true if the provider should be kept in a filtered pool.
+type ProviderFilter func(p *Provider) bool
+
+// Filter returns a new pool with only the providers that match all of the filters.
+func (pool ProviderPool) Filter(filters ...ProviderFilter) ProviderPool {
+	filtered := make(ProviderPool)
+providers:
+	for name, p := range pool {
+		for _, filter := range filters {
+			if !filter(p) {
+				continue providers
+			}
+		}
+		filtered[name] = p
+	}
+	return filtered
+}
+
+// RemoveExpired deletes all providers from the pool whose chains are expired (or cannot
+// be parsed) at the specified time, returning the names of the removed providers.
+func (pool ProviderPool) RemoveExpired(now time.Time) (removed []string) {
+	for name, p := range pool {
+		if expires, err := p.Expires(); err != nil || expires.Before(now) {
+			delete(pool, name)
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// IssuedBy matches providers whose leaf certificate was issued by a certificate
+// authority with the specified common name or organization.
+func IssuedBy(issuer string) ProviderFilter {
+	return func(p *Provider) bool {
+		cert, err := p.GetLeafCertificate()
+		if err != nil {
+			return false
+		}
+
+		if cert.Issuer.CommonName == issuer {
+			return true
+		}
+
+		for _, org := range cert.Issuer.Organization {
+			if org == issuer {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ExpiresBefore matches providers with a chain that expires before the specified time.
+func ExpiresBefore(t time.Time) ProviderFilter {
+	return func(p *Provider) bool {
+		expires, err := p.Expires()
+		if err != nil {
+			return false
+		}
+		return expires.Before(t)
+	}
+}
+
+// ValidAt matches providers whose certificates are all valid at the specified time.
+func ValidAt(t time.Time) ProviderFilter {
+	return func(p *Provider) bool {
+		certs, err := p.GetCertificates()
+		if err != nil {
+			return false
+		}
+
+		for _, cert := range certs {
+			if t.Before(cert.NotBefore) || t.After(cert.NotAfter) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,116 @@
+package trust_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trisacrypto/trisa/pkg/trust"
+	"github.com/trisacrypto/trisa/pkg/trust/mock"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestPoolDiff(t *testing.T) {
+	pool := loadPoolFixture(t)
+
+	// A pool should not differ from itself
+	diff, err := pool.Diff(pool)
+	require.NoError(t, err)
+	require.True(t, diff.IsEmpty())
+
+	// Reissue the mock certificate and remove the fixture
+	reissued := loadMockProvider(t)
+	updated := trust.NewPool(reissued)
+
+	diff, err = pool.Diff(updated)
+	require.NoError(t, err)
+	require.False(t, diff.IsEmpty())
+	require.Empty(t, diff.Added)
+	require.Len(t, diff.Removed, 1)
+	require.Contains(t, diff.Removed, "docs.trisa.dev")
+	require.Len(t, diff.Changed, 1)
+	require.Contains(t, diff.Changed, "Test")
+
+	// Only the leaf certificate should have changed, the CAs are the same
+	changed := diff.Changed["Test"]
+	require.Len(t, changed.Added, 1)
+	require.Len(t, changed.Removed, 1)
+	leaf, err := reissued.GetLeafCertificate()
+	require.NoError(t, err)
+	require.Equal(t, trust.Fingerprint(leaf), trust.Fingerprint(changed.Added[0]))
+
+	// Reverse the diff
+	diff, err = updated.Diff(pool)
+	require.NoError(t, err)
+	require.Len(t, diff.Added, 1)
+	require.Contains(t, diff.Added, "docs.trisa.dev")
+	require.Empty(t, diff.Removed)
+	require.Len(t, diff.Changed, 1)
+}
+
+func TestPoolMerge(t *testing.T) {
+	pool := loadPoolFixture(t)
+	reissued := loadMockProvider(t)
+	other := trust.NewPool(reissued)
+
+	merged, err := pool.Merge(other, trust.KeepExisting)
+	require.NoError(t, err)
+	requirePoolsEqual(t, pool, merged)
+
+	merged, err = pool.Merge(other, trust.ReplaceExisting)
+	require.NoError(t, err)
+	require.Len(t, merged, 2)
+	require.Same(t, other["Test"], merged["Test"])
+	require.NotSame(t, other["Test"], pool["Test"], "original pool should not be modified")
+
+	// The reissued certificate is created later so it expires later
+	merged, err = pool.Merge(other, trust.KeepNewest)
+	require.NoError(t, err)
+	require.Same(t, other["Test"], merged["Test"])
+
+	_, err = pool.Merge(other, trust.FailOnConflict)
+	require.ErrorIs(t, err, trust.ErrPoolConflict)
+
+	// Identical providers should not conflict
+	merged, err = pool.Merge(pool, trust.FailOnConflict)
+	require.NoError(t, err)
+	requirePoolsEqual(t, pool, merged)
+}
+
+func TestPoolFilter(t *testing.T) {
+	pool := loadPoolFixture(t)
+
+	filtered := pool.Filter(trust.IssuedBy("Test Intermediate CA"))
+	require.Len(t, filtered, 1)
+	require.Contains(t, filtered, "Test")
+
+	filtered = pool.Filter(trust.IssuedBy("TRISA"))
+	require.Len(t, filtered, 1)
+	require.Contains(t, filtered, "docs.trisa.dev")
+
+	filtered = pool.Filter(trust.ExpiresBefore(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.Len(t, filtered, 1)
+	require.Contains(t, filtered, "docs.trisa.dev")
+
+	filtered = pool.Filter(trust.ValidAt(time.Now()), trust.IssuedBy("TRISA"))
+	require.Empty(t, filtered)
+
+	require.Len(t, pool.Filter(), 2)
+
+	removed := pool.RemoveExpired(time.Now())
+	require.Equal(t, []string{"docs.trisa.dev"}, removed)
+	require.Len(t, pool, 1)
+	require.Contains(t, pool, "Test")
+}
+
+func loadMockProvider(t *testing.T) *trust.Provider {
+	// Ensure the reissued certificate has a later expiration than the original
+	time.Sleep(time.Second)
+
+	data, err := mock.Chain()
+	require.NoError(t, err, "could not create mock chain")
+
+	provider, err := trust.Decrypt(data, pkcs12.DefaultPassword)
+	require.NoError(t, err, "could not decrypt mock chain")
+	return provider
+}
@@ -14,6 +14,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"time"
 
 	"software.sslmate.com/src/go-pkcs12"
 )
@@ -112,6 +113,8 @@ func (p *Provider) Decode(in []byte) (err error) {
 			if p.key, err = ParsePrivateKey(block); err != nil {
 				return err
 			}
+		case BlockEncryptedKey:
+			return ErrPassphraseRequired
 		default:
 			return fmt.Errorf("unhandled block type %q", block.Type)
 		}
@@ -208,6 +211,41 @@ func (p *Provider) GetLeafCertificate() (*x509.Certificate, error) {
 	return x509.ParseCertificate(p.chain.Certificate[0])
 }
 
+// GetCertificates returns the parsed x509 certificates of the chain, starting with the
+// leaf certificate, returning an error if there are no certificates or a parse error.
+func (p *Provider) GetCertificates() (certs []*x509.Certificate, err error) {
+	if len(p.chain.Certificate) == 0 {
+		return nil, ErrNoCertificates
+	}
+
+	certs = make([]*x509.Certificate, 0, len(p.chain.Certificate))
+	for i, asn1Data := range p.chain.Certificate {
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(asn1Data); err != nil {
+			return nil, fmt.Errorf("could not parse certificate %d: %s", i, err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// Expires returns the earliest expiration time of the certificates in the chain, which
+// is the time after which the chain can no longer be verified.
+func (p *Provider) Expires() (_ time.Time, err error) {
+	var certs []*x509.Certificate
+	if certs, err = p.GetCertificates(); err != nil {
+		return time.Time{}, err
+	}
+
+	expires := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(expires) {
+			expires = cert.NotAfter
+		}
+	}
+	return expires, nil
+}
+
 // GetKey returns the private key, or nil if this is a public provider.
 func (p *Provider) GetKey() interface{} {
 	return p.key
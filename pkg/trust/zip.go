@@ -21,17 +21,44 @@ const (
 	CompressionAuto = "auto"
 )
 
+// Pool formats
+const (
+	FormatTruststore = ".pfx"
+	FormatBundle     = ".bundle"
+	FormatDirectory  = ".dir"
+)
+
 var validFormats = map[string]struct{}{
-	CompressionGZIP: {},
-	CompressionZIP:  {},
-	CompressionNone: {},
-	CompressionAuto: {},
+	CompressionGZIP:  {},
+	CompressionZIP:   {},
+	CompressionNone:  {},
+	CompressionAuto:  {},
+	FormatTruststore: {},
+	FormatBundle:     {},
+	FormatDirectory:  {},
+}
+
+// Formats that can contain multiple providers.
+var poolFormats = map[string]struct{}{
+	CompressionZIP:   {},
+	FormatTruststore: {},
+	FormatBundle:     {},
+	FormatDirectory:  {},
 }
 
 var extensionAliases = map[string]string{
-	".crt":  CompressionNone,
-	".p12":  CompressionNone,
-	".gzip": CompressionGZIP,
+	".crt":        CompressionNone,
+	".p12":        CompressionNone,
+	".gzip":       CompressionGZIP,
+	".truststore": FormatTruststore,
+}
+
+// Extensions of files that are read from a directory of PEM certificates.
+var directoryExtensions = map[string]struct{}{
+	".pem":    {},
+	".crt":    {},
+	".cer":    {},
+	".bundle": {},
 }
 
 // Serializer maintains options for compression, encoding, and pkcs12 encryption when
@@ -52,6 +79,13 @@ type Serializer struct {
 	// during extraction the format is detected from the file extension, otherwise an
 	// error is returned if directly from bytes. During compression if auto, then the
 	// format is "gz" for Providers and "zip" for ProviderPools.
+	//
+	// ProviderPools can additionally be serialized as a PKCS12 truststore containing
+	// only certificates ("pfx"), an annotated PEM bundle ("bundle"), or a directory
+	// with an annotated PEM file per provider ("dir"). Java KeyStore (JKS) files are
+	// not supported; Java services should load the truststore as a PKCS12 keystore.
+	// When reading a pool with the auto format, directories are detected from the path
+	// and the format of data that has no file extension is detected from its contents.
 	Format string
 
 	// Internal helper fields
@@ -120,7 +154,7 @@ func (s *Serializer) Read(r io.Reader) (_ *Provider, err error) {
 			return nil, err
 		}
 		fallthrough
-	case CompressionNone, CompressionZIP:
+	case CompressionNone, CompressionZIP, FormatBundle:
 		if data, err = io.ReadAll(r); err != nil {
 			return nil, err
 		}
@@ -138,14 +172,27 @@ func (s *Serializer) Read(r io.Reader) (_ *Provider, err error) {
 // ReadPool and extract a ProviderPool from the reader objects. If no readers are
 // provided then an empty pool is returned. If the Format is CompressionZip, this method
 // expects that the archive has been opened and is operating on all internal readers.
+// Each reader is decoded as a single provider unless the format is a truststore or an
+// annotated bundle, which may contain multiple certificate chains.
 func (s *Serializer) ReadPool(readers ...io.Reader) (pool ProviderPool, err error) {
+	// Set internal fields for downstream computation
+	s.multiple = false
+
 	pool = make(ProviderPool)
 	for _, r := range readers {
-		var p *Provider
-		if p, err = s.Read(r); err != nil {
+		var data []byte
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+
+		var mode string
+		if mode, err = s.readFormat(data); err != nil {
+			return nil, err
+		}
+
+		if err = s.decodePool(pool, mode, data); err != nil {
 			return nil, err
 		}
-		pool.Add(p)
 	}
 	return pool, nil
 }
@@ -195,8 +242,8 @@ func (s *Serializer) ReadFile(path string) (p *Provider, err error) {
 }
 
 // ReadPoolFile and extract a ProviderPool from it. This method primarily expects a Zip
-// archive with multiple public provider files contained.
-// TODO: handle directories
+// archive with multiple public provider files contained, but can also read truststores,
+// PEM bundles, and directories of PEM encoded certificate files.
 func (s *Serializer) ReadPoolFile(path string) (p ProviderPool, err error) {
 	// Set internal fields for downstream computation
 	s.path = path
@@ -226,11 +273,40 @@ func (s *Serializer) ReadPoolFile(path string) (p ProviderPool, err error) {
 		return s.ReadPool(readers...)
 	}
 
+	if mode == FormatDirectory {
+		var entries []os.DirEntry
+		if entries, err = os.ReadDir(path); err != nil {
+			return nil, err
+		}
+
+		pool := make(ProviderPool)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			if _, ok := directoryExtensions[filepath.Ext(entry.Name())]; !ok {
+				continue
+			}
+
+			var data []byte
+			if data, err = os.ReadFile(filepath.Join(path, entry.Name())); err != nil {
+				return nil, err
+			}
+
+			if err = s.decodePool(pool, FormatBundle, data); err != nil {
+				return nil, fmt.Errorf("could not read %s: %w", entry.Name(), err)
+			}
+		}
+		return pool, nil
+	}
+
 	// Handle all other file types
 	var f *os.File
 	if f, err = os.Open(path); err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
 	return s.ReadPool(f)
 }
@@ -270,6 +346,25 @@ func (s *Serializer) Write(p *Provider, w io.Writer) (err error) {
 		return err
 	}
 
+	// Annotated bundles are written directly, appending the key if private
+	if mode == FormatBundle {
+		var data []byte
+		if data, err = EncodeBundle(p); err != nil {
+			return err
+		}
+
+		if s.Private && p.key != nil {
+			var key []byte
+			if key, err = PEMEncodePrivateKey(p.key); err != nil {
+				return err
+			}
+			data = append(data, key...)
+		}
+
+		_, err = w.Write(data)
+		return err
+	}
+
 	// Serialize the data
 	var data []byte
 	if s.Private {
@@ -324,6 +419,22 @@ func (s *Serializer) WritePool(pool ProviderPool, w io.Writer) (err error) {
 
 	// Compress and write the data to disk
 	switch mode {
+	case FormatBundle:
+		var data []byte
+		if data, err = EncodeBundle(pool.sorted()...); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatTruststore:
+		var data []byte
+		if data, err = EncodeTruststore(pool, s.Password); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatDirectory:
+		return ErrDirectoryPath
 	case CompressionGZIP:
 		archive := gzip.NewWriter(w)
 		for _, p := range pool {
@@ -393,20 +504,69 @@ func (s *Serializer) WriteFile(p *Provider, path string) (err error) {
 	return s.Write(p, f)
 }
 
-// WritePoolFile with the encoded provider pool object.
+// WritePoolFile with the encoded provider pool object. If the format is a directory,
+// the directory is created if it does not exist and an annotated PEM file is written
+// for each provider in the pool.
 func (s *Serializer) WritePoolFile(pool ProviderPool, path string) (err error) {
 	// Set internal fields for downstream computation
 	s.path = path
 	s.multiple = len(pool) > 1
 
+	var mode string
+	if mode, err = s.getFormat(); err != nil {
+		return err
+	}
+
+	if mode == FormatDirectory {
+		if err = os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+
+		// Common names that differ only by case or spacing map to the same file name
+		names := make(map[string]struct{}, len(pool))
+		for _, p := range pool.sorted() {
+			var data []byte
+			if data, err = EncodeBundle(p.Public()); err != nil {
+				return err
+			}
+
+			base := directoryFileName(p.String())
+			name := base + CompressionNone
+			for n := 1; ; n++ {
+				if _, ok := names[name]; !ok {
+					break
+				}
+				name = fmt.Sprintf("%s-%d%s", base, n, CompressionNone)
+			}
+			names[name] = struct{}{}
+
+			if err = os.WriteFile(filepath.Join(path, name), data, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	var f *os.File
 	if f, err = os.Create(path); err != nil {
 		return err
 	}
+	defer f.Close()
 
 	return s.WritePool(pool, f)
 }
 
+// Returns a file name for the provider with the given common name that cannot escape
+// the pool directory.
+func directoryFileName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer(" ", "_", "/", "_", "\\", "_").Replace(name)
+	if name == "" || name == "." || name == ".." {
+		return "provider"
+	}
+	return name
+}
+
 func (s *Serializer) getFormat() (string, error) {
 	if s.Format == "" {
 		s.Format = CompressionAuto
@@ -421,6 +581,9 @@ func (s *Serializer) getFormat() (string, error) {
 	var ext string
 	if s.path != "" {
 		ext = filepath.Ext(s.path)
+		if info, err := os.Stat(s.path); (err == nil && info.IsDir()) || strings.HasSuffix(s.path, string(filepath.Separator)) {
+			ext = FormatDirectory
+		}
 	}
 
 	// Handle extension aliases
@@ -446,13 +609,124 @@ func (s *Serializer) getFormat() (string, error) {
 		return "", fmt.Errorf("cannot write %s format to %s", s.Format, s.path)
 	}
 
-	if s.multiple && s.Format != CompressionZIP {
+	if _, ok := poolFormats[s.Format]; s.multiple && !ok {
 		return "", fmt.Errorf("cannot write multiple providers to %s", s.Format)
 	}
 
 	return s.Format, nil
 }
 
+// Returns the format to read the data with; if the format is auto and there is no
+// file extension to detect the format from, then the format is detected from the data.
+func (s *Serializer) readFormat(data []byte) (mode string, err error) {
+	if mode, err = s.getFormat(); err != nil {
+		return "", err
+	}
+
+	if s.Format == CompressionAuto && filepath.Ext(s.path) == "" {
+		switch {
+		case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+			mode = CompressionGZIP
+		case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+			mode = CompressionZIP
+		case len(data) > 0 && data[0] == 0x30:
+			// DER encoded PKCS12 data is either a private provider or a truststore
+			if s.Private {
+				mode = CompressionNone
+			} else {
+				mode = FormatTruststore
+			}
+		default:
+			mode = FormatBundle
+		}
+	}
+	return mode, nil
+}
+
+// Decode the data in the specified format and add all of its providers to the pool.
+func (s *Serializer) decodePool(pool ProviderPool, mode string, data []byte) (err error) {
+	switch mode {
+	case CompressionGZIP:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return err
+		}
+
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+		return s.decodePool(pool, CompressionNone, data)
+	case CompressionZIP:
+		// Data is either an opened file from an archive or an archive in memory
+		if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+			return s.decodePool(pool, CompressionNone, data)
+		}
+
+		var archive *zip.Reader
+		if archive, err = zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+			return err
+		}
+
+		for _, f := range archive.File {
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err != nil {
+				return err
+			}
+
+			var contents []byte
+			contents, err = io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+
+			if err = s.decodePool(pool, CompressionNone, contents); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatTruststore:
+		var truststore ProviderPool
+		if truststore, err = DecodeTruststore(data, s.Password); err != nil {
+			return err
+		}
+
+		for _, p := range truststore {
+			pool.Add(p)
+		}
+		return nil
+	case CompressionNone:
+		var p *Provider
+		if s.Private {
+			if p, err = Decrypt(data, s.Password); err != nil {
+				return err
+			}
+		} else {
+			if p, err = New(data); err != nil {
+				return err
+			}
+		}
+		pool.Add(p)
+		return nil
+	case FormatBundle:
+		if s.Private {
+			return s.decodePool(pool, CompressionNone, data)
+		}
+
+		var providers []*Provider
+		if providers, err = DecodeBundle(data); err != nil {
+			return err
+		}
+
+		for _, p := range providers {
+			pool.Add(p)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unhandled format %q", mode)
+	}
+}
+
 func (s *Serializer) getZipExt() string {
 	if s.Private {
 		return ".p12"